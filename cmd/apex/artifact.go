@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/lyndonlyu/apex/internal/artifact"
 	"github.com/lyndonlyu/apex/internal/manifest"
@@ -13,8 +14,12 @@ import (
 )
 
 var artifactRunFilter string
+var artifactTypeFilter string
+var artifactNodeFilter string
+var artifactSinceFilter string
 var artifactGCDryRun bool
 var artifactImpactFormat string
+var artifactSetType string
 
 var artifactCmd = &cobra.Command{
 	Use:   "artifact",
@@ -41,10 +46,35 @@ var artifactGCCmd = &cobra.Command{
 }
 
 var artifactImpactCmd = &cobra.Command{
-	Use:   "impact <hash>",
-	Short: "Show downstream impact of an artifact",
-	Args:  cobra.ExactArgs(1),
-	RunE:  impactArtifact,
+	Use:   "impact <hash | file>",
+	Short: "Show downstream impact of an artifact and the runs that produced or consumed it",
+	Long: `Show the artifacts derived from an artifact and the run nodes that produced
+or consumed it. Given a file instead of a hash, its current content is looked up.`,
+	Args: cobra.ExactArgs(1),
+	RunE: impactArtifact,
+}
+
+var artifactSchemaCmd = &cobra.Command{
+	Use:   "schema <hash> <schema.json>",
+	Short: "Validate an artifact against a JSON schema and attach it",
+	Args:  cobra.ExactArgs(2),
+	RunE:  schemaArtifact,
+}
+
+var artifactTypeCmd = &cobra.Command{
+	Use:   "type <hash> <type>",
+	Short: "Set the type of an artifact",
+	Long:  "Set the type of an artifact: " + joinTypes() + ".",
+	Args:  cobra.ExactArgs(2),
+	RunE:  typeArtifact,
+}
+
+var artifactVerifyCmd = &cobra.Command{
+	Use:   "verify [hash...]",
+	Short: "Rehash stored blobs to detect corruption",
+	Long: `Rehash the blobs of the given artifacts, or of every artifact, and report
+the ones that are missing or no longer match their hash or size.`,
+	RunE: verifyArtifacts,
 }
 
 var artifactDepsCmd = &cobra.Command{
@@ -56,9 +86,13 @@ var artifactDepsCmd = &cobra.Command{
 
 func init() {
 	artifactListCmd.Flags().StringVar(&artifactRunFilter, "run", "", "Filter by run ID")
+	artifactListCmd.Flags().StringVar(&artifactTypeFilter, "type", "", "Filter by artifact type")
+	artifactListCmd.Flags().StringVar(&artifactNodeFilter, "node", "", "Filter by node ID")
+	artifactListCmd.Flags().StringVar(&artifactSinceFilter, "since", "", "Only artifacts created within this duration (e.g. 24h)")
 	artifactGCCmd.Flags().BoolVar(&artifactGCDryRun, "dry-run", false, "Preview without deleting")
 	artifactImpactCmd.Flags().StringVar(&artifactImpactFormat, "format", "", "Output format (json)")
-	artifactCmd.AddCommand(artifactListCmd, artifactInfoCmd, artifactGCCmd, artifactImpactCmd, artifactDepsCmd)
+	artifactCmd.AddCommand(artifactListCmd, artifactInfoCmd, artifactGCCmd, artifactImpactCmd, artifactDepsCmd,
+		artifactSchemaCmd, artifactTypeCmd, artifactVerifyCmd)
 }
 
 func listArtifacts(cmd *cobra.Command, args []string) error {
@@ -67,17 +101,23 @@ func listArtifacts(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	store := artifact.NewStore(filepath.Join(home, ".apex", "artifacts"))
+	defer store.Close()
 
-	var arts []*artifact.Artifact
-	var listErr error
-
-	if artifactRunFilter != "" {
-		arts, listErr = store.ListByRun(artifactRunFilter)
-	} else {
-		arts, listErr = store.List()
+	q := artifact.Query{
+		RunID:  artifactRunFilter,
+		NodeID: artifactNodeFilter,
+		Type:   artifact.Type(artifactTypeFilter),
 	}
-	if listErr != nil {
-		return fmt.Errorf("artifact list: %w", listErr)
+	if artifactSinceFilter != "" {
+		since, parseErr := time.ParseDuration(artifactSinceFilter)
+		if parseErr != nil {
+			return fmt.Errorf("artifact list: invalid --since %q: %w", artifactSinceFilter, parseErr)
+		}
+		q.Since = time.Now().Add(-since)
+	}
+	arts, err := store.Find(q)
+	if err != nil {
+		return fmt.Errorf("artifact list: %w", err)
 	}
 
 	if len(arts) == 0 {
@@ -85,7 +125,7 @@ func listArtifacts(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Printf("%-12s  %-20s  %-12s  %s\n", "HASH", "NAME", "RUN", "SIZE")
+	fmt.Printf("%-12s  %-20s  %-16s  %-12s  %s\n", "HASH", "NAME", "TYPE", "RUN", "SIZE")
 	for _, a := range arts {
 		short := a.Hash
 		if len(short) > 12 {
@@ -95,7 +135,7 @@ func listArtifacts(cmd *cobra.Command, args []string) error {
 		if len(runShort) > 12 {
 			runShort = runShort[:12]
 		}
-		fmt.Printf("%-12s  %-20s  %-12s  %s\n", short, a.Name, runShort, humanSize(a.Size))
+		fmt.Printf("%-12s  %-20s  %-16s  %-12s  %s\n", short, a.Name, a.Type, runShort, humanSize(a.Size))
 	}
 	return nil
 }
@@ -106,6 +146,7 @@ func infoArtifact(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	store := artifact.NewStore(filepath.Join(home, ".apex", "artifacts"))
+	defer store.Close()
 
 	a, err := store.Get(args[0])
 	if err != nil {
@@ -117,16 +158,103 @@ func infoArtifact(cmd *cobra.Command, args []string) error {
 	fmt.Printf("RunID:     %s\n", a.RunID)
 	fmt.Printf("NodeID:    %s\n", a.NodeID)
 	fmt.Printf("Size:      %s\n", humanSize(a.Size))
+	if a.Encoding != "" {
+		fmt.Printf("Encoding:  %s\n", a.Encoding)
+	}
 	fmt.Printf("CreatedAt: %s\n", a.CreatedAt)
+	if a.Type != "" {
+		fmt.Printf("Type:      %s (context policy %s)\n", a.Type, a.Type.Policy())
+	}
+	if a.NormHash != "" {
+		fmt.Printf("NormHash:  %s\n", a.NormHash)
+	}
+	if a.SchemaHash != "" {
+		fmt.Printf("Schema:    %s\n", a.SchemaHash)
+	}
+	for _, r := range a.Producers {
+		fmt.Printf("Producer:  %s/%s\n", r.RunID, r.NodeID)
+	}
+	for _, r := range a.Consumers {
+		fmt.Printf("Consumer:  %s/%s\n", r.RunID, r.NodeID)
+	}
+	return nil
+}
+
+func schemaArtifact(cmd *cobra.Command, args []string) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	store := artifact.NewStore(filepath.Join(home, ".apex", "artifacts"))
+	defer store.Close()
+
+	schema, err := os.ReadFile(args[1])
+	if err != nil {
+		return fmt.Errorf("artifact schema: %w", err)
+	}
+	a, err := store.AttachSchema(args[0], schema)
+	if err != nil {
+		return fmt.Errorf("artifact schema: %w", err)
+	}
+	fmt.Printf("Attached schema %s to %s\n", shortHash(a.SchemaHash), shortHash(a.Hash))
+	return nil
+}
+
+func typeArtifact(cmd *cobra.Command, args []string) error {
+	t, err := artifact.ParseType(args[1])
+	if err != nil {
+		return fmt.Errorf("artifact type: %w (want %s)", err, joinTypes())
+	}
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	store := artifact.NewStore(filepath.Join(home, ".apex", "artifacts"))
+	defer store.Close()
+	if err := store.SetType(args[0], t); err != nil {
+		return fmt.Errorf("artifact type: %w", err)
+	}
+	fmt.Printf("%s is now %s\n", shortHash(args[0]), t)
+	return nil
+}
+
+func verifyArtifacts(cmd *cobra.Command, args []string) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	store := artifact.NewStore(filepath.Join(home, ".apex", "artifacts"))
+	defer store.Close()
+
+	checked, problems, err := store.Verify(args...)
+	if err != nil {
+		return fmt.Errorf("artifact verify: %w", err)
+	}
+	for _, p := range problems {
+		fmt.Printf("  %s  %-20s  %s\n", shortHash(p.Hash), p.Name, p.Reason)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("artifact verify: %d of %d blob(s) corrupt or missing", len(problems), checked)
+	}
+	fmt.Printf("Verified %d artifact(s): all blobs match their hashes.\n", checked)
 	return nil
 }
 
+func joinTypes() string {
+	names := make([]string, len(artifact.Types))
+	for i, t := range artifact.Types {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
 func gcArtifacts(cmd *cobra.Command, args []string) error {
 	home, err := homeDir()
 	if err != nil {
 		return err
 	}
 	artStore := artifact.NewStore(filepath.Join(home, ".apex", "artifacts"))
+	defer artStore.Close()
 	manStore := manifest.NewStore(filepath.Join(home, ".apex", "runs"))
 
 	// Collect valid run IDs from manifest store.
@@ -144,15 +272,31 @@ func gcArtifacts(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("artifact gc: find orphans: %w", err)
 	}
 
+	cache := artifact.NewCache(artStore)
 	if len(orphans) == 0 {
 		fmt.Println("No orphan artifacts found.")
-		return nil
+		return pruneCache(cache)
 	}
 
 	if artifactGCDryRun {
 		fmt.Printf("[dry-run] Would remove %d orphan artifact(s):\n", len(orphans))
+		orphaned := make(map[string]bool, len(orphans))
 		for _, o := range orphans {
 			fmt.Printf("  %s  %s\n", o.Hash[:12], o.Name)
+			orphaned[o.Hash] = true
+		}
+		entries, cacheErr := cache.Entries()
+		if cacheErr != nil {
+			return fmt.Errorf("artifact gc: %w", cacheErr)
+		}
+		stale := 0
+		for _, e := range entries {
+			if orphaned[e.ResultHash] {
+				stale++
+			}
+		}
+		if stale > 0 {
+			fmt.Printf("[dry-run] Would drop %d result cache entries pointing at them.\n", stale)
 		}
 		return nil
 	}
@@ -166,6 +310,21 @@ func gcArtifacts(cmd *cobra.Command, args []string) error {
 		removed++
 	}
 	fmt.Printf("Removed %d orphan artifact(s).\n", removed)
+	return pruneCache(cache)
+}
+
+// pruneCache drops the result cache entries whose result artifact is gone.
+func pruneCache(cache *artifact.Cache) error {
+	if artifactGCDryRun {
+		return nil
+	}
+	dropped, err := cache.Prune()
+	if err != nil {
+		return fmt.Errorf("artifact gc: prune result cache: %w", err)
+	}
+	if len(dropped) > 0 {
+		fmt.Printf("Dropped %d stale result cache entries.\n", len(dropped))
+	}
 	return nil
 }
 
@@ -181,7 +340,15 @@ func impactArtifact(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("artifact impact: %w", err)
 	}
 
-	result := lg.Impact(args[0])
+	hash := args[0]
+	if info, statErr := os.Stat(hash); statErr == nil && info.Mode().IsRegular() {
+		data, readErr := os.ReadFile(hash)
+		if readErr != nil {
+			return fmt.Errorf("artifact impact: %w", readErr)
+		}
+		hash = artifact.ContentHash(data)
+	}
+	result := lg.Impact(hash)
 
 	if artifactImpactFormat == "json" {
 		fmt.Println(artifact.FormatImpactJSON(result))
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lyndonlyu/apex/internal/artifact"
+	"github.com/lyndonlyu/apex/internal/config"
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+	"github.com/lyndonlyu/apex/internal/instructions"
+	"github.com/lyndonlyu/apex/internal/kg"
+	"github.com/lyndonlyu/apex/internal/manifest"
+	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/repomap"
+	"github.com/spf13/cobra"
+)
+
+var contextExplainFormat string
+var contextExplainPrompt bool
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Inspect how node prompts were assembled",
+}
+
+var contextExplainCmd = &cobra.Command{
+	Use:   "explain <run-id> <node-id>",
+	Short: "Show the context build report for a node",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runContextExplain,
+}
+
+func init() {
+	contextExplainCmd.Flags().StringVar(&contextExplainFormat, "format", "", "Output format (json)")
+	contextExplainCmd.Flags().BoolVar(&contextExplainPrompt, "prompt", false, "Also print the assembled prompt")
+	contextCmd.AddCommand(contextExplainCmd)
+}
+
+// saveContextArtifacts stores the assembled prompt and its build report in
+// the artifact store and returns their content hashes.
+func saveContextArtifacts(store *artifact.Store, runID, nodeID, prompt string, report *apexctx.Report) (string, string, error) {
+	promptArt, err := store.Save(fmt.Sprintf("prompt-%s.md", nodeID), []byte(prompt), runID, nodeID)
+	if err != nil {
+		return "", "", fmt.Errorf("save prompt: %w", err)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal context report: %w", err)
+	}
+	reportArt, err := store.Save(fmt.Sprintf("context-%s.json", nodeID), data, runID, nodeID)
+	if err != nil {
+		return "", "", fmt.Errorf("save context report: %w", err)
+	}
+	return promptArt.Hash, reportArt.Hash, nil
+}
+
+// loadContextReport reads a context build report from the artifact store.
+func loadContextReport(store *artifact.Store, hash string) (*apexctx.Report, error) {
+	data, err := store.Data(hash)
+	if err != nil {
+		return nil, err
+	}
+	var report apexctx.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("unmarshal context report: %w", err)
+	}
+	return &report, nil
+}
+
+func runContextExplain(cmd *cobra.Command, args []string) error {
+	runID, nodeID := args[0], args[1]
+
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(filepath.Join(home, ".apex", "config.yaml"))
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+
+	m, err := manifest.NewStore(filepath.Join(cfg.BaseDir, "runs")).Load(runID)
+	if err != nil {
+		return fmt.Errorf("context explain: load run %s: %w", runID, err)
+	}
+	node := m.Node(nodeID)
+	if node == nil {
+		return fmt.Errorf("context explain: run %s has no node %q", runID, nodeID)
+	}
+	if node.ContextReportHash == "" {
+		return fmt.Errorf("context explain: no context report recorded for node %q", nodeID)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	gate, err := newReadGate(cfg, wd)
+	if err != nil {
+		return err
+	}
+	auditReads(cfg, gate)
+	store := artifact.NewStore(filepath.Join(cfg.BaseDir, "artifacts"))
+	defer store.Close()
+	store.SetGate(gate)
+	report, err := loadContextReport(store, node.ContextReportHash)
+	if err != nil {
+		return fmt.Errorf("context explain: %w", err)
+	}
+
+	if contextExplainFormat == "json" {
+		out, fmtErr := apexctx.FormatReportJSON(report)
+		if fmtErr != nil {
+			return fmtErr
+		}
+		fmt.Println(out)
+	} else {
+		fmt.Printf("Run %s, node %s: %s\n", runID, nodeID, node.Task)
+		fmt.Printf("Prompt: %s\n\n", node.PromptHash)
+		fmt.Print(apexctx.FormatReport(report))
+	}
+
+	if contextExplainPrompt && node.PromptHash != "" {
+		prompt, dataErr := store.Data(node.PromptHash)
+		if dataErr != nil {
+			return fmt.Errorf("context explain: %w", dataErr)
+		}
+		fmt.Printf("\n--- Prompt ---\n%s\n", prompt)
+	}
+	return nil
+}
+
+// repoMapCachePath returns where the repository map for root is cached. Each
+// work tree gets its own file so switching projects does not thrash the cache.
+func repoMapCachePath(baseDir, root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(baseDir, "cache", "repomap", hex.EncodeToString(sum[:8])+".json")
+}
+
+// contextProviders returns the providers every run prompt is built with:
+// project instructions, the repository map for the work tree at dir, and
+// the knowledge graph around the entities a node's task mentions. With a
+// non-nil gate they read through it.
+func contextProviders(cfg *config.Config, dir string, gate apexctx.Gate) []apexctx.Provider {
+	root := repomap.FindRoot(dir)
+	instr := instructions.NewProvider(root, cfg.Context.InstructionFiles,
+		filepath.Join(cfg.BaseDir, "instructions.md"), cfg.Context.InstructionMaxTokens)
+	rmap := repomap.NewProvider(root, repoMapCachePath(cfg.BaseDir, root))
+	var graph *kg.Provider
+	if graphDir, err := kgDir(); err == nil {
+		graph = kg.NewDBProvider(filepath.Join(graphDir, "kg.db"),
+			cfg.Context.KGQueryDepth, cfg.Context.MaxKGNodes)
+	}
+	if gate != nil {
+		instr.SetGate(gate)
+		rmap.SetGate(gate)
+		if graph != nil {
+			graph.SetGate(gate)
+		}
+	}
+	providers := []apexctx.Provider{instr, rmap}
+	if graph != nil {
+		providers = append(providers, graph)
+	}
+	return providers
+}
+
+// memorySearcher adapts a memory snapshot to the context builder so prompts
+// are built from a fixed, versioned view of memory. Paths in hidden, the
+// memories retracted since the snapshot was taken, are left out.
+type memorySearcher struct {
+	snap   *memory.Snapshot
+	hidden map[string]bool
+}
+
+func (m memorySearcher) Search(ctx context.Context, query string, topK int) ([]apexctx.SearchResult, error) {
+	limit := topK
+	if limit > 0 {
+		limit += len(m.hidden)
+	}
+	ranked := m.snap.Rank(query, limit)
+	results := make([]apexctx.SearchResult, 0, len(ranked))
+	for _, r := range ranked {
+		if m.hidden[r.Path] {
+			continue
+		}
+		if topK > 0 && len(results) == topK {
+			break
+		}
+		results = append(results, apexctx.SearchResult{ID: r.Path, Text: r.Text, Score: float32(r.Score), Type: r.Type})
+	}
+	return results, nil
+}
+
+func (m memorySearcher) Version() string {
+	return m.snap.Version
+}
+
+// saveMemorySnapshot archives a memory snapshot in the artifact store. The
+// snapshot version doubles as its artifact hash.
+func saveMemorySnapshot(store *artifact.Store, runID string, snap *memory.Snapshot) error {
+	data, err := snap.Marshal()
+	if err != nil {
+		return err
+	}
+	art, err := store.Save("memory-snapshot.json", data, runID, "")
+	if err != nil {
+		return fmt.Errorf("save memory snapshot: %w", err)
+	}
+	if art.Hash != snap.Version {
+		return fmt.Errorf("memory snapshot stored as %s, expected %s", art.Hash, snap.Version)
+	}
+	return nil
+}
+
+// loadMemorySnapshot reads an archived memory snapshot by version.
+func loadMemorySnapshot(store *artifact.Store, version string) (*memory.Snapshot, error) {
+	data, err := store.Data(version)
+	if err != nil {
+		return nil, fmt.Errorf("load memory snapshot %s: %w", version, err)
+	}
+	return memory.ParseSnapshot(data, version)
+}
+
+// memoryIDs returns the IDs of the memories whose blocks made it into a
+// prompt, so that a later retraction can find what they influenced.
+func memoryIDs(store *memory.Store, report *apexctx.Report) []string {
+	var ids []string
+	for _, b := range report.Blocks {
+		if b.Source != "memory" || b.Dropped {
+			continue
+		}
+		if e, ok := store.Lookup(b.Path); ok {
+			ids = append(ids, e.ID)
+		}
+	}
+	return ids
+}
+
+// recordMemoryHits counts every memory block that made it into a prompt as
+// an access, raising its confidence and resetting its decay clock.
+func recordMemoryHits(store *memory.Store, report *apexctx.Report) {
+	for _, b := range report.Blocks {
+		if b.Source != "memory" || b.Dropped {
+			continue
+		}
+		if err := store.RecordHit(b.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: memory hit %s: %v\n", b.Path, err)
+		}
+	}
+}
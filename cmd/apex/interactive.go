@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -8,9 +9,13 @@ import (
 	"strings"
 
 	"github.com/chzyer/readline"
+	"github.com/lyndonlyu/apex/internal/artifact"
 	"github.com/lyndonlyu/apex/internal/config"
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+	"github.com/lyndonlyu/apex/internal/gitdiff"
 	"github.com/lyndonlyu/apex/internal/governance"
-	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/instructions"
+	"github.com/lyndonlyu/apex/internal/repomap"
 	"github.com/spf13/cobra"
 )
 
@@ -20,6 +25,59 @@ type session struct {
 	lastOutput  string
 	attachments []string
 	home        string
+
+	// Work-tree state at session start, used by /diff and, with
+	// context.include_diff, to show each turn what earlier turns changed.
+	cwd      string
+	diffBase *gitdiff.Base
+	tracker  *gitdiff.Tracker
+
+	// gate vets the files read into prompts; nil reads them directly.
+	gate apexctx.Gate
+}
+
+// buildPrompt wraps task with the project instructions and, with
+// context.include_diff, the work-tree diff since the session started,
+// ranking the files changed by the previous turn first. The task is
+// returned unchanged when there is nothing to add.
+func (s *session) buildPrompt(task string) string {
+	instr := instructions.NewProvider(repomap.FindRoot(s.cwd), s.cfg.Context.InstructionFiles,
+		filepath.Join(s.cfg.BaseDir, "instructions.md"), s.cfg.Context.InstructionMaxTokens)
+	if s.gate != nil {
+		instr.SetGate(s.gate)
+	}
+	providers := []apexctx.Provider{instr}
+	if s.cfg.Context.IncludeDiff && s.diffBase != nil {
+		var focus []string
+		if len(s.turns) > 0 {
+			focus = s.tracker.Touched(turnID(len(s.turns) - 1))
+		}
+		diff := gitdiff.NewProvider(s.cwd, *s.diffBase, focus)
+		if s.gate != nil {
+			diff.SetGate(s.gate)
+		}
+		providers = append(providers, diff)
+	}
+	b := apexctx.NewBuilder(apexctx.Options{
+		TokenBudget: s.cfg.Context.TokenBudget,
+		Providers:   providers,
+		Classify:    artifact.FilePolicy,
+	})
+	prompt, report, err := b.BuildWithReport(context.Background(), task)
+	if err != nil || len(report.Blocks) <= 1 {
+		return task
+	}
+	for _, w := range report.Warnings {
+		fmt.Println(styleDim.Render("  warning: " + w))
+	}
+	for _, e := range report.Errors {
+		fmt.Println(styleDim.Render("  skipped: " + e))
+	}
+	return prompt
+}
+
+func turnID(i int) string {
+	return fmt.Sprintf("turn-%d", i)
 }
 
 type turn struct {
@@ -100,6 +158,19 @@ func startInteractive(cmd *cobra.Command, args []string) error {
 	})
 
 	s := &session{cfg: cfg, home: home}
+	if cwd, cwdErr := os.Getwd(); cwdErr == nil {
+		s.cwd = cwd
+		if base, baseErr := gitdiff.Capture(cwd); baseErr == nil {
+			s.diffBase = &base
+			s.tracker = gitdiff.NewTracker(cwd, base)
+		}
+		if gate, gateErr := newReadGate(cfg, cwd); gateErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: read gate: %v\n", gateErr)
+		} else {
+			auditReads(cfg, gate)
+			s.gate = gate
+		}
+	}
 
 	printBanner(cfg)
 
@@ -183,9 +254,17 @@ func startInteractive(cmd *cobra.Command, args []string) error {
 			s.attachments = nil
 		}
 
+		taskInput = s.buildPrompt(taskInput)
+
 		// Execute task
 		fmt.Println() // blank line after input
+		if s.tracker != nil {
+			s.tracker.Begin(turnID(len(s.turns)))
+		}
 		summary, err := runInteractiveTask(s.cfg, taskInput, s.context())
+		if s.tracker != nil {
+			s.tracker.End(turnID(len(s.turns)))
+		}
 		if err != nil {
 			fmt.Println(styleError.Render("  Error: " + err.Error()))
 		}
@@ -204,11 +283,11 @@ func (s *session) saveSession() {
 	if len(s.turns) == 0 {
 		return
 	}
-	memDir := filepath.Join(s.cfg.BaseDir, "memory")
-	store, err := memory.NewStore(memDir)
+	store, closeStore, err := openMemoryStore(s.cfg)
 	if err != nil {
 		return
 	}
+	defer closeStore()
 	var tasks []string
 	for _, t := range s.turns {
 		tasks = append(tasks, t.task)
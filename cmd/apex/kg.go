@@ -14,6 +14,9 @@ var (
 	kgListProject string
 	kgQueryDepth  int
 	kgQueryFormat string
+	kgQueryProj   string
+	kgQueryNS     string
+	kgQueryLimit  int
 )
 
 var kgCmd = &cobra.Command{
@@ -28,10 +31,19 @@ var kgListCmd = &cobra.Command{
 }
 
 var kgQueryCmd = &cobra.Command{
-	Use:   "query <name>",
-	Short: "Query entities by name and show related nodes",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runKGQuery,
+	Use:   "query <name | MATCH ...>",
+	Short: "Query entities by name, or with a MATCH query, and show related nodes",
+	Long: `Look up entities by name and show related nodes, or run a query such as
+
+  MATCH (f:function)-[:calls*1..3]->(g) WHERE g.name = "Open" RETURN f
+  MATCH (f:file)-[:imports]->(p:package {name: "fmt"})
+  MATCH shortestPath((a {name: "main"})-[*]-(b {name: "Open"}))
+
+Relationships point -[]-> or <-[]-, or -[]- for either direction; [:a|b*1..3]
+selects types and hop counts. WHERE takes var.prop = "v", != "v" and
+CONTAINS "v" joined by AND over name, type, project, namespace and id.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runKGQuery,
 }
 
 var kgStatsCmd = &cobra.Command{
@@ -44,28 +56,52 @@ func init() {
 	kgListCmd.Flags().StringVar(&kgListType, "type", "", "Filter by entity type")
 	kgListCmd.Flags().StringVar(&kgListProject, "project", "", "Filter by project")
 	kgQueryCmd.Flags().IntVar(&kgQueryDepth, "depth", 2, "BFS traversal depth")
-	kgQueryCmd.Flags().StringVar(&kgQueryFormat, "format", "", "Output format (json)")
+	kgQueryCmd.Flags().StringVar(&kgQueryFormat, "format", "table", "Output format (table, json, dot)")
+	kgQueryCmd.Flags().StringVar(&kgQueryProj, "project", "", "Only match entities of this project (MATCH queries)")
+	kgQueryCmd.Flags().StringVar(&kgQueryNS, "namespace", "", "Only match entities of this namespace (MATCH queries)")
+	kgQueryCmd.Flags().IntVar(&kgQueryLimit, "limit", kg.DefaultQueryLimit, "Maximum rows or paths when the query has no LIMIT")
 	kgCmd.AddCommand(kgListCmd, kgQueryCmd, kgStatsCmd)
 }
 
-func openGraph() (*kg.Graph, error) {
+// kgDir is where the knowledge graph and its index state live.
+func kgDir() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("kg: home dir: %w", err)
+		return "", fmt.Errorf("kg: home dir: %w", err)
 	}
-	dir := filepath.Join(home, ".claude", "kg")
-	g, err := kg.New(dir)
+	return filepath.Join(home, ".claude", "kg"), nil
+}
+
+// openKGDB opens ~/.claude/kg/kg.db. Its first migration imports the
+// graph.json earlier versions kept in the same directory.
+func openKGDB() (*kg.DB, error) {
+	dir, err := kgDir()
+	if err != nil {
+		return nil, err
+	}
+	db, err := kg.OpenDB(filepath.Join(dir, "kg.db"))
 	if err != nil {
 		return nil, fmt.Errorf("kg: open graph: %w", err)
 	}
-	return g, nil
+	return db, nil
+}
+
+// openGraph opens the knowledge graph. The returned close function
+// releases the database.
+func openGraph() (*kg.Graph, func(), error) {
+	db, err := openKGDB()
+	if err != nil {
+		return nil, nil, err
+	}
+	return kg.NewDBGraph(db), func() { db.Close() }, nil
 }
 
 func runKGList(cmd *cobra.Command, args []string) error {
-	g, err := openGraph()
+	g, closeGraph, err := openGraph()
 	if err != nil {
 		return err
 	}
+	defer closeGraph()
 
 	entities := g.List(kg.EntityType(kgListType), kgListProject)
 	fmt.Print(kg.FormatEntitiesTable(entities))
@@ -73,10 +109,41 @@ func runKGList(cmd *cobra.Command, args []string) error {
 }
 
 func runKGQuery(cmd *cobra.Command, args []string) error {
-	g, err := openGraph()
+	switch kgQueryFormat {
+	case "", "table", "json", "dot":
+	default:
+		return fmt.Errorf("unknown format %q (want table, json or dot)", kgQueryFormat)
+	}
+	var query *kg.Query
+	if kg.IsQuery(args[0]) {
+		q, err := kg.ParseQuery(args[0])
+		if err != nil {
+			return err
+		}
+		query = q
+	}
+
+	g, closeGraph, err := openGraph()
 	if err != nil {
 		return err
 	}
+	defer closeGraph()
+
+	if query != nil {
+		res, err := g.Query(query, kg.QueryOptions{Project: kgQueryProj, Namespace: kgQueryNS, Limit: kgQueryLimit})
+		if err != nil {
+			return err
+		}
+		switch kgQueryFormat {
+		case "json":
+			fmt.Println(kg.FormatJSON(res))
+		case "dot":
+			fmt.Print(kg.FormatDOT(res.Entities(), res.Relationships))
+		default:
+			fmt.Print(kg.FormatQueryTable(res))
+		}
+		return nil
+	}
 
 	name := args[0]
 	matches := g.QueryByName(name)
@@ -85,6 +152,18 @@ func runKGQuery(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if kgQueryFormat == "dot" {
+		var ents []*kg.Entity
+		var rels []*kg.Relationship
+		for _, center := range matches {
+			related, r := g.QueryRelated(center.ID, kgQueryDepth, 0)
+			ents = append(append(ents, center), related...)
+			rels = append(rels, r...)
+		}
+		fmt.Print(kg.FormatDOT(ents, rels))
+		return nil
+	}
+
 	for _, center := range matches {
 		related, rels := g.QueryRelated(center.ID, kgQueryDepth, 0)
 
@@ -108,10 +187,11 @@ func runKGQuery(cmd *cobra.Command, args []string) error {
 }
 
 func runKGStats(cmd *cobra.Command, args []string) error {
-	g, err := openGraph()
+	g, closeGraph, err := openGraph()
 	if err != nil {
 		return err
 	}
+	defer closeGraph()
 
 	stats := g.Stats()
 	fmt.Printf("Entities:      %d\n", stats.TotalEntities)
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var kgImportCmd = &cobra.Command{
+	Use:   "import <graph.json>",
+	Short: "Import a JSON knowledge graph into kg.db",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runKGImport,
+}
+
+func init() {
+	kgCmd.AddCommand(kgImportCmd)
+}
+
+func runKGImport(cmd *cobra.Command, args []string) error {
+	db, err := openKGDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ents, rels, err := db.ImportJSON(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d entities and %d relationships from %s.\n", ents, rels, args[0])
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+
+	"github.com/lyndonlyu/apex/internal/kg"
+	"github.com/spf13/cobra"
+)
+
+var kgIndexProject string
+
+var kgIndexCmd = &cobra.Command{
+	Use:   "index [path]",
+	Short: "Index Go source into the knowledge graph (incremental)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runKGIndex,
+}
+
+func init() {
+	kgIndexCmd.Flags().StringVar(&kgIndexProject, "project", "", "Project for indexed entities (default: module path)")
+	kgCmd.AddCommand(kgIndexCmd)
+}
+
+// kgIndexState is the index state file for the module at modDir: one per
+// module checkout, shared by runs that index any part of it, so indexing
+// one checkout does not invalidate another.
+func kgIndexState(dir, modDir string) string {
+	sum := sha256.Sum256([]byte(modDir))
+	return filepath.Join(dir, "index", hex.EncodeToString(sum[:8])+".json")
+}
+
+func runKGIndex(cmd *cobra.Command, args []string) error {
+	root := "."
+	if len(args) == 1 {
+		root = args[0]
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	dir, err := kgDir()
+	if err != nil {
+		return err
+	}
+	g, closeGraph, err := openGraph()
+	if err != nil {
+		return err
+	}
+	defer closeGraph()
+
+	modDir, _, err := kg.FindModule(root)
+	if err != nil {
+		return err
+	}
+	ix := &kg.Indexer{Graph: g, StatePath: kgIndexState(dir, modDir), Project: kgIndexProject}
+	result, err := ix.Index(root)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Indexed %s: %d files (%d parsed, %d removed), %d entities, %d relationships added.\n",
+		result.Module, result.Files, result.Parsed, result.Removed, result.Entities, result.Relationships)
+	for _, f := range result.Unparsable {
+		fmt.Printf("  skipped, does not parse: %s\n", f.Reason)
+	}
+	return nil
+}
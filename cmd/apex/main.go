@@ -22,7 +22,13 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+// memoryNamespace isolates session-tier memory, e.g. between terminals
+// running apex in parallel. It defaults to $APEX_NAMESPACE.
+var memoryNamespace string
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&memoryNamespace, "namespace", os.Getenv("APEX_NAMESPACE"),
+		"Session memory namespace (isolates parallel terminals)")
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(historyCmd)
@@ -63,6 +69,7 @@ func init() {
 	rootCmd.AddCommand(templateCmd)
 	rootCmd.AddCommand(analyticsCmd)
 	rootCmd.AddCommand(precheckCmd)
+	rootCmd.AddCommand(contextCmd)
 }
 
 func main() {
@@ -5,12 +5,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/lyndonlyu/apex/internal/config"
 	"github.com/lyndonlyu/apex/internal/embedding"
+	"github.com/lyndonlyu/apex/internal/executor"
 	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/repomap"
+	"github.com/lyndonlyu/apex/internal/sandbox"
 	"github.com/lyndonlyu/apex/internal/search"
+	"github.com/lyndonlyu/apex/internal/staging"
+	"github.com/lyndonlyu/apex/internal/statedb"
 	"github.com/lyndonlyu/apex/internal/vectordb"
 	"github.com/spf13/cobra"
 )
@@ -33,57 +40,366 @@ var memoryIndexCmd = &cobra.Command{
 	RunE:  indexMemory,
 }
 
+var memoryPendingCmd = &cobra.Command{
+	Use:   "pending",
+	Short: "List extracted memories awaiting confirmation",
+	RunE:  listPendingMemory,
+}
+
+var memoryConfirmCmd = &cobra.Command{
+	Use:   "confirm <id>",
+	Short: "Confirm a pending memory and commit it, or pin a committed memory at full confidence",
+	Args:  cobra.ExactArgs(1),
+	RunE:  confirmMemory,
+}
+
+var memoryRejectCmd = &cobra.Command{
+	Use:   "reject <id>",
+	Short: "Reject a pending memory",
+	Args:  cobra.ExactArgs(1),
+	RunE:  rejectMemory,
+}
+
+var memoryHistoryCmd = &cobra.Command{
+	Use:   "history <id>",
+	Short: "Show the version chain of a memory",
+	Args:  cobra.ExactArgs(1),
+	RunE:  showMemoryHistory,
+}
+
 func init() {
 	memoryCmd.AddCommand(memorySearchCmd)
 	memoryCmd.AddCommand(memoryIndexCmd)
+	memoryCmd.AddCommand(memoryPendingCmd)
+	memoryCmd.AddCommand(memoryConfirmCmd)
+	memoryCmd.AddCommand(memoryRejectCmd)
+	memoryCmd.AddCommand(memoryHistoryCmd)
 }
 
-func loadSearchDeps() (*config.Config, *memory.Store, *vectordb.VectorDB, *embedding.Client, error) {
+// openStager opens the staging pipeline in the runtime database. The
+// returned close function releases the database.
+func openStager() (*config.Config, *staging.Stager, func(), error) {
 	home, err := homeDir()
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, err
+	}
+	cfg, err := config.Load(filepath.Join(home, ".apex", "config.yaml"))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("config error: %w", err)
+	}
+	store, closeStore, err := openMemoryStore(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stager, closeStager, err := newStager(cfg, store)
+	if err != nil {
+		closeStore()
+		return nil, nil, nil, err
+	}
+	return cfg, stager, func() { closeStager(); closeStore() }, nil
+}
+
+// newStager opens the staging pipeline in the runtime database, committing
+// confirmed memories to store. The returned close function releases the
+// runtime database.
+func newStager(cfg *config.Config, store *memory.Store) (*staging.Stager, func(), error) {
+	runtimeDir := filepath.Join(cfg.BaseDir, "runtime")
+	if err := os.MkdirAll(runtimeDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create runtime dir: %w", err)
+	}
+	sdb, err := statedb.Open(filepath.Join(runtimeDir, "runtime.db"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("statedb: %w", err)
+	}
+	stager, err := staging.New(sdb.RawDB(), store)
+	if err != nil {
+		sdb.Close()
+		return nil, nil, err
+	}
+	return stager, func() { sdb.Close() }, nil
+}
+
+// newConflictDetector builds the staging conflict detector from config. The
+// embedding tier is used when the vector DB and embedding API are both
+// available; NLI only when enabled. The returned close function releases
+// the vector DB.
+func newConflictDetector(cfg *config.Config, sb sandbox.Sandbox) (*staging.Detector, func()) {
+	opts := staging.DetectorOptions{
+		SimilarityThreshold: cfg.Staging.SimilarityThreshold,
+		EscalateAbove:       cfg.Staging.EscalateAbove,
+	}
+	closeFn := func() {}
+
+	embedder := newEmbedder(cfg)
+	if embedder.Available() {
+		if vdb, err := openVectorDB(cfg, embedder); err == nil {
+			opts.Embedder, opts.Index = embedder, vdb
+			closeFn = func() { vdb.Close() }
+		}
+	}
+	if cfg.Staging.NLI {
+		opts.NLI = staging.NewModelNLI(executor.New(executor.Options{
+			Model:          cfg.Staging.NLIModel,
+			Effort:         "low",
+			Timeout:        time.Duration(cfg.Staging.NLITimeout) * time.Second,
+			Binary:         cfg.Claude.Binary,
+			Sandbox:        sb,
+			PermissionMode: "plan",
+		}))
+	}
+	return staging.NewDetector(opts), closeFn
+}
+
+func listPendingMemory(cmd *cobra.Command, args []string) error {
+	_, stager, closeFn, err := openStager()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	entries, err := stager.ListPending()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No memories awaiting confirmation.")
+		return nil
+	}
+
+	fmt.Printf("%d memory candidate(s) awaiting confirmation:\n\n", len(entries))
+	for _, e := range entries {
+		fmt.Printf("  %s  [%s] %.2f  (run %s)\n", e.ID, e.Category, e.Confidence, e.Source)
+		fmt.Printf("         %s\n", e.Content)
+		if e.Evidence != "" {
+			fmt.Printf("         evidence: %s\n", e.Evidence)
+		}
+		if resolutions, resErr := stager.Resolutions(e.ID); resErr == nil && len(resolutions) > 0 {
+			fmt.Printf("         conflict: %s\n", resolutions[len(resolutions)-1].Reason)
+		}
+		fmt.Println()
+	}
+	fmt.Println("Confirm with: apex memory confirm <id>   Reject with: apex memory reject <id>")
+	return nil
+}
+
+func confirmMemory(cmd *cobra.Command, args []string) error {
+	// Committed memories (mem-<uuid> IDs or .md paths) are confirmed in place.
+	if strings.HasPrefix(args[0], "mem-") || strings.HasSuffix(args[0], ".md") {
+		_, store, _, _, closeDeps, err := loadSearchDeps()
+		if err != nil {
+			return err
+		}
+		defer closeDeps()
+		if err := store.Confirm(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Confirmed %s (confidence 1.00, no decay).\n", args[0])
+		return nil
+	}
+
+	cfg, stager, closeFn, err := openStager()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	detector, closeDetector := newConflictDetector(cfg, nil)
+	defer closeDetector()
+
+	res, err := stager.Confirm(context.Background(), args[0], detector)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Committed %s to memory.\n", args[0])
+	if len(res.Superseded) > 0 {
+		fmt.Printf("Superseded: %s\n", strings.Join(res.Superseded, ", "))
+	}
+	return nil
+}
+
+func rejectMemory(cmd *cobra.Command, args []string) error {
+	_, stager, closeFn, err := openStager()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	if err := stager.Reject(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Rejected %s.\n", args[0])
+	return nil
+}
+
+// openMemoryStore opens the memory store configured by memory.backend. The
+// returned close function releases the memory database, if any.
+func openMemoryStore(cfg *config.Config) (*memory.Store, func(), error) {
+	memDir := filepath.Join(cfg.BaseDir, "memory")
+	if cfg.Memory.Backend == "files" {
+		store, err := memory.NewStore(memDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("memory error: %w", err)
+		}
+		store.SetScope(memoryScope(cfg))
+		return store, func() {}, nil
+	}
+	db, err := memory.OpenDB(filepath.Join(cfg.BaseDir, "memory.db"), memDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("memory error: %w", err)
+	}
+	store, err := memory.NewDBStore(memDir, db, cfg.Memory.ExportMarkdown)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("memory error: %w", err)
+	}
+	store.SetScope(memoryScope(cfg))
+	return store, func() { db.Close() }, nil
+}
+
+// memoryScope reads the global tier, the project tier of the git
+// repository containing the working directory (if any) and the session
+// tier of --namespace (if set).
+func memoryScope(cfg *config.Config) memory.Scope {
+	sc := memory.Scope{Namespace: memoryNamespace, Weights: cfg.Memory.TierWeights}
+	if wd, err := os.Getwd(); err == nil {
+		if root := repomap.FindRoot(wd); root != wd || isGitRoot(root) {
+			sc.Project = memory.ProjectKey(root)
+		}
+	}
+	return sc
+}
+
+// isGitRoot reports whether dir is the top of a git work tree.
+func isGitRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// newEmbedder returns the embedder selected by cfg.Embedding. A bad
+// provider setting is reported and yields an unavailable embedder, so
+// search degrades to keywords instead of failing.
+func newEmbedder(cfg *config.Config) embedding.Embedder {
+	e, err := embedding.New(embedding.Options{
+		Provider:   cfg.Embedding.Provider,
+		BaseURL:    cfg.Embedding.BaseURL,
+		APIKey:     os.Getenv(cfg.Embedding.APIKeyEnv),
+		Model:      cfg.Embedding.Model,
+		Dimensions: cfg.Embedding.Dimensions,
+		BatchSize:  cfg.Embedding.BatchSize,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return embedding.NewClient("", cfg.Embedding.Model, cfg.Embedding.Dimensions)
+	}
+	return e
+}
+
+// embeddingHint tells the user how to make the configured provider available.
+func embeddingHint(cfg *config.Config) string {
+	if cfg.Embedding.Provider == "openai" {
+		return "set " + cfg.Embedding.APIKeyEnv + " or use embedding.provider: hash"
+	}
+	return "check embedding.provider and embedding.base_url"
+}
+
+// openVectorDB opens vectors.db for embedder. Vectors from another
+// provider, model or size cannot be compared with the embedder's, so when
+// the recorded fingerprint differs the index is recreated empty; the vector
+// reconciler (or apex memory index on the files backend) refills it.
+func openVectorDB(cfg *config.Config, embedder embedding.Embedder) (*vectordb.VectorDB, error) {
+	path := filepath.Join(cfg.BaseDir, "vectors.db")
+	vdb, err := vectordb.Open(path, embedder.Dimensions())
+	if err != nil {
+		return nil, err
+	}
+	want := embedder.Fingerprint()
+	have, err := vdb.Fingerprint()
+	if err != nil {
+		vdb.Close()
+		return nil, err
+	}
+	if have == want {
+		return vdb, nil
+	}
+	if have == "" {
+		// Indexes from before fingerprints were written by the OpenAI client.
+		n, err := vdb.Count()
+		if err != nil {
+			vdb.Close()
+			return nil, err
+		}
+		legacy := embedding.NewClient("", cfg.Embedding.Model, cfg.Embedding.Dimensions).Fingerprint()
+		if n == 0 || want == legacy {
+			return vdb, vdb.SetFingerprint(want)
+		}
+	}
+
+	vdb.Close()
+	vdb, err = vectordb.Recreate(path, embedder.Dimensions())
+	if err != nil {
+		return nil, err
+	}
+	if err := vdb.SetFingerprint(want); err != nil {
+		vdb.Close()
+		return nil, err
+	}
+	hint := "apex memory reindex"
+	if cfg.Memory.Backend == "files" {
+		hint = "apex memory index"
+	}
+	fmt.Fprintf(os.Stderr, "Embedding provider changed; vectors.db was reset (rebuild with: %s).\n", hint)
+	return vdb, nil
+}
+
+// loadSearchDeps opens the memory store, vector DB and embedder. The
+// returned close function releases the memory and vector databases.
+func loadSearchDeps() (*config.Config, *memory.Store, *vectordb.VectorDB, embedding.Embedder, func(), error) {
+	home, err := homeDir()
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
 	}
 	configPath := filepath.Join(home, ".apex", "config.yaml")
 	cfg, err := config.Load(configPath)
 	if err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("config error: %w", err)
+		return nil, nil, nil, nil, nil, fmt.Errorf("config error: %w", err)
 	}
 
 	if err := cfg.EnsureDirs(); err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("failed to create dirs: %w", err)
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to create dirs: %w", err)
 	}
 
-	memDir := filepath.Join(cfg.BaseDir, "memory")
-	store, err := memory.NewStore(memDir)
+	store, closeStore, err := openMemoryStore(cfg)
 	if err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("memory error: %w", err)
+		return nil, nil, nil, nil, nil, err
 	}
 
-	vdbPath := filepath.Join(cfg.BaseDir, "vectors.db")
-	vdb, vdbErr := vectordb.Open(vdbPath, cfg.Embedding.Dimensions)
+	embedder := newEmbedder(cfg)
+	if !embedder.Available() {
+		fmt.Fprintf(os.Stderr, "warning: embedding unavailable (%s for vector search)\n", embeddingHint(cfg))
+	}
+
+	vdb, vdbErr := openVectorDB(cfg, embedder)
 	if vdbErr != nil {
 		fmt.Fprintf(os.Stderr, "warning: vector DB unavailable: %v\n", vdbErr)
 	}
-
-	apiKey := os.Getenv(cfg.Embedding.APIKeyEnv)
-	embedder := embedding.NewClient(apiKey, cfg.Embedding.Model, cfg.Embedding.Dimensions)
-	if !embedder.Available() {
-		fmt.Fprintf(os.Stderr, "warning: embedding unavailable (set %s for vector search)\n", cfg.Embedding.APIKeyEnv)
+	closeFn := func() {
+		if vdb != nil {
+			vdb.Close()
+		}
+		closeStore()
 	}
 
-	return cfg, store, vdb, embedder, nil
+	return cfg, store, vdb, embedder, closeFn, nil
 }
 
 func searchMemory(cmd *cobra.Command, args []string) error {
 	query := args[0]
 
-	_, store, vdb, embedder, err := loadSearchDeps()
+	_, store, vdb, embedder, closeDeps, err := loadSearchDeps()
 	if err != nil {
 		return err
 	}
-	if vdb != nil {
-		defer vdb.Close()
-	}
+	defer closeDeps()
 
 	engine := search.New(vdb, store, embedder)
 	results, err := engine.Hybrid(context.Background(), query, 20)
@@ -102,7 +418,14 @@ func searchMemory(cmd *cobra.Command, args []string) error {
 		if r.Type != "" {
 			source = r.Type + "/" + source
 		}
-		fmt.Printf("  [%s] %.2f  %s\n", source, r.Score, r.ID)
+		if r.Tier != "" {
+			source = r.Tier + " " + source
+		}
+		fmt.Printf("  [%s] %.2f  %s", source, r.Score, r.ID)
+		if r.Confidence > 0 {
+			fmt.Printf("  (confidence %.2f)", r.Confidence)
+		}
+		fmt.Println()
 		if r.Text != "" {
 			text := r.Text
 			if len(text) > 120 {
@@ -116,57 +439,53 @@ func searchMemory(cmd *cobra.Command, args []string) error {
 }
 
 func indexMemory(cmd *cobra.Command, args []string) error {
-	cfg, _, vdb, embedder, err := loadSearchDeps()
+	cfg, store, vdb, embedder, closeDeps, err := loadSearchDeps()
 	if err != nil {
 		return err
 	}
-	if vdb != nil {
-		defer vdb.Close()
-	}
+	defer closeDeps()
 
 	if vdb == nil {
 		return fmt.Errorf("vector DB unavailable, cannot index")
 	}
 	if !embedder.Available() {
-		return fmt.Errorf("embedding client unavailable (set %s)", cfg.Embedding.APIKeyEnv)
+		return fmt.Errorf("embedding client unavailable (%s)", embeddingHint(cfg))
 	}
 
-	memDir := filepath.Join(cfg.BaseDir, "memory")
-	var files []string
-	filepath.Walk(memDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-		if strings.HasSuffix(path, ".md") || strings.HasSuffix(path, ".jsonl") {
-			files = append(files, path)
-		}
-		return nil
-	})
+	// The database tracks which vectors are stale; only those are embedded.
+	if cfg.Memory.Backend == "sqlite" {
+		return reconcileVectors(context.Background(), cfg, store, vdb, embedder)
+	}
+
+	// Read through the store so memories are indexed whether or not they
+	// are exported as files.
+	snap, err := store.Snapshot()
+	if err != nil {
+		return err
+	}
+	files := make([]string, 0, len(snap.Files))
+	for rel := range snap.Files {
+		files = append(files, rel)
+	}
+	sort.Strings(files)
 
 	fmt.Printf("Indexing %d memory files...\n", len(files))
 
 	indexed := 0
-	for _, path := range files {
-		data, readErr := os.ReadFile(path)
-		if readErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: skip %s: %v\n", path, readErr)
-			continue
-		}
-
-		text := string(data)
+	for _, rel := range files {
+		text := snap.Files[rel]
 		if len(text) > 8000 {
 			text = text[:8000]
 		}
 
 		vec, embedErr := embedder.Embed(context.Background(), text)
 		if embedErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: embed failed for %s: %v\n", path, embedErr)
+			fmt.Fprintf(os.Stderr, "warning: embed failed for %s: %v\n", rel, embedErr)
 			continue
 		}
 
-		rel, _ := filepath.Rel(memDir, path)
 		if idxErr := vdb.Index(context.Background(), rel, text, vec); idxErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: index failed for %s: %v\n", path, idxErr)
+			fmt.Fprintf(os.Stderr, "warning: index failed for %s: %v\n", rel, idxErr)
 			continue
 		}
 
@@ -177,3 +496,40 @@ func indexMemory(cmd *cobra.Command, args []string) error {
 	fmt.Printf("\nDone. Indexed %d/%d files.\n", indexed, len(files))
 	return nil
 }
+
+func showMemoryHistory(cmd *cobra.Command, args []string) error {
+	_, store, _, _, closeDeps, err := loadSearchDeps()
+	if err != nil {
+		return err
+	}
+	defer closeDeps()
+
+	chain, err := store.History(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("History of %s (%d version(s)):\n\n", args[0], len(chain))
+	for _, e := range chain {
+		marker := " "
+		if e.IsCurrent {
+			marker = "*"
+		}
+		validTo := e.ValidTo
+		if validTo == "" {
+			validTo = "now"
+		}
+		fmt.Printf("%s v%d  %s  [%s]\n", marker, e.Version, e.ID, e.Type)
+		fmt.Printf("         valid %s .. %s\n", e.ValidFrom, validTo)
+		fmt.Printf("         %s\n", e.Path)
+		if e.RetractedAt != "" {
+			fmt.Printf("         retracted %s: %s\n", e.RetractedAt, e.RetractReason)
+		}
+		text := e.Content
+		if len(text) > 120 {
+			text = text[:120] + "..."
+		}
+		fmt.Printf("         %s\n\n", text)
+	}
+	return nil
+}
@@ -26,10 +26,11 @@ func init() {
 }
 
 func runMemoryCleanup(cmd *cobra.Command, args []string) error {
-	memDir, err := memoryDir()
+	_, store, closeStore, err := loadMemoryConfig()
 	if err != nil {
 		return err
 	}
+	defer closeStore()
 
 	cfg := memclean.DefaultConfig()
 	if cleanupMaxEntries < 0 {
@@ -40,7 +41,7 @@ func runMemoryCleanup(cmd *cobra.Command, args []string) error {
 	}
 
 	if cleanupDryRun {
-		result, toRemove, err := memclean.DryRun(memDir, cfg)
+		result, toRemove, err := memclean.DryRun(store, cfg)
 		if err != nil {
 			return fmt.Errorf("memory cleanup dry-run: %w", err)
 		}
@@ -65,7 +66,7 @@ func runMemoryCleanup(cmd *cobra.Command, args []string) error {
 	}
 
 	// Full cleanup: Scan → Evaluate → Execute
-	entries, err := memclean.Scan(memDir)
+	entries, err := memclean.ScanStore(store)
 	if err != nil {
 		return fmt.Errorf("memory cleanup scan: %w", err)
 	}
@@ -77,7 +78,7 @@ func runMemoryCleanup(cmd *cobra.Command, args []string) error {
 
 	toRemove, toKeep := memclean.Evaluate(entries, cfg, time.Now())
 
-	_, removed, err := memclean.Execute(memDir, toRemove)
+	_, removed, err := memclean.Execute(store, toRemove)
 	if err != nil {
 		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
 	}
@@ -5,53 +5,165 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/lyndonlyu/apex/internal/config"
+	"github.com/lyndonlyu/apex/internal/memory"
 	"github.com/lyndonlyu/apex/internal/memport"
+	"github.com/lyndonlyu/apex/internal/redact"
 	"github.com/spf13/cobra"
 )
 
 var (
 	memExportCategory string
 	memExportOutput   string
+	memExportSince    string
+	memExportUntil    string
+	memExportNoRedact bool
 	memImportStrategy string
+	memPortFormat     string
 )
 
 var memoryExportCmd = &cobra.Command{
 	Use:   "export",
-	Short: "Export memory files to JSON",
+	Short: "Export memory files to JSON or JSONL",
 	RunE:  runMemoryExport,
 }
 
 var memoryImportCmd = &cobra.Command{
 	Use:   "import <file>",
-	Short: "Import memory files from a JSON export",
+	Short: "Import memory files from a JSON or JSONL export",
 	Args:  cobra.ExactArgs(1),
 	RunE:  runMemoryImport,
 }
 
 func init() {
-	memoryExportCmd.Flags().StringVar(&memExportCategory, "category", "", "Filter by category (decisions|facts|sessions)")
-	memoryExportCmd.Flags().StringVar(&memExportOutput, "output", "", "Write JSON to file instead of stdout")
-	memoryImportCmd.Flags().StringVar(&memImportStrategy, "strategy", "skip", "Merge strategy for existing files (skip|overwrite)")
+	memoryExportCmd.Flags().StringVar(&memExportCategory, "category", "", "Filter by category, comma-separated (decisions|facts|sessions); matches every tier")
+	memoryExportCmd.Flags().StringVar(&memExportOutput, "output", "", "Write the archive to file instead of stdout")
+	memoryExportCmd.Flags().StringVar(&memExportSince, "since", "", "Only memories created on or after this date (YYYY-MM-DD or RFC3339)")
+	memoryExportCmd.Flags().StringVar(&memExportUntil, "until", "", "Only memories created on or before this date (YYYY-MM-DD or RFC3339)")
+	memoryExportCmd.Flags().BoolVar(&memExportNoRedact, "no-redact", false, "Export content as is instead of redacting secrets")
+	memoryExportCmd.Flags().StringVar(&memPortFormat, "format", "", "Archive format (json|jsonl); default from the file extension, else json")
+	memoryImportCmd.Flags().StringVar(&memImportStrategy, "strategy", "skip", "Merge strategy for existing files (skip|overwrite|merge)")
+	memoryImportCmd.Flags().StringVar(&memPortFormat, "format", "", "Archive format (json|jsonl); default from the file extension, else json")
 	memoryCmd.AddCommand(memoryExportCmd)
 	memoryCmd.AddCommand(memoryImportCmd)
 }
 
-func memoryDir() (string, error) {
-	home, err := os.UserHomeDir()
+// archiveFormat returns the memory archive format for path: the --format
+// flag if set, else jsonl for .jsonl files and json otherwise.
+func archiveFormat(path string) (string, error) {
+	switch memPortFormat {
+	case "json", "jsonl":
+		return memPortFormat, nil
+	case "":
+		if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+			return "jsonl", nil
+		}
+		return "json", nil
+	}
+	return "", fmt.Errorf("invalid format %q (use json or jsonl)", memPortFormat)
+}
+
+// parseDateFlag parses a YYYY-MM-DD or RFC3339 date. With endOfDay a bare
+// date means the last instant of that day, so --until includes it.
+func parseDateFlag(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD or RFC3339)", value)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// exportOptions builds the export filters from flags. Unless --no-redact is
+// given, secrets are redacted with the configured rules, even when
+// redaction of the audit log is turned off.
+func exportOptions(cfg *config.Config) (memport.ExportOptions, error) {
+	var opts memport.ExportOptions
+	for _, c := range strings.Split(memExportCategory, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			opts.Categories = append(opts.Categories, c)
+		}
+	}
+	var err error
+	if opts.Since, err = parseDateFlag(memExportSince, false); err != nil {
+		return opts, err
+	}
+	if opts.Until, err = parseDateFlag(memExportUntil, true); err != nil {
+		return opts, err
+	}
+	if !memExportNoRedact {
+		rc := cfg.Redaction
+		rc.Enabled = true
+		opts.Redactor = redact.New(rc)
+	}
+	return opts, nil
+}
+
+// loadMemoryConfig loads the configuration and opens the memory store the
+// archive commands read and write.
+func loadMemoryConfig() (*config.Config, *memory.Store, func(), error) {
+	home, err := homeDir()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cfg, err := config.Load(filepath.Join(home, ".apex", "config.yaml"))
 	if err != nil {
-		return "", fmt.Errorf("memory: home dir: %w", err)
+		return nil, nil, nil, fmt.Errorf("config error: %w", err)
 	}
-	return filepath.Join(home, ".claude", "memory"), nil
+	store, closeStore, err := openMemoryStore(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cfg, store, closeStore, nil
 }
 
 func runMemoryExport(cmd *cobra.Command, args []string) error {
-	memDir, err := memoryDir()
+	cfg, store, closeStore, err := loadMemoryConfig()
 	if err != nil {
 		return err
 	}
+	defer closeStore()
+	opts, err := exportOptions(cfg)
+	if err != nil {
+		return fmt.Errorf("memory export: %w", err)
+	}
+	format, err := archiveFormat(memExportOutput)
+	if err != nil {
+		return fmt.Errorf("memory export: %w", err)
+	}
 
-	data, err := memport.Export(memDir, memExportCategory)
+	if format == "jsonl" {
+		out := os.Stdout
+		if memExportOutput != "" {
+			f, err := os.Create(memExportOutput)
+			if err != nil {
+				return fmt.Errorf("memory export: write file: %w", err)
+			}
+			defer f.Close()
+			out = f
+		}
+		n, err := memport.ExportJSONL(out, store, opts)
+		if err != nil {
+			return fmt.Errorf("memory export: %w", err)
+		}
+		if memExportOutput != "" {
+			fmt.Printf("Exported %d entries to %s\n", n, memExportOutput)
+		}
+		return nil
+	}
+
+	data, err := memport.ExportWith(store, opts)
 	if err != nil {
 		return fmt.Errorf("memory export: %w", err)
 	}
@@ -73,27 +185,64 @@ func runMemoryExport(cmd *cobra.Command, args []string) error {
 }
 
 func runMemoryImport(cmd *cobra.Command, args []string) error {
-	memDir, err := memoryDir()
+	strategy := memport.MergeStrategy(memImportStrategy)
+	if strategy != memport.MergeSkip && strategy != memport.MergeOverwrite && strategy != memport.MergeThreeWay {
+		return fmt.Errorf("memory import: invalid strategy %q (use skip, overwrite or merge)", memImportStrategy)
+	}
+	format, err := archiveFormat(args[0])
 	if err != nil {
-		return err
+		return fmt.Errorf("memory import: %w", err)
 	}
 
-	strategy := memport.MergeStrategy(memImportStrategy)
-	if strategy != memport.MergeSkip && strategy != memport.MergeOverwrite {
-		return fmt.Errorf("memory import: invalid strategy %q (use skip or overwrite)", memImportStrategy)
+	cfg, store, closeStore, err := loadMemoryConfig()
+	if err != nil {
+		return err
 	}
+	defer closeStore()
 
-	data, err := memport.ReadFile(args[0])
-	if err != nil {
-		return fmt.Errorf("memory import: read file: %w", err)
+	opts := memport.ImportOptions{
+		Strategy:  strategy,
+		StatePath: filepath.Join(cfg.BaseDir, "memory", ".memport-state.json"),
+	}
+	if strategy == memport.MergeThreeWay {
+		// Conflicts become pending memories: apex memory pending/confirm/reject.
+		stager, closeStager, err := newStager(cfg, store)
+		if err != nil {
+			return fmt.Errorf("memory import: %w", err)
+		}
+		defer closeStager()
+		opts.Stager = stager
 	}
 
-	result, err := memport.Import(memDir, data, strategy)
-	if err != nil {
-		return fmt.Errorf("memory import: %w", err)
+	var result *memport.ImportResult
+	if format == "jsonl" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("memory import: read file: %w", err)
+		}
+		defer f.Close()
+		result, err = memport.ImportJSONL(store, f, opts)
+		if err != nil {
+			return fmt.Errorf("memory import: %w", err)
+		}
+	} else {
+		data, err := memport.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("memory import: read file: %w", err)
+		}
+		result, err = memport.ImportWith(store, data, opts)
+		if err != nil {
+			return fmt.Errorf("memory import: %w", err)
+		}
 	}
 
-	fmt.Printf("Import complete: Added=%d  Skipped=%d  Overwritten=%d\n",
-		result.Added, result.Skipped, result.Overwritten)
+	fmt.Printf("Import complete: Added=%d  Skipped=%d  Overwritten=%d  Duplicates=%d  Merged=%d  Conflicts=%d\n",
+		result.Added, result.Skipped, result.Overwritten, result.Duplicates, result.Merged, result.Conflicts)
+	if len(result.Staged) > 0 {
+		fmt.Printf("%d conflicts staged for review (apex memory pending):\n", len(result.Staged))
+		for _, id := range result.Staged {
+			fmt.Printf("  %s\n", id)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var memoryPromoteCmd = &cobra.Command{
+	Use:   "promote <id>",
+	Short: "Move a project memory to the global tier",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMemoryPromote,
+}
+
+func init() {
+	memoryCmd.AddCommand(memoryPromoteCmd)
+}
+
+func runMemoryPromote(cmd *cobra.Command, args []string) error {
+	_, store, _, _, closeDeps, err := loadSearchDeps()
+	if err != nil {
+		return err
+	}
+	defer closeDeps()
+
+	e, err := store.Promote(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Promoted %s to global memory as %s (%s).\n", args[0], e.ID, e.Path)
+	return nil
+}
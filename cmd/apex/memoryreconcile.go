@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/spf13/cobra"
+)
+
+var memoryReconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Flag same-named memories that disagree between projects",
+	RunE:  runMemoryReconcile,
+}
+
+func init() {
+	memoryCmd.AddCommand(memoryReconcileCmd)
+}
+
+func runMemoryReconcile(cmd *cobra.Command, args []string) error {
+	_, store, _, _, closeDeps, err := loadSearchDeps()
+	if err != nil {
+		return err
+	}
+	defer closeDeps()
+
+	conflicts, err := store.Contradictions()
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		fmt.Println("No contradictions between projects.")
+		return nil
+	}
+
+	fmt.Printf("%d contradiction(s) between projects:\n\n", len(conflicts))
+	for _, c := range conflicts {
+		fmt.Printf("  %s %q\n", c.Type, c.Slug)
+		for _, e := range c.Entries {
+			tier, key, _ := memory.SplitTier(e.Path)
+			where := tier
+			if key != "" {
+				where = tier + " " + key
+			}
+			text := strings.Join(strings.Fields(e.Content), " ")
+			if len(text) > 100 {
+				text = text[:100] + "..."
+			}
+			fmt.Printf("    %-40s %s  %s\n", where, e.ID, text)
+		}
+		fmt.Println()
+	}
+	fmt.Println("Resolve by promoting the right one: apex memory promote <id>")
+	return nil
+}
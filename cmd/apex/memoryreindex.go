@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lyndonlyu/apex/internal/config"
+	"github.com/lyndonlyu/apex/internal/embedding"
+	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/vecsync"
+	"github.com/lyndonlyu/apex/internal/vectordb"
+	"github.com/spf13/cobra"
+)
+
+// vectorFlushTimeout bounds the final sync when a run stops its reconciler.
+const vectorFlushTimeout = 30 * time.Second
+
+var reindexFull bool
+
+var memoryReindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Embed memories whose vectors are pending or failed (--full rebuilds vectors.db)",
+	RunE:  runMemoryReindex,
+}
+
+func init() {
+	memoryReindexCmd.Flags().BoolVar(&reindexFull, "full", false, "Delete vectors.db and embed every memory again")
+	memoryCmd.AddCommand(memoryReindexCmd)
+}
+
+// startVectorSync runs the vector reconciler for store in the background.
+// The returned function stops it, then syncs whatever is still pending for
+// up to vectorFlushTimeout. Without the sqlite backend or an embedding API
+// key there is nothing to do.
+func startVectorSync(cfg *config.Config, store *memory.Store) func() {
+	if cfg.Memory.Backend != "sqlite" {
+		return func() {}
+	}
+	embedder := newEmbedder(cfg)
+	if !embedder.Available() {
+		return func() {}
+	}
+	vdb, err := openVectorDB(cfg, embedder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: vector sync disabled: %v\n", err)
+		return func() {}
+	}
+
+	rec := vecsync.New(store, vdb, embedder, retryPolicyFromConfig(cfg),
+		time.Duration(cfg.Memory.VectorSyncSecs)*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rec.Run(ctx, func(err error) {
+			fmt.Fprintf(os.Stderr, "warning: vector sync: %v\n", err)
+		})
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), vectorFlushTimeout)
+		defer flushCancel()
+		st, err := rec.Sync(flushCtx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: vector sync: %v\n", err)
+		} else if st.Failed > 0 {
+			fmt.Fprintf(os.Stderr, "warning: %d memories could not be embedded; retry with: apex memory reindex\n", st.Failed)
+		}
+		vdb.Close()
+	}
+}
+
+// reconcileVectors checks the index against the store and embeds every
+// pending memory, printing what it did.
+func reconcileVectors(ctx context.Context, cfg *config.Config, store *memory.Store, vdb *vectordb.VectorDB, embedder embedding.Embedder) error {
+	rec := vecsync.New(store, vdb, embedder, retryPolicyFromConfig(cfg), 0)
+	checked, err := rec.Check(ctx)
+	if err != nil {
+		return err
+	}
+	synced, err := rec.Sync(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Embedded %d, removed %d, failed %d (%d found out of sync).\n",
+		synced.Synced, checked.Deleted+synced.Deleted, synced.Failed, checked.Requeued)
+	if synced.Failed > 0 {
+		return fmt.Errorf("%d memories failed to embed", synced.Failed)
+	}
+	return nil
+}
+
+func runMemoryReindex(cmd *cobra.Command, args []string) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(filepath.Join(home, ".apex", "config.yaml"))
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+	if err := cfg.EnsureDirs(); err != nil {
+		return fmt.Errorf("failed to create dirs: %w", err)
+	}
+	if cfg.Memory.Backend != "sqlite" {
+		return fmt.Errorf("memory reindex requires memory.backend: sqlite (use apex memory index)")
+	}
+	embedder := newEmbedder(cfg)
+	if !embedder.Available() {
+		return fmt.Errorf("embedding client unavailable (%s)", embeddingHint(cfg))
+	}
+
+	store, closeStore, err := openMemoryStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	if reindexFull {
+		vdb, err := vectordb.Recreate(filepath.Join(cfg.BaseDir, "vectors.db"), embedder.Dimensions())
+		if err != nil {
+			return fmt.Errorf("vector DB: %w", err)
+		}
+		vdb.Close()
+		if err := store.RequeueVectors(); err != nil {
+			return err
+		}
+		fmt.Println("Rebuilding vectors.db from scratch...")
+	}
+	vdb, err := openVectorDB(cfg, embedder)
+	if err != nil {
+		return fmt.Errorf("vector DB: %w", err)
+	}
+	defer vdb.Close()
+
+	return reconcileVectors(context.Background(), cfg, store, vdb, embedder)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/lyndonlyu/apex/internal/audit"
+	"github.com/lyndonlyu/apex/internal/dag"
+	"github.com/lyndonlyu/apex/internal/manifest"
+	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/notify"
+	"github.com/lyndonlyu/apex/internal/redact"
+	"github.com/lyndonlyu/apex/internal/retraction"
+	"github.com/spf13/cobra"
+)
+
+var retractReason string
+
+var memoryRetractCmd = &cobra.Command{
+	Use:   "retract <id>",
+	Short: "Withdraw a wrong memory and report the runs it influenced",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMemoryRetract,
+}
+
+func init() {
+	memoryRetractCmd.Flags().StringVar(&retractReason, "reason", "", "Why the memory is wrong (required)")
+	_ = memoryRetractCmd.MarkFlagRequired("reason")
+	memoryCmd.AddCommand(memoryRetractCmd)
+}
+
+func runMemoryRetract(cmd *cobra.Command, args []string) error {
+	cfg, store, _, _, closeDeps, err := loadSearchDeps()
+	if err != nil {
+		return err
+	}
+	defer closeDeps()
+
+	e, err := store.Retract(args[0], retractReason)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Retracted %s (%s): %s\n", e.ID, e.Path, e.RetractReason)
+
+	manifests, err := manifest.NewStore(filepath.Join(cfg.BaseDir, "runs")).Recent(int(^uint(0) >> 1))
+	if err != nil {
+		return fmt.Errorf("read run manifests: %w", err)
+	}
+	logger, err := audit.NewLogger(filepath.Join(cfg.BaseDir, "audit"))
+	if err != nil {
+		return fmt.Errorf("audit init: %w", err)
+	}
+	logger.SetRedactor(redact.New(cfg.Redaction))
+	records, err := logger.FindByMemoryID(e.ID)
+	if err != nil {
+		return fmt.Errorf("read audit log: %w", err)
+	}
+
+	uses := retraction.Find(manifests, records, e.ID)
+	logger.Log(audit.Entry{
+		Task:      fmt.Sprintf("[memory_retract] %s", e.ID),
+		RiskLevel: "info",
+		Outcome:   "retracted",
+		Model:     cfg.Claude.Model,
+		Error:     e.RetractReason,
+	})
+	if len(uses) == 0 {
+		fmt.Println("No recorded action included it.")
+		return nil
+	}
+
+	// One warning per affected action, filed under the action's trace so
+	// that 'apex trace' shows it next to the work it casts doubt on.
+	for _, u := range uses {
+		where := u.ActionID
+		if u.RunID != "" {
+			where = fmt.Sprintf("run %s node %s", u.RunID, u.NodeID)
+		}
+		msg := fmt.Sprintf("memory %s was retracted (%s); %s used it", e.ID, e.RetractReason, where)
+		for _, dispatchErr := range notifyDispatcher.Dispatch(notify.Event{
+			Type:    "retraction_warning",
+			TaskID:  u.ActionID,
+			Message: msg,
+			Level:   "WARN",
+			TraceID: u.TraceID,
+		}) {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", dispatchErr)
+		}
+		logger.Log(audit.Entry{
+			Task:           fmt.Sprintf("[retraction_warning] %s", msg),
+			RiskLevel:      "info",
+			Outcome:        "detected",
+			Model:          cfg.Claude.Model,
+			TraceID:        u.TraceID,
+			ParentActionID: u.ActionID,
+		})
+	}
+
+	runs := make(map[string]bool)
+	for _, u := range uses {
+		if u.RunID != "" {
+			runs[u.RunID] = true
+		}
+	}
+	fmt.Printf("\n%d actions in %d runs included it (%d traces):\n", len(uses), len(runs), len(retraction.TraceIDs(uses)))
+	for _, u := range uses {
+		run := u.RunID
+		if run == "" {
+			run = "-"
+		}
+		fmt.Printf("  %-20s %-36s %-12s %-10s %s\n", u.Timestamp, run, u.NodeID, u.Status, u.TraceID)
+	}
+	if len(uses) > cfg.Memory.RetractBlastRadius {
+		fmt.Printf("\nBlast radius exceeded: %d actions > memory.retract_blast_radius (%d). Review these runs before relying on their results.\n",
+			len(uses), cfg.Memory.RetractBlastRadius)
+	}
+	return nil
+}
+
+// retractionGuard watches a running DAG for memories retracted while it
+// runs. After every node it checks whether a memory some node was prepared
+// with has been retracted, and invalidates the nodes that have not started
+// yet: their context is rebuilt without it, or, beyond the blast radius,
+// they are held for a human.
+type retractionGuard struct {
+	store   *memory.Store
+	limit   int
+	rebuild func(n *dag.Node, hidden map[string]bool) ([]string, error)
+	warn    func(memoryID, reason string, nodes []string)
+
+	mu     sync.Mutex
+	used   map[string][]string // node ID -> memory IDs in its context
+	hidden map[string]bool     // paths of retracted memories
+	warned map[string]bool     // retracted memory IDs already reported
+}
+
+func newRetractionGuard(store *memory.Store, limit int) *retractionGuard {
+	return &retractionGuard{
+		store:  store,
+		limit:  limit,
+		used:   make(map[string][]string),
+		hidden: make(map[string]bool),
+		warned: make(map[string]bool),
+	}
+}
+
+// setUsed records the memories in a node's context.
+func (g *retractionGuard) setUsed(nodeID string, ids []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.used[nodeID] = ids
+}
+
+// usedBy returns the memories in a node's context.
+func (g *retractionGuard) usedBy(nodeID string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.used[nodeID]
+}
+
+// hiddenPaths returns a copy of the paths of the retracted memories.
+func (g *retractionGuard) hiddenPaths() map[string]bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make(map[string]bool, len(g.hidden))
+	for p := range g.hidden {
+		out[p] = true
+	}
+	return out
+}
+
+// check looks for retracted memories and propagates them to d.
+func (g *retractionGuard) check(d *dag.DAG) (retraction.Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, mems := range g.used {
+		for _, id := range mems {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	entries, err := g.store.Retracted(ids)
+	if err != nil || len(entries) == 0 {
+		return retraction.Result{}, err
+	}
+	retracted := make(map[string]bool, len(entries))
+	for id, e := range entries {
+		retracted[id] = true
+		g.hidden[e.Path] = true
+	}
+
+	for id, e := range entries {
+		if g.warned[id] || g.warn == nil {
+			continue
+		}
+		g.warned[id] = true
+		var nodes []string
+		for nodeID, mems := range g.used {
+			for _, m := range mems {
+				if m == id {
+					nodes = append(nodes, nodeID)
+					break
+				}
+			}
+		}
+		sort.Strings(nodes)
+		g.warn(id, e.RetractReason, nodes)
+	}
+
+	hidden := make(map[string]bool, len(g.hidden))
+	for p := range g.hidden {
+		hidden[p] = true
+	}
+	return retraction.Propagate(d, g.used, retracted, g.limit, func(n *dag.Node) error {
+		ids, err := g.rebuild(n, hidden)
+		if err != nil {
+			return err
+		}
+		g.used[n.ID] = ids
+		return nil
+	})
+}
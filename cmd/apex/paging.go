@@ -2,9 +2,13 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/lyndonlyu/apex/internal/artifact"
+	"github.com/lyndonlyu/apex/internal/config"
 	"github.com/lyndonlyu/apex/internal/paging"
 	"github.com/spf13/cobra"
 )
@@ -65,8 +69,28 @@ func runPagingFetch(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Use an empty in-memory store — real integration would use artifact store.
-	store := &memoryStore{data: map[string]string{}}
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(filepath.Join(home, ".apex", "config.yaml"))
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	gate, err := newReadGate(cfg, wd)
+	if err != nil {
+		return err
+	}
+	auditReads(cfg, gate)
+	artifacts := artifact.NewStore(filepath.Join(cfg.BaseDir, "artifacts"))
+	defer artifacts.Close()
+	artifacts.SetGate(gate)
+
+	store := artifactContent{store: artifacts}
 	budget := paging.DefaultBudget()
 	pager := paging.NewPager(store, budget)
 
@@ -98,15 +122,19 @@ func runPagingBudget(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// memoryStore is a simple in-memory ContentStore for CLI usage.
-type memoryStore struct {
-	data map[string]string
+// artifactContent pages artifacts from the artifact store, read through the
+// store's gate.
+type artifactContent struct {
+	store *artifact.Store
 }
 
-func (m *memoryStore) GetContent(artifactID string) (string, error) {
-	content, ok := m.data[artifactID]
-	if !ok {
+func (a artifactContent) GetContent(artifactID string) (string, error) {
+	if _, err := a.store.Get(artifactID); err != nil {
 		return "", fmt.Errorf("artifact %q not found", artifactID)
 	}
-	return content, nil
+	data, err := a.store.Data(artifactID)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
 }
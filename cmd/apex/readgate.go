@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lyndonlyu/apex/internal/audit"
+	"github.com/lyndonlyu/apex/internal/config"
+	"github.com/lyndonlyu/apex/internal/readgate"
+	"github.com/lyndonlyu/apex/internal/redact"
+	"github.com/lyndonlyu/apex/internal/repomap"
+)
+
+// newReadGate returns the gate for reads whose content is shown or put into
+// prompts. security.allowed_read_paths defaults to the repository around
+// dir and the apex base dir.
+func newReadGate(cfg *config.Config, dir string) (*readgate.Gate, error) {
+	allowed := cfg.Security.AllowedReadPaths
+	if len(allowed) == 0 {
+		allowed = []string{repomap.FindRoot(dir), cfg.BaseDir}
+	}
+	paths := make([]string, 0, len(allowed))
+	for _, p := range allowed {
+		if rest, ok := strings.CutPrefix(p, "~/"); ok {
+			home, err := homeDir()
+			if err != nil {
+				return nil, err
+			}
+			p = filepath.Join(home, rest)
+		}
+		paths = append(paths, p)
+	}
+	return readgate.New(paths, redact.New(cfg.Redaction))
+}
+
+// auditReads logs every read through g to the audit log for the rest of the
+// command. It is for one-shot commands; apex run collects the reads and
+// logs them with the rest of the run.
+func auditReads(cfg *config.Config, g *readgate.Gate) {
+	logger, err := audit.NewLogger(filepath.Join(cfg.BaseDir, "audit"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: audit init failed: %v\n", err)
+		return
+	}
+	logger.SetRedactor(redact.New(cfg.Redaction))
+	g.Audit = func(r readgate.Read) {
+		logger.Log(readAuditEntry(r, cfg.Claude.Model))
+	}
+}
+
+// readAuditEntry describes a gated read for the audit log. The (dev, inode)
+// pair identifies the file that was read even if the path is later
+// replaced.
+func readAuditEntry(r readgate.Read, model string) audit.Entry {
+	outcome := "allowed"
+	if r.Denied != "" {
+		outcome = "denied"
+	}
+	return audit.Entry{
+		Task:      fmt.Sprintf("[read] %s (dev %d, inode %d)", r.Path, r.Dev, r.Inode),
+		RiskLevel: "info",
+		Outcome:   outcome,
+		Model:     model,
+		Error:     r.Denied,
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lyndonlyu/apex/internal/artifact"
+	"github.com/lyndonlyu/apex/internal/config"
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+	"github.com/lyndonlyu/apex/internal/manifest"
+)
+
+// runReplay rebuilds the prompts of a past run from the memory snapshot it
+// recorded and compares them with the prompts stored at the time. Nothing is
+// executed. Differences point at inputs outside the snapshot: the work tree,
+// instruction files, or the diff context of follow-up nodes.
+func runReplay(runID string) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(filepath.Join(home, ".apex", "config.yaml"))
+	if err != nil {
+		return fmt.Errorf("config error: %w", err)
+	}
+
+	m, err := manifest.NewStore(filepath.Join(cfg.BaseDir, "runs")).Load(runID)
+	if err != nil {
+		return fmt.Errorf("replay: load run %s: %w", runID, err)
+	}
+	if m.MemoryVersion == "" {
+		return fmt.Errorf("replay: run %s did not record a memory snapshot", runID)
+	}
+
+	store := artifact.NewStore(filepath.Join(cfg.BaseDir, "artifacts"))
+	defer store.Close()
+	snap, err := loadMemorySnapshot(store, m.MemoryVersion)
+	if err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+
+	// Read through the gate like the run did, so the same files are left
+	// out.
+	var providers []apexctx.Provider
+	if wd, wdErr := os.Getwd(); wdErr == nil {
+		var gate apexctx.Gate
+		if g, gateErr := newReadGate(cfg, wd); gateErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: read gate: %v\n", gateErr)
+		} else {
+			auditReads(cfg, g)
+			gate = g
+		}
+		providers = contextProviders(cfg, wd, gate)
+	}
+
+	fmt.Printf("[REPLAY] %s\n", m.Task)
+	fmt.Printf("Run: %s  Memory: %s\n", runID, m.MemoryVersion)
+
+	matched := 0
+	for _, n := range m.Nodes {
+		budget := cfg.Context.TokenBudget
+		if n.ContextReportHash != "" {
+			if recorded, loadErr := loadContextReport(store, n.ContextReportHash); loadErr == nil {
+				budget = recorded.Budget
+			}
+		}
+
+		b := apexctx.NewBuilder(apexctx.Options{
+			TokenBudget:   budget,
+			Searcher:      memorySearcher{snap: snap},
+			MemoryVersion: m.MemoryVersion,
+			Providers:     providers,
+			Classify:      artifact.FilePolicy,
+		})
+		prompt, report, buildErr := b.BuildWithReport(context.Background(), n.Task)
+		if buildErr != nil {
+			return fmt.Errorf("replay: node %s: %w", n.ID, buildErr)
+		}
+		sum := sha256.Sum256([]byte(prompt))
+		hash := hex.EncodeToString(sum[:])
+
+		status := "DIFF"
+		switch {
+		case n.PromptHash == "":
+			status = "NO RECORD"
+		case hash == n.PromptHash:
+			status = "MATCH"
+			matched++
+		}
+
+		fmt.Printf("\n=== [%s] %s ===\n", status, n.ID)
+		if status == "DIFF" {
+			fmt.Printf("recorded %s, rebuilt %s\n", shortHash(n.PromptHash), shortHash(hash))
+		}
+		if explainFlag {
+			fmt.Print(apexctx.FormatReport(report))
+			fmt.Println()
+		}
+		fmt.Println(prompt)
+	}
+
+	fmt.Printf("\n%d/%d prompts reproduced exactly.\n", matched, len(m.Nodes))
+	if matched < len(m.Nodes) {
+		fmt.Printf("Recorded prompts: apex context explain %s <node-id> --prompt\n", runID)
+	}
+	return nil
+}
+
+func shortHash(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
@@ -5,27 +5,36 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lyndonlyu/apex/internal/approval"
+	"github.com/lyndonlyu/apex/internal/artifact"
 	"github.com/lyndonlyu/apex/internal/audit"
 	"github.com/lyndonlyu/apex/internal/config"
 	"github.com/lyndonlyu/apex/internal/cost"
 	apexctx "github.com/lyndonlyu/apex/internal/context"
 	"github.com/lyndonlyu/apex/internal/dag"
 	"github.com/lyndonlyu/apex/internal/executor"
+	"github.com/lyndonlyu/apex/internal/extractor"
 	"github.com/lyndonlyu/apex/internal/filelock"
+	"github.com/lyndonlyu/apex/internal/gitdiff"
 	"github.com/lyndonlyu/apex/internal/governance"
 	"github.com/lyndonlyu/apex/internal/health"
+	"github.com/lyndonlyu/apex/internal/invalidation"
 	"github.com/lyndonlyu/apex/internal/killswitch"
 	"github.com/lyndonlyu/apex/internal/manifest"
 	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/notify"
 	"github.com/lyndonlyu/apex/internal/outbox"
 	"github.com/lyndonlyu/apex/internal/planner"
 	"github.com/lyndonlyu/apex/internal/pool"
+	"github.com/lyndonlyu/apex/internal/readgate"
 	"github.com/lyndonlyu/apex/internal/redact"
+	"github.com/lyndonlyu/apex/internal/repomap"
 	"github.com/lyndonlyu/apex/internal/retry"
 	"github.com/lyndonlyu/apex/internal/sandbox"
 	"github.com/lyndonlyu/apex/internal/snapshot"
@@ -38,21 +47,35 @@ import (
 
 var dryRun bool
 var yesFlag bool
+var explainFlag bool
+var replayRunID string
+var noCacheFlag bool
 
 func init() {
 	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show execution plan and cost estimate without executing tasks (planning step still runs)")
 	runCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Auto-approve risk confirmations (non-interactive mode)")
+	runCmd.Flags().BoolVar(&explainFlag, "explain", false, "With --dry-run or --replay, print the context build report for each step")
+	runCmd.Flags().StringVar(&replayRunID, "replay", "", "Rebuild the prompts of a past run from its memory snapshot and compare them")
+	runCmd.Flags().BoolVar(&noCacheFlag, "no-cache", false, "Call the model for every step even when cache.enabled has a stored result")
 }
 
 var runCmd = &cobra.Command{
 	Use:   "run [task]",
 	Short: "Execute a task via Claude Code",
 	Long:  "Classify risk, decompose into DAG, then execute concurrently via Claude Code CLI.",
-	Args:  cobra.MinimumNArgs(1),
-	RunE:  runTask,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if replayRunID != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	RunE: runTask,
 }
 
 func runTask(cmd *cobra.Command, args []string) error {
+	if replayRunID != "" {
+		return runReplay(replayRunID)
+	}
 	task := args[0]
 
 	// Load config
@@ -107,10 +130,11 @@ func runTask(cmd *cobra.Command, args []string) error {
 	sdb.SetQueue(wq)
 
 	// Memory staging pipeline
-	memDir := filepath.Join(cfg.BaseDir, "memory")
-	memStore, memStoreErr := memory.NewStore(memDir)
+	memStore, closeMemStore, memStoreErr := openMemoryStore(cfg)
 	if memStoreErr != nil {
 		fmt.Fprintf(os.Stderr, "warning: memory store init failed: %v\n", memStoreErr)
+	} else {
+		defer closeMemStore()
 	}
 
 	var stager *staging.Stager
@@ -288,15 +312,63 @@ func runTask(cmd *cobra.Command, args []string) error {
 	}
 
 	// Build enriched prompts for each DAG node (keep original Task for display/audit)
-	ctxBuilder := apexctx.NewBuilder(apexctx.Options{
+	// Files read into prompts, by the builder and by its providers, go
+	// through the read gate; the reads are audited once the run's audit
+	// logger exists.
+	var readsMu sync.Mutex
+	var reads []readgate.Read
+	var gate apexctx.Gate
+	var providers []apexctx.Provider
+	if wd, wdErr := os.Getwd(); wdErr == nil {
+		if g, gateErr := newReadGate(cfg, wd); gateErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: read gate: %v\n", gateErr)
+		} else {
+			g.Audit = func(r readgate.Read) {
+				readsMu.Lock()
+				reads = append(reads, r)
+				readsMu.Unlock()
+			}
+			gate = g
+		}
+		providers = contextProviders(cfg, wd, gate)
+	}
+	ctxOpts := apexctx.Options{
 		TokenBudget: cfg.Context.TokenBudget,
-	})
+		Providers:   providers,
+		Reader:      gate,
+		Classify:    artifact.FilePolicy,
+	}
+	// Build every prompt from one memory snapshot so the run can be replayed.
+	var memSnap *memory.Snapshot
+	if memStore != nil {
+		if ms, msErr := memStore.Snapshot(); msErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: memory snapshot failed: %v\n", msErr)
+		} else {
+			memSnap = ms
+			ctxOpts.Searcher = memorySearcher{snap: ms}
+			ctxOpts.MemoryVersion = ms.Version
+		}
+	}
+	ctxBuilder := apexctx.NewBuilder(ctxOpts)
 
 	enrichedTasks := make(map[string]string)
+	contextReports := make(map[string]*apexctx.Report)
 	for _, node := range d.Nodes {
-		enriched, buildErr := ctxBuilder.Build(context.Background(), node.Task)
+		enriched, report, buildErr := ctxBuilder.BuildWithReport(context.Background(), node.Task)
 		if buildErr == nil {
 			enrichedTasks[node.ID] = enriched
+			contextReports[node.ID] = report
+		}
+	}
+	// Provider warnings (e.g. truncated instructions) are the same for every
+	// node, so report each one once.
+	warned := make(map[string]bool)
+	for _, report := range contextReports {
+		for _, w := range report.Warnings {
+			if !warned[w] {
+				warned[w] = true
+				fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+			}
 		}
 	}
 
@@ -327,6 +399,15 @@ func runTask(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stdout, "  Budget: %d/%d (%d%%)\n", totalTokens, cfg.Context.TokenBudget,
 			totalTokens*100/max(cfg.Context.TokenBudget, 1))
 
+		if explainFlag {
+			for i, n := range d.NodeSlice() {
+				if report, ok := contextReports[n.ID]; ok {
+					fmt.Printf("\n[%d] %s\n", i+1, n.ID)
+					fmt.Print(apexctx.FormatReport(report))
+				}
+			}
+		}
+
 		est := cost.EstimateRun(enrichedTasks, cfg.Claude.Model)
 		fmt.Printf("\nCost estimate: %s (%d calls, %s)\n", cost.FormatCost(est.TotalCost), est.NodeCount, est.Model)
 
@@ -343,6 +424,31 @@ func runTask(cmd *cobra.Command, args []string) error {
 
 	runID := uuid.New().String()
 
+	// Store assembled prompts and their build reports so that
+	// 'apex context explain' can show what each node actually saw.
+	artStore := artifact.NewStore(filepath.Join(cfg.BaseDir, "artifacts"))
+	artStore.SetCompression(int64(cfg.Artifacts.CompressAboveKB) * 1024)
+	defer artStore.Close()
+	var memoryVersion string
+	if memSnap != nil {
+		if saveErr := saveMemorySnapshot(artStore, runID, memSnap); saveErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", saveErr)
+		} else {
+			memoryVersion = memSnap.Version
+		}
+	}
+	promptHashes := make(map[string]string)
+	reportHashes := make(map[string]string)
+	for id, enriched := range enrichedTasks {
+		promptHash, reportHash, saveErr := saveContextArtifacts(artStore, runID, id, enriched, contextReports[id])
+		if saveErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: context artifacts for %s: %v\n", id, saveErr)
+			continue
+		}
+		promptHashes[id] = promptHash
+		reportHashes[id] = reportHash
+	}
+
 	// Execute DAG
 	exec := executor.New(executor.Options{
 		Model:          cfg.Claude.Model,
@@ -355,12 +461,7 @@ func runTask(cmd *cobra.Command, args []string) error {
 
 	runner := pool.NewClaudeRunner(exec)
 	p := pool.New(cfg.Pool.MaxConcurrent, runner)
-	retryPolicy := retry.Policy{
-		MaxAttempts: cfg.Retry.MaxAttempts,
-		InitDelay:   time.Duration(cfg.Retry.InitDelaySeconds) * time.Second,
-		Multiplier:  cfg.Retry.Multiplier,
-		MaxDelay:    time.Duration(cfg.Retry.MaxDelaySeconds) * time.Second,
-	}
+	retryPolicy := retryPolicyFromConfig(cfg)
 	p.RetryPolicy = &retryPolicy
 
 	// Second kill switch check right before execution
@@ -382,6 +483,256 @@ func runTask(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Snapshot saved (%s)\n", snap.Message)
 	}
 
+	// Record the memories in each prompt so that a retraction can trace
+	// what they influenced; memories retracted while the run is going are
+	// propagated to the nodes that have not started yet.
+	var hashMu sync.Mutex
+	var guard *retractionGuard
+	if memSnap != nil {
+		guard = newRetractionGuard(memStore, cfg.Memory.RetractBlastRadius)
+		for id, report := range contextReports {
+			guard.setUsed(id, memoryIDs(memStore, report))
+		}
+		guard.rebuild = func(n *dag.Node, hidden map[string]bool) ([]string, error) {
+			orig, ok := origTasks[n.ID]
+			if !ok {
+				return nil, fmt.Errorf("no prompt was built for %s", n.ID)
+			}
+			nodeOpts := ctxOpts
+			nodeOpts.Searcher = memorySearcher{snap: memSnap, hidden: hidden}
+			prompt, report, buildErr := apexctx.NewBuilder(nodeOpts).BuildWithReport(context.Background(), orig)
+			if buildErr != nil {
+				return nil, buildErr
+			}
+			promptHash, reportHash, saveErr := saveContextArtifacts(artStore, runID, n.ID, prompt, report)
+			if saveErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: context artifacts for %s: %v\n", n.ID, saveErr)
+			}
+			hashMu.Lock()
+			if saveErr == nil {
+				promptHashes[n.ID] = promptHash
+				reportHashes[n.ID] = reportHash
+			}
+			contextReports[n.ID] = report
+			hashMu.Unlock()
+			n.Task = prompt
+			return memoryIDs(memStore, report), nil
+		}
+		guard.warn = func(memoryID, reason string, nodes []string) {
+			msg := fmt.Sprintf("memory %s was retracted during the run (%s); used by %s", memoryID, reason, strings.Join(nodes, ", "))
+			for _, dispatchErr := range notifyDispatcher.Dispatch(notify.Event{
+				Type:    "retraction_warning",
+				TaskID:  runID,
+				Message: msg,
+				Level:   "WARN",
+				TraceID: tc.TraceID,
+			}) {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", dispatchErr)
+			}
+		}
+	}
+
+	// Attribute work-tree changes to the nodes that made them, so changed
+	// files can be recorded as node outputs. With context.include_diff,
+	// nodes with upstream dependencies also get their prompt rebuilt right
+	// before they run, including the work-tree diff since the snapshot with
+	// the files their ancestors touched ranked first.
+	var tracker *gitdiff.Tracker
+	var diffBase gitdiff.Base
+	if baseRev, baseErr := snapMgr.Base(runID); baseErr != nil {
+		if cfg.Context.IncludeDiff {
+			fmt.Fprintf(os.Stderr, "warning: diff context disabled: %v\n", baseErr)
+		}
+	} else if base, captureErr := gitdiff.BaseAt(cwd, baseRev); captureErr != nil {
+		if cfg.Context.IncludeDiff {
+			fmt.Fprintf(os.Stderr, "warning: diff context disabled: %v\n", captureErr)
+		}
+	} else {
+		tracker = gitdiff.NewTracker(cwd, base)
+		diffBase = base
+	}
+	if tracker != nil {
+		p.Prepare = func(n *dag.Node) string {
+			tracker.Begin(n.ID)
+			orig, ok := origTasks[n.ID]
+			if !cfg.Context.IncludeDiff || !ok || len(n.Depends) == 0 {
+				return n.Task
+			}
+			focus := tracker.Touched(d.Ancestors(n.ID)...)
+			nodeOpts := ctxOpts
+			diffProvider := gitdiff.NewProvider(cwd, diffBase, focus)
+			if gate != nil {
+				diffProvider.SetGate(gate)
+			}
+			nodeOpts.Providers = append(providers[:len(providers):len(providers)], diffProvider)
+			if guard != nil {
+				nodeOpts.Searcher = memorySearcher{snap: memSnap, hidden: guard.hiddenPaths()}
+			}
+			nodeBuilder := apexctx.NewBuilder(nodeOpts)
+			prompt, report, buildErr := nodeBuilder.BuildWithReport(context.Background(), orig)
+			if buildErr != nil {
+				return n.Task
+			}
+			promptHash, reportHash, saveErr := saveContextArtifacts(artStore, runID, n.ID, prompt, report)
+			if saveErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: context artifacts for %s: %v\n", n.ID, saveErr)
+			} else {
+				hashMu.Lock()
+				promptHashes[n.ID] = promptHash
+				reportHashes[n.ID] = reportHash
+				hashMu.Unlock()
+			}
+			hashMu.Lock()
+			contextReports[n.ID] = report
+			hashMu.Unlock()
+			if guard != nil {
+				guard.setUsed(n.ID, memoryIDs(memStore, report))
+			}
+			return prompt
+		}
+		p.Finish = func(n *dag.Node) { tracker.End(n.ID) }
+	}
+
+	// Record each node's result and changed files as artifacts, linked in
+	// the lineage graph to its prompt, its upstream nodes' outputs and the
+	// file versions it replaced.
+	var recorder *artifact.Recorder
+	root := repomap.FindRoot(cwd)
+	if lineage, lineageErr := artifact.NewLineageGraph(filepath.Join(cfg.BaseDir, "artifacts")); lineageErr != nil {
+		fmt.Fprintf(os.Stderr, "warning: artifact lineage disabled: %v\n", lineageErr)
+	} else {
+		recorder = artifact.NewRecorder(artStore, lineage, runID)
+		finish := p.Finish
+		p.Finish = func(n *dag.Node) {
+			if finish != nil {
+				finish(n)
+			}
+			hashMu.Lock()
+			promptHash := promptHashes[n.ID]
+			hashMu.Unlock()
+			recordNodeOutputs(recorder, n, promptHash, tracker, root, diffBase)
+		}
+	}
+
+	// With cache.enabled, a node whose prompt, model settings and referenced
+	// input files match an earlier execution reuses its stored result
+	// instead of calling Claude. Only nodes that left the work tree
+	// unchanged are cached, since a hit replays the result, not the edits.
+	var cacheMu sync.Mutex
+	cacheHits := make(map[string]string) // node ID -> run the result came from
+	if cfg.Cache.Enabled && !noCacheFlag {
+		if tracker == nil {
+			fmt.Fprintf(os.Stderr, "warning: result cache disabled: no snapshot to detect changed files\n")
+		} else {
+			resultCache := artifact.NewCache(artStore)
+			cacheKeys := make(map[string]string)
+			p.Cached = func(n *dag.Node, task string) (string, bool) {
+				hashMu.Lock()
+				report := contextReports[n.ID]
+				hashMu.Unlock()
+				key := artifact.CacheKey{
+					Prompt:         task,
+					Model:          cfg.Claude.Model,
+					Effort:         cfg.Claude.Effort,
+					PermissionMode: cfg.Claude.PermissionMode,
+					Inputs:         artifact.InputChecksums(root, referencedFiles(root, origTasks[n.ID], report)),
+				}.Hash()
+				entry, data, ok := resultCache.Lookup(key)
+				cacheMu.Lock()
+				defer cacheMu.Unlock()
+				cacheKeys[n.ID] = key
+				delete(cacheHits, n.ID)
+				if !ok {
+					return "", false
+				}
+				cacheHits[n.ID] = entry.RunID
+				fmt.Printf("[CACHE] %s: reused the result of run %s\n", n.ID, entry.RunID)
+				return string(data), true
+			}
+			finish := p.Finish
+			p.Finish = func(n *dag.Node) {
+				finish(n)
+				cacheMu.Lock()
+				key := cacheKeys[n.ID]
+				_, hit := cacheHits[n.ID]
+				cacheMu.Unlock()
+				if hit || key == "" || n.Status != dag.Completed || len(tracker.Touched(n.ID)) > 0 {
+					return
+				}
+				art, saveErr := artStore.Save(fmt.Sprintf("result-%s.txt", n.ID), []byte(n.Result), runID, n.ID)
+				if saveErr == nil {
+					saveErr = resultCache.Put(key, art.Hash, runID, n.ID)
+				}
+				if saveErr != nil {
+					fmt.Fprintf(os.Stderr, "warning: cache result of %s: %v\n", n.ID, saveErr)
+				}
+			}
+		}
+	}
+
+	// Watch the files each node's upstream nodes changed. When one changes
+	// again after the node completed, the node and its completed dependents
+	// are invalidated and run again, or held for review once the same input
+	// has invalidated them invalidation.max_invalidations times.
+	var watcher *invalidation.Watcher
+	var invalidations []invalidation.Event
+	var invalidationMu sync.Mutex
+	reportInvalidation := func(res invalidation.Result, checkErr error) {
+		if checkErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: invalidation check: %v\n", checkErr)
+		}
+		if len(res.Requeued) > 0 {
+			fmt.Printf("[INVALIDATION] rerunning %s after their inputs changed\n", strings.Join(res.Requeued, ", "))
+		}
+		if len(res.Escalated) > 0 {
+			fmt.Printf("[INVALIDATION] held %s for review (invalidation.max_invalidations %d)\n", strings.Join(res.Escalated, ", "), cfg.Invalidation.MaxInvalidations)
+		}
+		invalidationMu.Lock()
+		invalidations = append(invalidations, res.Events...)
+		invalidationMu.Unlock()
+	}
+	if tracker != nil && cfg.Invalidation.Enabled {
+		opts := invalidation.Options{
+			Root:             root,
+			Debounce:         time.Duration(cfg.Invalidation.DebounceMs) * time.Millisecond,
+			Window:           time.Duration(cfg.Invalidation.WindowSecs) * time.Second,
+			MaxInvalidations: cfg.Invalidation.MaxInvalidations,
+		}
+		if recorder != nil {
+			opts.Producer = recorder.LatestFile
+		}
+		watcher = invalidation.New(opts)
+		prepare := p.Prepare
+		p.Prepare = func(n *dag.Node) string {
+			watcher.Begin(n.ID, tracker.Touched(d.Ancestors(n.ID)...))
+			return prepare(n)
+		}
+		finish := p.Finish
+		p.Finish = func(n *dag.Node) {
+			finish(n)
+			reportInvalidation(watcher.Check(d))
+		}
+	}
+
+	if guard != nil {
+		finish := p.Finish
+		p.Finish = func(n *dag.Node) {
+			if finish != nil {
+				finish(n)
+			}
+			res, checkErr := guard.check(d)
+			if checkErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: retraction check: %v\n", checkErr)
+			}
+			if len(res.Invalidated) > 0 {
+				fmt.Printf("[RETRACTION] rebuilt context of %s without retracted memories\n", strings.Join(res.Invalidated, ", "))
+			}
+			if len(res.Escalated) > 0 {
+				fmt.Printf("[RETRACTION] held %s for review (memory.retract_blast_radius %d)\n", strings.Join(res.Escalated, ", "), cfg.Memory.RetractBlastRadius)
+			}
+		}
+	}
+
 	killCtx, killCancel := ks.Watch(context.Background())
 	defer killCancel()
 
@@ -404,11 +755,40 @@ func runTask(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Embed new memories in the background; stopping syncs what the run
+	// committed before the store closes.
+	if memStore != nil {
+		defer startVectorSync(cfg, memStore)()
+	}
+
 	fmt.Println("Executing...")
 	start := time.Now()
 	execErr := p.Execute(killCtx, d)
+	// Inputs that changed too late for a node's Finish check are caught
+	// here, once nothing is writing to the work tree any more.
+	for watcher != nil && execErr == nil && !ks.WasTriggered() {
+		res, flushErr := watcher.Flush(d)
+		reportInvalidation(res, flushErr)
+		if len(res.Requeued) == 0 {
+			break
+		}
+		execErr = p.Execute(killCtx, d)
+	}
 	duration := time.Since(start)
 
+	if recorder != nil {
+		if saveErr := recorder.Save(); saveErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: artifact lineage save failed: %v\n", saveErr)
+		}
+	}
+
+	// Count the memories each step's final prompt included as accesses.
+	if memStore != nil {
+		for _, report := range contextReports {
+			recordMemoryHits(memStore, report)
+		}
+	}
+
 	// Detect kill switch interruption (reliable: doesn't depend on file still existing)
 	killedBySwitch := ks.WasTriggered()
 
@@ -438,6 +818,13 @@ func runTask(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	nodeMemoryIDs := func(id string) []string {
+		if guard == nil {
+			return nil
+		}
+		return guard.usedBy(id)
+	}
+
 	// Audit
 	auditDir := filepath.Join(cfg.BaseDir, "audit")
 	logger, auditInitErr := audit.NewLogger(auditDir)
@@ -470,6 +857,32 @@ func runTask(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Log the reads made through the read gate
+	if logger != nil {
+		readsMu.Lock()
+		for _, r := range reads {
+			entry := readAuditEntry(r, cfg.Claude.Model)
+			entry.TraceID = tc.TraceID
+			logger.Log(entry)
+		}
+		readsMu.Unlock()
+	}
+
+	// Log every invalidation with the chain of changes that caused it
+	if logger != nil {
+		for _, ev := range invalidations {
+			logger.Log(audit.Entry{
+				Task:           fmt.Sprintf("[invalidation] %s %s", ev.NodeID, ev.Path),
+				RiskLevel:      "info",
+				Outcome:        ev.Action,
+				Model:          cfg.Claude.Model,
+				Error:          strings.Join(ev.Cause, " -> "),
+				TraceID:        tc.TraceID,
+				ParentActionID: nodeActionIDs[ev.NodeID],
+			})
+		}
+	}
+
 	// Log each node
 	if logger != nil {
 		for _, n := range d.Nodes {
@@ -478,6 +891,9 @@ func runTask(cmd *cobra.Command, args []string) error {
 			if n.Status == dag.Failed {
 				nodeOutcome = "failure"
 				nodeErr = n.Error
+			} else if n.Status == dag.Escalated {
+				nodeOutcome = "escalated"
+				nodeErr = n.Error
 			} else if killedBySwitch && (n.Status == dag.Pending || n.Status == dag.Running) {
 				nodeOutcome = "interrupted"
 				nodeErr = "kill switch activated"
@@ -498,6 +914,7 @@ func runTask(cmd *cobra.Command, args []string) error {
 				TraceID:        tc.TraceID,
 				ParentActionID: parentActionID,
 				ActionID:       nodeActionIDs[n.ID],
+				MemoryIDs:      nodeMemoryIDs(n.ID),
 			})
 		}
 	}
@@ -558,12 +975,22 @@ func runTask(cmd *cobra.Command, args []string) error {
 	var nodeResults []manifest.NodeResult
 	for _, n := range d.Nodes {
 		nr := manifest.NodeResult{
-			ID:       n.ID,
-			Task:     n.Task,
-			Status:   n.Status.String(),
-			ActionID: nodeActionIDs[n.ID],
+			ID:                n.ID,
+			Task:              n.Task,
+			Status:            n.Status.String(),
+			ActionID:          nodeActionIDs[n.ID],
+			PromptHash:        promptHashes[n.ID],
+			ContextReportHash: reportHashes[n.ID],
+			MemoryIDs:         nodeMemoryIDs(n.ID),
+		}
+		if recorder != nil {
+			nr.OutputHashes = recorder.Outputs(n.ID)
+		}
+		if from, ok := cacheHits[n.ID]; ok {
+			nr.CacheHit = true
+			nr.CachedFrom = from
 		}
-		if n.Status == dag.Failed {
+		if n.Status == dag.Failed || n.Status == dag.Escalated {
 			nr.Error = n.Error
 		}
 		nodeResults = append(nodeResults, nr)
@@ -581,6 +1008,7 @@ func runTask(cmd *cobra.Command, args []string) error {
 		Outcome:         outcome,
 		TraceID:         tc.TraceID,
 		RollbackQuality: string(rollbackResult.Quality),
+		MemoryVersion:   memoryVersion,
 		Nodes:           nodeResults,
 	}
 
@@ -591,6 +1019,9 @@ func runTask(cmd *cobra.Command, args []string) error {
 	// Print results
 	fmt.Println("\n--- Results ---")
 	fmt.Println(d.Summary())
+	if len(cacheHits) > 0 {
+		fmt.Printf("Result cache: %d of %d steps reused a stored result\n", len(cacheHits), len(d.Nodes))
+	}
 
 	if execErr != nil {
 		return fmt.Errorf("execution error: %w", execErr)
@@ -598,12 +1029,16 @@ func runTask(cmd *cobra.Command, args []string) error {
 
 	// Save to memory via staging pipeline
 	if stager != nil {
+		detector, closeDetector := newConflictDetector(cfg, sb)
 		if stageID, stageErr := stager.Stage(d.Summary(), "session", runID); stageErr != nil {
 			fmt.Fprintf(os.Stderr, "warning: staging failed: %v\n", stageErr)
-		} else {
-			stager.Verify(stageID)
-			stager.Commit(stageID)
+		} else if _, resolveErr := stager.Resolve(context.Background(), stageID, detector); resolveErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: staging commit failed: %v\n", resolveErr)
+		}
+		if cfg.Extractor.Enabled && outcome == "success" {
+			extractMemories(cfg, sb, stager, detector, runID, task, d, nodeActionIDs)
 		}
+		closeDetector()
 	} else if memStore != nil {
 		memStore.SaveSession("run", task, d.Summary())
 	}
@@ -616,6 +1051,94 @@ func runTask(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// retryPolicyFromConfig builds the retry policy from the retry section.
+func retryPolicyFromConfig(cfg *config.Config) retry.Policy {
+	return retry.Policy{
+		MaxAttempts: cfg.Retry.MaxAttempts,
+		InitDelay:   time.Duration(cfg.Retry.InitDelaySeconds) * time.Second,
+		Multiplier:  cfg.Retry.Multiplier,
+		MaxDelay:    time.Duration(cfg.Retry.MaxDelaySeconds) * time.Second,
+	}
+}
+
+// extractMemories asks the extractor model for durable facts, decisions and
+// incidents in the node results and stages each one. Decisions below the
+// confirmation threshold stay PENDING for `apex memory confirm`; everything
+// else goes through conflict resolution.
+func extractMemories(cfg *config.Config, sb sandbox.Sandbox, stager *staging.Stager, detector *staging.Detector, runID, task string, d *dag.DAG, actionIDs map[string]string) {
+	in := extractor.Input{RunID: runID, Task: task}
+	ids := make([]string, 0, len(d.Nodes))
+	for id := range d.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		n := d.Nodes[id]
+		in.Nodes = append(in.Nodes, extractor.NodeInput{
+			ID:       n.ID,
+			ActionID: actionIDs[n.ID],
+			Task:     n.Task,
+			Result:   n.Result,
+		})
+	}
+
+	extractExec := executor.New(executor.Options{
+		Model:          cfg.Extractor.Model,
+		Effort:         "low",
+		Timeout:        time.Duration(cfg.Extractor.Timeout) * time.Second,
+		Binary:         cfg.Claude.Binary,
+		Sandbox:        sb,
+		PermissionMode: "plan",
+	})
+	cwd, _ := os.Getwd()
+	candidates, err := extractor.Extract(context.Background(), extractExec, in, cwd, cfg.Extractor.MinConfidence)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: memory extraction failed: %v\n", err)
+		return
+	}
+
+	committed, held, dropped := 0, 0, 0
+	for _, c := range candidates {
+		stageID, stageErr := stager.StageWithConfidence(c.Content, c.Category, runID, c.Confidence, extractor.FormatEvidence(c.Evidence))
+		if stageErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: staging failed: %v\n", stageErr)
+			continue
+		}
+		if c.Claim != nil {
+			if claimErr := stager.SetClaim(stageID, *c.Claim); claimErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", claimErr)
+			}
+		}
+		if c.Category == extractor.CategoryDecision && c.Confidence < cfg.Extractor.ConfirmBelow {
+			held++
+			continue
+		}
+		res, resolveErr := stager.Resolve(context.Background(), stageID, detector)
+		if resolveErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: staging commit failed: %v\n", resolveErr)
+			continue
+		}
+		switch res.Action {
+		case staging.ActionCommit, staging.ActionSupersede:
+			committed++
+		case staging.ActionEscalate:
+			held++
+		default:
+			dropped++
+		}
+		if res.Action != staging.ActionCommit {
+			fmt.Printf("Memory %s: %s (%s)\n", res.Action, c.Content, res.Reason)
+		}
+	}
+
+	if committed > 0 || held > 0 || dropped > 0 {
+		fmt.Printf("Memory: %d extracted, %d awaiting confirmation, %d dropped\n", committed, held, dropped)
+	}
+	if held > 0 {
+		fmt.Println("Review with: apex memory pending")
+	}
+}
+
 // isTerminal returns true if stdin is connected to a terminal (TTY).
 func isTerminal() bool {
 	fi, err := os.Stdin.Stat()
@@ -624,3 +1147,86 @@ func isTerminal() bool {
 	}
 	return fi.Mode()&os.ModeCharDevice != 0
 }
+
+// recordNodeOutputs saves what n consumed and produced once it finished:
+// its prompt and the outputs of the nodes it depends on go in, its result
+// and the files it changed come out. Each changed file also consumes the
+// version it replaced, so lineage runs from one file version to the next.
+func recordNodeOutputs(rec *artifact.Recorder, n *dag.Node, promptHash string, tracker *gitdiff.Tracker, root string, base gitdiff.Base) {
+	inputs := []string{promptHash}
+	for _, dep := range n.Depends {
+		inputs = append(inputs, rec.Outputs(dep)...)
+	}
+	if err := rec.Consume(n.ID, inputs...); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: record %s inputs: %v\n", n.ID, err)
+	}
+	var changed []string
+	if tracker != nil {
+		changed = tracker.Touched(n.ID)
+	}
+	for _, path := range changed {
+		before := func() ([]byte, bool) { return gitdiff.Show(root, base, path) }
+		if err := rec.ConsumeFile(n.ID, path, before); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: record %s input %s: %v\n", n.ID, path, err)
+		}
+	}
+
+	if n.Status == dag.Completed {
+		if _, err := rec.Produce(n.ID, fmt.Sprintf("result-%s.txt", n.ID), []byte(n.Result)); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: record %s result: %v\n", n.ID, err)
+		}
+	}
+	for _, path := range changed {
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			// Deleted by the node; nothing was produced.
+			continue
+		}
+		if _, err := rec.ProduceFile(n.ID, path, data); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: record %s output %s: %v\n", n.ID, path, err)
+		}
+	}
+}
+
+// referencedFiles returns the files under root a node's prompt refers to:
+// the paths of its context blocks and the words of its task that name an
+// existing file, relative to root.
+func referencedFiles(root, task string, report *apexctx.Report) []string {
+	var candidates []string
+	if report != nil {
+		for _, b := range report.Blocks {
+			if b.Path != "" {
+				candidates = append(candidates, b.Path)
+			}
+		}
+	}
+	for _, word := range strings.Fields(task) {
+		candidates = append(candidates, strings.Trim(word, "`'\"()[]{}<>,;:!?"))
+	}
+
+	seen := make(map[string]bool)
+	var out []string
+	for _, c := range candidates {
+		c = strings.TrimSuffix(c, ".")
+		if c == "" {
+			continue
+		}
+		path := c
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		if !seen[rel] {
+			seen[rel] = true
+			out = append(out, rel)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
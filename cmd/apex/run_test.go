@@ -1,10 +1,14 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	apexctx "github.com/lyndonlyu/apex/internal/context"
 	"github.com/lyndonlyu/apex/internal/governance"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRunCommandRiskGating(t *testing.T) {
@@ -17,3 +21,23 @@ func TestRunCommandRiskGating(t *testing.T) {
 	assert.True(t, governance.Classify("delete from users table").ShouldRequireApproval())
 	assert.True(t, governance.Classify("deploy to production with encryption key").ShouldReject())
 }
+
+func TestReferencedFiles(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "pkg"), 0755))
+	for _, f := range []string{"main.go", "pkg/util.go", "README.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, f), []byte("x"), 0644))
+	}
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("x"), 0644))
+
+	report := &apexctx.Report{Blocks: []apexctx.BlockReport{
+		{ID: "task", Source: "task"},
+		{ID: "pkg/util.go", Source: "diff", Path: "pkg/util.go"},
+		{ID: "mem", Source: "memory", Path: "mem-123"},
+		{ID: "abs", Source: "file", Path: filepath.Join(root, "README.md")},
+		{ID: "out", Source: "file", Path: outside},
+	}}
+	got := referencedFiles(root, "Explain `main.go`, then pkg/util.go and missing.go.", report)
+	assert.Equal(t, []string{"README.md", "main.go", "pkg/util.go"}, got)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/chzyer/readline"
+	"github.com/lyndonlyu/apex/internal/gitdiff"
 	"github.com/lyndonlyu/apex/internal/health"
 	"github.com/spf13/cobra"
 )
@@ -48,7 +50,7 @@ func init() {
 		// Context
 		{name: "mention", group: "Context", desc: "Attach a file to next task", handler: cmdMention},
 		{name: "context", group: "Context", desc: "Show session context stats", handler: cmdContext},
-		{name: "diff", group: "Context", desc: "Show git diff summary", handler: cmdDiff},
+		{name: "diff", group: "Context", desc: "Show changes since session start (/diff full for patches)", handler: cmdDiff},
 
 		// Memory
 		{name: "memory", group: "Memory", desc: "Search or clear session memory", handler: cmdMemory},
@@ -521,11 +523,35 @@ func cmdContext(s *session, args string, rl *readline.Instance) bool {
 }
 
 func cmdDiff(s *session, args string, rl *readline.Instance) bool {
-	c := exec.Command("git", "diff", "--stat")
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	if err := c.Run(); err != nil {
+	if s.diffBase == nil {
+		c := exec.Command("git", "diff", "--stat")
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		if err := c.Run(); err != nil {
+			fmt.Println(styleError.Render("  git diff error: " + err.Error()))
+		}
+		fmt.Println()
+		return false
+	}
+
+	blocks, err := gitdiff.NewProvider(s.cwd, *s.diffBase, nil).Provide(context.Background(), "")
+	if err != nil {
 		fmt.Println(styleError.Render("  git diff error: " + err.Error()))
+		fmt.Println()
+		return false
+	}
+	if len(blocks) == 0 {
+		fmt.Println(styleInfo.Render("  No changes since session start."))
+		fmt.Println()
+		return false
+	}
+	for _, b := range blocks {
+		summary, patch, _ := strings.Cut(b.Text, "\n")
+		fmt.Printf("  %-50s %s\n", b.Path, summary)
+		if args == "full" {
+			fmt.Println(patch)
+			fmt.Println()
+		}
 	}
 	fmt.Println()
 	return false
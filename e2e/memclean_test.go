@@ -16,14 +16,12 @@ import (
 func TestMemoryCleanupDryRun(t *testing.T) {
 	env := newTestEnv(t)
 
-	// Create .claude/memory/ with a few .md files so the scan finds entries.
-	claudeMemDir := filepath.Join(env.Home, ".claude", "memory", "facts")
-	require.NoError(t, os.MkdirAll(claudeMemDir, 0755),
-		"creating .claude/memory/facts should not fail")
-
+	// Seed the apex memory store with a few .md files so the scan finds
+	// entries; they are migrated into memory.db on first use.
+	memDir := filepath.Join(env.Home, ".apex", "memory", "facts")
 	for _, name := range []string{"fact-one.md", "fact-two.md", "fact-three.md"} {
 		require.NoError(t,
-			os.WriteFile(filepath.Join(claudeMemDir, name), []byte("test content"), 0644),
+			os.WriteFile(filepath.Join(memDir, name), []byte("test content"), 0644),
 			"writing test memory file should not fail")
 	}
 
@@ -40,15 +38,10 @@ func TestMemoryCleanupDryRun(t *testing.T) {
 }
 
 // TestMemoryCleanupEmpty verifies that running cleanup on an empty memory
-// directory prints "Nothing to clean" and exits 0.
+// store prints "Nothing to clean" and exits 0.
 func TestMemoryCleanupEmpty(t *testing.T) {
 	env := newTestEnv(t)
 
-	// Ensure .claude/memory/ exists but is empty (no .md or .jsonl files).
-	claudeMemDir := filepath.Join(env.Home, ".claude", "memory")
-	require.NoError(t, os.MkdirAll(claudeMemDir, 0755),
-		"creating .claude/memory should not fail")
-
 	stdout, stderr, exitCode := env.runApex("memory", "cleanup")
 
 	assert.Equal(t, 0, exitCode,
@@ -49,6 +49,11 @@ func TestMemoryImportFile(t *testing.T) {
 
 	assert.Equal(t, 0, exitCode, "apex memory import should exit 0; stderr=%s", stderr)
 	assert.Contains(t, stdout, "Added=1", "import should report 1 added entry")
+
+	// The memory lands in the store apex reads, not only on disk.
+	stdout, stderr, exitCode = env.runApex("memory", "search", "content")
+	assert.Equal(t, 0, exitCode, "apex memory search should exit 0; stderr=%s", stderr)
+	assert.Contains(t, stdout, "Found 1 result", "imported memory should be searchable")
 }
 
 // TestMemoryImportSkip verifies that importing the same file twice with
@@ -86,3 +91,37 @@ func TestMemoryImportSkip(t *testing.T) {
 	assert.Equal(t, 0, exitCode2, "second import should exit 0; stderr=%s", stderr2)
 	assert.Contains(t, stdout2, "Skipped=1", "second import should report 1 skipped entry")
 }
+
+// TestMemoryExportJSONLRedacted verifies that a JSONL export of the apex
+// memory store redacts secrets, and that importing it elsewhere recognises
+// a memory present under another name as a duplicate.
+func TestMemoryExportJSONLRedacted(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Files present before the first command are migrated into memory.db.
+	memDir := filepath.Join(env.Home, ".apex", "memory", "facts")
+	require.NoError(t, os.WriteFile(filepath.Join(memDir, "key.md"),
+		[]byte("Deploy key is sk-ant-REDACTED.\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(memDir, "go.md"), []byte("Project uses Go 1.25.\n"), 0644))
+
+	archive := filepath.Join(env.Home, "memory.jsonl")
+	stdout, stderr, exitCode := env.runApex("memory", "export", "--output", archive, "--category", "facts")
+	require.Equal(t, 0, exitCode, "export should exit 0; stderr=%s", stderr)
+	assert.Contains(t, stdout, "Exported 2 entries")
+
+	data := env.readFile(archive)
+	assert.Contains(t, data, `"format":"apex-memory-jsonl"`)
+	assert.NotContains(t, data, "sk-ant-api03", "secrets are redacted on export")
+
+	other := newTestEnv(t)
+	require.NoError(t, os.WriteFile(filepath.Join(other.Home, ".apex", "memory", "facts", "go-version.md"),
+		[]byte("Project uses Go 1.25.\n"), 0644))
+	stdout, stderr, exitCode = other.runApex("memory", "import", archive)
+	require.Equal(t, 0, exitCode, "import should exit 0; stderr=%s", stderr)
+	assert.Contains(t, stdout, "Added=1")
+	assert.Contains(t, stdout, "Duplicates=1")
+
+	stdout, stderr, exitCode = other.runApex("memory", "search", "Deploy")
+	require.Equal(t, 0, exitCode, "search should exit 0; stderr=%s", stderr)
+	assert.Contains(t, stdout, "Found 1 result")
+}
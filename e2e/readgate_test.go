@@ -0,0 +1,43 @@
+package e2e_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunReportsDeniedInstructionFile checks that an instruction file
+// linking outside the allowed read paths is left out of the prompt and
+// shows up in the node's context explain report.
+func TestRunReportsDeniedInstructionFile(t *testing.T) {
+	env := newTestEnv(t)
+
+	secret := filepath.Join(t.TempDir(), "notes.md")
+	require.NoError(t, os.WriteFile(secret, []byte("outside the repository\n"), 0644))
+	require.NoError(t, os.Symlink(secret, filepath.Join(env.WorkDir, "CLAUDE.md")))
+
+	_, stderr, code := env.runApex("run", "say hello")
+	require.Equal(t, 0, code, "stderr: %s", stderr)
+
+	manifests, _ := filepath.Glob(filepath.Join(env.runsDir(), "*", "manifest.json"))
+	require.Len(t, manifests, 1)
+	runID := filepath.Base(filepath.Dir(manifests[0]))
+	var m struct {
+		Nodes []struct {
+			ID string `json:"id"`
+		} `json:"nodes"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(env.readFile(manifests[0])), &m))
+	require.Len(t, m.Nodes, 1)
+
+	stdout, stderr, code := env.runApex("context", "explain", runID, m.Nodes[0].ID, "--prompt")
+	require.Equal(t, 0, code, "stderr: %s", stderr)
+	assert.Contains(t, stdout, "Errors:")
+	assert.Contains(t, stdout, "instructions: ")
+	assert.Contains(t, stdout, "CLAUDE.md")
+	assert.NotContains(t, stdout, "outside the repository")
+}
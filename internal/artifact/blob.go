@@ -0,0 +1,137 @@
+package artifact
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// encodingGzip marks blobs stored gzip-compressed, as <hash>.gz.
+const encodingGzip = "gzip"
+
+// compressFile writes a gzip-compressed copy of the file at path next to it
+// and returns the copy's path.
+func compressFile(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("artifact: compress blob: %w", err)
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(path), ".tmp-*.gz")
+	if err != nil {
+		return "", fmt.Errorf("artifact: compress blob: %w", err)
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("artifact: compress blob: %w", err)
+	}
+	return out.Name(), nil
+}
+
+// decodeBlob returns a reader for the content of the blob f stored with
+// encoding. Closing it closes f.
+func decodeBlob(f *os.File, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case "":
+		return f, nil
+	case encodingGzip:
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("artifact: decompress blob: %w", err)
+		}
+		return gzipBlob{Reader: zr, f: f}, nil
+	default:
+		return nil, fmt.Errorf("artifact: unknown blob encoding %q", encoding)
+	}
+}
+
+type gzipBlob struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (b gzipBlob) Close() error {
+	err := b.Reader.Close()
+	if closeErr := b.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Problem is a blob that does not hold what the catalog says it does.
+type Problem struct {
+	Hash   string
+	Name   string
+	Reason string
+}
+
+// Verify rehashes the blobs of the artifacts with the given hashes, or of
+// every artifact when none are given. It returns how many artifacts it
+// checked and the ones whose blob is missing, unreadable, or no longer
+// matches its hash or size.
+func (s *Store) Verify(hashes ...string) (int, []Problem, error) {
+	var arts []*Artifact
+	if len(hashes) == 0 {
+		all, err := s.List()
+		if err != nil {
+			return 0, nil, err
+		}
+		arts = all
+	}
+	for _, h := range hashes {
+		a, err := s.Get(h)
+		if err != nil {
+			return 0, nil, err
+		}
+		arts = append(arts, a)
+	}
+
+	var problems []Problem
+	for _, a := range arts {
+		if reason := s.verifyBlob(a); reason != "" {
+			problems = append(problems, Problem{Hash: a.Hash, Name: a.Name, Reason: reason})
+		}
+	}
+	return len(arts), problems, nil
+}
+
+// verifyBlob returns what is wrong with a's blob, or "".
+func (s *Store) verifyBlob(a *Artifact) string {
+	f, err := os.Open(s.blobPath(a.Hash, a.Encoding))
+	if os.IsNotExist(err) {
+		return "blob missing"
+	}
+	if err != nil {
+		return err.Error()
+	}
+	rc, err := decodeBlob(f, a.Encoding)
+	if err != nil {
+		f.Close()
+		return err.Error()
+	}
+	defer rc.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, rc)
+	if err != nil {
+		return fmt.Sprintf("read blob: %v", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != a.Hash {
+		return "content hashes to " + shortHash(got)
+	}
+	if size != a.Size {
+		return fmt.Sprintf("size %d, cataloged as %d", size, a.Size)
+	}
+	return ""
+}
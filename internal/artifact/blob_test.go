@@ -0,0 +1,84 @@
+package artifact
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveFromCompressesLargeBlobs(t *testing.T) {
+	s := testStore(t)
+	s.SetCompression(1024)
+	content := strings.Repeat("a line of a large report\n", 200)
+
+	big, err := s.SaveFrom("report.md", strings.NewReader(content), "run-1", "a")
+	require.NoError(t, err)
+	assert.Equal(t, encodingGzip, big.Encoding)
+	assert.Equal(t, int64(len(content)), big.Size)
+	assert.Equal(t, ContentHash([]byte(content)), big.Hash)
+	assert.Equal(t, NormalizedHash("report.md", []byte(content)), big.NormHash)
+	fi, err := os.Stat(s.blobPath(big.Hash, encodingGzip))
+	require.NoError(t, err)
+	assert.Less(t, fi.Size(), big.Size)
+
+	rc, err := s.Open(big.Hash)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, content, string(got))
+
+	small, err := s.Save("note.txt", []byte("short"), "run-1", "a")
+	require.NoError(t, err)
+	assert.Empty(t, small.Encoding)
+
+	// Saving the same content again deduplicates against the compressed blob.
+	again, err := s.Save("copy.md", []byte(content), "run-2", "b")
+	require.NoError(t, err)
+	assert.Equal(t, big.Hash, again.Hash)
+	assert.Len(t, again.Producers, 2)
+}
+
+func TestVerify(t *testing.T) {
+	s := testStore(t)
+	s.SetCompression(1024)
+	ok, err := s.Save("ok.txt", []byte("fine"), "run-1", "a")
+	require.NoError(t, err)
+	flipped, err := s.Save("flipped.txt", []byte("original"), "run-1", "a")
+	require.NoError(t, err)
+	gone, err := s.Save("gone.txt", []byte("deleted"), "run-1", "a")
+	require.NoError(t, err)
+	packed, err := s.Save("packed.txt", []byte(strings.Repeat("x", 2048)), "run-1", "a")
+	require.NoError(t, err)
+
+	checked, problems, err := s.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, 4, checked)
+	assert.Empty(t, problems)
+
+	require.NoError(t, os.WriteFile(s.blobPath(flipped.Hash, ""), []byte("0riginal"), 0o644))
+	require.NoError(t, os.Remove(s.blobPath(gone.Hash, "")))
+	require.NoError(t, os.WriteFile(s.blobPath(packed.Hash, encodingGzip), []byte("not gzip"), 0o644))
+
+	checked, problems, err = s.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, 4, checked)
+	require.Len(t, problems, 3)
+	assert.Equal(t, "flipped.txt", problems[0].Name)
+	assert.Contains(t, problems[0].Reason, "content hashes to")
+	assert.Equal(t, "gone.txt", problems[1].Name)
+	assert.Equal(t, "blob missing", problems[1].Reason)
+	assert.Equal(t, "packed.txt", problems[2].Name)
+	assert.Contains(t, problems[2].Reason, "decompress")
+
+	checked, problems, err = s.Verify(ok.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, 1, checked)
+	assert.Empty(t, problems)
+	_, _, err = s.Verify("missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,191 @@
+package artifact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheKey describes one node execution. Running the same prompt with the
+// same model settings against the same input files is expected to give the
+// same result, so the result can be reused.
+type CacheKey struct {
+	Prompt         string
+	Model          string
+	Effort         string
+	PermissionMode string
+	// Inputs maps the files the prompt references to their normalized
+	// hashes; see InputChecksums.
+	Inputs map[string]string
+}
+
+// Hash returns the key the result is cached under.
+func (k CacheKey) Hash() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "model=%s\neffort=%s\npermission_mode=%s\n", k.Model, k.Effort, k.PermissionMode)
+	paths := make([]string, 0, len(k.Inputs))
+	for p := range k.Inputs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		fmt.Fprintf(&b, "input=%s %s\n", p, k.Inputs[p])
+	}
+	b.WriteString("prompt=\n")
+	b.WriteString(k.Prompt)
+	return sha256sum([]byte(b.String()))
+}
+
+// InputChecksums returns the normalized hashes of the files at paths,
+// relative to root. A missing file maps to "", so creating it changes the
+// key too.
+func InputChecksums(root string, paths []string) map[string]string {
+	out := make(map[string]string, len(paths))
+	for _, p := range paths {
+		data, err := os.ReadFile(filepath.Join(root, p))
+		if err != nil {
+			out[p] = ""
+			continue
+		}
+		out[p] = NormalizedHash(p, data)
+	}
+	return out
+}
+
+// CacheEntry points a cache key at the stored result of the node execution
+// that produced it.
+type CacheEntry struct {
+	Key        string `json:"key"`
+	ResultHash string `json:"result_hash"`
+	RunID      string `json:"run_id"`
+	NodeID     string `json:"node_id"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// Cache maps cache keys to result artifacts in a Store. Entries live in
+// cache.json next to the store index; results whose artifact has been
+// garbage-collected are misses and are dropped by Prune.
+type Cache struct {
+	store *Store
+	mu    sync.Mutex
+}
+
+// NewCache creates a Cache over store.
+func NewCache(store *Store) *Cache {
+	return &Cache{store: store}
+}
+
+// Lookup returns the entry cached under key and its result, if both exist.
+func (c *Cache) Lookup(key string) (*CacheEntry, []byte, bool) {
+	c.mu.Lock()
+	entries, err := c.load()
+	c.mu.Unlock()
+	if err != nil {
+		return nil, nil, false
+	}
+	for _, e := range entries {
+		if e.Key != key {
+			continue
+		}
+		data, err := c.store.Data(e.ResultHash)
+		if err != nil {
+			return nil, nil, false
+		}
+		return e, data, true
+	}
+	return nil, nil, false
+}
+
+// Put caches the result artifact resultHash, produced by nodeID of runID,
+// under key, replacing an earlier entry for the key.
+func (c *Cache) Put(key, resultHash, runID, nodeID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := c.load()
+	if err != nil {
+		return err
+	}
+	entry := &CacheEntry{
+		Key:        key,
+		ResultHash: resultHash,
+		RunID:      runID,
+		NodeID:     nodeID,
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	replaced := false
+	for i, e := range entries {
+		if e.Key == key {
+			entries[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		entries = append(entries, entry)
+	}
+	return c.save(entries)
+}
+
+// Entries returns all cache entries.
+func (c *Cache) Entries() ([]*CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.load()
+}
+
+// Prune drops the entries whose result artifact is no longer in the store
+// and returns them.
+func (c *Cache) Prune() ([]*CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	var kept, dropped []*CacheEntry
+	for _, e := range entries {
+		if _, err := c.store.Get(e.ResultHash); err != nil {
+			dropped = append(dropped, e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if len(dropped) == 0 {
+		return nil, nil
+	}
+	return dropped, c.save(kept)
+}
+
+func (c *Cache) path() string {
+	return filepath.Join(c.store.dir, "cache.json")
+}
+
+func (c *Cache) load() ([]*CacheEntry, error) {
+	data, err := os.ReadFile(c.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("artifact: read cache: %w", err)
+	}
+	var entries []*CacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("artifact: unmarshal cache: %w", err)
+	}
+	return entries, nil
+}
+
+func (c *Cache) save(entries []*CacheEntry) error {
+	if err := os.MkdirAll(c.store.dir, 0o755); err != nil {
+		return fmt.Errorf("artifact: mkdir store dir: %w", err)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("artifact: marshal cache: %w", err)
+	}
+	return os.WriteFile(c.path(), data, 0o644)
+}
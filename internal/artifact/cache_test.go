@@ -0,0 +1,90 @@
+package artifact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheKeyHash(t *testing.T) {
+	key := CacheKey{
+		Prompt: "summarize main.go", Model: "m", Effort: "high", PermissionMode: "default",
+		Inputs: map[string]string{"main.go": "aaa", "go.mod": "bbb"},
+	}
+	same := key
+	same.Inputs = map[string]string{"go.mod": "bbb", "main.go": "aaa"}
+	assert.Equal(t, key.Hash(), same.Hash(), "input order does not matter")
+
+	for name, change := range map[string]func(k *CacheKey){
+		"prompt": func(k *CacheKey) { k.Prompt += "!" },
+		"model":  func(k *CacheKey) { k.Model = "other" },
+		"effort": func(k *CacheKey) { k.Effort = "low" },
+		"mode":   func(k *CacheKey) { k.PermissionMode = "plan" },
+		"input":  func(k *CacheKey) { k.Inputs = map[string]string{"main.go": "ccc", "go.mod": "bbb"} },
+	} {
+		k := key
+		change(&k)
+		assert.NotEqual(t, key.Hash(), k.Hash(), name)
+	}
+}
+
+func TestInputChecksums(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.json"), []byte(`{"b":1, "a":2}`), 0644))
+	sums := InputChecksums(root, []string{"a.json", "missing.go"})
+	assert.Equal(t, NormalizedHash("a.json", []byte(`{"a":2,"b":1}`)), sums["a.json"])
+	assert.Equal(t, "", sums["missing.go"])
+}
+
+func TestCacheLookupAndPut(t *testing.T) {
+	s := testStore(t)
+	c := NewCache(s)
+	_, _, ok := c.Lookup("k1")
+	assert.False(t, ok)
+
+	art, err := s.Save("result-a.txt", []byte("the answer"), "run-1", "a")
+	require.NoError(t, err)
+	require.NoError(t, c.Put("k1", art.Hash, "run-1", "a"))
+
+	entry, data, ok := NewCache(s).Lookup("k1")
+	require.True(t, ok)
+	assert.Equal(t, "the answer", string(data))
+	assert.Equal(t, "run-1", entry.RunID)
+	assert.Equal(t, "a", entry.NodeID)
+
+	// A newer result replaces the entry.
+	art2, err := s.Save("result-a.txt", []byte("a better answer"), "run-2", "a")
+	require.NoError(t, err)
+	require.NoError(t, c.Put("k1", art2.Hash, "run-2", "a"))
+	entries, err := c.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "run-2", entries[0].RunID)
+}
+
+func TestCachePrune(t *testing.T) {
+	s := testStore(t)
+	c := NewCache(s)
+	keep, err := s.Save("result-a.txt", []byte("kept"), "run-1", "a")
+	require.NoError(t, err)
+	gone, err := s.Save("result-b.txt", []byte("collected"), "run-0", "b")
+	require.NoError(t, err)
+	require.NoError(t, c.Put("k1", keep.Hash, "run-1", "a"))
+	require.NoError(t, c.Put("k2", gone.Hash, "run-0", "b"))
+
+	require.NoError(t, s.Remove(gone.Hash))
+	_, _, ok := c.Lookup("k2")
+	assert.False(t, ok, "result collected")
+
+	dropped, err := c.Prune()
+	require.NoError(t, err)
+	require.Len(t, dropped, 1)
+	assert.Equal(t, "k2", dropped[0].Key)
+	entries, err := c.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "k1", entries[0].Key)
+}
@@ -0,0 +1,240 @@
+package artifact
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lyndonlyu/apex/internal/migration"
+	"github.com/lyndonlyu/apex/internal/writerq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The catalog is artifacts.db in the store directory. Artifacts are indexed
+// by run, node, type and creation time; producer and consumer references
+// live in their own table, indexed by the run node they name.
+
+const artifactsSchema = `CREATE TABLE IF NOT EXISTS artifacts (
+	hash        TEXT PRIMARY KEY,
+	name        TEXT NOT NULL,
+	run_id      TEXT NOT NULL DEFAULT '',
+	node_id     TEXT NOT NULL DEFAULT '',
+	size        INTEGER NOT NULL,
+	created_at  TEXT NOT NULL,
+	type        TEXT NOT NULL DEFAULT '',
+	norm_hash   TEXT NOT NULL DEFAULT '',
+	schema_hash TEXT NOT NULL DEFAULT '',
+	encoding    TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS artifacts_run ON artifacts(run_id);
+CREATE INDEX IF NOT EXISTS artifacts_node ON artifacts(node_id);
+CREATE INDEX IF NOT EXISTS artifacts_type ON artifacts(type);
+CREATE INDEX IF NOT EXISTS artifacts_created ON artifacts(created_at);`
+
+const refsSchema = `CREATE TABLE IF NOT EXISTS artifact_refs (
+	hash    TEXT NOT NULL,
+	role    TEXT NOT NULL,
+	run_id  TEXT NOT NULL,
+	node_id TEXT NOT NULL,
+	PRIMARY KEY (hash, role, run_id, node_id)
+);
+CREATE INDEX IF NOT EXISTS artifact_refs_node ON artifact_refs(run_id, node_id);`
+
+const (
+	roleProducer = "producer"
+	roleConsumer = "consumer"
+)
+
+const insertArtifactSQL = `INSERT OR IGNORE INTO artifacts
+	(hash, name, run_id, node_id, size, created_at, type, norm_hash, schema_hash, encoding)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+const insertRefSQL = `INSERT OR IGNORE INTO artifact_refs (hash, role, run_id, node_id) VALUES (?, ?, ?, ?)`
+
+const selectArtifactSQL = `SELECT hash, name, run_id, node_id, size, created_at, type, norm_hash, schema_hash, encoding FROM artifacts`
+
+// openCatalog opens or creates the catalog in dir and migrates it. The
+// first migrations import the index.json of earlier versions, if any.
+func openCatalog(dir string) (*sql.DB, *writerq.Queue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, fmt.Errorf("artifact: mkdir store dir: %w", err)
+	}
+	path := filepath.Join(dir, "artifacts.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("artifact: open catalog: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("artifact: ping catalog: %w", err)
+	}
+	for _, p := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000", "PRAGMA wal_checkpoint(TRUNCATE)"} {
+		if _, err := db.Exec(p); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("artifact: %s: %w", p, err)
+		}
+	}
+	if _, err := migrations(filepath.Join(dir, "index.json")).Migrate(db, path); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("artifact: migrate catalog: %w", err)
+	}
+	return db, writerq.New(db), nil
+}
+
+// migrations returns the catalog schema history.
+func migrations(indexPath string) *migration.Registry {
+	r := migration.NewRegistry()
+	r.Add(1, "create artifacts table", artifactsSchema)
+	r.Add(2, "create artifact_refs table", refsSchema)
+	r.AddFunc(3, "import index.json", func(db *sql.DB) error {
+		index, err := readIndexFile(indexPath)
+		if err != nil || len(index) == 0 {
+			return err
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		for _, a := range index {
+			for _, op := range insertOps(a) {
+				if _, err := tx.Exec(op.SQL, op.Args...); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Commit()
+	})
+	return r
+}
+
+// readIndexFile reads the JSON index earlier versions kept; a missing file
+// yields nil.
+func readIndexFile(path string) ([]*Artifact, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var index []*Artifact
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return index, nil
+}
+
+// insertOps returns the writes that add a to the catalog. An artifact that
+// is already there keeps its row; only new references are added.
+func insertOps(a *Artifact) []writerq.Op {
+	ops := []writerq.Op{{SQL: insertArtifactSQL, Args: []any{
+		a.Hash, a.Name, a.RunID, a.NodeID, a.Size, a.CreatedAt,
+		string(a.Type), a.NormHash, a.SchemaHash, a.Encoding,
+	}}}
+	for _, r := range a.Producers {
+		ops = append(ops, writerq.Op{SQL: insertRefSQL, Args: []any{a.Hash, roleProducer, r.RunID, r.NodeID}})
+	}
+	for _, r := range a.Consumers {
+		ops = append(ops, writerq.Op{SQL: insertRefSQL, Args: []any{a.Hash, roleConsumer, r.RunID, r.NodeID}})
+	}
+	return ops
+}
+
+// Query selects artifacts from the catalog. Zero fields match everything.
+type Query struct {
+	RunID  string
+	NodeID string
+	Type   Type
+	Since  time.Time // created at or after
+	Until  time.Time // created before
+}
+
+// where returns the SQL condition for q and its arguments.
+func (q Query) where() (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if q.RunID != "" {
+		add("run_id = ?", q.RunID)
+	}
+	if q.NodeID != "" {
+		add("node_id = ?", q.NodeID)
+	}
+	if q.Type != "" {
+		add("type = ?", string(q.Type))
+	}
+	if !q.Since.IsZero() {
+		add("created_at >= ?", q.Since.UTC().Format(time.RFC3339))
+	}
+	if !q.Until.IsZero() {
+		add("created_at < ?", q.Until.UTC().Format(time.RFC3339))
+	}
+	if len(conds) == 0 {
+		return "1", nil
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// find returns the artifacts matching where, in the order they were
+// stored, with their references.
+func find(db *sql.DB, where string, args []any) ([]*Artifact, error) {
+	rows, err := db.Query(selectArtifactSQL+" WHERE "+where+" ORDER BY rowid", args...)
+	if err != nil {
+		return nil, fmt.Errorf("artifact: query catalog: %w", err)
+	}
+	defer rows.Close()
+	var arts []*Artifact
+	byHash := make(map[string]*Artifact)
+	for rows.Next() {
+		var a Artifact
+		var typ string
+		if err := rows.Scan(&a.Hash, &a.Name, &a.RunID, &a.NodeID, &a.Size, &a.CreatedAt,
+			&typ, &a.NormHash, &a.SchemaHash, &a.Encoding); err != nil {
+			return nil, fmt.Errorf("artifact: scan catalog: %w", err)
+		}
+		a.Type = Type(typ)
+		arts = append(arts, &a)
+		byHash[a.Hash] = &a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("artifact: query catalog: %w", err)
+	}
+	if len(arts) == 0 {
+		return nil, nil
+	}
+
+	refs, err := db.Query(`SELECT hash, role, run_id, node_id FROM artifact_refs
+		WHERE hash IN (SELECT hash FROM artifacts WHERE `+where+`) ORDER BY rowid`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("artifact: query references: %w", err)
+	}
+	defer refs.Close()
+	for refs.Next() {
+		var hash, role string
+		var r Ref
+		if err := refs.Scan(&hash, &role, &r.RunID, &r.NodeID); err != nil {
+			return nil, fmt.Errorf("artifact: scan references: %w", err)
+		}
+		a := byHash[hash]
+		if a == nil {
+			continue
+		}
+		if role == roleProducer {
+			a.Producers = append(a.Producers, r)
+		} else {
+			a.Consumers = append(a.Consumers, r)
+		}
+	}
+	if err := refs.Err(); err != nil {
+		return nil, fmt.Errorf("artifact: query references: %w", err)
+	}
+	return arts, nil
+}
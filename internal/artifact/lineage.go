@@ -7,10 +7,21 @@ import (
 	"path/filepath"
 )
 
-// Dependency represents that FromHash depends on ToHash.
+// Dependency represents that FromHash depends on ToHash. Edges recorded by
+// a run also name the run and node that consumed ToHash to produce
+// FromHash.
 type Dependency struct {
 	FromHash string `json:"from_hash"`
 	ToHash   string `json:"to_hash"`
+	RunID    string `json:"run_id,omitempty"`
+	NodeID   string `json:"node_id,omitempty"`
+}
+
+// Use records that a run's node produced or consumed an artifact.
+type Use struct {
+	RunID  string `json:"run_id"`
+	NodeID string `json:"node_id"`
+	Role   string `json:"role"` // "produced" or "consumed"
 }
 
 // ImpactResult holds the result of an impact analysis.
@@ -18,6 +29,7 @@ type ImpactResult struct {
 	RootHash string   `json:"root_hash"`
 	Affected []string `json:"affected"` // hashes of affected artifacts
 	Depth    int      `json:"depth"`    // max BFS depth reached
+	Uses     []Use    `json:"uses,omitempty"`
 }
 
 // LineageGraph tracks dependencies between artifacts.
@@ -57,21 +69,62 @@ func (lg *LineageGraph) AddDependency(fromHash, toHash string) error {
 	return nil
 }
 
-// RemoveDependency removes the matching (fromHash, toHash) pair if found.
-func (lg *LineageGraph) RemoveDependency(fromHash, toHash string) {
-	for i, d := range lg.deps {
-		if d.FromHash == fromHash && d.ToHash == toHash {
-			lg.deps = append(lg.deps[:i], lg.deps[i+1:]...)
+// Link records that runID's nodeID consumed toHash to produce fromHash.
+// Unlike AddDependency, the same pair is kept once per run and node.
+func (lg *LineageGraph) Link(fromHash, toHash, runID, nodeID string) {
+	for _, d := range lg.deps {
+		if d.FromHash == fromHash && d.ToHash == toHash && d.RunID == runID && d.NodeID == nodeID {
 			return
 		}
 	}
+	lg.deps = append(lg.deps, Dependency{FromHash: fromHash, ToHash: toHash, RunID: runID, NodeID: nodeID})
+}
+
+// Uses returns the run nodes that produced or consumed hash, in the order
+// they were linked.
+func (lg *LineageGraph) Uses(hash string) []Use {
+	seen := make(map[Use]bool)
+	var result []Use
+	for _, d := range lg.deps {
+		if d.RunID == "" {
+			continue
+		}
+		var u Use
+		switch hash {
+		case d.FromHash:
+			u = Use{RunID: d.RunID, NodeID: d.NodeID, Role: "produced"}
+		case d.ToHash:
+			u = Use{RunID: d.RunID, NodeID: d.NodeID, Role: "consumed"}
+		default:
+			continue
+		}
+		if !seen[u] {
+			seen[u] = true
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// RemoveDependency removes the matching (fromHash, toHash) pair if found,
+// including every run's link between them.
+func (lg *LineageGraph) RemoveDependency(fromHash, toHash string) {
+	kept := lg.deps[:0]
+	for _, d := range lg.deps {
+		if d.FromHash != fromHash || d.ToHash != toHash {
+			kept = append(kept, d)
+		}
+	}
+	lg.deps = kept
 }
 
 // DirectDeps returns all toHash values where FromHash == hash.
 func (lg *LineageGraph) DirectDeps(hash string) []string {
 	var result []string
+	seen := make(map[string]bool)
 	for _, d := range lg.deps {
-		if d.FromHash == hash {
+		if d.FromHash == hash && !seen[d.ToHash] {
+			seen[d.ToHash] = true
 			result = append(result, d.ToHash)
 		}
 	}
@@ -81,8 +134,10 @@ func (lg *LineageGraph) DirectDeps(hash string) []string {
 // DirectDependents returns all fromHash values where ToHash == hash.
 func (lg *LineageGraph) DirectDependents(hash string) []string {
 	var result []string
+	seen := make(map[string]bool)
 	for _, d := range lg.deps {
-		if d.ToHash == hash {
+		if d.ToHash == hash && !seen[d.FromHash] {
+			seen[d.FromHash] = true
 			result = append(result, d.FromHash)
 		}
 	}
@@ -91,9 +146,10 @@ func (lg *LineageGraph) DirectDependents(hash string) []string {
 
 // Impact performs BFS starting from hash, finding all artifacts that depend on
 // it (direct and transitive). Uses a visited set to avoid cycles. Depth is the
-// max BFS level reached.
+// max BFS level reached. Uses lists the run nodes that produced or consumed
+// hash itself.
 func (lg *LineageGraph) Impact(hash string) *ImpactResult {
-	result := &ImpactResult{RootHash: hash}
+	result := &ImpactResult{RootHash: hash, Uses: lg.Uses(hash)}
 
 	visited := map[string]bool{hash: true}
 	queue := []string{hash}
@@ -17,11 +17,16 @@ import (
 //
 //	Max depth: <depth>
 //
-// If no affected, output: "No downstream impact for <hash[:12]>"
+//	Runs (N):
+//	  produced  <run_id[:12]>  <node_id>
+//	  consumed  <run_id[:12]>  <node_id>
+//
+// The runs section is left out when no run recorded the artifact. If
+// neither are there, output: "No downstream impact for <hash[:12]>"
 func FormatImpact(result *ImpactResult) string {
 	rootShort := shortHash(result.RootHash)
 
-	if len(result.Affected) == 0 {
+	if len(result.Affected) == 0 && len(result.Uses) == 0 {
 		return fmt.Sprintf("No downstream impact for %s", rootShort)
 	}
 
@@ -32,6 +37,12 @@ func FormatImpact(result *ImpactResult) string {
 		fmt.Fprintf(&b, "  %s\n", shortHash(h))
 	}
 	fmt.Fprintf(&b, "\nMax depth: %d", result.Depth)
+	if len(result.Uses) > 0 {
+		fmt.Fprintf(&b, "\n\nRuns (%d):", len(result.Uses))
+		for _, u := range result.Uses {
+			fmt.Fprintf(&b, "\n  %-8s  %-12s  %s", u.Role, shortHash(u.RunID), u.NodeID)
+		}
+	}
 	return b.String()
 }
 
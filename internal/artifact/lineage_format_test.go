@@ -33,6 +33,15 @@ func TestFormatImpact(t *testing.T) {
 
 	got = FormatImpact(empty)
 	assert.Equal(t, "No downstream impact for abcdef123456", got)
+
+	// Case 3: runs that produced or consumed the artifact.
+	used := &ImpactResult{
+		RootHash: "abcdef1234567890",
+		Uses:     []Use{{RunID: "0123456789abcdef", NodeID: "n1", Role: "produced"}},
+	}
+	got = FormatImpact(used)
+	assert.Contains(t, got, "Runs (1):")
+	assert.Contains(t, got, "produced  0123456789ab  n1")
 }
 
 func TestFormatImpactJSON(t *testing.T) {
@@ -111,3 +111,26 @@ func TestImpactNoCycle(t *testing.T) {
 	assert.ElementsMatch(t, []string{"C", "B"}, result.Affected)
 	// Should not hang or panic — the visited set prevents infinite loops.
 }
+
+func TestLinkRecordsUses(t *testing.T) {
+	lg := testLineageGraph(t)
+
+	lg.Link("out", "in", "run-1", "n1")
+	lg.Link("out", "in", "run-1", "n1") // dedup
+	lg.Link("out", "in", "run-2", "n1")
+	lg.Link("next", "out", "run-2", "n2")
+
+	assert.Equal(t, []string{"in"}, lg.DirectDeps("out"), "one hash per pair")
+	assert.Equal(t, []Use{
+		{RunID: "run-1", NodeID: "n1", Role: "produced"},
+		{RunID: "run-2", NodeID: "n1", Role: "produced"},
+		{RunID: "run-2", NodeID: "n2", Role: "consumed"},
+	}, lg.Uses("out"))
+
+	result := lg.Impact("in")
+	assert.ElementsMatch(t, []string{"out", "next"}, result.Affected)
+	assert.Len(t, result.Uses, 2)
+
+	lg.RemoveDependency("out", "in")
+	assert.Empty(t, lg.DirectDeps("out"))
+}
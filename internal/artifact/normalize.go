@@ -0,0 +1,140 @@
+package artifact
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/format"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Normalize returns data with formatting-only differences removed, by the
+// rules for name's extension: Go source is gofmt-ed, JSON and YAML are
+// re-encoded with sorted keys, and everything has trailing whitespace and
+// trailing blank lines stripped. Every value of a JSON stream and every
+// document of a YAML stream is re-encoded, one per line. Content that does
+// not parse as its extension says only gets the whitespace rule.
+func Normalize(name string, data []byte) []byte {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".go":
+		if out, err := format.Source(data); err == nil {
+			data = out
+		}
+	case ".json":
+		if out, ok := canonicalJSON(data); ok {
+			data = out
+		}
+	case ".yaml", ".yml":
+		if out, ok := canonicalYAML(data); ok {
+			data = out
+		}
+	}
+	return trimTrailingSpace(data)
+}
+
+// NormalizedHash returns the hash of Normalize(name, data), so two
+// versions of a file that differ only in formatting share it.
+func NormalizedHash(name string, data []byte) string {
+	return sha256sum(Normalize(name, data))
+}
+
+// canonicalJSON re-encodes every value of a JSON stream with sorted object
+// keys and no insignificant whitespace, one value per line. Numbers are
+// kept as written. It fails on empty input and on anything that is not a
+// sequence of JSON values.
+func canonicalJSON(data []byte) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var values [][]byte
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+		out, err := json.Marshal(v) // maps marshal with sorted keys
+		if err != nil {
+			return nil, false
+		}
+		values = append(values, out)
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	return bytes.Join(values, []byte("\n")), true
+}
+
+// canonicalYAML re-encodes every document of a YAML stream as canonical
+// JSON, one document per line. It fails on empty input and on anything
+// that does not parse.
+func canonicalYAML(data []byte) ([]byte, bool) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs [][]byte
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+		out, err := json.Marshal(yamlToJSON(v))
+		if err != nil {
+			return nil, false
+		}
+		docs = append(docs, out)
+	}
+	if len(docs) == 0 {
+		return nil, false
+	}
+	return bytes.Join(docs, []byte("\n")), true
+}
+
+// yamlToJSON converts the map[any]any nodes YAML may decode into string
+// keyed maps so the value can be encoded as JSON.
+func yamlToJSON(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, e := range x {
+			x[k] = yamlToJSON(e)
+		}
+		return x
+	case map[any]any:
+		m := make(map[string]any, len(x))
+		for k, e := range x {
+			m[toString(k)] = yamlToJSON(e)
+		}
+		return m
+	case []any:
+		for i, e := range x {
+			x[i] = yamlToJSON(e)
+		}
+		return x
+	}
+	return v
+}
+
+func toString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	out, _ := json.Marshal(v)
+	return string(out)
+}
+
+// trimTrailingSpace strips trailing spaces and tabs from every line, turns
+// CRLF into LF and drops trailing blank lines.
+func trimTrailingSpace(data []byte) []byte {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return []byte(strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n")
+}
@@ -0,0 +1,42 @@
+package artifact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizedHashIgnoresFormatting(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     string
+		sameHash bool
+	}{
+		{"main.go", "package a\n\nfunc A(){return}", "package a\n\nfunc A() { return }\n", true},
+		{"main.go", "package a\n\nfunc A() { return }\n", "package a\n\nfunc B() { return }\n", false},
+		{"a.json", `{"b": 1, "a": [1, 2.50]}`, "{\n  \"a\": [1, 2.50],\n  \"b\": 1\n}\n", true},
+		{"a.json", `{"a": 1}`, `{"a": 2}`, false},
+		{"a.yaml", "b: 1\na:\n  - x\n", "a: [x]\nb: 1\n", true},
+		{"notes.md", "line  \r\nnext\t\n\n\n", "line\nnext\n", true},
+		{"broken.json", "{not json  \n", "{not json\n", true},
+		{"deploy.yaml", "kind: A\n---\nreplicas: 3\n", "kind: A\n---\nreplicas: 50\n", false},
+		{"deploy.yaml", "kind: A\n---\nb: 1\na: 2\n", "kind:   A\n---\n{a: 2, b: 1}\n", true},
+		{"events.json", "{\"n\": 1}\n{\"n\": 2}\n", "{\"n\": 1}\n{\"n\": 3}\n", false},
+		{"events.json", "{\"b\": 1, \"a\": 2}\n{\"n\": 2}", "{\"a\":2,\"b\":1} {\"n\":2}\n", true},
+		{"broken.yaml", "a: 1\n---\nb: [\n", "a: 1\n---\nb: [\n  ", true},
+		{"broken.yaml", "a: 1\n---\nb: [1\n", "a: 1\n---\nb: [2\n", false},
+		{"broken.json", "{\"a\": 1} {oops", "{\"a\": 1} {oops  ", true},
+	}
+	for _, c := range cases {
+		same := NormalizedHash(c.name, []byte(c.a)) == NormalizedHash(c.name, []byte(c.b))
+		assert.Equal(t, c.sameHash, same, "%s: %q vs %q", c.name, c.a, c.b)
+	}
+}
+
+func TestNormalizeEveryDocument(t *testing.T) {
+	got := Normalize("deploy.yaml", []byte("kind: A\n---\nspec:\n  replicas: 3\n"))
+	assert.Equal(t, "{\"kind\":\"A\"}\n{\"spec\":{\"replicas\":3}}\n", string(got))
+
+	got = Normalize("events.json", []byte("{\"n\": 1}\n\n{\"n\": 2}\n"))
+	assert.Equal(t, "{\"n\":1}\n{\"n\":2}\n", string(got))
+}
@@ -0,0 +1,148 @@
+package artifact
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Recorder saves what the nodes of one run consumed and produced, linking
+// every output to the node's inputs in the lineage graph. It is safe for
+// concurrent use by nodes running in parallel.
+type Recorder struct {
+	store   *Store
+	lineage *LineageGraph
+	runID   string
+
+	mu      sync.Mutex
+	inputs  map[string][]string    // node ID -> consumed hashes
+	outputs map[string][]string    // node ID -> produced hashes
+	files   map[string]fileVersion // path -> latest version produced by the run
+}
+
+type fileVersion struct {
+	hash, normHash, nodeID string
+}
+
+// NewRecorder creates a Recorder for runID.
+func NewRecorder(store *Store, lineage *LineageGraph, runID string) *Recorder {
+	return &Recorder{
+		store:   store,
+		lineage: lineage,
+		runID:   runID,
+		inputs:  make(map[string][]string),
+		outputs: make(map[string][]string),
+		files:   make(map[string]fileVersion),
+	}
+}
+
+// Consume records artifacts nodeID read, also as consumers in the store.
+// Call it before Produce so the outputs are linked to them.
+func (r *Recorder) Consume(nodeID string, hashes ...string) error {
+	for _, h := range hashes {
+		if h == "" {
+			continue
+		}
+		r.mu.Lock()
+		seen := contains(r.inputs[nodeID], h)
+		if !seen {
+			r.inputs[nodeID] = append(r.inputs[nodeID], h)
+		}
+		r.mu.Unlock()
+		if seen {
+			continue
+		}
+		if err := r.store.AddConsumer(h, r.runID, nodeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConsumeFile records that nodeID read or replaced the file at path. The
+// version is the one an earlier node of the run produced or, if none did,
+// the content returned by before, which reports false when the file did
+// not exist before the run.
+func (r *Recorder) ConsumeFile(nodeID, path string, before func() ([]byte, bool)) error {
+	r.mu.Lock()
+	v, ok := r.files[path]
+	r.mu.Unlock()
+	hash := v.hash
+	if !ok {
+		data, existed := before()
+		if !existed {
+			return nil
+		}
+		art, err := r.store.Save(path, data, r.runID, "")
+		if err != nil {
+			return err
+		}
+		hash = art.Hash
+	}
+	return r.Consume(nodeID, hash)
+}
+
+// Produce saves data as an output of nodeID and links it to everything the
+// node consumed so far.
+func (r *Recorder) Produce(nodeID, name string, data []byte) (*Artifact, error) {
+	art, err := r.store.Save(name, data, r.runID, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("artifact: record %s: %w", name, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !contains(r.outputs[nodeID], art.Hash) {
+		r.outputs[nodeID] = append(r.outputs[nodeID], art.Hash)
+	}
+	for _, in := range r.inputs[nodeID] {
+		if in != art.Hash {
+			r.lineage.Link(art.Hash, in, r.runID, nodeID)
+		}
+	}
+	return art, nil
+}
+
+// ProduceFile is Produce for a file nodeID wrote; later nodes that
+// consume path consume this version.
+func (r *Recorder) ProduceFile(nodeID, path string, data []byte) (*Artifact, error) {
+	art, err := r.Produce(nodeID, path, data)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.files[path] = fileVersion{hash: art.Hash, normHash: art.NormHash, nodeID: nodeID}
+	r.mu.Unlock()
+	return art, nil
+}
+
+// LatestFile returns the node that produced the run's latest version of
+// path and that version's normalized hash; ok is false if no node of the
+// run wrote path.
+func (r *Recorder) LatestFile(path string) (nodeID, normHash string, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.files[path]
+	return v.nodeID, v.normHash, ok
+}
+
+// Outputs returns the hashes nodeID produced.
+func (r *Recorder) Outputs(nodeID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.outputs[nodeID]...)
+}
+
+// Save persists the lineage graph.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lineage.Save()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package artifact
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderLinksOutputsToInputs(t *testing.T) {
+	dir := t.TempDir()
+	store := testStoreAt(t, dir)
+	lg, err := NewLineageGraph(dir)
+	require.NoError(t, err)
+	rec := NewRecorder(store, lg, "run-1")
+
+	prompt, err := store.Save("prompt-a.md", []byte("do a"), "run-1", "a")
+	require.NoError(t, err)
+	rec.Consume("a", prompt.Hash)
+	before := func() ([]byte, bool) { return []byte("v1"), true }
+	require.NoError(t, rec.ConsumeFile("a", "main.go", before))
+	result, err := rec.Produce("a", "result-a.txt", []byte("done"))
+	require.NoError(t, err)
+	v2, err := rec.ProduceFile("a", "main.go", []byte("v2"))
+	require.NoError(t, err)
+
+	v1 := ContentHash([]byte("v1"))
+	assert.ElementsMatch(t, []string{prompt.Hash, v1}, lg.DirectDeps(result.Hash))
+	assert.ElementsMatch(t, []string{prompt.Hash, v1}, lg.DirectDeps(v2.Hash))
+	assert.Equal(t, []string{result.Hash, v2.Hash}, rec.Outputs("a"))
+
+	// A later node replaces the version the run produced, not the base.
+	rec.Consume("b", rec.Outputs("a")...)
+	require.NoError(t, rec.ConsumeFile("b", "main.go", func() ([]byte, bool) {
+		t.Fatal("base content read for a file the run already produced")
+		return nil, false
+	}))
+	v3, err := rec.ProduceFile("b", "main.go", []byte("v3"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{result.Hash, v2.Hash}, lg.DirectDeps(v3.Hash))
+	node, norm, ok := rec.LatestFile("main.go")
+	assert.True(t, ok)
+	assert.Equal(t, "b", node)
+	assert.Equal(t, v3.NormHash, norm)
+	_, _, ok = rec.LatestFile("other.go")
+	assert.False(t, ok)
+
+	assert.Equal(t, []Use{
+		{RunID: "run-1", NodeID: "a", Role: "produced"},
+		{RunID: "run-1", NodeID: "b", Role: "consumed"},
+	}, lg.Uses(v2.Hash))
+	assert.ElementsMatch(t, []string{result.Hash, v2.Hash, v3.Hash}, lg.Impact(v1).Affected)
+
+	require.NoError(t, rec.Save())
+	reloaded, err := NewLineageGraph(dir)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.Uses(v2.Hash), 2)
+}
+
+func TestRecorderNewFileHasNoInput(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := NewLineageGraph(dir)
+	require.NoError(t, err)
+	rec := NewRecorder(testStoreAt(t, dir), lg, "run-1")
+
+	require.NoError(t, rec.ConsumeFile("a", "new.go", func() ([]byte, bool) { return nil, false }))
+	art, err := rec.ProduceFile("a", "new.go", []byte("package x"))
+	require.NoError(t, err)
+	assert.Empty(t, lg.DirectDeps(art.Hash))
+}
+
+func TestStoreConcurrentSaves(t *testing.T) {
+	s := testStore(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Save("f", []byte{byte(i)}, "run-1", "")
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	arts, err := s.List()
+	require.NoError(t, err)
+	assert.Len(t, arts, 20)
+}
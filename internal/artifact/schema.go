@@ -0,0 +1,122 @@
+package artifact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ValidateSchema checks a JSON document against a JSON schema. It covers
+// the keywords artifact contracts use — type, required, properties,
+// additionalProperties (false), items and enum — and ignores the rest.
+func ValidateSchema(schema, data []byte) error {
+	var s map[string]any
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("artifact: schema is not a JSON object: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("artifact: content is not JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("artifact: content is not a single JSON document")
+	}
+	return validate(s, v, "$")
+}
+
+func validate(s map[string]any, v any, path string) error {
+	if t, ok := s["type"]; ok && !typeMatches(t, v) {
+		return fmt.Errorf("artifact: %s: expected %v, got %s", path, t, jsonType(v))
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			found = found || fmt.Sprint(e) == fmt.Sprint(v)
+		}
+		if !found {
+			return fmt.Errorf("artifact: %s: %v is not one of %v", path, v, enum)
+		}
+	}
+
+	switch x := v.(type) {
+	case map[string]any:
+		if req, ok := s["required"].([]any); ok {
+			for _, r := range req {
+				if _, present := x[fmt.Sprint(r)]; !present {
+					return fmt.Errorf("artifact: %s: missing required property %q", path, r)
+				}
+			}
+		}
+		props, _ := s["properties"].(map[string]any)
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub, ok := props[k].(map[string]any)
+			if !ok {
+				if extra, set := s["additionalProperties"].(bool); set && !extra {
+					return fmt.Errorf("artifact: %s: unexpected property %q", path, k)
+				}
+				continue
+			}
+			if err := validate(sub, x[k], path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, e := range x {
+				if err := validate(items, e, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// typeMatches reports whether v has the schema type t, a name or a list
+// of names.
+func typeMatches(t any, v any) bool {
+	if list, ok := t.([]any); ok {
+		for _, e := range list {
+			if typeMatches(e, v) {
+				return true
+			}
+		}
+		return false
+	}
+	want := fmt.Sprint(t)
+	got := jsonType(v)
+	if want == "number" && got == "integer" {
+		return true
+	}
+	return want == got
+}
+
+func jsonType(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := x.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
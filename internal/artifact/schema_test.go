@@ -0,0 +1,39 @@
+package artifact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["name", "tags"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string"},
+    "port": {"type": "integer"},
+    "level": {"enum": ["low", "high"]},
+    "tags": {"type": "array", "items": {"type": "string"}}
+  }
+}`
+
+func TestValidateSchema(t *testing.T) {
+	valid := `{"name": "api", "port": 8080, "level": "low", "tags": ["a"]}`
+	assert.NoError(t, ValidateSchema([]byte(testSchema), []byte(valid)))
+
+	for _, doc := range []string{
+		`{"tags": []}`,
+		`{"name": 1, "tags": []}`,
+		`{"name": "api", "port": 80.5, "tags": []}`,
+		`{"name": "api", "level": "mid", "tags": []}`,
+		`{"name": "api", "tags": [1]}`,
+		`{"name": "api", "tags": [], "extra": true}`,
+		`[1, 2]`,
+		`not json`,
+		valid + ` {"name": 1}`,
+	} {
+		assert.Error(t, ValidateSchema([]byte(testSchema), []byte(doc)), doc)
+	}
+	assert.Error(t, ValidateSchema([]byte(`[]`), []byte(valid)), "schema must be an object")
+}
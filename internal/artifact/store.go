@@ -1,13 +1,21 @@
 package artifact
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/lyndonlyu/apex/internal/readgate"
+	"github.com/lyndonlyu/apex/internal/writerq"
 )
 
 // Artifact represents a content-addressed artifact stored by the Store.
@@ -18,11 +26,37 @@ type Artifact struct {
 	NodeID    string `json:"node_id"`
 	Size      int64  `json:"size"`
 	CreatedAt string `json:"created_at"`
+	Type      Type   `json:"type,omitempty"`
+	// NormHash is the hash of the content with formatting-only
+	// differences removed; see Normalize.
+	NormHash string `json:"norm_hash,omitempty"`
+	// SchemaHash references a JSON schema artifact the content conforms to.
+	SchemaHash string `json:"schema_hash,omitempty"`
+	Producers  []Ref  `json:"producers,omitempty"`
+	Consumers  []Ref  `json:"consumers,omitempty"`
+	// Encoding is how the blob is stored: "" as is, "gzip" compressed.
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Ref names the node of a run that produced or consumed an artifact.
+type Ref struct {
+	RunID  string `json:"run_id"`
+	NodeID string `json:"node_id"`
 }
 
-// Store provides content-addressed artifact storage backed by the filesystem.
+// Store provides content-addressed artifact storage: blobs on the
+// filesystem, sharded by the first two hex digits of their hash, and a
+// SQLite catalog opened on first use. Writes go through a single writer
+// queue, so nodes running in parallel, and other apex processes, can share
+// a Store.
 type Store struct {
-	dir string
+	dir           string
+	gate          *readgate.Gate
+	compressAbove int64
+
+	mu    sync.Mutex // guards opening and closing the catalog
+	db    *sql.DB
+	queue *writerq.Queue
 }
 
 // NewStore creates a new Store rooted at dir.
@@ -30,28 +64,121 @@ func NewStore(dir string) *Store {
 	return &Store{dir: dir}
 }
 
-// Save persists data as a named artifact. If content with the same SHA-256 hash
-// already exists the existing artifact is returned (deduplication).
+// Close drains pending catalog writes and closes the catalog. The Store
+// reopens it if it is used again.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	s.queue.Close()
+	err := s.db.Close()
+	s.db, s.queue = nil, nil
+	return err
+}
+
+// SetGate makes Data and Open read blobs through g, so their content is
+// redacted and the reads are audited. Used for content that is shown or put
+// into prompts.
+func (s *Store) SetGate(g *readgate.Gate) {
+	s.gate = g
+}
+
+// SetCompression makes Save store blobs of at least minSize bytes
+// compressed; 0 disables compression. Blobs already stored are unaffected.
+func (s *Store) SetCompression(minSize int64) {
+	s.compressAbove = minSize
+}
+
+// Save persists data as a named artifact, typed by InferType. If content with
+// the same SHA-256 hash already exists the existing artifact is returned
+// (deduplication). Either way a non-empty nodeID is recorded as a producer.
 func (s *Store) Save(name string, data []byte, runID, nodeID string) (*Artifact, error) {
-	hash := sha256sum(data)
+	return s.save(name, bytes.NewReader(data), data, runID, nodeID)
+}
+
+// SaveFrom is Save for content read from r, which is streamed to disk
+// instead of being held in memory. The normalized checksum is only computed
+// for content up to 8 MiB.
+func (s *Store) SaveFrom(name string, r io.Reader, runID, nodeID string) (*Artifact, error) {
+	return s.save(name, r, nil, runID, nodeID)
+}
+
+// maxNormalizeSize bounds the streamed content SaveFrom reads back into
+// memory to compute its normalized checksum.
+const maxNormalizeSize = 8 << 20
 
-	// Deduplication: return existing artifact if hash already indexed.
-	index, err := s.loadIndex()
+// save streams r into a temporary blob while hashing it, then moves the
+// blob into place and catalogs it. data is r's content if the caller
+// already holds it in memory.
+func (s *Store) save(name string, r io.Reader, data []byte, runID, nodeID string) (*Artifact, error) {
+	_, queue, err := s.catalog()
 	if err != nil {
 		return nil, err
 	}
-	for _, a := range index {
-		if a.Hash == hash {
-			return a, nil
+	blobDir := filepath.Join(s.dir, "blobs")
+	if err := os.MkdirAll(blobDir, 0o755); err != nil {
+		return nil, fmt.Errorf("artifact: mkdir blob dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(blobDir, ".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("artifact: write blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("artifact: write blob: %w", err)
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	// Deduplication: return the existing artifact if the hash is cataloged.
+	existing, err := s.lookup(hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if nodeID == "" || hasRef(existing.Producers, runID, nodeID) {
+			return existing, nil
+		}
+		if err := queue.Submit(context.Background(), insertRefSQL, hash, roleProducer, runID, nodeID); err != nil {
+			return nil, fmt.Errorf("artifact: record producer: %w", err)
 		}
+		existing.Producers = append(existing.Producers, Ref{RunID: runID, NodeID: nodeID})
+		return existing, nil
 	}
 
-	// Write blob.
-	bp := s.blobPath(hash)
+	var normHash string
+	if data == nil && size <= maxNormalizeSize {
+		if data, err = os.ReadFile(tmp.Name()); err != nil {
+			return nil, fmt.Errorf("artifact: read blob: %w", err)
+		}
+	}
+	if data != nil || size == 0 {
+		normHash = NormalizedHash(name, data)
+	}
+
+	// Move the blob into place.
+	src, encoding := tmp.Name(), ""
+	if s.compressAbove > 0 && size >= s.compressAbove {
+		if src, err = compressFile(tmp.Name()); err != nil {
+			return nil, err
+		}
+		defer os.Remove(src)
+		encoding = encodingGzip
+	}
+	bp := s.blobPath(hash, encoding)
 	if err := os.MkdirAll(filepath.Dir(bp), 0o755); err != nil {
 		return nil, fmt.Errorf("artifact: mkdir blob dir: %w", err)
 	}
-	if err := os.WriteFile(bp, data, 0o644); err != nil {
+	if err := os.Chmod(src, 0o644); err != nil {
+		return nil, fmt.Errorf("artifact: write blob: %w", err)
+	}
+	if err := os.Rename(src, bp); err != nil {
 		return nil, fmt.Errorf("artifact: write blob: %w", err)
 	}
 
@@ -60,144 +187,282 @@ func (s *Store) Save(name string, data []byte, runID, nodeID string) (*Artifact,
 		Name:      name,
 		RunID:     runID,
 		NodeID:    nodeID,
-		Size:      int64(len(data)),
+		Size:      size,
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Type:      InferType(name),
+		NormHash:  normHash,
+		Encoding:  encoding,
+	}
+	if nodeID != "" {
+		art.Producers = []Ref{{RunID: runID, NodeID: nodeID}}
+	}
+	if err := queue.SubmitAll(context.Background(), insertOps(art)); err != nil {
+		return nil, fmt.Errorf("artifact: catalog %s: %w", shortHash(hash), err)
 	}
 
-	index = append(index, art)
-	if err := s.saveIndex(index); err != nil {
+	// Another writer may have cataloged the same content first, with its
+	// own encoding; its blob is the one that counts.
+	stored, err := s.Get(hash)
+	if err != nil {
 		return nil, err
 	}
-	return art, nil
+	if stored.Encoding != encoding {
+		_ = os.Remove(bp)
+	}
+	return stored, nil
 }
 
 // Get returns the artifact with the given hash, or an error if not found.
 func (s *Store) Get(hash string) (*Artifact, error) {
-	index, err := s.loadIndex()
+	a, err := s.lookup(hash)
 	if err != nil {
 		return nil, err
 	}
-	for _, a := range index {
-		if a.Hash == hash {
-			return a, nil
-		}
+	if a == nil {
+		return nil, fmt.Errorf("artifact: not found: %s", hash)
 	}
-	return nil, fmt.Errorf("artifact: not found: %s", hash)
+	return a, nil
 }
 
-// Data reads and returns the raw blob content for the given hash.
+// Data reads and returns the content of the artifact with the given hash.
 func (s *Store) Data(hash string) ([]byte, error) {
-	bp := s.blobPath(hash)
-	data, err := os.ReadFile(bp)
+	rc, err := s.Open(hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
 	if err != nil {
 		return nil, fmt.Errorf("artifact: read blob: %w", err)
 	}
 	return data, nil
 }
 
-// List returns all indexed artifacts. Returns nil (not an empty slice) when the
-// store is empty.
-func (s *Store) List() ([]*Artifact, error) {
-	index, err := s.loadIndex()
+// Open returns a reader for the content of the artifact with the given
+// hash, decompressing it if the blob is stored compressed. With a gate set,
+// the content is read and redacted as a whole before it is returned.
+func (s *Store) Open(hash string) (io.ReadCloser, error) {
+	a, err := s.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+	bp := s.blobPath(hash, a.Encoding)
+	var f *os.File
+	if s.gate != nil {
+		f, err = s.gate.Open(bp)
+	} else {
+		f, err = os.Open(bp)
+	}
 	if err != nil {
+		return nil, fmt.Errorf("artifact: read blob: %w", err)
+	}
+	rc, err := decodeBlob(f, a.Encoding)
+	if err != nil {
+		f.Close()
 		return nil, err
 	}
-	if len(index) == 0 {
-		return nil, nil
+	if s.gate == nil {
+		return rc, nil
 	}
-	return index, nil
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("artifact: read blob: %w", err)
+	}
+	return io.NopCloser(bytes.NewReader(s.gate.Redact(data))), nil
+}
+
+// List returns all cataloged artifacts in the order they were stored.
+// Returns nil (not an empty slice) when the store is empty.
+func (s *Store) List() ([]*Artifact, error) {
+	return s.Find(Query{})
 }
 
 // ListByRun returns all artifacts belonging to the given runID.
 func (s *Store) ListByRun(runID string) ([]*Artifact, error) {
-	index, err := s.loadIndex()
+	return s.Find(Query{RunID: runID})
+}
+
+// Find returns the artifacts matching q in the order they were stored.
+func (s *Store) Find(q Query) ([]*Artifact, error) {
+	db, _, err := s.catalog()
 	if err != nil {
 		return nil, err
 	}
-	var result []*Artifact
-	for _, a := range index {
-		if a.RunID == runID {
-			result = append(result, a)
-		}
+	where, args := q.where()
+	return find(db, where, args)
+}
+
+// AddConsumer records that runID's nodeID read the artifact.
+func (s *Store) AddConsumer(hash, runID, nodeID string) error {
+	return s.exec(hash, insertRefSQL, hash, roleConsumer, runID, nodeID)
+}
+
+// SetType overrides the inferred type of an artifact.
+func (s *Store) SetType(hash string, t Type) error {
+	return s.exec(hash, `UPDATE artifacts SET type = ? WHERE hash = ?`, string(t), hash)
+}
+
+// AttachSchema validates the artifact against a JSON schema, stores the
+// schema as an artifact of the same run and references it from the
+// artifact.
+func (s *Store) AttachSchema(hash string, schema []byte) (*Artifact, error) {
+	a, err := s.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.Data(hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateSchema(schema, data); err != nil {
+		return nil, err
+	}
+	sch, err := s.Save(fmt.Sprintf("schema-%s.json", shortHash(hash)), schema, a.RunID, "")
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	if err := s.SetType(sch.Hash, TypeAPIContract); err != nil {
+		return nil, err
+	}
+	if err := s.exec(hash, `UPDATE artifacts SET schema_hash = ? WHERE hash = ?`, sch.Hash, hash); err != nil {
+		return nil, err
+	}
+	return s.Get(hash)
 }
 
-// Remove deletes the blob and removes the artifact from the index. Returns an
-// error if the hash is not found in the index.
+// Remove deletes the blob and removes the artifact from the catalog. Returns
+// an error if the hash is not cataloged.
 func (s *Store) Remove(hash string) error {
-	index, err := s.loadIndex()
+	a, err := s.Get(hash)
 	if err != nil {
 		return err
 	}
-	found := -1
-	for i, a := range index {
-		if a.Hash == hash {
-			found = i
-			break
-		}
+	_, queue, err := s.catalog()
+	if err != nil {
+		return err
 	}
-	if found == -1 {
-		return fmt.Errorf("artifact: not found: %s", hash)
+	err = queue.SubmitAll(context.Background(), []writerq.Op{
+		{SQL: `DELETE FROM artifact_refs WHERE hash = ?`, Args: []any{hash}},
+		{SQL: `DELETE FROM artifacts WHERE hash = ?`, Args: []any{hash}},
+	})
+	if err != nil {
+		return fmt.Errorf("artifact: remove %s: %w", shortHash(hash), err)
 	}
-
 	// Remove blob (best-effort; may already be gone).
-	_ = os.Remove(s.blobPath(hash))
-
-	index = append(index[:found], index[found+1:]...)
-	return s.saveIndex(index)
+	_ = os.Remove(s.blobPath(hash, a.Encoding))
+	return nil
 }
 
+// orphanBatch bounds the run IDs bound to one FindOrphans query.
+const orphanBatch = 500
+
 // FindOrphans returns artifacts whose RunID is not in the provided set of valid
 // run IDs.
 func (s *Store) FindOrphans(validRunIDs map[string]bool) ([]*Artifact, error) {
-	index, err := s.loadIndex()
+	db, _, err := s.catalog()
 	if err != nil {
 		return nil, err
 	}
+	rows, err := db.Query(`SELECT DISTINCT run_id FROM artifacts`)
+	if err != nil {
+		return nil, fmt.Errorf("artifact: query catalog: %w", err)
+	}
+	var orphanRuns []any
+	for rows.Next() {
+		var runID string
+		if err := rows.Scan(&runID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("artifact: scan catalog: %w", err)
+		}
+		if !validRunIDs[runID] {
+			orphanRuns = append(orphanRuns, runID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("artifact: query catalog: %w", err)
+	}
+
 	var orphans []*Artifact
-	for _, a := range index {
-		if !validRunIDs[a.RunID] {
-			orphans = append(orphans, a)
+	for len(orphanRuns) > 0 {
+		batch := orphanRuns[:min(orphanBatch, len(orphanRuns))]
+		orphanRuns = orphanRuns[len(batch):]
+		where := "run_id IN (?" + strings.Repeat(", ?", len(batch)-1) + ")"
+		arts, err := find(db, where, batch)
+		if err != nil {
+			return nil, err
 		}
+		orphans = append(orphans, arts...)
 	}
 	return orphans, nil
 }
 
 // --- private helpers --------------------------------------------------------
 
-func (s *Store) indexPath() string {
-	return filepath.Join(s.dir, "index.json")
+// catalog opens the catalog on first use.
+func (s *Store) catalog() (*sql.DB, *writerq.Queue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		db, queue, err := openCatalog(s.dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.db, s.queue = db, queue
+	}
+	return s.db, s.queue, nil
 }
 
-func (s *Store) blobPath(hash string) string {
-	return filepath.Join(s.dir, "blobs", hash[:2], hash)
+// lookup returns the cataloged artifact with the given hash, or nil.
+func (s *Store) lookup(hash string) (*Artifact, error) {
+	db, _, err := s.catalog()
+	if err != nil {
+		return nil, err
+	}
+	arts, err := find(db, "hash = ?", []any{hash})
+	if err != nil || len(arts) == 0 {
+		return nil, err
+	}
+	return arts[0], nil
 }
 
-func (s *Store) loadIndex() ([]*Artifact, error) {
-	data, err := os.ReadFile(s.indexPath())
+// exec applies a write to the cataloged artifact with the given hash.
+func (s *Store) exec(hash, query string, args ...any) error {
+	if _, err := s.Get(hash); err != nil {
+		return err
+	}
+	_, queue, err := s.catalog()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("artifact: read index: %w", err)
+		return err
 	}
-	var index []*Artifact
-	if err := json.Unmarshal(data, &index); err != nil {
-		return nil, fmt.Errorf("artifact: unmarshal index: %w", err)
+	if err := queue.Submit(context.Background(), query, args...); err != nil {
+		return fmt.Errorf("artifact: update %s: %w", shortHash(hash), err)
 	}
-	return index, nil
+	return nil
 }
 
-func (s *Store) saveIndex(index []*Artifact) error {
-	if err := os.MkdirAll(s.dir, 0o755); err != nil {
-		return fmt.Errorf("artifact: mkdir store dir: %w", err)
+func hasRef(refs []Ref, runID, nodeID string) bool {
+	for _, r := range refs {
+		if r.RunID == runID && r.NodeID == nodeID {
+			return true
+		}
 	}
-	data, err := json.MarshalIndent(index, "", "  ")
-	if err != nil {
-		return fmt.Errorf("artifact: marshal index: %w", err)
+	return false
+}
+
+// blobPath returns where the blob with the given hash and encoding lives.
+func (s *Store) blobPath(hash, encoding string) string {
+	p := filepath.Join(s.dir, "blobs", hash[:2], hash)
+	if encoding == encodingGzip {
+		p += ".gz"
 	}
-	return os.WriteFile(s.indexPath(), data, 0o644)
+	return p
+}
+
+// ContentHash returns the hash data is stored under.
+func ContentHash(data []byte) string {
+	return sha256sum(data)
 }
 
 func sha256sum(data []byte) string {
@@ -0,0 +1,87 @@
+package artifact
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+)
+
+// Type classifies what an artifact holds.
+type Type string
+
+const (
+	TypeCodeModule      Type = "code_module"
+	TypeAPIContract     Type = "api_contract"
+	TypeDataset         Type = "dataset"
+	TypeConfig          Type = "config"
+	TypeTestSuite       Type = "test_suite"
+	TypeReport          Type = "report"
+	TypeSecurityFinding Type = "security_finding"
+)
+
+// Types lists every artifact type.
+var Types = []Type{
+	TypeCodeModule, TypeAPIContract, TypeDataset, TypeConfig,
+	TypeTestSuite, TypeReport, TypeSecurityFinding,
+}
+
+// ParseType validates an artifact type name.
+func ParseType(s string) (Type, error) {
+	for _, t := range Types {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("artifact: unknown type %q", s)
+}
+
+// InferType guesses the type of an artifact from its name.
+func InferType(name string) Type {
+	base := strings.ToLower(filepath.Base(name))
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	switch {
+	case strings.HasSuffix(base, "_test.go"), strings.HasPrefix(base, "test_") && ext == ".py",
+		strings.Contains(base, ".test."), strings.Contains(base, ".spec."):
+		return TypeTestSuite
+	case ext == ".sarif", strings.Contains(stem, "vuln"), strings.Contains(stem, "security"):
+		return TypeSecurityFinding
+	case ext == ".proto", ext == ".graphql", strings.Contains(stem, "openapi"), strings.Contains(stem, "swagger"):
+		return TypeAPIContract
+	case ext == ".csv", ext == ".tsv", ext == ".jsonl", ext == ".parquet":
+		return TypeDataset
+	case ext == ".yaml", ext == ".yml", ext == ".toml", ext == ".ini", ext == ".env", ext == ".json":
+		if strings.HasPrefix(stem, "context-") || strings.HasPrefix(stem, "memory-snapshot") {
+			return TypeReport
+		}
+		return TypeConfig
+	case ext == ".go", ext == ".py", ext == ".js", ext == ".ts", ext == ".java", ext == ".rs",
+		ext == ".c", ext == ".cpp", ext == ".h", ext == ".sh", ext == ".sql":
+		return TypeCodeModule
+	}
+	return TypeReport
+}
+
+// Policy returns the compression policy artifacts of type t get by default
+// when they are included in a prompt, see FilePolicy.
+func (t Type) Policy() apexctx.CompressionPolicy {
+	switch t {
+	case TypeCodeModule, TypeTestSuite:
+		return apexctx.PolicyStructural
+	case TypeAPIContract, TypeConfig, TypeSecurityFinding:
+		return apexctx.PolicyExact
+	case TypeDataset:
+		return apexctx.PolicyReference
+	default:
+		return apexctx.PolicySummarizable
+	}
+}
+
+// FilePolicy is the compression policy of the file at path in a prompt:
+// that of its inferred artifact type. apex uses it as the context
+// builder's Classify.
+func FilePolicy(path string) apexctx.CompressionPolicy {
+	return InferType(path).Policy()
+}
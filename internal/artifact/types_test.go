@@ -0,0 +1,66 @@
+package artifact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferType(t *testing.T) {
+	cases := map[string]Type{
+		"internal/kg/graph.go":      TypeCodeModule,
+		"internal/kg/graph_test.go": TypeTestSuite,
+		"web/app.spec.ts":           TypeTestSuite,
+		"api/openapi.yaml":          TypeAPIContract,
+		"proto/run.proto":           TypeAPIContract,
+		"data/users.csv":            TypeDataset,
+		"config.yaml":               TypeConfig,
+		"gosec.sarif":               TypeSecurityFinding,
+		"result-n1.txt":             TypeReport,
+		"prompt-n1.md":              TypeReport,
+		"context-n1.json":           TypeReport,
+	}
+	for name, want := range cases {
+		assert.Equal(t, want, InferType(name), name)
+	}
+}
+
+func TestTypePolicy(t *testing.T) {
+	assert.Equal(t, apexctx.PolicyStructural, TypeCodeModule.Policy())
+	assert.Equal(t, apexctx.PolicyExact, TypeAPIContract.Policy())
+	assert.Equal(t, apexctx.PolicyReference, TypeDataset.Policy())
+	assert.Equal(t, apexctx.PolicySummarizable, TypeReport.Policy())
+}
+
+func TestFilePolicyClassifiesPromptFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users.csv"), []byte("id,name\n1,ada\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.proto"), []byte("syntax = \"proto3\";\n"), 0644))
+
+	b := apexctx.NewBuilder(apexctx.Options{
+		TokenBudget: 60000,
+		Files:       []string{filepath.Join(dir, "users.csv"), filepath.Join(dir, "run.proto")},
+		Classify:    FilePolicy,
+	})
+	_, report, err := b.BuildWithReport(context.Background(), "task")
+	require.NoError(t, err)
+	policies := map[string]apexctx.CompressionPolicy{}
+	for _, blk := range report.Blocks {
+		policies[filepath.Base(blk.ID)] = blk.Policy
+	}
+	assert.Equal(t, apexctx.PolicyReference, policies["users.csv"], "dataset")
+	assert.Equal(t, apexctx.PolicyExact, policies["run.proto"], "api_contract")
+}
+
+func TestParseType(t *testing.T) {
+	got, err := ParseType("dataset")
+	require.NoError(t, err)
+	assert.Equal(t, TypeDataset, got)
+	_, err = ParseType("binary")
+	assert.Error(t, err)
+}
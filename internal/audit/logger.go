@@ -46,23 +46,25 @@ type Entry struct {
 	SandboxLevel   string
 	TraceID        string
 	ParentActionID string
-	ActionID       string // optional; auto-generated if empty
+	ActionID       string   // optional; auto-generated if empty
+	MemoryIDs      []string // memories included in the action's context
 }
 
 type Record struct {
-	Timestamp    string `json:"timestamp"`
-	ActionID     string `json:"action_id"`
-	Task         string `json:"task"`
-	RiskLevel    string `json:"risk_level"`
-	Outcome      string `json:"outcome"`
-	DurationMs   int64  `json:"duration_ms"`
-	Model        string `json:"model"`
-	Error        string `json:"error,omitempty"`
-	SandboxLevel   string `json:"sandbox_level,omitempty"`
-	TraceID        string `json:"trace_id,omitempty"`
-	ParentActionID string `json:"parent_action_id,omitempty"`
-	PrevHash       string `json:"prev_hash,omitempty"`
-	Hash           string `json:"hash,omitempty"`
+	Timestamp      string   `json:"timestamp"`
+	ActionID       string   `json:"action_id"`
+	Task           string   `json:"task"`
+	RiskLevel      string   `json:"risk_level"`
+	Outcome        string   `json:"outcome"`
+	DurationMs     int64    `json:"duration_ms"`
+	Model          string   `json:"model"`
+	Error          string   `json:"error,omitempty"`
+	SandboxLevel   string   `json:"sandbox_level,omitempty"`
+	TraceID        string   `json:"trace_id,omitempty"`
+	ParentActionID string   `json:"parent_action_id,omitempty"`
+	MemoryIDs      []string `json:"memory_ids,omitempty"`
+	PrevHash       string   `json:"prev_hash,omitempty"`
+	Hash           string   `json:"hash,omitempty"`
 }
 
 type Logger struct {
@@ -128,17 +130,18 @@ func (l *Logger) Log(entry Entry) error {
 		actionID = uuid.New().String()
 	}
 	record := Record{
-		Timestamp:    time.Now().UTC().Format(time.RFC3339),
-		ActionID:     actionID,
-		Task:         entry.Task,
-		RiskLevel:    entry.RiskLevel,
-		Outcome:      entry.Outcome,
-		DurationMs:   entry.Duration.Milliseconds(),
-		Model:        entry.Model,
-		Error:        entry.Error,
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+		ActionID:       actionID,
+		Task:           entry.Task,
+		RiskLevel:      entry.RiskLevel,
+		Outcome:        entry.Outcome,
+		DurationMs:     entry.Duration.Milliseconds(),
+		Model:          entry.Model,
+		Error:          entry.Error,
 		SandboxLevel:   entry.SandboxLevel,
 		TraceID:        entry.TraceID,
 		ParentActionID: entry.ParentActionID,
+		MemoryIDs:      entry.MemoryIDs,
 		PrevHash:       l.lastHash,
 	}
 	// Redact sensitive data before hashing
@@ -315,6 +318,37 @@ func (l *Logger) FindByTraceID(traceID string) ([]Record, error) {
 	return results, nil
 }
 
+// FindByMemoryID returns every record whose context included the memory
+// with the given ID, oldest first.
+func (l *Logger) FindByMemoryID(memoryID string) ([]Record, error) {
+	files, err := auditFiles(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	results := make([]Record, 0)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var r Record
+			if err := json.Unmarshal([]byte(line), &r); err != nil {
+				continue
+			}
+			for _, id := range r.MemoryIDs {
+				if id == memoryID {
+					results = append(results, r)
+					break
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
 func (l *Logger) Dir() string {
 	return l.dir
 }
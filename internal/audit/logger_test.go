@@ -445,6 +445,34 @@ func TestFindByTraceID(t *testing.T) {
 	assert.Equal(t, "task-3", results[1].Task)
 }
 
+func TestFindByMemoryID(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := NewLogger(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, logger.Log(Entry{
+		Task: "task-1", RiskLevel: "LOW", Outcome: "success",
+		Duration: time.Second, Model: "test", MemoryIDs: []string{"mem-a", "mem-b"},
+	}))
+	require.NoError(t, logger.Log(Entry{
+		Task: "task-2", RiskLevel: "LOW", Outcome: "success",
+		Duration: time.Second, Model: "test", MemoryIDs: []string{"mem-b"},
+	}))
+
+	results, err := logger.FindByMemoryID("mem-a")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "task-1", results[0].Task)
+
+	results, err = logger.FindByMemoryID("mem-b")
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	valid, _, err := logger.Verify()
+	require.NoError(t, err)
+	assert.True(t, valid, "memory IDs are covered by the hash chain")
+}
+
 func TestFindByTraceIDEmpty(t *testing.T) {
 	dir := t.TempDir()
 	logger, err := NewLogger(dir)
@@ -2,9 +2,13 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 
+	"github.com/lyndonlyu/apex/internal/instructions"
+	"github.com/lyndonlyu/apex/internal/memory"
 	"github.com/lyndonlyu/apex/internal/redact"
 	"gopkg.in/yaml.v3"
 )
@@ -29,18 +33,83 @@ type PlannerConfig struct {
 	Timeout int    `yaml:"timeout"`
 }
 
+type ExtractorConfig struct {
+	Enabled       bool    `yaml:"enabled"` // opt-in: one more model call after every successful run
+	Model         string  `yaml:"model"`
+	Timeout       int     `yaml:"timeout"`
+	ConfirmBelow  float64 `yaml:"confirm_below"`  // decisions below this confidence wait for human confirmation
+	MinConfidence float64 `yaml:"min_confidence"` // candidates below this confidence are discarded
+}
+
+type StagingConfig struct {
+	SimilarityThreshold float64 `yaml:"similarity_threshold"` // cosine similarity for embedding-tier conflict candidates
+	EscalateAbove       float64 `yaml:"escalate_above"`       // contradictions between entries this confident are left for a human
+	NLI                 bool    `yaml:"nli"`                  // classify similar pairs with a small model
+	NLIModel            string  `yaml:"nli_model"`
+	NLITimeout          int     `yaml:"nli_timeout"`
+}
+
+type MemoryConfig struct {
+	Backend        string `yaml:"backend"`          // "sqlite" (memory.db with full-text search) or "files"
+	ExportMarkdown bool   `yaml:"export_markdown"`  // with sqlite, also write memories as markdown/JSONL files
+	VectorSyncSecs int    `yaml:"vector_sync_secs"` // how often the background reconciler embeds pending memories
+
+	// TierWeights multiplies search scores per memory tier (session,
+	// project, global) when results from several tiers are merged.
+	TierWeights map[string]float64 `yaml:"tier_weights"`
+
+	// RetractBlastRadius is how many unstarted nodes of a run a memory
+	// retraction may invalidate and rebuild; beyond it they are escalated.
+	RetractBlastRadius int `yaml:"retract_blast_radius"`
+}
+
 type PoolConfig struct {
 	MaxConcurrent int `yaml:"max_concurrent"`
 }
 
 type EmbeddingConfig struct {
+	Provider   string `yaml:"provider"` // "openai", "openai-compatible" (local llama.cpp, Ollama, ...) or "hash" (offline)
+	BaseURL    string `yaml:"base_url"` // server root; required for openai-compatible
 	Model      string `yaml:"model"`
 	APIKeyEnv  string `yaml:"api_key_env"`
 	Dimensions int    `yaml:"dimensions"`
+	BatchSize  int    `yaml:"batch_size"` // max inputs per embeddings request
 }
 
 type ContextConfig struct {
-	TokenBudget int `yaml:"token_budget"`
+	TokenBudget          int      `yaml:"token_budget"`
+	IncludeDiff          bool     `yaml:"include_diff"`           // add the work-tree diff since the run snapshot to follow-up prompts
+	InstructionFiles     []string `yaml:"instruction_files"`      // file names looked up in the repo root and parent dirs
+	InstructionMaxTokens int      `yaml:"instruction_max_tokens"` // cap for the pinned instructions block
+	KGQueryDepth         int      `yaml:"kg_query_depth"`         // hops around each entity the task mentions
+	MaxKGNodes           int      `yaml:"max_kg_nodes"`           // entities described in the knowledge graph block; the rest are listed by ID
+}
+
+// InvalidationConfig controls the watcher that reruns completed nodes of a
+// run whose input files changed afterwards.
+type InvalidationConfig struct {
+	Enabled          bool `yaml:"enabled"`           // opt-in: every rerun is another model call
+	DebounceMs       int  `yaml:"debounce_ms"`       // a changed input must stay unchanged this long before it counts
+	WindowSecs       int  `yaml:"window_secs"`       // circuit breaker window per (input, node)
+	MaxInvalidations int  `yaml:"max_invalidations"` // invalidations per window before the node is escalated
+}
+
+// CacheConfig controls the result cache, which reuses the stored result of
+// an earlier node execution with the same prompt, model settings and
+// referenced input files.
+type CacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// ArtifactsConfig controls how the artifact store keeps blobs.
+type ArtifactsConfig struct {
+	CompressAboveKB int `yaml:"compress_above_kb"` // gzip blobs at least this large; 0 disables
+}
+
+// SecurityConfig restricts the files whose content apex reads into prompts
+// or prints: context building, paging and artifact reads.
+type SecurityConfig struct {
+	AllowedReadPaths []string `yaml:"allowed_read_paths"` // empty: the repository root and the apex base dir
 }
 
 type RetryConfig struct {
@@ -61,16 +130,23 @@ type SandboxConfig struct {
 }
 
 type Config struct {
-	Claude     ClaudeConfig           `yaml:"claude"`
-	Governance GovernanceConfig       `yaml:"governance"`
-	Planner    PlannerConfig          `yaml:"planner"`
-	Pool       PoolConfig             `yaml:"pool"`
-	Embedding  EmbeddingConfig        `yaml:"embedding"`
-	Context    ContextConfig          `yaml:"context"`
-	Retry      RetryConfig            `yaml:"retry"`
-	Sandbox    SandboxConfig          `yaml:"sandbox"`
-	Redaction  redact.RedactionConfig `yaml:"redaction"`
-	BaseDir    string                 `yaml:"-"`
+	Claude       ClaudeConfig           `yaml:"claude"`
+	Governance   GovernanceConfig       `yaml:"governance"`
+	Planner      PlannerConfig          `yaml:"planner"`
+	Extractor    ExtractorConfig        `yaml:"extractor"`
+	Staging      StagingConfig          `yaml:"staging"`
+	Memory       MemoryConfig           `yaml:"memory"`
+	Pool         PoolConfig             `yaml:"pool"`
+	Embedding    EmbeddingConfig        `yaml:"embedding"`
+	Context      ContextConfig          `yaml:"context"`
+	Invalidation InvalidationConfig     `yaml:"invalidation"`
+	Cache        CacheConfig            `yaml:"cache"`
+	Artifacts    ArtifactsConfig        `yaml:"artifacts"`
+	Retry        RetryConfig            `yaml:"retry"`
+	Sandbox      SandboxConfig          `yaml:"sandbox"`
+	Redaction    redact.RedactionConfig `yaml:"redaction"`
+	Security     SecurityConfig         `yaml:"security"`
+	BaseDir      string                 `yaml:"-"`
 }
 
 func Default() *Config {
@@ -94,16 +170,47 @@ func Default() *Config {
 			Model:   "claude-opus-4-6",
 			Timeout: 120,
 		},
+		Extractor: ExtractorConfig{
+			Model:         "claude-haiku-4-5",
+			Timeout:       120,
+			ConfirmBelow:  0.9,
+			MinConfidence: 0.3,
+		},
+		Staging: StagingConfig{
+			SimilarityThreshold: 0.85,
+			EscalateAbove:       0.9,
+			NLIModel:            "claude-haiku-4-5",
+			NLITimeout:          60,
+		},
+		Memory: MemoryConfig{
+			Backend:        "sqlite",
+			ExportMarkdown: true,
+			VectorSyncSecs: 30,
+			TierWeights:    maps.Clone(memory.DefaultTierWeights),
+
+			RetractBlastRadius: 10,
+		},
 		Pool: PoolConfig{
 			MaxConcurrent: 4,
 		},
 		Embedding: EmbeddingConfig{
+			Provider:   "openai",
 			Model:      "text-embedding-3-small",
 			APIKeyEnv:  "OPENAI_API_KEY",
 			Dimensions: 1536,
+			BatchSize:  64,
 		},
 		Context: ContextConfig{
-			TokenBudget: 60000,
+			TokenBudget:          60000,
+			InstructionFiles:     slices.Clone(instructions.DefaultFiles),
+			InstructionMaxTokens: instructions.DefaultMaxTokens,
+			KGQueryDepth:         2,
+			MaxKGNodes:           200,
+		},
+		Invalidation: InvalidationConfig{
+			DebounceMs:       500,
+			WindowSecs:       600,
+			MaxInvalidations: 3,
 		},
 		Retry: RetryConfig{
 			MaxAttempts:      3,
@@ -162,9 +269,42 @@ func Load(path string) (*Config, error) {
 	if cfg.Planner.Timeout == 0 {
 		cfg.Planner.Timeout = 120
 	}
+	if cfg.Extractor.Model == "" {
+		cfg.Extractor.Model = "claude-haiku-4-5"
+	}
+	if cfg.Extractor.Timeout == 0 {
+		cfg.Extractor.Timeout = 120
+	}
+	if cfg.Staging.SimilarityThreshold == 0 {
+		cfg.Staging.SimilarityThreshold = 0.85
+	}
+	if cfg.Staging.EscalateAbove == 0 {
+		cfg.Staging.EscalateAbove = 0.9
+	}
+	if cfg.Staging.NLIModel == "" {
+		cfg.Staging.NLIModel = "claude-haiku-4-5"
+	}
+	if cfg.Staging.NLITimeout == 0 {
+		cfg.Staging.NLITimeout = 60
+	}
+	if cfg.Memory.Backend == "" {
+		cfg.Memory.Backend = "sqlite"
+	}
+	if cfg.Memory.VectorSyncSecs == 0 {
+		cfg.Memory.VectorSyncSecs = 30
+	}
+	if len(cfg.Memory.TierWeights) == 0 {
+		cfg.Memory.TierWeights = maps.Clone(memory.DefaultTierWeights)
+	}
+	if cfg.Memory.RetractBlastRadius == 0 {
+		cfg.Memory.RetractBlastRadius = 10
+	}
 	if cfg.Pool.MaxConcurrent == 0 {
 		cfg.Pool.MaxConcurrent = 4
 	}
+	if cfg.Embedding.Provider == "" {
+		cfg.Embedding.Provider = "openai"
+	}
 	if cfg.Embedding.Model == "" {
 		cfg.Embedding.Model = "text-embedding-3-small"
 	}
@@ -174,9 +314,30 @@ func Load(path string) (*Config, error) {
 	if cfg.Embedding.Dimensions == 0 {
 		cfg.Embedding.Dimensions = 1536
 	}
+	if cfg.Embedding.BatchSize == 0 {
+		cfg.Embedding.BatchSize = 64
+	}
 	if cfg.Context.TokenBudget == 0 {
 		cfg.Context.TokenBudget = 60000
 	}
+	if len(cfg.Context.InstructionFiles) == 0 {
+		cfg.Context.InstructionFiles = slices.Clone(instructions.DefaultFiles)
+	}
+	if cfg.Context.InstructionMaxTokens == 0 {
+		cfg.Context.InstructionMaxTokens = instructions.DefaultMaxTokens
+	}
+	if cfg.Context.KGQueryDepth == 0 {
+		cfg.Context.KGQueryDepth = 2
+	}
+	if cfg.Context.MaxKGNodes == 0 {
+		cfg.Context.MaxKGNodes = 200
+	}
+	if cfg.Invalidation.WindowSecs == 0 {
+		cfg.Invalidation.WindowSecs = 600
+	}
+	if cfg.Invalidation.MaxInvalidations == 0 {
+		cfg.Invalidation.MaxInvalidations = 3
+	}
 	if cfg.Retry.MaxAttempts == 0 {
 		cfg.Retry.MaxAttempts = 3
 	}
@@ -246,6 +407,61 @@ func (c *Config) Validate() error {
 	if c.Context.TokenBudget < 1000 || c.Context.TokenBudget > 1000000 {
 		return fmt.Errorf("context.token_budget must be 1000-1000000, got %d", c.Context.TokenBudget)
 	}
+	if c.Context.InstructionMaxTokens < 1 || c.Context.InstructionMaxTokens > c.Context.TokenBudget {
+		return fmt.Errorf("context.instruction_max_tokens must be 1-%d (token_budget), got %d",
+			c.Context.TokenBudget, c.Context.InstructionMaxTokens)
+	}
+	if c.Extractor.ConfirmBelow < 0 || c.Extractor.ConfirmBelow > 1 {
+		return fmt.Errorf("extractor.confirm_below must be 0-1, got %.2f", c.Extractor.ConfirmBelow)
+	}
+	if c.Extractor.MinConfidence < 0 || c.Extractor.MinConfidence > 1 {
+		return fmt.Errorf("extractor.min_confidence must be 0-1, got %.2f", c.Extractor.MinConfidence)
+	}
+	if c.Staging.SimilarityThreshold <= 0 || c.Staging.SimilarityThreshold > 1 {
+		return fmt.Errorf("staging.similarity_threshold must be in (0, 1], got %.2f", c.Staging.SimilarityThreshold)
+	}
+	if c.Memory.Backend != "sqlite" && c.Memory.Backend != "files" {
+		return fmt.Errorf("memory.backend must be sqlite/files, got %q", c.Memory.Backend)
+	}
+	if c.Memory.VectorSyncSecs < 1 {
+		return fmt.Errorf("memory.vector_sync_secs must be >= 1, got %d", c.Memory.VectorSyncSecs)
+	}
+	for tier, w := range c.Memory.TierWeights {
+		if _, ok := memory.DefaultTierWeights[tier]; !ok {
+			return fmt.Errorf("memory.tier_weights: unknown tier %q (session/project/global)", tier)
+		}
+		if w <= 0 || w > 10 {
+			return fmt.Errorf("memory.tier_weights.%s must be in (0, 10], got %.2f", tier, w)
+		}
+	}
+	if c.Memory.RetractBlastRadius < 1 {
+		return fmt.Errorf("memory.retract_blast_radius must be >= 1, got %d", c.Memory.RetractBlastRadius)
+	}
+	if c.Artifacts.CompressAboveKB < 0 {
+		return fmt.Errorf("artifacts.compress_above_kb must be >= 0, got %d", c.Artifacts.CompressAboveKB)
+	}
+	if c.Invalidation.DebounceMs < 0 {
+		return fmt.Errorf("invalidation.debounce_ms must be >= 0, got %d", c.Invalidation.DebounceMs)
+	}
+	if c.Invalidation.WindowSecs < 1 {
+		return fmt.Errorf("invalidation.window_secs must be >= 1, got %d", c.Invalidation.WindowSecs)
+	}
+	if c.Invalidation.MaxInvalidations < 1 {
+		return fmt.Errorf("invalidation.max_invalidations must be >= 1, got %d", c.Invalidation.MaxInvalidations)
+	}
+	validProvider := map[string]bool{"openai": true, "openai-compatible": true, "hash": true}
+	if !validProvider[c.Embedding.Provider] {
+		return fmt.Errorf("embedding.provider must be openai/openai-compatible/hash, got %q", c.Embedding.Provider)
+	}
+	if c.Embedding.Provider == "openai-compatible" && c.Embedding.BaseURL == "" {
+		return fmt.Errorf("embedding.base_url is required for provider openai-compatible")
+	}
+	if c.Embedding.Dimensions < 1 || c.Embedding.Dimensions > 16384 {
+		return fmt.Errorf("embedding.dimensions must be 1-16384, got %d", c.Embedding.Dimensions)
+	}
+	if c.Embedding.BatchSize < 1 || c.Embedding.BatchSize > 2048 {
+		return fmt.Errorf("embedding.batch_size must be 1-2048, got %d", c.Embedding.BatchSize)
+	}
 	validSandbox := map[string]bool{"auto": true, "docker": true, "ulimit": true, "none": true}
 	if !validSandbox[c.Sandbox.Level] {
 		return fmt.Errorf("sandbox.level must be auto/docker/ulimit/none, got %q", c.Sandbox.Level)
@@ -257,6 +473,7 @@ func (c *Config) EnsureDirs() error {
 	dirs := []string{
 		filepath.Join(c.BaseDir, "memory", "decisions"),
 		filepath.Join(c.BaseDir, "memory", "facts"),
+		filepath.Join(c.BaseDir, "memory", "incidents"),
 		filepath.Join(c.BaseDir, "memory", "sessions"),
 		filepath.Join(c.BaseDir, "audit"),
 	}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/lyndonlyu/apex/internal/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -112,9 +113,186 @@ func TestLoadConfigPhase3Override(t *testing.T) {
 	assert.Equal(t, 3072, cfg.Embedding.Dimensions)
 }
 
+func TestLoadEmbeddingProvider(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, "openai", cfg.Embedding.Provider)
+	assert.Equal(t, 64, cfg.Embedding.BatchSize)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	content := []byte(`embedding:
+  provider: openai-compatible
+  base_url: http://localhost:11434
+  model: nomic-embed-text
+  dimensions: 768
+`)
+	require.NoError(t, os.WriteFile(configPath, content, 0644))
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "openai-compatible", cfg.Embedding.Provider)
+	assert.Equal(t, "http://localhost:11434", cfg.Embedding.BaseURL)
+	assert.Equal(t, 64, cfg.Embedding.BatchSize)
+	require.NoError(t, cfg.Validate())
+
+	cfg.Embedding.BaseURL = ""
+	assert.Error(t, cfg.Validate())
+	cfg.Embedding.Provider = "cohere"
+	assert.Error(t, cfg.Validate())
+}
+
 func TestDefaultConfigPhase4(t *testing.T) {
 	cfg := Default()
 	assert.Equal(t, 60000, cfg.Context.TokenBudget)
+	assert.Equal(t, 4000, cfg.Context.InstructionMaxTokens)
+	assert.Equal(t, 2, cfg.Context.KGQueryDepth)
+	assert.Equal(t, 200, cfg.Context.MaxKGNodes)
+	assert.Contains(t, cfg.Context.InstructionFiles, "CLAUDE.md")
+}
+
+func TestValidateInstructionCap(t *testing.T) {
+	cfg := Default()
+	require.NoError(t, cfg.Validate())
+	cfg.Context.InstructionMaxTokens = cfg.Context.TokenBudget + 1
+	assert.Error(t, cfg.Validate())
+}
+
+func TestDefaultExtractorConfig(t *testing.T) {
+	cfg := Default()
+	assert.False(t, cfg.Extractor.Enabled, "extraction costs a model call per run")
+	assert.Equal(t, "claude-haiku-4-5", cfg.Extractor.Model)
+	assert.Equal(t, 0.9, cfg.Extractor.ConfirmBelow)
+	assert.Equal(t, 0.3, cfg.Extractor.MinConfidence)
+}
+
+func TestLoadExtractorConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	content := []byte(`extractor:
+  enabled: true
+  confirm_below: 0.75
+`)
+	require.NoError(t, os.WriteFile(configPath, content, 0644))
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.True(t, cfg.Extractor.Enabled)
+	assert.Equal(t, 0.75, cfg.Extractor.ConfirmBelow)
+	assert.Equal(t, 0.3, cfg.Extractor.MinConfidence)
+	assert.Equal(t, 120, cfg.Extractor.Timeout)
+
+	cfg.Extractor.ConfirmBelow = 1.5
+	assert.Error(t, cfg.Validate())
+}
+
+func TestLoadInvalidationConfig(t *testing.T) {
+	cfg := Default()
+	assert.False(t, cfg.Invalidation.Enabled, "reruns cost model calls")
+	assert.Equal(t, 500, cfg.Invalidation.DebounceMs)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("invalidation:\n  enabled: true\n  debounce_ms: 0\n"), 0644))
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.True(t, cfg.Invalidation.Enabled)
+	assert.Equal(t, 0, cfg.Invalidation.DebounceMs)
+	assert.Equal(t, 600, cfg.Invalidation.WindowSecs)
+	assert.Equal(t, 3, cfg.Invalidation.MaxInvalidations)
+	require.NoError(t, cfg.Validate())
+
+	cfg.Invalidation.DebounceMs = -1
+	assert.Error(t, cfg.Validate())
+}
+
+func TestLoadCacheConfig(t *testing.T) {
+	assert.False(t, Default().Cache.Enabled, "the result cache is opt-in")
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("cache:\n  enabled: true\n"), 0644))
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.True(t, cfg.Cache.Enabled)
+}
+
+func TestLoadArtifactsConfig(t *testing.T) {
+	assert.Zero(t, Default().Artifacts.CompressAboveKB, "compression is opt-in")
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("artifacts:\n  compress_above_kb: 256\n"), 0644))
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, 256, cfg.Artifacts.CompressAboveKB)
+
+	cfg.Artifacts.CompressAboveKB = -1
+	assert.Error(t, cfg.Validate())
+}
+
+func TestLoadSecurityConfig(t *testing.T) {
+	assert.Empty(t, Default().Security.AllowedReadPaths)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("security:\n  allowed_read_paths:\n    - ~/src\n    - /srv/docs\n"), 0644))
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"~/src", "/srv/docs"}, cfg.Security.AllowedReadPaths)
+}
+
+func TestLoadStagingConfig(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, 0.85, cfg.Staging.SimilarityThreshold)
+	assert.False(t, cfg.Staging.NLI)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("staging:\n  nli: true\n  escalate_above: 0.8\n"), 0644))
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.True(t, cfg.Staging.NLI)
+	assert.Equal(t, 0.8, cfg.Staging.EscalateAbove)
+	assert.Equal(t, 0.85, cfg.Staging.SimilarityThreshold)
+	assert.Equal(t, "claude-haiku-4-5", cfg.Staging.NLIModel)
+}
+
+func TestLoadMemoryConfig(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, "sqlite", cfg.Memory.Backend)
+	assert.True(t, cfg.Memory.ExportMarkdown)
+	assert.Equal(t, 30, cfg.Memory.VectorSyncSecs)
+	assert.Equal(t, 10, cfg.Memory.RetractBlastRadius)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("memory:\n  export_markdown: false\n"), 0644))
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite", cfg.Memory.Backend)
+	assert.False(t, cfg.Memory.ExportMarkdown)
+
+	assert.Equal(t, 10, cfg.Memory.RetractBlastRadius)
+
+	cfg.Memory.RetractBlastRadius = -1
+	assert.Error(t, cfg.Validate())
+	cfg.Memory.RetractBlastRadius = 10
+
+	cfg.Memory.Backend = "postgres"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestLoadTierWeights(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("memory:\n  tier_weights:\n    global: 0.5\n"), 0644))
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, cfg.Memory.TierWeights["global"])
+	assert.Equal(t, 1.0, cfg.Memory.TierWeights["project"], "unset tiers keep their default")
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, 0.8, memory.DefaultTierWeights["global"], "loading leaves the defaults alone")
+
+	cfg.Memory.TierWeights["team"] = 1
+	assert.Error(t, cfg.Validate())
 }
 
 func TestLoadConfigPhase4Override(t *testing.T) {
@@ -122,11 +300,13 @@ func TestLoadConfigPhase4Override(t *testing.T) {
 	configPath := filepath.Join(dir, "config.yaml")
 	content := []byte(`context:
   token_budget: 30000
+  include_diff: true
 `)
 	require.NoError(t, os.WriteFile(configPath, content, 0644))
 	cfg, err := Load(configPath)
 	require.NoError(t, err)
 	assert.Equal(t, 30000, cfg.Context.TokenBudget)
+	assert.True(t, cfg.Context.IncludeDiff)
 }
 
 func TestDefaultConfigPhase10(t *testing.T) {
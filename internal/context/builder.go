@@ -2,6 +2,7 @@ package context
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -22,11 +23,52 @@ type Searcher interface {
 	Search(ctx context.Context, query string, topK int) ([]SearchResult, error)
 }
 
+// VersionedSearcher is a Searcher over a fixed snapshot of memory. Builds
+// using one record its version so they can be reproduced later.
+type VersionedSearcher interface {
+	Searcher
+	Version() string
+}
+
+// Provider contributes additional content blocks to a prompt, such as a
+// repository map or a diff of the working tree. Provide may return blocks
+// together with an error describing what it had to leave out.
+type Provider interface {
+	Name() string
+	Provide(ctx context.Context, task string) ([]ContentBlock, error)
+}
+
+// FileReader reads the files listed in Options.Files, e.g. through a
+// path security gate.
+type FileReader interface {
+	ReadFile(path string) ([]byte, error)
+}
+
+// Gate is the path security gate providers read through. Check vets a file
+// opened by other means, such as a database, and Redact scrubs text derived
+// from files, such as a diff.
+type Gate interface {
+	FileReader
+	Check(path string) error
+	Redact(data []byte) []byte
+}
+
 // Options configures the context Builder.
 type Options struct {
 	TokenBudget int
 	Searcher    Searcher
 	Files       []string
+	Providers   []Provider
+	// Reader reads Files; nil reads them directly. Files that exist but
+	// cannot be read, including reads the reader refuses, are listed in
+	// the build Report's Errors, as are provider errors.
+	Reader FileReader
+	// Classify picks the compression policy of each of Files; nil
+	// classifies by file extension.
+	Classify func(path string) CompressionPolicy
+	// MemoryVersion pins the build to a memory snapshot. When set, Searcher
+	// must be a VersionedSearcher reporting the same version.
+	MemoryVersion string
 }
 
 // Builder assembles optimized prompts within a token budget.
@@ -47,12 +89,38 @@ type ContentBlock struct {
 	Text     string
 	Policy   CompressionPolicy
 	Priority int
+
+	// Title is the section heading used when rendering provider blocks.
+	// Defaults to Source.
+	Title string
+	// DegradeTo, when more aggressive than Policy, is the policy the block
+	// jumps to when degraded instead of stepping through Degrade.
+	DegradeTo CompressionPolicy
+	// Pinned blocks are never compressed, degraded, or dropped by
+	// fitBudget, just like the task block. Providers of pinned blocks are
+	// responsible for capping their size.
+	Pinned bool
+	// Warning is surfaced in the build Report, e.g. when a provider had to
+	// truncate its content.
+	Warning string
 }
 
 // Build assembles an optimized prompt for the given task within the token budget.
 // It gathers content from the task, memory search results, and files, then
 // compresses and degrades content as needed to fit the budget.
 func (b *Builder) Build(ctx context.Context, task string) (string, error) {
+	prompt, _, err := b.BuildWithReport(ctx, task)
+	return prompt, err
+}
+
+// BuildWithReport behaves like Build but also returns a Report describing
+// every block that was considered and what fitBudget did to it.
+func (b *Builder) BuildWithReport(ctx context.Context, task string) (string, *Report, error) {
+	memoryVersion, err := b.memoryVersion()
+	if err != nil {
+		return "", nil, err
+	}
+
 	var blocks []ContentBlock
 
 	// 1. Create task block (highest priority, exact policy).
@@ -84,10 +152,14 @@ func (b *Builder) Build(ctx context.Context, task string) (string, error) {
 	}
 
 	// 3. Read files and classify them.
+	var readErrors []string
 	for _, path := range b.opts.Files {
-		data, err := os.ReadFile(path)
+		data, err := b.readFile(path)
 		if err != nil {
-			// Skip files that can't be read.
+			// Skip files that can't be read; a missing file is not an error.
+			if !errors.Is(err, os.ErrNotExist) {
+				readErrors = append(readErrors, fmt.Sprintf("file %s: %v", path, err))
+			}
 			continue
 		}
 		blocks = append(blocks, ContentBlock{
@@ -95,21 +167,74 @@ func (b *Builder) Build(ctx context.Context, task string) (string, error) {
 			Source:   "file",
 			Path:     path,
 			Text:     string(data),
-			Policy:   classifyFile(path),
+			Policy:   b.classify(path),
 			Priority: 60,
 		})
 	}
 
-	// 4. Sort blocks by priority descending.
+	// 4. Collect blocks from providers. A failing provider must not block
+	// the run, but its error, such as a denied read, is reported. Blocks
+	// returned along with an error are kept.
+	for _, p := range b.opts.Providers {
+		provided, err := p.Provide(ctx, task)
+		if err != nil {
+			readErrors = append(readErrors, fmt.Sprintf("%s: %v", p.Name(), err))
+		}
+		blocks = append(blocks, provided...)
+	}
+
+	// 5. Sort blocks by priority descending.
 	sort.SliceStable(blocks, func(i, j int) bool {
 		return blocks[i].Priority > blocks[j].Priority
 	})
 
-	// 5. Apply compression to fit within budget.
-	blocks = fitBudget(blocks, b.opts.TokenBudget)
+	// 6. Apply compression to fit within budget.
+	kept, blockReports := fitBudget(append([]ContentBlock(nil), blocks...), b.opts.TokenBudget)
+
+	report := &Report{
+		Budget:        b.opts.TokenBudget,
+		TotalTokens:   totalTokens(kept),
+		MemoryVersion: memoryVersion,
+		Blocks:        blockReports,
+		Errors:        readErrors,
+	}
+	for _, blk := range blocks {
+		if blk.Warning != "" {
+			report.Warnings = append(report.Warnings, blk.Warning)
+		}
+	}
+
+	// 7. Assemble the final prompt.
+	return assemble(kept), report, nil
+}
+
+// readFile reads one of Options.Files through the configured Reader.
+func (b *Builder) readFile(path string) ([]byte, error) {
+	if b.opts.Reader != nil {
+		return b.opts.Reader.ReadFile(path)
+	}
+	return os.ReadFile(path)
+}
 
-	// 6. Assemble the final prompt.
-	return assemble(blocks), nil
+// classify returns the compression policy of one of Options.Files.
+func (b *Builder) classify(path string) CompressionPolicy {
+	if b.opts.Classify != nil {
+		return b.opts.Classify(path)
+	}
+	return classifyFile(path)
+}
+
+// memoryVersion returns the version of the configured memory snapshot, or an
+// error if the build is pinned to a different one.
+func (b *Builder) memoryVersion() (string, error) {
+	var version string
+	if vs, ok := b.opts.Searcher.(VersionedSearcher); ok {
+		version = vs.Version()
+	}
+	if b.opts.MemoryVersion != "" && version != b.opts.MemoryVersion {
+		return "", fmt.Errorf("context: memory snapshot is version %q, build is pinned to %q", version, b.opts.MemoryVersion)
+	}
+	return version, nil
 }
 
 // classifyFile returns the appropriate CompressionPolicy for a file based on
@@ -130,16 +255,29 @@ func classifyFile(path string) CompressionPolicy {
 
 // fitBudget compresses and degrades blocks to fit within the token budget.
 // It preserves original text for each block so that compression can be
-// re-applied at increasing levels of aggressiveness.
-func fitBudget(blocks []ContentBlock, budget int) []ContentBlock {
+// re-applied at increasing levels of aggressiveness. It returns the surviving
+// blocks along with a BlockReport for every input block, in input order,
+// including the ones that had to be dropped.
+func fitBudget(blocks []ContentBlock, budget int) ([]ContentBlock, []BlockReport) {
 	// Save original text so we can re-compress from source at each level.
 	type blockState struct {
 		original string
 		applied  bool // whether compression has been applied
+		report   int  // index into reports
 	}
 	state := make([]blockState, len(blocks))
+	reports := make([]BlockReport, len(blocks))
 	for i := range blocks {
-		state[i] = blockState{original: blocks[i].Text, applied: false}
+		state[i] = blockState{original: blocks[i].Text, applied: false, report: i}
+		reports[i] = BlockReport{
+			ID:             blocks[i].ID,
+			Source:         blocks[i].Source,
+			Path:           blocks[i].Path,
+			Priority:       blocks[i].Priority,
+			Policy:         blocks[i].Policy,
+			Pinned:         blocks[i].Pinned,
+			OriginalTokens: EstimateTokens(blocks[i].Text),
+		}
 	}
 
 	// Iteratively compress, degrade, and remove blocks until we fit.
@@ -153,7 +291,7 @@ func fitBudget(blocks []ContentBlock, budget int) []ContentBlock {
 		// (lowest priority first).
 		compressed := false
 		for i := len(blocks) - 1; i >= 0; i-- {
-			if blocks[i].Source == "task" {
+			if fixed(blocks[i]) {
 				continue
 			}
 			if !state[i].applied {
@@ -171,10 +309,10 @@ func fitBudget(blocks []ContentBlock, budget int) []ContentBlock {
 		// non-task block.
 		degraded := false
 		for i := len(blocks) - 1; i >= 0; i-- {
-			if blocks[i].Source == "task" {
+			if fixed(blocks[i]) {
 				continue
 			}
-			newPolicy := Degrade(blocks[i].Policy)
+			newPolicy := degradeBlock(blocks[i])
 			if newPolicy != blocks[i].Policy {
 				blocks[i].Policy = newPolicy
 				blocks[i].Text = Compress(newPolicy, blocks[i].Path, state[i].original)
@@ -191,7 +329,10 @@ func fitBudget(blocks []ContentBlock, budget int) []ContentBlock {
 		// non-task block.
 		removed := false
 		for i := len(blocks) - 1; i >= 0; i-- {
-			if blocks[i].Source != "task" {
+			if !fixed(blocks[i]) {
+				r := &reports[state[i].report]
+				r.FinalPolicy = blocks[i].Policy
+				r.Dropped = true
 				blocks = append(blocks[:i], blocks[i+1:]...)
 				state = append(state[:i], state[i+1:]...)
 				removed = true
@@ -200,12 +341,32 @@ func fitBudget(blocks []ContentBlock, budget int) []ContentBlock {
 		}
 
 		if !removed {
-			// Only the task block remains; nothing more we can do.
+			// Only the task and pinned blocks remain; nothing more we can do.
 			break
 		}
 	}
 
-	return blocks
+	for i := range blocks {
+		r := &reports[state[i].report]
+		r.FinalPolicy = blocks[i].Policy
+		r.FinalTokens = EstimateTokens(blocks[i].Text)
+		r.Compressed = state[i].applied
+	}
+
+	return blocks, reports
+}
+
+// fixed reports whether fitBudget must leave a block untouched.
+func fixed(b ContentBlock) bool {
+	return b.Source == "task" || b.Pinned
+}
+
+// degradeBlock returns the next policy for a block, honouring DegradeTo.
+func degradeBlock(b ContentBlock) CompressionPolicy {
+	if b.DegradeTo > b.Policy {
+		return b.DegradeTo
+	}
+	return Degrade(b.Policy)
 }
 
 // totalTokens returns the estimated total token count across all blocks.
@@ -225,6 +386,7 @@ func assemble(blocks []ContentBlock) string {
 	var taskText string
 	var memoryBlocks []ContentBlock
 	var fileBlocks []ContentBlock
+	var otherBlocks []ContentBlock
 
 	for _, b := range blocks {
 		switch b.Source {
@@ -234,6 +396,8 @@ func assemble(blocks []ContentBlock) string {
 			memoryBlocks = append(memoryBlocks, b)
 		case "file":
 			fileBlocks = append(fileBlocks, b)
+		default:
+			otherBlocks = append(otherBlocks, b)
 		}
 	}
 
@@ -249,6 +413,15 @@ func assemble(blocks []ContentBlock) string {
 		}
 	}
 
+	// Provider sections.
+	for _, o := range otherBlocks {
+		title := o.Title
+		if title == "" {
+			title = o.Source
+		}
+		sb.WriteString(fmt.Sprintf("\n\n## %s\n\n%s", title, o.Text))
+	}
+
 	// File sections.
 	for _, f := range fileBlocks {
 		sb.WriteString(fmt.Sprintf("\n\n## File: %s\n\n%s", f.Path, f.Text))
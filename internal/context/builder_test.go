@@ -3,6 +3,7 @@ package context
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -95,3 +96,73 @@ func TestBuildDegradation(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, result, "task")
 }
+
+type mockProvider struct {
+	blocks []ContentBlock
+	err    error
+}
+
+func (m *mockProvider) Name() string { return "mock" }
+
+func (m *mockProvider) Provide(ctx context.Context, task string) ([]ContentBlock, error) {
+	return m.blocks, m.err
+}
+
+func TestBuildWithProvider(t *testing.T) {
+	p := &mockProvider{blocks: []ContentBlock{
+		{ID: "map", Source: "repomap", Title: "Repository Map", Text: "cmd/apex\ninternal/context", Policy: PolicyStructural, Priority: 20},
+	}}
+	b := NewBuilder(Options{TokenBudget: 60000, Providers: []Provider{p}})
+	result, err := b.Build(context.Background(), "task")
+	require.NoError(t, err)
+	assert.Contains(t, result, "## Repository Map")
+	assert.Contains(t, result, "internal/context")
+}
+
+func TestBuildProviderError(t *testing.T) {
+	p := &mockProvider{err: assert.AnError}
+	b := NewBuilder(Options{TokenBudget: 60000, Providers: []Provider{p}})
+	result, report, err := b.BuildWithReport(context.Background(), "task")
+	require.NoError(t, err)
+	assert.Contains(t, result, "task")
+	assert.Equal(t, []string{"mock: " + assert.AnError.Error()}, report.Errors)
+}
+
+func TestFitBudgetDegradeTo(t *testing.T) {
+	text := "# Map\n\nintro line\n\n" + strings.Repeat("## section\nline of detail\n", 20)
+	blocks := []ContentBlock{
+		{ID: "task", Source: "task", Text: "task", Policy: PolicyExact, Priority: 100},
+		{ID: "map", Source: "repomap", Path: "repomap", Text: text, Policy: PolicyStructural, Priority: 20, DegradeTo: PolicyReference},
+	}
+	kept, reports := fitBudget(blocks, 15)
+	require.Len(t, kept, 2)
+	assert.Equal(t, PolicyReference, kept[1].Policy)
+	assert.Equal(t, PolicyReference, reports[1].FinalPolicy)
+	assert.Contains(t, kept[1].Text, "[ref: repomap")
+}
+
+type versionedSearcher struct {
+	mockSearchEngine
+	version string
+}
+
+func (v *versionedSearcher) Version() string { return v.version }
+
+func TestBuildPinnedMemoryVersion(t *testing.T) {
+	s := &versionedSearcher{version: "v1"}
+	s.results = []SearchResult{{ID: "facts/a.md", Text: "pinned fact"}}
+
+	b := NewBuilder(Options{TokenBudget: 60000, Searcher: s, MemoryVersion: "v1"})
+	prompt, report, err := b.BuildWithReport(context.Background(), "task")
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "pinned fact")
+	assert.Equal(t, "v1", report.MemoryVersion)
+
+	b = NewBuilder(Options{TokenBudget: 60000, Searcher: s, MemoryVersion: "v2"})
+	_, _, err = b.BuildWithReport(context.Background(), "task")
+	assert.ErrorContains(t, err, "pinned")
+
+	b = NewBuilder(Options{TokenBudget: 60000, MemoryVersion: "v1"})
+	_, _, err = b.BuildWithReport(context.Background(), "task")
+	assert.Error(t, err)
+}
@@ -31,6 +31,39 @@ func (p CompressionPolicy) String() string {
 	}
 }
 
+// MarshalText encodes the policy as its String form so that reports
+// serialise as "structural" rather than an opaque integer.
+func (p CompressionPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a policy previously encoded by MarshalText.
+func (p *CompressionPolicy) UnmarshalText(text []byte) error {
+	parsed, err := ParsePolicy(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// ParsePolicy converts a policy name (as returned by String) back into a
+// CompressionPolicy.
+func ParsePolicy(name string) (CompressionPolicy, error) {
+	switch strings.ToLower(name) {
+	case "exact":
+		return PolicyExact, nil
+	case "structural":
+		return PolicyStructural, nil
+	case "summarizable":
+		return PolicySummarizable, nil
+	case "reference":
+		return PolicyReference, nil
+	default:
+		return PolicyExact, fmt.Errorf("context: unknown compression policy %q", name)
+	}
+}
+
 // Degrade returns the next more aggressive compression policy.
 // PolicySummarizable degrades to PolicyReference.
 // PolicyStructural degrades to PolicySummarizable.
@@ -69,9 +102,13 @@ func CompressExact(text string) string {
 }
 
 // CompressStructural keeps function/type signatures, package declarations, and
-// import statements while truncating function bodies. For non-code text it
-// falls back to CompressSummarizable.
+// import statements while truncating function bodies. Unified diffs keep
+// their headers and changed lines. For other non-code text it falls back to
+// CompressSummarizable.
 func CompressStructural(text string) string {
+	if looksLikeDiff(text) {
+		return CompressDiff(text)
+	}
 	if !looksLikeCode(text) {
 		return CompressSummarizable(text)
 	}
@@ -152,6 +189,19 @@ func CompressStructural(text string) string {
 	return strings.Join(out, "\n") + "\n"
 }
 
+// CompressDiff drops the unchanged context lines of a unified diff, keeping
+// file and hunk headers and every added or removed line.
+func CompressDiff(text string) string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, " ") || line == "" {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
 // CompressSummarizable keeps markdown headings and the first non-empty
 // paragraph (the first contiguous block of non-heading, non-blank lines)
 // after the title heading.
@@ -236,6 +286,11 @@ func looksLikeCode(text string) bool {
 	return false
 }
 
+// looksLikeDiff returns true if the text contains a unified diff.
+func looksLikeDiff(text string) bool {
+	return strings.HasPrefix(text, "diff --git ") || strings.Contains(text, "\ndiff --git ")
+}
+
 // isSigLine returns true if a trimmed line looks like a function, type, or
 // class declaration.
 func isSigLine(trimmed string) bool {
@@ -43,3 +43,13 @@ func TestCompressStructuralNonCode(t *testing.T) {
 	result := CompressStructural(text)
 	assert.NotEmpty(t, result)
 }
+
+func TestCompressStructuralDiff(t *testing.T) {
+	text := "+1 -1 lines\ndiff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n package main\n-func old() {}\n+func new() {}\n // trailing\n"
+	result := CompressStructural(text)
+	assert.Contains(t, result, "+1 -1 lines")
+	assert.Contains(t, result, "@@ -1,3 +1,3 @@")
+	assert.Contains(t, result, "-func old() {}")
+	assert.Contains(t, result, "+func new() {}")
+	assert.NotContains(t, result, " package main")
+}
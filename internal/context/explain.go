@@ -0,0 +1,138 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// BlockReport records what happened to a single ContentBlock while the
+// prompt was being fitted to the token budget.
+type BlockReport struct {
+	ID             string            `json:"id"`
+	Source         string            `json:"source"`
+	Path           string            `json:"path,omitempty"`
+	Priority       int               `json:"priority"`
+	Policy         CompressionPolicy `json:"policy"`       // policy the block started with
+	FinalPolicy    CompressionPolicy `json:"final_policy"` // policy after any degradation
+	Pinned         bool              `json:"pinned,omitempty"`
+	OriginalTokens int               `json:"original_tokens"`
+	FinalTokens    int               `json:"final_tokens"`
+	Compressed     bool              `json:"compressed"`
+	Dropped        bool              `json:"dropped"`
+}
+
+// Degraded reports whether the block ended up on a more aggressive policy
+// than the one it started with.
+func (r BlockReport) Degraded() bool {
+	return r.FinalPolicy != r.Policy
+}
+
+// Report explains how a prompt was assembled: which blocks were considered,
+// how each was compressed or degraded, and which ones fitBudget dropped.
+type Report struct {
+	Budget        int           `json:"budget"`
+	TotalTokens   int           `json:"total_tokens"`
+	MemoryVersion string        `json:"memory_version,omitempty"`
+	Blocks        []BlockReport `json:"blocks"`
+	Warnings      []string      `json:"warnings,omitempty"`
+	Errors        []string      `json:"errors,omitempty"` // files and providers that could not be read, e.g. denied by the read gate
+}
+
+// Dropped returns the reports of all blocks removed by fitBudget.
+func (r *Report) Dropped() []BlockReport {
+	var out []BlockReport
+	for _, b := range r.Blocks {
+		if b.Dropped {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// status returns a one-word summary of the block outcome.
+func (r BlockReport) status() string {
+	switch {
+	case r.Dropped:
+		return "dropped"
+	case r.Pinned:
+		return "pinned"
+	case r.Degraded():
+		return "degraded"
+	case r.Compressed:
+		return "compressed"
+	default:
+		return "kept"
+	}
+}
+
+// FormatReport renders a Report as an aligned table followed by a summary
+// of dropped blocks.
+//
+//	Budget: 812/60000 tokens (3 blocks, 1 dropped)
+//
+//	ID          SOURCE  PRI  POLICY        FINAL       TOKENS     STATUS
+//	task        task    100  exact         exact       12 -> 12   kept
+func FormatReport(r *Report) string {
+	if r == nil {
+		return "No context report."
+	}
+
+	idW := len("ID")
+	for _, b := range r.Blocks {
+		if l := len(b.ID); l > idW {
+			idW = l
+		}
+	}
+
+	var sb strings.Builder
+	dropped := r.Dropped()
+	fmt.Fprintf(&sb, "Budget: %d/%d tokens (%d blocks, %d dropped)\n",
+		r.TotalTokens, r.Budget, len(r.Blocks), len(dropped))
+	if r.MemoryVersion != "" {
+		fmt.Fprintf(&sb, "Memory: %s\n", r.MemoryVersion)
+	}
+	sb.WriteString("\n")
+
+	rowFmt := fmt.Sprintf("%%-%ds  %%-8s  %%4s  %%-13s %%-13s %%-12s %%s\n", idW)
+	fmt.Fprintf(&sb, rowFmt, "ID", "SOURCE", "PRI", "POLICY", "FINAL", "TOKENS", "STATUS")
+	for _, b := range r.Blocks {
+		final := b.FinalPolicy.String()
+		if b.Dropped {
+			final = "-"
+		}
+		fmt.Fprintf(&sb, rowFmt, b.ID, b.Source, fmt.Sprintf("%d", b.Priority),
+			b.Policy.String(), final,
+			fmt.Sprintf("%d -> %d", b.OriginalTokens, b.FinalTokens), b.status())
+	}
+
+	if len(dropped) > 0 {
+		sb.WriteString("\nDropped by fitBudget:\n")
+		for _, b := range dropped {
+			fmt.Fprintf(&sb, "  %s (%s, %d tokens at %s)\n", b.ID, b.Source, b.OriginalTokens, b.FinalPolicy)
+		}
+	}
+
+	if len(r.Warnings) > 0 {
+		sb.WriteString("\nWarnings:\n")
+		for _, w := range r.Warnings {
+			fmt.Fprintf(&sb, "  %s\n", w)
+		}
+	}
+	if len(r.Errors) > 0 {
+		sb.WriteString("\nErrors:\n")
+		for _, e := range r.Errors {
+			fmt.Fprintf(&sb, "  %s\n", e)
+		}
+	}
+	return sb.String()
+}
+
+// FormatReportJSON returns the report as indented JSON.
+func FormatReportJSON(r *Report) (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("context: marshal report: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,156 @@
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildWithReportListsEveryBlock(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(dir+"/main.go", []byte("package main\n\nfunc main() {}\n"), 0644))
+
+	engine := &mockSearchEngine{
+		results: []SearchResult{{ID: "facts/go.md", Text: "We use Go 1.25", Score: 0.9, Type: "fact"}},
+	}
+	b := NewBuilder(Options{TokenBudget: 60000, Searcher: engine, Files: []string{dir + "/main.go"}})
+	prompt, report, err := b.BuildWithReport(context.Background(), "Modify main")
+	require.NoError(t, err)
+	require.NotNil(t, report)
+
+	require.Len(t, report.Blocks, 3)
+	assert.Equal(t, "task", report.Blocks[0].Source)
+	assert.Equal(t, "memory", report.Blocks[1].Source)
+	assert.Equal(t, "file", report.Blocks[2].Source)
+	assert.Equal(t, PolicyStructural, report.Blocks[2].Policy)
+	assert.Equal(t, 60000, report.Budget)
+	assert.Equal(t, EstimateTokens(prompt) > 0, report.TotalTokens > 0)
+	for _, br := range report.Blocks {
+		assert.False(t, br.Dropped)
+		assert.Equal(t, br.OriginalTokens, br.FinalTokens)
+	}
+}
+
+func TestBuildWithReportRecordsDroppedBlocks(t *testing.T) {
+	engine := &mockSearchEngine{
+		results: []SearchResult{
+			{ID: "facts/a.md", Text: "# Fact A\n" + strings.Repeat("fact A content\n", 40), Score: 0.9, Type: "fact"},
+			{ID: "facts/b.md", Text: "# Fact B\n" + strings.Repeat("fact B content\n", 40), Score: 0.5, Type: "fact"},
+		},
+	}
+	b := NewBuilder(Options{TokenBudget: 15, Searcher: engine})
+	prompt, report, err := b.BuildWithReport(context.Background(), "task")
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "task")
+
+	dropped := report.Dropped()
+	require.NotEmpty(t, dropped)
+	for _, d := range dropped {
+		assert.Equal(t, "memory", d.Source)
+		assert.Equal(t, 0, d.FinalTokens)
+		assert.Equal(t, PolicyReference, d.FinalPolicy, "blocks are degraded as far as possible before being dropped")
+	}
+}
+
+func TestFormatReport(t *testing.T) {
+	r := &Report{
+		Budget:      100,
+		TotalTokens: 40,
+		Blocks: []BlockReport{
+			{ID: "task", Source: "task", Priority: 100, Policy: PolicyExact, FinalPolicy: PolicyExact, OriginalTokens: 10, FinalTokens: 10},
+			{ID: "notes.md", Source: "file", Priority: 60, Policy: PolicySummarizable, FinalPolicy: PolicyReference, OriginalTokens: 200, FinalTokens: 30, Compressed: true},
+			{ID: "big.md", Source: "file", Priority: 60, Policy: PolicySummarizable, FinalPolicy: PolicyReference, OriginalTokens: 900, Compressed: true, Dropped: true},
+		},
+	}
+	out := FormatReport(r)
+	assert.Contains(t, out, "Budget: 40/100 tokens (3 blocks, 1 dropped)")
+	assert.Contains(t, out, "200 -> 30")
+	assert.Contains(t, out, "degraded")
+	assert.Contains(t, out, "Dropped by fitBudget:")
+	assert.Contains(t, out, "big.md (file, 900 tokens at reference)")
+
+	assert.Equal(t, "No context report.", FormatReport(nil))
+}
+
+func TestFormatReportJSONRoundTrip(t *testing.T) {
+	r := &Report{
+		Budget: 100,
+		Blocks: []BlockReport{{ID: "task", Source: "task", Policy: PolicyStructural, FinalPolicy: PolicyReference}},
+	}
+	out, err := FormatReportJSON(r)
+	require.NoError(t, err)
+	assert.Contains(t, out, `"policy": "structural"`)
+
+	var parsed Report
+	require.NoError(t, json.Unmarshal([]byte(out), &parsed))
+	assert.Equal(t, PolicyReference, parsed.Blocks[0].FinalPolicy)
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, p := range []CompressionPolicy{PolicyExact, PolicyStructural, PolicySummarizable, PolicyReference} {
+		parsed, err := ParsePolicy(p.String())
+		require.NoError(t, err)
+		assert.Equal(t, p, parsed)
+	}
+	_, err := ParsePolicy("bogus")
+	assert.Error(t, err)
+}
+
+func TestBuildWithReportPinnedBlockSurvives(t *testing.T) {
+	pinned := &mockProvider{blocks: []ContentBlock{{
+		ID: "instructions", Source: "instructions", Text: strings.Repeat("Always run gofmt.\n", 20),
+		Policy: PolicyExact, Priority: 95, Pinned: true, Warning: "instructions truncated",
+	}}}
+	engine := &mockSearchEngine{
+		results: []SearchResult{{ID: "facts/a.md", Text: "# Fact A\n" + strings.Repeat("detail\n", 30), Score: 0.9}},
+	}
+	b := NewBuilder(Options{TokenBudget: 10, Searcher: engine, Providers: []Provider{pinned}})
+	prompt, report, err := b.BuildWithReport(context.Background(), "task")
+	require.NoError(t, err)
+
+	assert.Contains(t, prompt, "Always run gofmt.")
+	require.Len(t, report.Blocks, 3)
+	assert.True(t, report.Blocks[1].Pinned)
+	assert.False(t, report.Blocks[1].Dropped)
+	assert.True(t, report.Blocks[2].Dropped)
+	assert.Equal(t, []string{"instructions truncated"}, report.Warnings)
+
+	out := FormatReport(report)
+	assert.Contains(t, out, "pinned")
+	assert.Contains(t, out, "Warnings:\n  instructions truncated")
+}
+
+type denyReader struct{}
+
+func (denyReader) ReadFile(path string) ([]byte, error) {
+	if strings.HasSuffix(path, "secret.txt") {
+		return nil, fmt.Errorf("read of %s denied: outside the allowed read paths", path)
+	}
+	return os.ReadFile(path)
+}
+
+func TestBuildWithReportListsReadErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(dir+"/main.go", []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(dir+"/secret.txt", []byte("hunter2"), 0644))
+
+	b := NewBuilder(Options{
+		TokenBudget: 1000,
+		Files:       []string{dir + "/main.go", dir + "/secret.txt", dir + "/missing.go"},
+		Reader:      denyReader{},
+	})
+	prompt, report, err := b.BuildWithReport(context.Background(), "task")
+	require.NoError(t, err)
+
+	assert.Contains(t, prompt, "package main")
+	assert.NotContains(t, prompt, "hunter2")
+	require.Len(t, report.Errors, 1, "missing files are skipped silently")
+	assert.Contains(t, report.Errors[0], "secret.txt")
+	assert.Contains(t, FormatReport(report), "Errors:\n  file "+dir+"/secret.txt: read of")
+}
@@ -318,6 +318,36 @@ func (d *DAG) NodeSlice() []*Node {
 	return order
 }
 
+// Ancestors returns the IDs of all nodes that id depends on, directly or
+// transitively, sorted by ID. Thread-safe.
+func (d *DAG) Ancestors(id string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		n, ok := d.Nodes[id]
+		if !ok {
+			return
+		}
+		for _, dep := range n.Depends {
+			if !seen[dep] {
+				seen[dep] = true
+				visit(dep)
+			}
+		}
+	}
+	visit(id)
+
+	out := make([]string, 0, len(seen))
+	for dep := range seen {
+		out = append(out, dep)
+	}
+	sort.Strings(out)
+	return out
+}
+
 // RemoveNode removes a node from the DAG and strips it from all dependency lists.
 // Thread-safe. No-op if the node does not exist.
 func (d *DAG) RemoveNode(id string) {
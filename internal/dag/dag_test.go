@@ -207,6 +207,20 @@ func TestRemoveNodeNonexistent(t *testing.T) {
 	assert.Len(t, d.Nodes, 1)
 }
 
+func TestAncestors(t *testing.T) {
+	nodes := []NodeSpec{
+		{ID: "a", Task: "task a", Depends: []string{}},
+		{ID: "b", Task: "task b", Depends: []string{"a"}},
+		{ID: "c", Task: "task c", Depends: []string{"a"}},
+		{ID: "d", Task: "task d", Depends: []string{"b", "c"}},
+	}
+	d, _ := New(nodes)
+	assert.Equal(t, []string{"a", "b", "c"}, d.Ancestors("d"))
+	assert.Equal(t, []string{"a"}, d.Ancestors("b"))
+	assert.Empty(t, d.Ancestors("a"))
+	assert.Empty(t, d.Ancestors("missing"))
+}
+
 func readyIDs(nodes []*Node) []string {
 	ids := make([]string, len(nodes))
 	for i, n := range nodes {
@@ -219,6 +219,22 @@ func (d *DAG) Invalidate(id string) error {
 	return nil
 }
 
+// Revoke transitions a node that has not started (Pending, Blocked or
+// Ready) to Invalidated, when an input it was prepared with is withdrawn.
+func (d *DAG) Revoke(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n, ok := d.Nodes[id]
+	if !ok {
+		return fmt.Errorf("dag: node %q not found", id)
+	}
+	if n.Status != Pending && n.Status != Blocked && n.Status != Ready {
+		return fmt.Errorf("dag: cannot revoke node %q: current status is %s", id, n.Status)
+	}
+	n.Status = Invalidated
+	return nil
+}
+
 // Requeue transitions a node from Invalidated back to Pending.
 func (d *DAG) Requeue(id string) error {
 	d.mu.Lock()
@@ -249,6 +265,25 @@ func (d *DAG) Escalate(id string) error {
 	return nil
 }
 
+// Hold transitions a node that has not started, or was invalidated, to
+// Escalated and skips its pending dependents: a human has to decide
+// whether it can still run.
+func (d *DAG) Hold(id, reason string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n, ok := d.Nodes[id]
+	if !ok {
+		return fmt.Errorf("dag: node %q not found", id)
+	}
+	if n.Status != Pending && n.Status != Blocked && n.Status != Ready && n.Status != Invalidated {
+		return fmt.Errorf("dag: cannot hold node %q: current status is %s", id, n.Status)
+	}
+	n.Status = Escalated
+	n.Error = reason
+	d.cascadeSkip(id)
+	return nil
+}
+
 // MarkNeedsHuman transitions a node from Failed to NeedsHuman.
 func (d *DAG) MarkNeedsHuman(id string) error {
 	d.mu.Lock()
@@ -293,6 +293,36 @@ func TestInvalidateAndRequeue(t *testing.T) {
 	assert.Equal(t, Pending, d.Nodes["step1"].Status)
 }
 
+func TestRevoke(t *testing.T) {
+	d := makeLinearDAG(t)
+
+	require.NoError(t, d.Revoke("step2"))
+	assert.Equal(t, Invalidated, d.Nodes["step2"].Status)
+	require.NoError(t, d.Requeue("step2"))
+	assert.Equal(t, Pending, d.Nodes["step2"].Status)
+
+	d.Nodes["step1"].Status = Running
+	assert.Error(t, d.Revoke("step1"))
+}
+
+func TestHold(t *testing.T) {
+	d, err := New([]NodeSpec{
+		{ID: "step1", Task: "first"},
+		{ID: "step2", Task: "second", Depends: []string{"step1"}},
+		{ID: "step3", Task: "third", Depends: []string{"step2"}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, d.Hold("step2", "memory retracted"))
+	assert.Equal(t, Escalated, d.Nodes["step2"].Status)
+	assert.Equal(t, "memory retracted", d.Nodes["step2"].Error)
+	assert.Equal(t, Skipped, d.Nodes["step3"].Status, "dependents are skipped")
+	assert.Equal(t, Pending, d.Nodes["step1"].Status)
+
+	d.Nodes["step1"].Status = Completed
+	assert.Error(t, d.Hold("step1", ""))
+}
+
 func TestEscalate(t *testing.T) {
 	d := makeLinearDAG(t)
 
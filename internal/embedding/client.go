@@ -7,15 +7,27 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// DefaultBaseURL is the OpenAI API root.
+const DefaultBaseURL = "https://api.openai.com"
+
+// defaultBatchSize caps the inputs sent in one embeddings request.
+const defaultBatchSize = 64
+
+// Client talks to the OpenAI embeddings API or any server that implements
+// it (llama.cpp, Ollama, vLLM, ...).
 type Client struct {
-	apiKey     string
-	model      string
-	dimensions int
-	baseURL    string
-	httpClient *http.Client
+	provider    string
+	apiKey      string
+	keyOptional bool // local servers usually need no key
+	model       string
+	dimensions  int
+	baseURL     string
+	batchSize   int
+	httpClient  *http.Client
 }
 
 type embeddingRequest struct {
@@ -28,29 +40,94 @@ type embeddingResponse struct {
 }
 
 type embeddingData struct {
+	Index     int       `json:"index"`
 	Embedding []float32 `json:"embedding"`
 }
 
+// NewClient returns a client for the OpenAI API. It is unavailable without
+// an API key.
 func NewClient(apiKey string, model string, dimensions int) *Client {
 	return &Client{
+		provider:   "openai",
 		apiKey:     apiKey,
 		model:      model,
 		dimensions: dimensions,
-		baseURL:    "https://api.openai.com",
+		baseURL:    DefaultBaseURL,
+		batchSize:  defaultBatchSize,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
 	}
 }
 
+// NewCompatibleClient returns a client for an OpenAI-compatible server at
+// baseURL, e.g. http://localhost:11434 for Ollama. The API key is optional.
+func NewCompatibleClient(baseURL, apiKey, model string, dimensions int) *Client {
+	c := NewClient(apiKey, model, dimensions)
+	c.provider = "openai-compatible"
+	c.baseURL = normalizeBaseURL(baseURL)
+	c.keyOptional = true
+	return c
+}
+
+// normalizeBaseURL accepts the server root with or without the /v1 suffix.
+func normalizeBaseURL(u string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(u, "/"), "/v1")
+}
+
+// SetBatchSize sets the maximum number of inputs per request; n < 1 is ignored.
+func (c *Client) SetBatchSize(n int) {
+	if n >= 1 {
+		c.batchSize = n
+	}
+}
+
 func (c *Client) Available() bool {
-	return c.apiKey != ""
+	return c.apiKey != "" || (c.keyOptional && c.baseURL != "")
+}
+
+// Dimensions returns the vector size the client expects from the server.
+func (c *Client) Dimensions() int {
+	return c.dimensions
+}
+
+// Fingerprint identifies the provider, server, model and dimensions.
+func (c *Client) Fingerprint() string {
+	return fmt.Sprintf("%s|%s|%s|%d", c.provider, c.baseURL, c.model, c.dimensions)
 }
 
 func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+	vecs, err := c.request(ctx, text, 1)
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// EmbedBatch embeds texts in requests of at most the batch size, returning
+// vectors in input order.
+func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	results := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += c.batchSize {
+		end := start + c.batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		vecs, err := c.request(ctx, texts[start:end], end-start)
+		if err != nil {
+			return nil, fmt.Errorf("embed texts %d-%d: %w", start, end-1, err)
+		}
+		results = append(results, vecs...)
+	}
+	return results, nil
+}
+
+// request posts input (a string or a slice of n strings) and returns the n
+// vectors ordered by their index, checking each has the expected size.
+func (c *Client) request(ctx context.Context, input interface{}, n int) ([][]float32, error) {
 	if !c.Available() {
 		return nil, fmt.Errorf("embedding client unavailable: API key not set")
 	}
 
-	reqBody := embeddingRequest{Model: c.model, Input: text}
+	reqBody := embeddingRequest{Model: c.model, Input: input}
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
@@ -61,7 +138,9 @@ func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -86,18 +165,24 @@ func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
 	if len(embResp.Data) == 0 {
 		return nil, fmt.Errorf("empty embedding response")
 	}
+	if len(embResp.Data) != n {
+		return nil, fmt.Errorf("got %d embeddings for %d inputs", len(embResp.Data), n)
+	}
 
-	return embResp.Data[0].Embedding, nil
-}
-
-func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
-	results := make([][]float32, len(texts))
-	for i, text := range texts {
-		vec, err := c.Embed(ctx, text)
-		if err != nil {
-			return nil, fmt.Errorf("embed text %d: %w", i, err)
+	vecs := make([][]float32, n)
+	for i, d := range embResp.Data {
+		idx := d.Index
+		if n == 1 {
+			idx = 0
+		}
+		if idx < 0 || idx >= n || vecs[idx] != nil {
+			return nil, fmt.Errorf("bad embedding index %d in response", d.Index)
 		}
-		results[i] = vec
+		if len(d.Embedding) != c.dimensions {
+			return nil, fmt.Errorf("%w: item %d has %d dimensions, want %d",
+				ErrDimensionMismatch, i, len(d.Embedding), c.dimensions)
+		}
+		vecs[idx] = d.Embedding
 	}
-	return results, nil
+	return vecs, nil
 }
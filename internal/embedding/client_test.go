@@ -57,10 +57,13 @@ func TestEmbed(t *testing.T) {
 
 func TestEmbedBatch(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		resp := embeddingResponse{
-			Data: []embeddingData{
-				{Embedding: make([]float32, 1536)},
-			},
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var resp embeddingResponse
+		for i := range req.Input {
+			resp.Data = append(resp.Data, embeddingData{Index: i, Embedding: make([]float32, 1536)})
 		}
 		json.NewEncoder(w).Encode(resp)
 	}))
@@ -95,3 +98,45 @@ func TestEmbedAPIError(t *testing.T) {
 	_, err := c.Embed(context.Background(), "hello")
 	assert.Error(t, err)
 }
+
+func TestEmbedBatchSplitsAndOrders(t *testing.T) {
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		sizes = append(sizes, len(req.Input))
+		// Answer in reverse order; the client must sort by index.
+		var resp embeddingResponse
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, embeddingData{Index: i, Embedding: []float32{float32(len(req.Input[i])), 0}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	c := NewCompatibleClient(server.URL+"/v1/", "", "nomic-embed-text", 2)
+	c.SetBatchSize(2)
+	assert.True(t, c.Available(), "local servers need no key")
+
+	vecs, err := c.EmbedBatch(context.Background(), []string{"a", "bb", "ccc"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, sizes)
+	require.Len(t, vecs, 3)
+	for i, v := range vecs {
+		assert.Equal(t, float32(i+1), v[0])
+	}
+}
+
+func TestEmbedDimensionMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(embeddingResponse{Data: []embeddingData{{Embedding: make([]float32, 768)}}})
+	}))
+	defer server.Close()
+
+	c := NewCompatibleClient(server.URL, "", "nomic-embed-text", 1536)
+	_, err := c.Embed(context.Background(), "hello")
+	assert.ErrorIs(t, err, ErrDimensionMismatch)
+}
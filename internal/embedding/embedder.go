@@ -0,0 +1,60 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDimensionMismatch is returned when a server answers with vectors of a
+// different size than configured.
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// Embedder turns text into fixed-size vectors.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	Available() bool
+	Dimensions() int
+	// Fingerprint changes whenever vectors from this embedder stop being
+	// comparable with earlier ones (other provider, model or size), which
+	// means the vector index must be rebuilt.
+	Fingerprint() string
+}
+
+// Options selects and configures an embedding provider.
+type Options struct {
+	Provider   string // "openai" (default), "openai-compatible" or "hash"
+	BaseURL    string // server root; defaults to DefaultBaseURL for openai
+	APIKey     string
+	Model      string
+	Dimensions int
+	BatchSize  int
+}
+
+// New returns the embedder for opts.Provider.
+func New(opts Options) (Embedder, error) {
+	if opts.Dimensions < 1 {
+		return nil, fmt.Errorf("embedding: dimensions must be positive, got %d", opts.Dimensions)
+	}
+	switch opts.Provider {
+	case "", "openai":
+		c := NewClient(opts.APIKey, opts.Model, opts.Dimensions)
+		if opts.BaseURL != "" {
+			c.baseURL = normalizeBaseURL(opts.BaseURL)
+		}
+		c.SetBatchSize(opts.BatchSize)
+		return c, nil
+	case "openai-compatible":
+		if opts.BaseURL == "" {
+			return nil, fmt.Errorf("embedding: provider openai-compatible needs a base URL")
+		}
+		c := NewCompatibleClient(opts.BaseURL, opts.APIKey, opts.Model, opts.Dimensions)
+		c.SetBatchSize(opts.BatchSize)
+		return c, nil
+	case "hash":
+		return NewHashEmbedder(opts.Dimensions), nil
+	default:
+		return nil, fmt.Errorf("embedding: unknown provider %q", opts.Provider)
+	}
+}
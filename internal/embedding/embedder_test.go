@@ -0,0 +1,30 @@
+package embedding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSelectsProvider(t *testing.T) {
+	e, err := New(Options{Model: "text-embedding-3-small", Dimensions: 1536})
+	require.NoError(t, err)
+	assert.False(t, e.Available(), "openai needs a key")
+	assert.Equal(t, "openai|https://api.openai.com|text-embedding-3-small|1536", e.Fingerprint())
+
+	e, err = New(Options{Provider: "openai-compatible", BaseURL: "http://localhost:11434/v1", Model: "nomic", Dimensions: 768})
+	require.NoError(t, err)
+	assert.True(t, e.Available())
+	assert.Equal(t, "openai-compatible|http://localhost:11434|nomic|768", e.Fingerprint())
+
+	e, err = New(Options{Provider: "hash", Dimensions: 256})
+	require.NoError(t, err)
+	assert.True(t, e.Available())
+	assert.Equal(t, 256, e.Dimensions())
+
+	_, err = New(Options{Provider: "openai-compatible", Dimensions: 768})
+	assert.Error(t, err)
+	_, err = New(Options{Provider: "cohere", Dimensions: 768})
+	assert.Error(t, err)
+}
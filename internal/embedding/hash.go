@@ -0,0 +1,94 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// hashVersion is part of the fingerprint; bump it when the features change.
+const hashVersion = "ngram-v1"
+
+// HashEmbedder is an offline embedder. It hashes words, word bigrams and
+// character trigrams into a signed feature vector (the hashing trick), so
+// texts sharing vocabulary land close together. It needs no network and is
+// always available, but captures no meaning beyond shared n-grams.
+type HashEmbedder struct {
+	dimensions int
+}
+
+// NewHashEmbedder returns a HashEmbedder producing vectors of the given size.
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	return &HashEmbedder{dimensions: dimensions}
+}
+
+func (h *HashEmbedder) Available() bool { return h.dimensions > 0 }
+
+func (h *HashEmbedder) Dimensions() int { return h.dimensions }
+
+func (h *HashEmbedder) Fingerprint() string {
+	return fmt.Sprintf("hash|%s|%d", hashVersion, h.dimensions)
+}
+
+// Embed returns the L2-normalised feature vector of text. Text without any
+// words yields the zero vector.
+func (h *HashEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vec := make([]float64, h.dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		h.add(vec, "w:"+w, 1)
+		if i > 0 {
+			h.add(vec, "b:"+words[i-1]+" "+w, 0.5)
+		}
+		runes := []rune("^" + w + "$")
+		for j := 0; j+3 <= len(runes); j++ {
+			h.add(vec, "c:"+string(runes[j:j+3]), 0.25)
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	out := make([]float32, h.dimensions)
+	if norm == 0 {
+		return out, nil
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vec {
+		out[i] = float32(v / norm)
+	}
+	return out, nil
+}
+
+// add hashes feature to a bucket; one hash bit picks the sign so that
+// collisions cancel out on average instead of piling up.
+func (h *HashEmbedder) add(vec []float64, feature string, weight float64) {
+	f := fnv.New64a()
+	f.Write([]byte(feature))
+	sum := f.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vec[sum%uint64(len(vec))] += weight
+}
+
+func (h *HashEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	results := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec, err := h.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = vec
+	}
+	return results, nil
+}
@@ -0,0 +1,51 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestHashEmbedder(t *testing.T) {
+	h := NewHashEmbedder(256)
+	ctx := context.Background()
+
+	a, err := h.Embed(ctx, "Use PostgreSQL for the billing database")
+	require.NoError(t, err)
+	require.Len(t, a, 256)
+
+	var norm float64
+	for _, v := range a {
+		norm += float64(v) * float64(v)
+	}
+	assert.InDelta(t, 1, math.Sqrt(norm), 1e-5)
+
+	again, err := h.Embed(ctx, "use postgresql for the billing database!")
+	require.NoError(t, err)
+	assert.Equal(t, a, again, "deterministic and case-insensitive")
+
+	near, err := h.Embed(ctx, "billing database runs on PostgreSQL")
+	require.NoError(t, err)
+	far, err := h.Embed(ctx, "frontend uses tailwind css classes")
+	require.NoError(t, err)
+	assert.Greater(t, cosine(a, near), cosine(a, far))
+
+	empty, err := h.Embed(ctx, "  ...  ")
+	require.NoError(t, err)
+	assert.Equal(t, make([]float32, 256), empty)
+
+	batch, err := h.EmbedBatch(ctx, []string{"one", "two"})
+	require.NoError(t, err)
+	assert.Len(t, batch, 2)
+}
@@ -0,0 +1,250 @@
+// Package extractor pulls durable facts, decisions and incidents out of the
+// results of a finished run so they can be staged as memory candidates.
+package extractor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/lyndonlyu/apex/internal/executor"
+	"github.com/lyndonlyu/apex/internal/staging"
+)
+
+// Candidate categories. They match the staging categories used when the
+// candidate is committed to memory.
+const (
+	CategoryFact     = "fact"
+	CategoryDecision = "decision"
+	CategoryIncident = "incident"
+)
+
+// Evidence types.
+const (
+	EvidenceFileHash = "file_hash" // Ref is a path relative to the work tree
+	EvidenceActionID = "action_id" // Ref is a node ID of the run
+	EvidenceLogLine  = "log_line"  // Ref is a node ID, Value a line of its result
+)
+
+// maxResultChars bounds how much of each node result is shown to the model.
+const maxResultChars = 4000
+
+// Runner runs a prompt against a model. *executor.Executor satisfies it.
+type Runner interface {
+	Run(ctx context.Context, task string) (executor.Result, error)
+}
+
+// NodeInput is one finished node of the run.
+type NodeInput struct {
+	ID       string
+	ActionID string
+	Task     string
+	Result   string
+}
+
+// Input is the finished run handed to the extractor.
+type Input struct {
+	RunID string
+	Task  string
+	Nodes []NodeInput
+}
+
+// Evidence ties a candidate to something observable in the run. Verified is
+// set by Verify, never by the model.
+type Evidence struct {
+	Type     string `json:"type"`
+	Ref      string `json:"ref"`
+	Value    string `json:"value,omitempty"`
+	Verified bool   `json:"verified"`
+}
+
+// Candidate is a memory proposed by the extractor.
+type Candidate struct {
+	Category   string     `json:"category"`
+	Content    string     `json:"content"`
+	Confidence float64    `json:"confidence"`
+	Evidence   []Evidence `json:"evidence"`
+	// Claim is the structured form of a fact or decision, used by the
+	// staging conflict detector. Optional.
+	Claim *staging.Claim `json:"claim,omitempty"`
+}
+
+// BuildExtractorPrompt constructs the prompt asking the model for memory
+// candidates from the run's node results.
+func BuildExtractorPrompt(in Input) string {
+	var sb strings.Builder
+	sb.WriteString(`You extract durable knowledge from a finished task run.
+
+Return ONLY a JSON array. Each element has:
+- "category": "fact" (a stable truth about the project), "decision" (a choice that was made and why) or "incident" (something that went wrong and how it was handled)
+- "content": one or two self-contained sentences
+- "confidence": number between 0 and 1
+- "evidence": array of {"type", "ref", "value"} where type is one of
+  "file_hash" (ref: file path the claim is about),
+  "action_id" (ref: node ID that produced the claim),
+  "log_line" (ref: node ID, value: a line copied verbatim from that node's result)
+- "claim": optional {"entity", "property", "value"} for facts and decisions that set a single value (e.g. {"entity": "api", "property": "timeout", "value": "30s"})
+
+Rules:
+- Only include knowledge that stays useful after this run
+- Every element needs at least one evidence entry
+- Return [] when there is nothing worth keeping
+- Return valid JSON only, no markdown, no explanation
+
+`)
+	fmt.Fprintf(&sb, "Task: %s\n", in.Task)
+	for _, n := range in.Nodes {
+		result := n.Result
+		if r := []rune(result); len(r) > maxResultChars {
+			result = string(r[:maxResultChars]) + "\n[... truncated]"
+		}
+		fmt.Fprintf(&sb, "\n## Node %s: %s\n\n%s\n", n.ID, n.Task, result)
+	}
+	return sb.String()
+}
+
+var fencePattern = regexp.MustCompile("(?s)```(?:json)?\\s*\\n?(.*?)\\n?```")
+
+// ParseCandidates parses raw model output into candidates. It accepts bare
+// JSON or JSON wrapped in markdown code fences, drops entries with an
+// unknown category or empty content, and clamps confidence to [0, 1].
+func ParseCandidates(raw string) ([]Candidate, error) {
+	cleaned := raw
+	if matches := fencePattern.FindStringSubmatch(raw); len(matches) > 1 {
+		cleaned = matches[1]
+	}
+	cleaned = strings.TrimSpace(cleaned)
+
+	var parsed []Candidate
+	if err := json.Unmarshal([]byte(cleaned), &parsed); err != nil {
+		return nil, fmt.Errorf("extractor: parse output: %w", err)
+	}
+
+	var out []Candidate
+	for _, c := range parsed {
+		c.Content = strings.TrimSpace(c.Content)
+		if c.Content == "" {
+			continue
+		}
+		switch c.Category {
+		case CategoryFact, CategoryDecision, CategoryIncident:
+		default:
+			continue
+		}
+		if c.Confidence < 0 {
+			c.Confidence = 0
+		}
+		if c.Confidence > 1 {
+			c.Confidence = 1
+		}
+		if c.Claim != nil && (c.Claim.Entity == "" || c.Claim.Property == "" || c.Claim.Value == "") {
+			c.Claim = nil
+		}
+		for i := range c.Evidence {
+			c.Evidence[i].Verified = false
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// Verify checks each candidate's evidence against the run and the work tree
+// in dir. File evidence is resolved to the file's SHA-256, action evidence
+// to the node's action ID, and log lines must appear in the node's result.
+// Evidence that cannot be checked is dropped, and a candidate left with no
+// verified evidence has its confidence halved.
+func Verify(candidates []Candidate, in Input, dir string) []Candidate {
+	nodes := make(map[string]NodeInput, len(in.Nodes))
+	for _, n := range in.Nodes {
+		nodes[n.ID] = n
+	}
+
+	out := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		var kept []Evidence
+		for _, e := range c.Evidence {
+			if v, ok := verifyEvidence(e, nodes, dir); ok {
+				kept = append(kept, v)
+			}
+		}
+		c.Evidence = kept
+		if len(kept) == 0 {
+			c.Confidence /= 2
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func verifyEvidence(e Evidence, nodes map[string]NodeInput, dir string) (Evidence, bool) {
+	switch e.Type {
+	case EvidenceFileHash:
+		if e.Ref == "" || filepath.IsAbs(e.Ref) || strings.HasPrefix(filepath.Clean(e.Ref), "..") {
+			return e, false
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Ref))
+		if err != nil {
+			return e, false
+		}
+		sum := sha256.Sum256(data)
+		e.Value = hex.EncodeToString(sum[:])
+	case EvidenceActionID:
+		n, ok := nodes[e.Ref]
+		if !ok || n.ActionID == "" {
+			return e, false
+		}
+		e.Value = n.ActionID
+	case EvidenceLogLine:
+		n, ok := nodes[e.Ref]
+		line := strings.TrimSpace(e.Value)
+		if !ok || line == "" || !strings.Contains(n.Result, line) {
+			return e, false
+		}
+		e.Value = line
+	default:
+		return e, false
+	}
+	e.Verified = true
+	return e, true
+}
+
+// Extract asks the model for memory candidates from the run and verifies
+// their evidence against the work tree in dir. Candidates below
+// minConfidence after verification are discarded.
+func Extract(ctx context.Context, runner Runner, in Input, dir string, minConfidence float64) ([]Candidate, error) {
+	result, err := runner.Run(ctx, BuildExtractorPrompt(in))
+	if err != nil {
+		return nil, fmt.Errorf("extractor: run: %w", err)
+	}
+	candidates, err := ParseCandidates(result.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Candidate
+	for _, c := range Verify(candidates, in, dir) {
+		if c.Confidence >= minConfidence {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// FormatEvidence renders verified evidence as JSON for storage alongside a
+// staged candidate.
+func FormatEvidence(evidence []Evidence) string {
+	if len(evidence) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(evidence)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,130 @@
+package extractor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyndonlyu/apex/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRunner struct {
+	output string
+	err    error
+	prompt string
+}
+
+func (f *fakeRunner) Run(ctx context.Context, task string) (executor.Result, error) {
+	f.prompt = task
+	return executor.Result{Output: f.output}, f.err
+}
+
+func testInput() Input {
+	return Input{
+		RunID: "run-1",
+		Task:  "switch the cache to redis",
+		Nodes: []NodeInput{
+			{ID: "impl", ActionID: "act-1", Task: "replace cache", Result: "Replaced memcache client.\nAll 42 tests pass."},
+		},
+	}
+}
+
+func TestBuildExtractorPrompt(t *testing.T) {
+	p := BuildExtractorPrompt(testInput())
+	assert.Contains(t, p, "Task: switch the cache to redis")
+	assert.Contains(t, p, "## Node impl: replace cache")
+	assert.Contains(t, p, "All 42 tests pass.")
+	assert.Contains(t, p, `"log_line"`)
+}
+
+func TestParseCandidatesFenced(t *testing.T) {
+	raw := "```json\n" + `[
+		{"category": "decision", "content": "Use redis for caching.", "confidence": 0.8,
+		 "evidence": [{"type": "action_id", "ref": "impl", "verified": true}]},
+		{"category": "opinion", "content": "Nice code.", "confidence": 0.9},
+		{"category": "fact", "content": "  ", "confidence": 0.9},
+		{"category": "incident", "content": "Flaky test fixed.", "confidence": 1.7, "claim": {"entity": "ci"}},
+		{"category": "fact", "content": "API timeout is 30s.", "confidence": 0.7,
+		 "claim": {"entity": "api", "property": "timeout", "value": "30s"}}
+	]` + "\n```"
+	cands, err := ParseCandidates(raw)
+	require.NoError(t, err)
+	require.Len(t, cands, 3)
+	assert.Equal(t, CategoryDecision, cands[0].Category)
+	assert.False(t, cands[0].Evidence[0].Verified, "model cannot mark evidence verified")
+	assert.Equal(t, 1.0, cands[1].Confidence)
+	assert.Nil(t, cands[1].Claim, "incomplete claims are dropped")
+	require.NotNil(t, cands[2].Claim)
+	assert.Equal(t, "timeout", cands[2].Claim.Property)
+}
+
+func TestParseCandidatesInvalid(t *testing.T) {
+	_, err := ParseCandidates("not json")
+	assert.Error(t, err)
+}
+
+func TestVerifyEvidence(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cache.go"), []byte("package cache\n"), 0644))
+	sum := sha256.Sum256([]byte("package cache\n"))
+
+	cands := []Candidate{
+		{Category: CategoryFact, Content: "cache lives in cache.go", Confidence: 0.8, Evidence: []Evidence{
+			{Type: EvidenceFileHash, Ref: "cache.go"},
+			{Type: EvidenceFileHash, Ref: "../outside.go"},
+		}},
+		{Category: CategoryDecision, Content: "use redis", Confidence: 0.8, Evidence: []Evidence{
+			{Type: EvidenceActionID, Ref: "impl"},
+			{Type: EvidenceLogLine, Ref: "impl", Value: "All 42 tests pass."},
+		}},
+		{Category: CategoryIncident, Content: "made up", Confidence: 0.8, Evidence: []Evidence{
+			{Type: EvidenceLogLine, Ref: "impl", Value: "segfault"},
+			{Type: EvidenceActionID, Ref: "missing"},
+		}},
+	}
+
+	out := Verify(cands, testInput(), dir)
+	require.Len(t, out, 3)
+
+	require.Len(t, out[0].Evidence, 1)
+	assert.Equal(t, hex.EncodeToString(sum[:]), out[0].Evidence[0].Value)
+	assert.True(t, out[0].Evidence[0].Verified)
+	assert.Equal(t, 0.8, out[0].Confidence)
+
+	require.Len(t, out[1].Evidence, 2)
+	assert.Equal(t, "act-1", out[1].Evidence[0].Value)
+
+	assert.Empty(t, out[2].Evidence)
+	assert.Equal(t, 0.4, out[2].Confidence)
+}
+
+func TestExtractFiltersLowConfidence(t *testing.T) {
+	r := &fakeRunner{output: `[
+		{"category": "fact", "content": "Tests use testify.", "confidence": 0.9,
+		 "evidence": [{"type": "log_line", "ref": "impl", "value": "All 42 tests pass."}]},
+		{"category": "fact", "content": "Unsupported claim.", "confidence": 0.5}
+	]`}
+	cands, err := Extract(context.Background(), r, testInput(), t.TempDir(), 0.3)
+	require.NoError(t, err)
+	require.Len(t, cands, 1)
+	assert.Equal(t, "Tests use testify.", cands[0].Content)
+	assert.Contains(t, r.prompt, "switch the cache to redis")
+}
+
+func TestExtractRunError(t *testing.T) {
+	r := &fakeRunner{err: errors.New("timeout")}
+	_, err := Extract(context.Background(), r, testInput(), t.TempDir(), 0)
+	assert.Error(t, err)
+}
+
+func TestFormatEvidence(t *testing.T) {
+	assert.Equal(t, "", FormatEvidence(nil))
+	s := FormatEvidence([]Evidence{{Type: EvidenceActionID, Ref: "impl", Value: "act-1", Verified: true}})
+	assert.JSONEq(t, `[{"type":"action_id","ref":"impl","value":"act-1","verified":true}]`, s)
+}
@@ -0,0 +1,193 @@
+package gitdiff
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// maxPatchLines caps how much of a single file's patch is kept before the
+// context builder gets to compress it.
+const maxPatchLines = 300
+
+// Base is the point a diff is taken against: a commit-ish plus the set of
+// files that were already untracked at that point, so that only files
+// created afterwards are reported as new.
+type Base struct {
+	Rev       string
+	Untracked map[string]bool
+}
+
+// FileDiff is the change to a single file relative to a Base.
+type FileDiff struct {
+	Path    string
+	Added   int
+	Deleted int
+	Patch   string
+}
+
+// Capture records the current state of the work tree in dir as a Base
+// without touching the index or the stash list. Uncommitted changes are
+// captured with 'git stash create'; a clean tree falls back to HEAD.
+func Capture(dir string) (Base, error) {
+	rev, err := git(dir, "stash", "create")
+	if err != nil {
+		return Base{}, fmt.Errorf("gitdiff: stash create: %w", err)
+	}
+	if rev == "" {
+		rev, err = git(dir, "rev-parse", "HEAD")
+		if err != nil {
+			return Base{}, fmt.Errorf("gitdiff: rev-parse HEAD: %w", err)
+		}
+	}
+	return BaseAt(dir, rev)
+}
+
+// BaseAt returns a Base for rev, recording the files that are untracked in
+// dir right now.
+func BaseAt(dir, rev string) (Base, error) {
+	untracked, err := untrackedFiles(dir)
+	if err != nil {
+		return Base{}, err
+	}
+	b := Base{Rev: rev, Untracked: make(map[string]bool, len(untracked))}
+	for _, p := range untracked {
+		b.Untracked[p] = true
+	}
+	return b, nil
+}
+
+// Diff returns the per-file changes in dir since base, sorted by path.
+// Files created after base was taken are included as additions.
+func Diff(dir string, base Base) ([]FileDiff, error) {
+	out, err := gitRaw(dir, "diff", "--no-color", "--no-ext-diff", base.Rev)
+	if err != nil {
+		return nil, fmt.Errorf("gitdiff: diff %s: %w", base.Rev, err)
+	}
+	diffs := parse(out)
+
+	untracked, err := untrackedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range untracked {
+		if base.Untracked[p] {
+			continue
+		}
+		// --no-index exits 1 when the files differ, which is always the
+		// case against /dev/null; only the output matters.
+		patch, _ := gitRaw(dir, "diff", "--no-color", "--no-ext-diff", "--no-index", "--", "/dev/null", p)
+		for _, fd := range parse(patch) {
+			fd.Path = p
+			diffs = append(diffs, fd)
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// Show returns the content of path, relative to the top of the work tree,
+// as of base. It reports false when the file did not exist at base or was
+// untracked then.
+func Show(dir string, base Base, path string) ([]byte, bool) {
+	if base.Untracked[path] {
+		return nil, false
+	}
+	out, err := gitRaw(dir, "show", base.Rev+":"+path)
+	if err != nil {
+		return nil, false
+	}
+	return []byte(out), true
+}
+
+// Summary returns a one-line "+A -D" description of the change.
+func (f FileDiff) Summary() string {
+	return fmt.Sprintf("+%d -%d lines", f.Added, f.Deleted)
+}
+
+// parse splits unified diff output into per-file diffs.
+func parse(out string) []FileDiff {
+	var diffs []FileDiff
+	var cur *FileDiff
+	var lines []string
+
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		if len(lines) > maxPatchLines {
+			more := len(lines) - maxPatchLines
+			lines = append(lines[:maxPatchLines], fmt.Sprintf("... (%d more lines)", more))
+		}
+		cur.Patch = strings.Join(lines, "\n")
+		diffs = append(diffs, *cur)
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			cur = &FileDiff{Path: pathFromHeader(line)}
+			lines = []string{line}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		lines = append(lines, line)
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			if p := line[4:]; p != "/dev/null" {
+				cur.Path = strings.TrimPrefix(p, "b/")
+			}
+		case strings.HasPrefix(line, "--- "):
+			// Source file header; the path comes from "+++" or the diff header.
+		case strings.HasPrefix(line, "+"):
+			cur.Added++
+		case strings.HasPrefix(line, "-"):
+			cur.Deleted++
+		}
+	}
+	flush()
+
+	for i := range diffs {
+		diffs[i].Patch = strings.TrimRight(diffs[i].Patch, "\n")
+	}
+	return diffs
+}
+
+// pathFromHeader extracts the destination path from a "diff --git a/x b/x"
+// header line.
+func pathFromHeader(line string) string {
+	if idx := strings.LastIndex(line, " b/"); idx >= 0 {
+		return line[idx+3:]
+	}
+	return strings.TrimPrefix(line, "diff --git ")
+}
+
+func untrackedFiles(dir string) ([]string, error) {
+	out, err := gitRaw(dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("gitdiff: ls-files: %w", err)
+	}
+	var paths []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	out, err := gitRaw(dir, args...)
+	return strings.TrimSpace(out), err
+}
+
+func gitRaw(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,100 @@
+package gitdiff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@test.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@test.com")
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v failed: %s", args, out)
+	}
+	run("init")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("one\ntwo\n"), 0644))
+	run("add", ".")
+	run("commit", "-m", "initial")
+	return dir
+}
+
+func TestCaptureCleanTreeUsesHead(t *testing.T) {
+	dir := initGitRepo(t)
+	base, err := Capture(dir)
+	require.NoError(t, err)
+	head, err := git(dir, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, head, base.Rev)
+}
+
+func TestDiffSinceCapture(t *testing.T) {
+	dir := initGitRepo(t)
+	// Pre-existing uncommitted edit and untracked file are part of the base.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("one\ntwo\nthree\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "scratch.txt"), []byte("notes\n"), 0644))
+
+	base, err := Capture(dir)
+	require.NoError(t, err)
+
+	diffs, err := Diff(dir, base)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() int { return 1 }\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.go"), []byte("package a\n\nfunc B() {}\n"), 0644))
+
+	diffs, err = Diff(dir, base)
+	require.NoError(t, err)
+	require.Len(t, diffs, 2)
+
+	assert.Equal(t, "a.go", diffs[0].Path)
+	assert.Equal(t, 1, diffs[0].Added)
+	assert.Equal(t, 1, diffs[0].Deleted)
+	assert.Contains(t, diffs[0].Patch, "+func A() int { return 1 }")
+
+	assert.Equal(t, "new.go", diffs[1].Path)
+	assert.Equal(t, 3, diffs[1].Added)
+	assert.Equal(t, "+3 -0 lines", diffs[1].Summary())
+}
+
+func TestShowReturnsBaseContent(t *testing.T) {
+	dir := initGitRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("one\ntwo\nthree\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "scratch.txt"), []byte("notes\n"), 0644))
+	base, err := Capture(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("changed\n"), 0644))
+
+	data, ok := Show(dir, base, "b.txt")
+	assert.True(t, ok)
+	assert.Equal(t, "one\ntwo\nthree\n", string(data))
+
+	_, ok = Show(dir, base, "scratch.txt")
+	assert.False(t, ok, "untracked at base")
+	_, ok = Show(dir, base, "missing.go")
+	assert.False(t, ok)
+}
+
+func TestParseTruncatesLongPatches(t *testing.T) {
+	out := "diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -0,0 +1,400 @@\n"
+	for i := 0; i < 400; i++ {
+		out += "+line\n"
+	}
+	diffs := parse(out)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, 400, diffs[0].Added)
+	assert.Contains(t, diffs[0].Patch, "more lines)")
+}
@@ -0,0 +1,91 @@
+package gitdiff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+)
+
+// Block priorities. Files touched by upstream nodes rank just below memory so
+// follow-up nodes see the actual edits; the rest of the diff sits below
+// explicitly attached files.
+const (
+	FocusPriority = 75
+	Priority      = 50
+)
+
+// Provider injects the work-tree diff since a Base into prompts built by
+// context.Builder, one block per changed file.
+type Provider struct {
+	dir   string
+	base  Base
+	focus map[string]bool
+	gate  apexctx.Gate
+}
+
+// NewProvider creates a Provider for the work tree in dir. Files listed in
+// focus are given a higher priority than the rest of the diff.
+func NewProvider(dir string, base Base, focus []string) *Provider {
+	f := make(map[string]bool, len(focus))
+	for _, p := range focus {
+		f[p] = true
+	}
+	return &Provider{dir: dir, base: base, focus: f}
+}
+
+// SetGate makes the provider vet every changed file with g and redact the
+// patches. Diffs of files g refuses are left out and reported in the error
+// Provide returns; deleted files are vetted by the patch alone.
+func (p *Provider) SetGate(g apexctx.Gate) {
+	p.gate = g
+}
+
+// Name implements context.Provider.
+func (p *Provider) Name() string { return "diff" }
+
+// Provide implements context.Provider. Each block starts with a "+A -D"
+// summary line so that even the reference form says how big the change is.
+func (p *Provider) Provide(ctx context.Context, task string) ([]apexctx.ContentBlock, error) {
+	diffs, err := Diff(p.dir, p.base)
+	if err != nil {
+		return nil, err
+	}
+	var top string
+	if p.gate != nil {
+		// Diff paths are relative to the top of the work tree.
+		if top, err = git(p.dir, "rev-parse", "--show-toplevel"); err != nil {
+			return nil, fmt.Errorf("gitdiff: rev-parse --show-toplevel: %w", err)
+		}
+	}
+	blocks := make([]apexctx.ContentBlock, 0, len(diffs))
+	var denied []error
+	for _, d := range diffs {
+		text := d.Summary() + "\n" + d.Patch
+		if p.gate != nil {
+			if err := p.gate.Check(filepath.Join(top, d.Path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				denied = append(denied, err)
+				continue
+			}
+			text = string(p.gate.Redact([]byte(text)))
+		}
+		priority := Priority
+		if p.focus[d.Path] {
+			priority = FocusPriority
+		}
+		blocks = append(blocks, apexctx.ContentBlock{
+			ID:        "diff:" + d.Path,
+			Source:    "diff",
+			Path:      d.Path,
+			Title:     "Diff: " + d.Path,
+			Text:      text,
+			Policy:    apexctx.PolicyStructural,
+			DegradeTo: apexctx.PolicyReference,
+			Priority:  priority,
+		})
+	}
+	return blocks, errors.Join(denied...)
+}
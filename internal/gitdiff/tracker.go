@@ -0,0 +1,99 @@
+package gitdiff
+
+import (
+	"crypto/sha256"
+	"sort"
+	"sync"
+)
+
+// Tracker attributes changed files to the nodes (or interactive turns) that
+// changed them by comparing the diff before and after each one runs. When
+// nodes run concurrently a file changed while both were running is
+// attributed to both.
+type Tracker struct {
+	dir  string
+	base Base
+
+	mu      sync.Mutex
+	before  map[string]map[string][32]byte
+	touched map[string][]string
+}
+
+// NewTracker creates a Tracker for the work tree in dir relative to base.
+func NewTracker(dir string, base Base) *Tracker {
+	return &Tracker{
+		dir:     dir,
+		base:    base,
+		before:  make(map[string]map[string][32]byte),
+		touched: make(map[string][]string),
+	}
+}
+
+// Begin records the state of the work tree before id runs.
+func (t *Tracker) Begin(id string) {
+	state := t.state()
+	t.mu.Lock()
+	t.before[id] = state
+	t.mu.Unlock()
+}
+
+// End compares the work tree against the state recorded by Begin and
+// attributes every file whose diff changed to id.
+func (t *Tracker) End(id string) {
+	after := t.state()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	before, ok := t.before[id]
+	if !ok {
+		return
+	}
+	delete(t.before, id)
+
+	var changed []string
+	for p, sum := range after {
+		if before[p] != sum {
+			changed = append(changed, p)
+		}
+	}
+	for p := range before {
+		if _, still := after[p]; !still {
+			// Change was reverted back to the base content.
+			changed = append(changed, p)
+		}
+	}
+	sort.Strings(changed)
+	t.touched[id] = changed
+}
+
+// Touched returns the sorted, de-duplicated files changed by the given ids.
+func (t *Tracker) Touched(ids ...string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	seen := make(map[string]bool)
+	var out []string
+	for _, id := range ids {
+		for _, p := range t.touched[id] {
+			if !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// state maps every changed file to a hash of its patch. A failed diff is
+// treated as an empty state, which at worst loses attribution.
+func (t *Tracker) state() map[string][32]byte {
+	diffs, err := Diff(t.dir, t.base)
+	if err != nil {
+		return nil
+	}
+	state := make(map[string][32]byte, len(diffs))
+	for _, d := range diffs {
+		state[d.Path] = sha256.Sum256([]byte(d.Patch))
+	}
+	return state
+}
@@ -0,0 +1,45 @@
+package gitdiff
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerAttributesChanges(t *testing.T) {
+	dir := initGitRepo(t)
+	base, err := Capture(dir)
+	require.NoError(t, err)
+	tr := NewTracker(dir, base)
+
+	tr.Begin("n1")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0644))
+	tr.End("n1")
+
+	tr.Begin("n2")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c.go"), []byte("package a\n"), 0644))
+	tr.End("n2")
+
+	assert.Equal(t, []string{"a.go"}, tr.Touched("n1"))
+	assert.Equal(t, []string{"c.go"}, tr.Touched("n2"))
+	assert.Equal(t, []string{"a.go", "c.go"}, tr.Touched("n1", "n2", "unknown"))
+}
+
+func TestTrackerRevertCountsAsTouched(t *testing.T) {
+	dir := initGitRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("changed\n"), 0644))
+	head, err := git(dir, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	base, err := BaseAt(dir, head)
+	require.NoError(t, err)
+	tr := NewTracker(dir, base)
+
+	tr.Begin("n1")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("one\ntwo\n"), 0644))
+	tr.End("n1")
+
+	assert.Equal(t, []string{"b.txt"}, tr.Touched("n1"))
+}
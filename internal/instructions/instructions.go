@@ -0,0 +1,122 @@
+package instructions
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultFiles are the instruction file names looked up in the repository
+// root and its parent directories when none are configured.
+var DefaultFiles = []string{"CLAUDE.md", "AGENTS.md", "CONTRIBUTING.md", ".apex/instructions.md"}
+
+// Scope says where an instruction file was found. Scopes are listed from
+// lowest to highest precedence.
+type Scope string
+
+const (
+	ScopeGlobal Scope = "global" // ~/.apex/instructions.md
+	ScopeParent Scope = "parent" // a directory above the repository root
+	ScopeRepo   Scope = "repo"   // the repository root
+)
+
+// Source is a single discovered instruction file.
+type Source struct {
+	Path  string
+	Scope Scope
+	Text  string
+}
+
+// Discover returns the instruction files that apply to root, ordered from
+// lowest to highest precedence: the global file, then parent directories
+// from the filesystem root down, then root itself. Within a directory files
+// keep the order of names. Empty files and files whose content duplicates
+// an earlier one are skipped. globalPath may be empty.
+func Discover(root string, names []string, globalPath string) ([]Source, error) {
+	sources, unread, err := discover(root, names, globalPath, os.ReadFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(unread) > 0 {
+		return nil, fmt.Errorf("instructions: %w", unread[0])
+	}
+	return sources, nil
+}
+
+// discover is Discover reading files with read. Files that exist but cannot
+// be read, such as reads a path security gate refuses, are skipped and
+// their read errors returned.
+func discover(root string, names []string, globalPath string, read func(string) ([]byte, error)) ([]Source, []error, error) {
+	if len(names) == 0 {
+		names = DefaultFiles
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("instructions: resolve root: %w", err)
+	}
+
+	var sources []Source
+	var unread []error
+	seen := make(map[[32]byte]bool)
+	add := func(path string, scope Scope) error {
+		data, readErr := read(path)
+		if errors.Is(readErr, os.ErrNotExist) {
+			return nil
+		}
+		if readErr != nil {
+			unread = append(unread, readErr)
+			return nil
+		}
+		text := strings.TrimSpace(string(data))
+		sum := sha256.Sum256([]byte(text))
+		if text == "" || seen[sum] {
+			return nil
+		}
+		seen[sum] = true
+		sources = append(sources, Source{Path: path, Scope: scope, Text: text})
+		return nil
+	}
+
+	if globalPath != "" {
+		if err := add(globalPath, ScopeGlobal); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var parents []string
+	for dir := root; dir != filepath.Dir(dir); {
+		dir = filepath.Dir(dir)
+		parents = append(parents, dir)
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		for _, name := range names {
+			if err := add(filepath.Join(parents[i], name), ScopeParent); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for _, name := range names {
+		if err := add(filepath.Join(root, name), ScopeRepo); err != nil {
+			return nil, nil, err
+		}
+	}
+	return sources, unread, nil
+}
+
+// Merge renders sources as one markdown document, lowest precedence first,
+// with a preamble telling the model that later sections win on conflict.
+func Merge(sources []Source) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Sections are ordered from lowest to highest precedence. When instructions conflict, follow the later section.\n")
+	for _, s := range sources {
+		fmt.Fprintf(&sb, "\n### %s (%s)\n\n%s\n", s.Path, s.Scope, s.Text)
+	}
+	return sb.String()
+}
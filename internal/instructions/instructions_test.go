@@ -0,0 +1,64 @@
+package instructions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestDiscoverPrecedenceOrder(t *testing.T) {
+	tmp := t.TempDir()
+	parent := filepath.Join(tmp, "work")
+	root := filepath.Join(parent, "repo")
+	global := filepath.Join(tmp, "home", ".apex", "instructions.md")
+
+	write(t, global, "global rule")
+	write(t, filepath.Join(parent, "CLAUDE.md"), "parent rule")
+	write(t, filepath.Join(root, "CONTRIBUTING.md"), "contributing rule")
+	write(t, filepath.Join(root, "CLAUDE.md"), "repo rule")
+
+	sources, err := Discover(root, nil, global)
+	require.NoError(t, err)
+	require.Len(t, sources, 4)
+	assert.Equal(t, ScopeGlobal, sources[0].Scope)
+	assert.Equal(t, "parent rule", sources[1].Text)
+	assert.Equal(t, ScopeParent, sources[1].Scope)
+	assert.Equal(t, "repo rule", sources[2].Text)
+	assert.Equal(t, "contributing rule", sources[3].Text)
+	assert.Equal(t, ScopeRepo, sources[3].Scope)
+}
+
+func TestDiscoverConfiguredNamesAndDedup(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "CLAUDE.md"), "same")
+	write(t, filepath.Join(root, "AGENTS.md"), "same\n")
+	write(t, filepath.Join(root, "STYLE.md"), "style")
+	write(t, filepath.Join(root, "EMPTY.md"), "  \n")
+
+	sources, err := Discover(root, []string{"CLAUDE.md", "AGENTS.md", "STYLE.md", "EMPTY.md"}, "")
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	assert.Equal(t, "same", sources[0].Text)
+	assert.Equal(t, "style", sources[1].Text)
+}
+
+func TestMerge(t *testing.T) {
+	assert.Empty(t, Merge(nil))
+	out := Merge([]Source{
+		{Path: "/g.md", Scope: ScopeGlobal, Text: "use tabs"},
+		{Path: "/repo/CLAUDE.md", Scope: ScopeRepo, Text: "use spaces"},
+	})
+	assert.Contains(t, out, "follow the later section")
+	assert.Less(t, strings.Index(out, "use tabs"), strings.Index(out, "use spaces"))
+	assert.Contains(t, out, "### /repo/CLAUDE.md (repo)")
+}
@@ -0,0 +1,113 @@
+package instructions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+)
+
+// Priority places instructions directly below the task block.
+const Priority = 95
+
+// DefaultMaxTokens caps the merged instructions when no cap is configured.
+const DefaultMaxTokens = 4000
+
+// Provider injects the merged instruction files into every prompt as a
+// pinned, exact block. Because pinned blocks bypass the token budget, the
+// provider enforces its own cap.
+type Provider struct {
+	root       string
+	names      []string
+	globalPath string
+	maxTokens  int
+	gate       apexctx.Gate
+}
+
+// NewProvider creates a Provider for the repository at root. names lists
+// the instruction file names to look for (DefaultFiles when empty),
+// globalPath is the user-wide instructions file, and maxTokens caps the
+// merged block (DefaultMaxTokens when not positive). The block is pinned,
+// so it always has a cap.
+func NewProvider(root string, names []string, globalPath string, maxTokens int) *Provider {
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTokens
+	}
+	return &Provider{root: root, names: names, globalPath: globalPath, maxTokens: maxTokens}
+}
+
+// SetGate makes the provider read instruction files through g. Files g
+// refuses are left out and reported in the error Provide returns.
+func (p *Provider) SetGate(g apexctx.Gate) {
+	p.gate = g
+}
+
+// Name implements context.Provider.
+func (p *Provider) Name() string { return "instructions" }
+
+// Provide implements context.Provider. When the merged instructions exceed
+// the cap, the lowest-precedence files are dropped first; if the highest
+// precedence file alone is still too large it is truncated. Either way the
+// block carries a warning naming what was cut. Files that could not be read
+// are left out and reported in the returned error.
+func (p *Provider) Provide(ctx context.Context, task string) ([]apexctx.ContentBlock, error) {
+	read := os.ReadFile
+	if p.gate != nil {
+		read = p.gate.ReadFile
+	}
+	sources, unread, err := discover(p.root, p.names, p.globalPath, read)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, errors.Join(unread...)
+	}
+
+	text := Merge(sources)
+	total := apexctx.EstimateTokens(text)
+	var warning string
+	if total > p.maxTokens {
+		var dropped []string
+		for len(sources) > 1 && apexctx.EstimateTokens(Merge(sources)) > p.maxTokens {
+			dropped = append(dropped, sources[0].Path)
+			sources = sources[1:]
+		}
+		text = Merge(sources)
+		if apexctx.EstimateTokens(text) > p.maxTokens {
+			text = truncate(text, p.maxTokens)
+			dropped = append(dropped, sources[0].Path+" (truncated)")
+		}
+		warning = fmt.Sprintf("instructions: %d tokens exceed cap of %d; cut %s",
+			total, p.maxTokens, strings.Join(dropped, ", "))
+	}
+
+	return []apexctx.ContentBlock{{
+		ID:       "instructions",
+		Source:   "instructions",
+		Title:    "Project Instructions",
+		Text:     text,
+		Policy:   apexctx.PolicyExact,
+		Priority: Priority,
+		Pinned:   true,
+		Warning:  warning,
+	}}, errors.Join(unread...)
+}
+
+const truncatedMarker = "\n[... truncated]"
+
+// truncate cuts text to roughly maxTokens tokens, using the same estimate as
+// the context builder.
+func truncate(text string, maxTokens int) string {
+	runes := []rune(text)
+	limit := maxTokens*3 - len(truncatedMarker)
+	if limit < 0 {
+		limit = 0
+	}
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + truncatedMarker
+}
@@ -0,0 +1,103 @@
+package instructions
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+	"github.com/lyndonlyu/apex/internal/readgate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderPinnedExactBlock(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "CLAUDE.md"), "Run make test before committing.")
+
+	blocks, err := NewProvider(root, nil, "", 4000).Provide(context.Background(), "task")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.True(t, blocks[0].Pinned)
+	assert.Equal(t, apexctx.PolicyExact, blocks[0].Policy)
+	assert.Empty(t, blocks[0].Warning)
+	assert.Contains(t, blocks[0].Text, "Run make test")
+}
+
+func TestProviderNoFiles(t *testing.T) {
+	blocks, err := NewProvider(t.TempDir(), []string{"NOPE.md"}, "", 4000).Provide(context.Background(), "task")
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+}
+
+func TestProviderCapDropsLowestPrecedence(t *testing.T) {
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "repo")
+	global := filepath.Join(tmp, "global.md")
+	write(t, global, strings.Repeat("global filler ", 100))
+	write(t, filepath.Join(root, "CLAUDE.md"), "repo rule")
+
+	blocks, err := NewProvider(root, nil, global, 100).Provide(context.Background(), "task")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Contains(t, blocks[0].Text, "repo rule")
+	assert.NotContains(t, blocks[0].Text, "global filler")
+	assert.Contains(t, blocks[0].Warning, "exceed cap of 100")
+	assert.Contains(t, blocks[0].Warning, global)
+}
+
+func TestProviderCapTruncatesSingleFile(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "CLAUDE.md"), strings.Repeat("rule ", 500))
+
+	blocks, err := NewProvider(root, nil, "", 50).Provide(context.Background(), "task")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.LessOrEqual(t, apexctx.EstimateTokens(blocks[0].Text), 50)
+	assert.Contains(t, blocks[0].Text, "[... truncated]")
+	assert.Contains(t, blocks[0].Warning, "(truncated)")
+}
+
+func TestProviderAlwaysCaps(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "CLAUDE.md"), strings.Repeat("rule ", 5*DefaultMaxTokens))
+
+	blocks, err := NewProvider(root, nil, "", 0).Provide(context.Background(), "task")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.LessOrEqual(t, apexctx.EstimateTokens(blocks[0].Text), DefaultMaxTokens)
+	assert.Contains(t, blocks[0].Warning, "(truncated)")
+}
+
+func TestProviderSurvivesTightBudget(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "CLAUDE.md"), "Never edit generated files.")
+
+	b := apexctx.NewBuilder(apexctx.Options{
+		TokenBudget: 1,
+		Providers:   []apexctx.Provider{NewProvider(root, nil, "", 4000)},
+	})
+	prompt, err := b.Build(context.Background(), "task")
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "Never edit generated files.")
+}
+
+func TestProviderGateDeniesFile(t *testing.T) {
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "repo")
+	global := filepath.Join(tmp, "global.md")
+	write(t, global, "global rule")
+	write(t, filepath.Join(root, "CLAUDE.md"), "repo rule")
+
+	gate, err := readgate.New([]string{root}, nil)
+	require.NoError(t, err)
+	p := NewProvider(root, nil, global, 4000)
+	p.SetGate(gate)
+	blocks, err := p.Provide(context.Background(), "task")
+
+	assert.True(t, readgate.IsDenied(err), "got %v", err)
+	require.Len(t, blocks, 1)
+	assert.Contains(t, blocks[0].Text, "repo rule")
+	assert.NotContains(t, blocks[0].Text, "global rule")
+}
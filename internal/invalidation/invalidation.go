@@ -0,0 +1,336 @@
+// Package invalidation watches the workspace files the nodes of a running
+// DAG consumed. When one changes after a node completed, the node and its
+// completed dependents are invalidated and requeued so they run again on
+// the new content; a node whose input keeps changing is escalated instead.
+package invalidation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lyndonlyu/apex/internal/artifact"
+	"github.com/lyndonlyu/apex/internal/dag"
+)
+
+// Actions recorded in events.
+const (
+	ActionInvalidated = "invalidated" // requeued to run again
+	ActionEscalated   = "escalated"   // held for a human by the circuit breaker
+	ActionCycle       = "cycle"       // the change came from the node or a dependent; accepted
+)
+
+// Options configures a Watcher.
+type Options struct {
+	// Root is the workspace the input paths are relative to.
+	Root string
+
+	// Debounce is how long a changed input must keep the same content
+	// before it invalidates anything, so a file being written in several
+	// steps invalidates once.
+	Debounce time.Duration
+
+	// Window and MaxInvalidations make up the per-(artifact, consumer)
+	// circuit breaker: once a change to the same input has invalidated the
+	// same node MaxInvalidations times within Window, the next one
+	// escalates the node instead. MaxInvalidations <= 0 never escalates.
+	Window           time.Duration
+	MaxInvalidations int
+
+	// Producer reports the node of the run that wrote the latest recorded
+	// version of path and that version's normalized hash. Changes it
+	// cannot attribute are treated as made outside the run.
+	Producer func(path string) (nodeID, normHash string, ok bool)
+
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Event is one decision the watcher made, with the chain of causes that
+// led to it, the changed input first.
+type Event struct {
+	NodeID string
+	Path   string // the changed input
+	Action string
+	Cause  []string
+}
+
+// Result is what a check did.
+type Result struct {
+	Requeued  []string
+	Escalated []string
+	Events    []Event
+}
+
+type pending struct {
+	hash  string
+	since time.Time
+}
+
+// Watcher tracks the inputs of each node and invalidates the nodes whose
+// inputs changed. It is safe for concurrent use.
+type Watcher struct {
+	opts Options
+
+	mu       sync.Mutex
+	inputs   map[string]map[string]string      // node ID -> path -> normalized hash when it started
+	pending  map[string]map[string]pending     // node ID -> path -> change waiting out the debounce
+	breakers map[string]map[string][]time.Time // path -> node ID -> recent invalidations
+}
+
+// New creates a Watcher.
+func New(opts Options) *Watcher {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Watcher{
+		opts:     opts,
+		inputs:   make(map[string]map[string]string),
+		pending:  make(map[string]map[string]pending),
+		breakers: make(map[string]map[string][]time.Time),
+	}
+}
+
+// Begin records the content of the files nodeID consumes as it starts,
+// replacing what an earlier run of the node recorded.
+func (w *Watcher) Begin(nodeID string, paths []string) {
+	inputs := make(map[string]string, len(paths))
+	for _, p := range paths {
+		inputs[p] = w.hash(p)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inputs[nodeID] = inputs
+	delete(w.pending, nodeID)
+}
+
+// Check compares the inputs of every completed node against the workspace
+// and invalidates the nodes whose inputs changed and settled.
+func (w *Watcher) Check(d *dag.DAG) (Result, error) {
+	return w.check(d, false)
+}
+
+// Flush is Check without the debounce, for when nothing is writing to the
+// workspace any more.
+func (w *Watcher) Flush(d *dag.DAG) (Result, error) {
+	return w.check(d, true)
+}
+
+func (w *Watcher) check(d *dag.DAG, settled bool) (Result, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.opts.Now()
+	current := make(map[string]string)
+	hash := func(p string) string {
+		h, ok := current[p]
+		if !ok {
+			h = w.hash(p)
+			current[p] = h
+		}
+		return h
+	}
+
+	dependents := make(map[string][]string)
+	for _, n := range d.NodeSlice() {
+		for _, dep := range n.Depends {
+			dependents[dep] = append(dependents[dep], n.ID)
+		}
+	}
+
+	var res Result
+	handled := make(map[string]bool)
+	for _, nodeID := range sortedKeys(w.inputs) {
+		if handled[nodeID] {
+			continue
+		}
+		for _, path := range sortedKeys(w.inputs[nodeID]) {
+			was, cur := w.inputs[nodeID][path], hash(path)
+			if was == cur {
+				w.clearPending(nodeID, path)
+				continue
+			}
+			if !settled && !w.settle(nodeID, path, cur, now) {
+				continue
+			}
+
+			change := fmt.Sprintf("%s changed (%s -> %s) outside the run", path, short(was), short(cur))
+			if w.opts.Producer != nil {
+				if by, norm, ok := w.opts.Producer(path); ok && norm == cur {
+					change = fmt.Sprintf("%s changed (%s -> %s) by node %s", path, short(was), short(cur), by)
+					if by == nodeID || contains(d.Ancestors(by), nodeID) {
+						// The node's own work, directly or through a
+						// dependent: rerunning it would only feed the
+						// change back in.
+						w.inputs[nodeID][path] = cur
+						w.clearPending(nodeID, path)
+						res.Events = append(res.Events, Event{
+							NodeID: nodeID,
+							Path:   path,
+							Action: ActionCycle,
+							Cause:  []string{change, fmt.Sprintf("%s is %s or depends on it", by, nodeID)},
+						})
+						continue
+					}
+				}
+			}
+
+			if err := d.Invalidate(nodeID); err != nil {
+				// Not completed: a node that is running or queued picks
+				// up the change itself, so keep watching.
+				continue
+			}
+			w.inputs[nodeID][path] = cur
+			w.clearPending(nodeID, path)
+			handled[nodeID] = true
+
+			cause := []string{change, fmt.Sprintf("%s consumed %s", nodeID, path)}
+			action, err := w.settleNode(d, nodeID, path, cause, now, &res)
+			if err != nil {
+				return res, err
+			}
+			if err := w.cascade(d, dependents, nodeID, path, action, cause, handled, &res); err != nil {
+				return res, err
+			}
+			break
+		}
+	}
+	return res, nil
+}
+
+// settleNode requeues an invalidated node or, when its circuit breaker for
+// path is open, holds it for a human.
+func (w *Watcher) settleNode(d *dag.DAG, nodeID, path string, cause []string, now time.Time, res *Result) (string, error) {
+	if w.trip(path, nodeID, now) {
+		reason := fmt.Sprintf("%s changed more than %d times within %s", path, w.opts.MaxInvalidations, w.opts.Window)
+		if err := d.Hold(nodeID, reason); err != nil {
+			return "", err
+		}
+		res.Escalated = append(res.Escalated, nodeID)
+		res.Events = append(res.Events, Event{NodeID: nodeID, Path: path, Action: ActionEscalated, Cause: append(cause, reason)})
+		return ActionEscalated, nil
+	}
+	if err := d.Requeue(nodeID); err != nil {
+		return "", err
+	}
+	res.Requeued = append(res.Requeued, nodeID)
+	res.Events = append(res.Events, Event{NodeID: nodeID, Path: path, Action: ActionInvalidated, Cause: cause})
+	return ActionInvalidated, nil
+}
+
+// cascade invalidates the completed dependents of nodeID, which were built
+// on its outdated result, and requeues or holds them as nodeID was. The
+// visited set keeps a node from being reached twice.
+func (w *Watcher) cascade(d *dag.DAG, dependents map[string][]string, nodeID, path, action string, cause []string, visited map[string]bool, res *Result) error {
+	for _, id := range dependents[nodeID] {
+		if visited[id] {
+			continue
+		}
+		if err := d.Invalidate(id); err != nil {
+			continue // not completed yet; it waits for nodeID to run again
+		}
+		visited[id] = true
+		chain := append(append([]string(nil), cause...), fmt.Sprintf("%s depends on %s", id, nodeID))
+		if action == ActionEscalated {
+			reason := fmt.Sprintf("upstream node %s was escalated", nodeID)
+			if err := d.Hold(id, reason); err != nil {
+				return err
+			}
+			res.Escalated = append(res.Escalated, id)
+			res.Events = append(res.Events, Event{NodeID: id, Path: path, Action: ActionEscalated, Cause: append(chain, reason)})
+		} else {
+			if err := d.Requeue(id); err != nil {
+				return err
+			}
+			res.Requeued = append(res.Requeued, id)
+			res.Events = append(res.Events, Event{NodeID: id, Path: path, Action: ActionInvalidated, Cause: chain})
+		}
+		if err := w.cascade(d, dependents, id, path, action, chain, visited, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settle reports whether the change of path to hash, seen by nodeID, has
+// kept that content for the debounce interval.
+func (w *Watcher) settle(nodeID, path, hash string, now time.Time) bool {
+	byPath := w.pending[nodeID]
+	if byPath == nil {
+		byPath = make(map[string]pending)
+		w.pending[nodeID] = byPath
+	}
+	p, ok := byPath[path]
+	if !ok || p.hash != hash {
+		byPath[path] = pending{hash: hash, since: now}
+		return w.opts.Debounce <= 0
+	}
+	return now.Sub(p.since) >= w.opts.Debounce
+}
+
+func (w *Watcher) clearPending(nodeID, path string) {
+	delete(w.pending[nodeID], path)
+}
+
+// trip records an invalidation of nodeID caused by path and reports
+// whether it exceeds the breaker's limit.
+func (w *Watcher) trip(path, nodeID string, now time.Time) bool {
+	if w.opts.MaxInvalidations <= 0 {
+		return false
+	}
+	byNode := w.breakers[path]
+	if byNode == nil {
+		byNode = make(map[string][]time.Time)
+		w.breakers[path] = byNode
+	}
+	var recent []time.Time
+	for _, t := range byNode[nodeID] {
+		if w.opts.Window <= 0 || now.Sub(t) < w.opts.Window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	byNode[nodeID] = recent
+	return len(recent) > w.opts.MaxInvalidations
+}
+
+// hash returns the normalized hash of the file at path, or "" if it does
+// not exist.
+func (w *Watcher) hash(path string) string {
+	data, err := os.ReadFile(filepath.Join(w.opts.Root, path))
+	if err != nil {
+		return ""
+	}
+	return artifact.NormalizedHash(path, data)
+}
+
+func short(h string) string {
+	if h == "" {
+		return "absent"
+	}
+	if len(h) > 10 {
+		return h[:10]
+	}
+	return h
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
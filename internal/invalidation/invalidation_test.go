@@ -0,0 +1,253 @@
+package invalidation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lyndonlyu/apex/internal/artifact"
+	"github.com/lyndonlyu/apex/internal/dag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chain builds a -> b -> c with a and b completed.
+func chain(t *testing.T) *dag.DAG {
+	t.Helper()
+	d, err := dag.New([]dag.NodeSpec{
+		{ID: "a", Task: "write api.go"},
+		{ID: "b", Task: "use api.go", Depends: []string{"a"}},
+		{ID: "c", Task: "document", Depends: []string{"b"}},
+	})
+	require.NoError(t, err)
+	for _, id := range []string{"a", "b"} {
+		d.MarkRunning(id)
+		d.MarkCompleted(id, "done")
+	}
+	return d
+}
+
+func write(t *testing.T, root, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content), 0644))
+}
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func TestCheckRequeuesChangedConsumer(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "api.go", "package api\n")
+	d := chain(t)
+	w := New(Options{Root: root})
+	w.Begin("b", []string{"api.go"})
+
+	res, err := w.Check(d)
+	require.NoError(t, err)
+	assert.Empty(t, res.Events, "unchanged input")
+
+	// Formatting-only edits keep the normalized checksum.
+	write(t, root, "api.go", "package api   \n\n")
+	res, err = w.Check(d)
+	require.NoError(t, err)
+	assert.Empty(t, res.Events)
+
+	write(t, root, "api.go", "package api\n\nfunc New() {}\n")
+	res, err = w.Check(d)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, res.Requeued)
+	require.Len(t, res.Events, 1)
+	assert.Equal(t, ActionInvalidated, res.Events[0].Action)
+	assert.Equal(t, "api.go", res.Events[0].Path)
+	assert.Len(t, res.Events[0].Cause, 2)
+	assert.Contains(t, res.Events[0].Cause[0], "outside the run")
+	assert.Equal(t, "b consumed api.go", res.Events[0].Cause[1])
+	assert.Equal(t, dag.Pending, d.Nodes["b"].Status)
+	assert.Equal(t, dag.Completed, d.Nodes["a"].Status)
+
+	// The new content is the baseline now.
+	d.MarkRunning("b")
+	d.MarkCompleted("b", "again")
+	res, err = w.Check(d)
+	require.NoError(t, err)
+	assert.Empty(t, res.Events)
+}
+
+func TestCheckCascadesToCompletedDependents(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "api.go", "package api\n")
+	d := chain(t)
+	d.MarkRunning("c")
+	d.MarkCompleted("c", "done")
+	w := New(Options{Root: root})
+	w.Begin("b", []string{"api.go"})
+	w.Begin("c", []string{"api.go"})
+
+	write(t, root, "api.go", "package api\n\nvar X = 1\n")
+	res, err := w.Check(d)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, res.Requeued, "c is reached through b, not twice")
+	require.Len(t, res.Events, 2)
+	assert.Equal(t, "c depends on b", res.Events[1].Cause[2])
+	assert.Equal(t, dag.Pending, d.Nodes["c"].Status)
+}
+
+func TestCheckDebounces(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "api.go", "package api\n")
+	d := chain(t)
+	clk := &clock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w := New(Options{Root: root, Debounce: time.Second, Now: clk.now})
+	w.Begin("b", []string{"api.go"})
+
+	write(t, root, "api.go", "package api\n\nvar X = 1\n")
+	res, err := w.Check(d)
+	require.NoError(t, err)
+	assert.Empty(t, res.Requeued, "just changed")
+
+	clk.t = clk.t.Add(600 * time.Millisecond)
+	write(t, root, "api.go", "package api\n\nvar X = 2\n")
+	res, err = w.Check(d)
+	require.NoError(t, err)
+	assert.Empty(t, res.Requeued, "still changing")
+
+	clk.t = clk.t.Add(time.Second)
+	res, err = w.Check(d)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, res.Requeued)
+}
+
+func TestFlushIgnoresDebounce(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "api.go", "package api\n")
+	d := chain(t)
+	w := New(Options{Root: root, Debounce: time.Hour})
+	w.Begin("b", []string{"api.go"})
+
+	write(t, root, "api.go", "package api\n\nvar X = 1\n")
+	res, err := w.Flush(d)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, res.Requeued)
+}
+
+func TestCheckAcceptsChangesFromDependents(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "api.go", "package api\n")
+	d := chain(t)
+	d.MarkRunning("c")
+	d.MarkCompleted("c", "done")
+	var producer, norm string
+	w := New(Options{Root: root, Producer: func(path string) (string, string, bool) {
+		return producer, norm, producer != ""
+	}})
+	w.Begin("b", []string{"api.go"})
+
+	// c, downstream of b, rewrote b's input: rerunning b would loop.
+	content := "package api\n\n// Documented.\n"
+	write(t, root, "api.go", content)
+	producer, norm = "c", artifact.NormalizedHash("api.go", []byte(content))
+	res, err := w.Check(d)
+	require.NoError(t, err)
+	assert.Empty(t, res.Requeued)
+	require.Len(t, res.Events, 1)
+	assert.Equal(t, ActionCycle, res.Events[0].Action)
+	assert.Contains(t, res.Events[0].Cause[0], "by node c")
+	assert.Equal(t, dag.Completed, d.Nodes["b"].Status)
+
+	res, err = w.Check(d)
+	require.NoError(t, err)
+	assert.Empty(t, res.Events, "accepted once")
+
+	// A change the producer does not account for is invalidating.
+	write(t, root, "api.go", "package api\n\nvar Y = 1\n")
+	res, err = w.Check(d)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, res.Requeued)
+}
+
+func TestCircuitBreakerEscalates(t *testing.T) {
+	root := t.TempDir()
+	d := chain(t)
+	clk := &clock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w := New(Options{Root: root, Window: time.Minute, MaxInvalidations: 2, Now: clk.now})
+
+	change := func(i int) Result {
+		t.Helper()
+		write(t, root, "api.go", "package api\n\nvar X = "+string(rune('0'+i))+"\n")
+		res, err := w.Check(d)
+		require.NoError(t, err)
+		return res
+	}
+
+	write(t, root, "api.go", "package api\n")
+	w.Begin("b", []string{"api.go"})
+	for i := 1; i <= 2; i++ {
+		assert.Equal(t, []string{"b"}, change(i).Requeued)
+		d.MarkRunning("b")
+		d.MarkCompleted("b", "done")
+		clk.t = clk.t.Add(10 * time.Second)
+	}
+
+	res := change(3)
+	assert.Empty(t, res.Requeued)
+	assert.Equal(t, []string{"b"}, res.Escalated)
+	require.Len(t, res.Events, 1)
+	assert.Equal(t, ActionEscalated, res.Events[0].Action)
+	assert.Contains(t, res.Events[0].Cause[2], "more than 2 times")
+	assert.Equal(t, dag.Escalated, d.Nodes["b"].Status)
+	assert.Equal(t, dag.Skipped, d.Nodes["c"].Status)
+}
+
+func TestCircuitBreakerWindowExpires(t *testing.T) {
+	root := t.TempDir()
+	d := chain(t)
+	clk := &clock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w := New(Options{Root: root, Window: time.Minute, MaxInvalidations: 1, Now: clk.now})
+
+	write(t, root, "api.go", "package api\n")
+	w.Begin("b", []string{"api.go"})
+	for i := 1; i <= 3; i++ {
+		write(t, root, "api.go", "package api\n\nvar X = "+string(rune('0'+i))+"\n")
+		res, err := w.Check(d)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b"}, res.Requeued, "change %d", i)
+		d.MarkRunning("b")
+		d.MarkCompleted("b", "done")
+		clk.t = clk.t.Add(2 * time.Minute)
+	}
+}
+
+func TestCheckSkipsNodesNotCompleted(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "api.go", "package api\n")
+	d := chain(t)
+	w := New(Options{Root: root})
+	d.Nodes["b"].Status = dag.Running
+	w.Begin("b", []string{"api.go"})
+
+	write(t, root, "api.go", "package api\n\nvar X = 1\n")
+	res, err := w.Check(d)
+	require.NoError(t, err)
+	assert.Empty(t, res.Events)
+
+	d.MarkCompleted("b", "done")
+	res, err = w.Check(d)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, res.Requeued, "caught once it completes")
+}
+
+func TestDeletedInput(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "api.go", "package api\n")
+	d := chain(t)
+	w := New(Options{Root: root})
+	w.Begin("b", []string{"api.go"})
+
+	require.NoError(t, os.Remove(filepath.Join(root, "api.go")))
+	res, err := w.Check(d)
+	require.NoError(t, err)
+	require.Len(t, res.Events, 1)
+	assert.Contains(t, res.Events[0].Cause[0], "-> absent")
+}
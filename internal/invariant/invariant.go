@@ -11,6 +11,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	_ "github.com/lyndonlyu/apex/internal/vectordb" // registers the vec0 module for I8
 )
 
 // CheckResult holds the result of a single invariant check.
@@ -234,9 +236,83 @@ func (r *Runner) checkI7() CheckResult {
 }
 
 // --- I8: Dual-DB Consistency ---
+// Every current memory marked SYNCED in memory.db has a vector in
+// vectors.db, and every vector belongs to a current memory or to a path
+// still PENDING sync.
 func (r *Runner) checkI8() CheckResult {
-	return CheckResult{ID: "I8", Name: "Dual-DB Consistency", Status: "SKIP",
-		Detail: "vectors.db compensation not yet implemented"}
+	memPath := filepath.Join(r.baseDir, "memory.db")
+	if _, err := os.Stat(memPath); err != nil {
+		return CheckResult{ID: "I8", Name: "Dual-DB Consistency", Status: "SKIP", Detail: "no memory.db"}
+	}
+	memDB, err := sql.Open("sqlite3", "file:"+memPath+"?mode=ro")
+	if err != nil {
+		return CheckResult{ID: "I8", Name: "Dual-DB Consistency", Status: "ERROR", Detail: err.Error()}
+	}
+	defer memDB.Close()
+
+	rows, err := memDB.Query(`SELECT path, is_current, vec_sync_status FROM memories`)
+	if err != nil {
+		return CheckResult{ID: "I8", Name: "Dual-DB Consistency", Status: "SKIP", Detail: "memory.db has no vector sync status"}
+	}
+	current := make(map[string]bool)  // paths with a current memory
+	unsynced := make(map[string]bool) // paths with a PENDING or FAILED row
+	pending := make(map[string]bool)  // paths with a PENDING row
+	for rows.Next() {
+		var path, status string
+		var isCurrent bool
+		if err := rows.Scan(&path, &isCurrent, &status); err != nil {
+			rows.Close()
+			return CheckResult{ID: "I8", Name: "Dual-DB Consistency", Status: "ERROR", Detail: err.Error()}
+		}
+		if isCurrent {
+			current[path] = true
+		}
+		if status != "SYNCED" {
+			unsynced[path] = true
+		}
+		if status == "PENDING" {
+			pending[path] = true
+		}
+	}
+	rows.Close()
+
+	vectors := make(map[string]bool)
+	vecPath := filepath.Join(r.baseDir, "vectors.db")
+	if _, err := os.Stat(vecPath); err == nil {
+		vecDB, err := sql.Open("sqlite3", "file:"+vecPath+"?mode=ro")
+		if err != nil {
+			return CheckResult{ID: "I8", Name: "Dual-DB Consistency", Status: "ERROR", Detail: err.Error()}
+		}
+		defer vecDB.Close()
+		vrows, err := vecDB.Query(`SELECT m.memory_id FROM vec_meta m JOIN vec_memories v ON v.rowid = m.rowid`)
+		if err != nil {
+			return CheckResult{ID: "I8", Name: "Dual-DB Consistency", Status: "ERROR", Detail: err.Error()}
+		}
+		for vrows.Next() {
+			var id string
+			vrows.Scan(&id)
+			vectors[id] = true
+		}
+		vrows.Close()
+	}
+
+	missing, orphaned := 0, 0
+	for path := range current {
+		if !unsynced[path] && !vectors[path] {
+			missing++
+		}
+	}
+	for id := range vectors {
+		if !current[id] && !pending[id] {
+			orphaned++
+		}
+	}
+	if missing > 0 || orphaned > 0 {
+		return CheckResult{ID: "I8", Name: "Dual-DB Consistency", Status: "FAIL",
+			Detail: fmt.Sprintf("%d SYNCED memories without a vector, %d vectors without a memory", missing, orphaned)}
+	}
+	return CheckResult{ID: "I8", Name: "Dual-DB Consistency", Status: "PASS",
+		Detail: fmt.Sprintf("%d vectors match memory.db, %d paths awaiting sync", len(vectors), len(unsynced))}
 }
 
 // --- I9: Lock Ordering ---
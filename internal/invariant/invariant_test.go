@@ -1,10 +1,16 @@
 package invariant
 
 import (
+	"context"
 	"database/sql"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/retry"
+	"github.com/lyndonlyu/apex/internal/vecsync"
+	"github.com/lyndonlyu/apex/internal/vectordb"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -123,3 +129,46 @@ func TestI5TraceCompletenessPass(t *testing.T) {
 	require.Len(t, result, 1)
 	assert.Equal(t, "PASS", result[0].Status)
 }
+
+type constEmbedder struct{}
+
+func (constEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{1, 0}, nil
+}
+
+func (constEmbedder) Available() bool { return true }
+
+func TestI8DualDBConsistency(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir()
+	r := NewRunner(db, dir)
+
+	res := r.Run("I8")[0]
+	assert.Equal(t, "SKIP", res.Status)
+
+	memDB, err := memory.OpenDB(filepath.Join(dir, "memory.db"), filepath.Join(dir, "memory"))
+	require.NoError(t, err)
+	defer memDB.Close()
+	store, err := memory.NewDBStore(filepath.Join(dir, "memory"), memDB, false)
+	require.NoError(t, err)
+	vdb, err := vectordb.Open(filepath.Join(dir, "vectors.db"), 2)
+	require.NoError(t, err)
+	defer vdb.Close()
+
+	e, err := store.SaveEntry("fact", "cache", "Redis.")
+	require.NoError(t, err)
+	res = r.Run("I8")[0]
+	assert.Equal(t, "PASS", res.Status, "pending memories are not checked: %s", res.Detail)
+
+	rec := vecsync.New(store, vdb, constEmbedder{}, retry.DefaultPolicy(), time.Minute)
+	_, err = rec.Sync(context.Background())
+	require.NoError(t, err)
+	res = r.Run("I8")[0]
+	assert.Equal(t, "PASS", res.Status, res.Detail)
+
+	require.NoError(t, vdb.Delete(e.Path))
+	require.NoError(t, vdb.Index(context.Background(), "facts/orphan.md", "x", []float32{0, 1}))
+	res = r.Run("I8")[0]
+	assert.Equal(t, "FAIL", res.Status)
+	assert.Contains(t, res.Detail, "1 SYNCED memories without a vector, 1 vectors without a memory")
+}
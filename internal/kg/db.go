@@ -0,0 +1,463 @@
+package kg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lyndonlyu/apex/internal/migration"
+	"github.com/lyndonlyu/apex/internal/writerq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DB is the SQLite store behind a Graph created with NewDBGraph. Entities
+// are indexed by canonical name, type and project, relationships by both
+// endpoints, type and evidence file. All writes go through a writerq.Queue,
+// so several processes may share one kg.db.
+type DB struct {
+	db    *sql.DB
+	path  string
+	queue *writerq.Queue
+}
+
+// OpenDB opens or creates the knowledge graph database at path and migrates
+// it. The first migrations import the graph.json next to path, if any.
+func OpenDB(path string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("kg: mkdir: %w", err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("kg: open db: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("kg: ping db: %w", err)
+	}
+	for _, p := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000", "PRAGMA wal_checkpoint(TRUNCATE)"} {
+		if _, err := db.Exec(p); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("kg: %s: %w", p, err)
+		}
+	}
+	if _, err := migrations(filepath.Join(filepath.Dir(path), "graph.json")).Migrate(db, path); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("kg: migrate: %w", err)
+	}
+	return &DB{db: db, path: path, queue: writerq.New(db)}, nil
+}
+
+// Close drains pending writes and closes the database.
+func (d *DB) Close() error {
+	d.queue.Close()
+	return d.db.Close()
+}
+
+const entitiesSchema = `CREATE TABLE IF NOT EXISTS entities (
+	id             TEXT PRIMARY KEY,
+	type           TEXT NOT NULL,
+	canonical_name TEXT NOT NULL,
+	project        TEXT NOT NULL DEFAULT '',
+	namespace      TEXT NOT NULL DEFAULT '',
+	created_at     TEXT NOT NULL,
+	updated_at     TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS entities_key ON entities(canonical_name, project, namespace);
+CREATE INDEX IF NOT EXISTS entities_type ON entities(type);
+CREATE INDEX IF NOT EXISTS entities_project ON entities(project);`
+
+const relationshipsSchema = `CREATE TABLE IF NOT EXISTS relationships (
+	id            TEXT PRIMARY KEY,
+	from_id       TEXT NOT NULL,
+	to_id         TEXT NOT NULL,
+	rel_type      TEXT NOT NULL,
+	evidence      TEXT NOT NULL DEFAULT '',
+	evidence_kind TEXT NOT NULL DEFAULT 'text',
+	evidence_file TEXT NOT NULL DEFAULT '',
+	evidence_line INTEGER NOT NULL DEFAULT 0,
+	evidence_hash TEXT NOT NULL DEFAULT '',
+	created_at    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS relationships_from ON relationships(from_id);
+CREATE INDEX IF NOT EXISTS relationships_to ON relationships(to_id);
+CREATE INDEX IF NOT EXISTS relationships_type ON relationships(rel_type);
+CREATE INDEX IF NOT EXISTS relationships_file ON relationships(evidence_file);`
+
+// migrations returns the knowledge graph schema history.
+func migrations(jsonPath string) *migration.Registry {
+	r := migration.NewRegistry()
+	r.Add(1, "create entities table", entitiesSchema)
+	r.Add(2, "create relationships table", relationshipsSchema)
+	r.AddFunc(3, "import graph.json", func(db *sql.DB) error {
+		gf, err := readGraphFile(jsonPath)
+		if err != nil || gf == nil {
+			return err
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		write := func(ops []writerq.Op) error {
+			for _, op := range ops {
+				if _, err := tx.Exec(op.SQL, op.Args...); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if _, _, err := importGraph(tx, write, gf); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	return r
+}
+
+// readGraphFile reads a graph.json file; a missing file yields nil.
+func readGraphFile(path string) (*graphFile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var gf graphFile
+	if err := json.Unmarshal(data, &gf); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &gf, nil
+}
+
+// ImportJSON adds the entities and relationships of a graph.json file and
+// returns how many of each were new. An entity whose canonical key already
+// exists under another ID is merged into it.
+func (d *DB) ImportJSON(path string) (int, int, error) {
+	gf, err := readGraphFile(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("kg: %w", err)
+	}
+	if gf == nil {
+		return 0, 0, fmt.Errorf("kg: %s does not exist", path)
+	}
+	write := func(ops []writerq.Op) error {
+		return d.queue.SubmitAll(context.Background(), ops)
+	}
+	ents, rels, err := importGraph(d.db, write, gf)
+	if err != nil {
+		return ents, rels, fmt.Errorf("kg: import %s: %w", path, err)
+	}
+	return ents, rels, nil
+}
+
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// importGraph inserts gf using q to read and write to apply inserts.
+func importGraph(q querier, write func([]writerq.Op) error, gf *graphFile) (int, int, error) {
+	ids := make(map[string]string, len(gf.Entities))  // file ID -> stored ID
+	keys := make(map[string]string, len(gf.Entities)) // canonical key -> stored ID
+	var ops []writerq.Op
+	for _, e := range gf.Entities {
+		key := e.CanonicalName + "\x00" + e.Project + "\x00" + e.Namespace
+		id, ok := keys[key]
+		if !ok {
+			err := q.QueryRow(`SELECT id FROM entities WHERE id = ? OR (canonical_name = ? AND project = ? AND namespace = ?)`,
+				e.ID, e.CanonicalName, e.Project, e.Namespace).Scan(&id)
+			switch {
+			case err == sql.ErrNoRows:
+				ops = append(ops, writerq.Op{SQL: insertEntitySQL, Args: entityArgs(e)})
+				id = e.ID
+			case err != nil:
+				return 0, 0, err
+			}
+			keys[key] = id
+		}
+		ids[e.ID] = id
+	}
+	if err := write(ops); err != nil {
+		return 0, 0, err
+	}
+	ents := len(ops)
+
+	ops = nil
+	seen := make(map[string]bool, len(gf.Relationships))
+	for _, r := range gf.Relationships {
+		from, to := ids[r.FromID], ids[r.ToID]
+		if from == "" || to == "" || seen[r.ID] {
+			continue // dangling or repeated in the file
+		}
+		seen[r.ID] = true
+		var n int
+		if err := q.QueryRow(`SELECT COUNT(*) FROM relationships WHERE id = ?`, r.ID).Scan(&n); err != nil {
+			return ents, 0, err
+		}
+		if n > 0 {
+			continue
+		}
+		rel := *r
+		rel.FromID, rel.ToID = from, to
+		ops = append(ops, writerq.Op{SQL: insertRelationshipSQL, Args: relationshipArgs(&rel)})
+	}
+	if err := write(ops); err != nil {
+		return ents, 0, err
+	}
+	return ents, len(ops), nil
+}
+
+const (
+	entityColumns         = `id, type, canonical_name, project, namespace, created_at, updated_at`
+	insertEntitySQL       = `INSERT OR IGNORE INTO entities (` + entityColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	relationshipColumns   = `id, from_id, to_id, rel_type, evidence, created_at`
+	insertRelationshipSQL = `INSERT OR IGNORE INTO relationships (id, from_id, to_id, rel_type, evidence, evidence_kind, evidence_file, evidence_line, evidence_hash, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+)
+
+func entityArgs(e *Entity) []any {
+	return []any{e.ID, string(e.Type), e.CanonicalName, e.Project, e.Namespace,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano), e.UpdatedAt.UTC().Format(time.RFC3339Nano)}
+}
+
+func relationshipArgs(r *Relationship) []any {
+	ev := ParseEvidence(r.Evidence)
+	return []any{r.ID, r.FromID, r.ToID, string(r.RelType), r.Evidence,
+		string(ev.Kind), ev.File, ev.Line, ev.Hash, r.CreatedAt.UTC().Format(time.RFC3339Nano)}
+}
+
+type scanner interface{ Scan(...any) error }
+
+func scanEntity(row scanner) (*Entity, error) {
+	var e Entity
+	var etype, created, updated string
+	if err := row.Scan(&e.ID, &etype, &e.CanonicalName, &e.Project, &e.Namespace, &created, &updated); err != nil {
+		return nil, err
+	}
+	e.Type = EntityType(etype)
+	e.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+	e.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updated)
+	return &e, nil
+}
+
+func scanRelationship(row scanner) (*Relationship, error) {
+	var r Relationship
+	var rtype, created string
+	if err := row.Scan(&r.ID, &r.FromID, &r.ToID, &rtype, &r.Evidence, &created); err != nil {
+		return nil, err
+	}
+	r.RelType = RelType(rtype)
+	r.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+	return &r, nil
+}
+
+// ---------------------------------------------------------------------------
+// Graph operations
+// ---------------------------------------------------------------------------
+
+// entityByKey returns the entity with the canonical key, or nil.
+func (d *DB) entityByKey(name, project, namespace string) (*Entity, error) {
+	e, err := scanEntity(d.db.QueryRow(`SELECT `+entityColumns+` FROM entities
+		WHERE canonical_name = ? AND project = ? AND namespace = ?`, name, project, namespace))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+func (d *DB) entity(id string) (*Entity, error) {
+	e, err := scanEntity(d.db.QueryRow(`SELECT `+entityColumns+` FROM entities WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// addEntity inserts e unless its canonical key exists, and returns the
+// stored entity either way.
+func (d *DB) addEntity(e *Entity) (*Entity, error) {
+	ents, err := d.addEntities([]Entity{*e})
+	if err != nil {
+		return nil, err
+	}
+	return ents[0], nil
+}
+
+// addEntities inserts the specs whose canonical keys are new in one batch
+// and returns the stored entity for every spec.
+func (d *DB) addEntities(specs []Entity) ([]*Entity, error) {
+	out := make([]*Entity, len(specs))
+	var ops []writerq.Op
+	var missing []int
+	for i := range specs {
+		s := &specs[i]
+		e, err := d.entityByKey(s.CanonicalName, s.Project, s.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			out[i] = e
+			continue
+		}
+		if s.ID == "" {
+			s.ID = uuid.New().String()
+		}
+		if s.CreatedAt.IsZero() {
+			s.CreatedAt = time.Now().UTC()
+			s.UpdatedAt = s.CreatedAt
+		}
+		ops = append(ops, writerq.Op{SQL: insertEntitySQL, Args: entityArgs(s)})
+		missing = append(missing, i)
+	}
+	if len(ops) == 0 {
+		return out, nil
+	}
+	if err := d.queue.SubmitAll(context.Background(), ops); err != nil {
+		return nil, err
+	}
+	// Read back: a duplicate key in specs, or another writer, may have won.
+	for _, i := range missing {
+		s := specs[i]
+		e, err := d.entityByKey(s.CanonicalName, s.Project, s.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			return nil, fmt.Errorf("entity %q vanished after insert", s.CanonicalName)
+		}
+		out[i] = e
+	}
+	return out, nil
+}
+
+// addRelationships checks that every endpoint exists and inserts rels in
+// one batch.
+func (d *DB) addRelationships(rels []*Relationship) error {
+	checked := make(map[string]bool)
+	ops := make([]writerq.Op, 0, len(rels))
+	for _, r := range rels {
+		for _, id := range []string{r.FromID, r.ToID} {
+			if checked[id] {
+				continue
+			}
+			e, err := d.entity(id)
+			if err != nil {
+				return fmt.Errorf("kg: add relationship: %w", err)
+			}
+			if e == nil {
+				return fmt.Errorf("kg: entity not found: %s", id)
+			}
+			checked[id] = true
+		}
+		ops = append(ops, writerq.Op{SQL: insertRelationshipSQL, Args: relationshipArgs(r)})
+	}
+	if err := d.queue.SubmitAll(context.Background(), ops); err != nil {
+		return fmt.Errorf("kg: add relationship: %w", err)
+	}
+	return nil
+}
+
+func (d *DB) queryEntities(where string, args ...any) ([]*Entity, error) {
+	rows, err := d.db.Query(`SELECT `+entityColumns+` FROM entities`+where+` ORDER BY canonical_name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Entity
+	for rows.Next() {
+		e, err := scanEntity(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (d *DB) queryByName(name string) ([]*Entity, error) {
+	return d.queryEntities(` WHERE instr(lower(canonical_name), ?) > 0`, strings.ToLower(name))
+}
+
+func (d *DB) list(etype EntityType, project string) ([]*Entity, error) {
+	var conds []string
+	var args []any
+	if etype != "" {
+		conds = append(conds, "type = ?")
+		args = append(args, string(etype))
+	}
+	if project != "" {
+		conds = append(conds, "project = ?")
+		args = append(args, project)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	return d.queryEntities(where, args...)
+}
+
+// edges returns the relationships touching id.
+func (d *DB) edges(id string) ([]*Relationship, error) {
+	rows, err := d.db.Query(`SELECT `+relationshipColumns+` FROM relationships
+		WHERE from_id = ? OR to_id = ? ORDER BY created_at, id`, id, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Relationship
+	for rows.Next() {
+		r, err := scanRelationship(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// removeEntity deletes id and, first, every relationship referencing it.
+func (d *DB) removeEntity(id string) error {
+	return d.queue.SubmitAll(context.Background(), []writerq.Op{
+		{SQL: `DELETE FROM relationships WHERE from_id = ? OR to_id = ?`, Args: []any{id, id}},
+		{SQL: `DELETE FROM entities WHERE id = ?`, Args: []any{id}},
+	})
+}
+
+func (d *DB) removeRelationships(ids []string) error {
+	ops := make([]writerq.Op, len(ids))
+	for i, id := range ids {
+		ops[i] = writerq.Op{SQL: `DELETE FROM relationships WHERE id = ?`, Args: []any{id}}
+	}
+	return d.queue.SubmitAll(context.Background(), ops)
+}
+
+func (d *DB) stats() (Stats, error) {
+	s := Stats{EntitiesByType: make(map[string]int), RelsByType: make(map[string]int)}
+	count := func(query string, into map[string]int, total *int) error {
+		rows, err := d.db.Query(query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var t string
+			var n int
+			if err := rows.Scan(&t, &n); err != nil {
+				return err
+			}
+			into[t] = n
+			*total += n
+		}
+		return rows.Err()
+	}
+	if err := count(`SELECT type, COUNT(*) FROM entities GROUP BY type`, s.EntitiesByType, &s.TotalEntities); err != nil {
+		return s, err
+	}
+	err := count(`SELECT rel_type, COUNT(*) FROM relationships GROUP BY rel_type`, s.RelsByType, &s.TotalRelationships)
+	return s, err
+}
@@ -0,0 +1,153 @@
+package kg
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lyndonlyu/apex/internal/migration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDBGraph(t *testing.T) (*Graph, *DB) {
+	t.Helper()
+	db, err := OpenDB(filepath.Join(t.TempDir(), "kg.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewDBGraph(db), db
+}
+
+// TestBackendsAgree runs the same operations against both backends.
+func TestBackendsAgree(t *testing.T) {
+	dbGraph, _ := testDBGraph(t)
+	for name, g := range map[string]*Graph{"json": testGraph(t), "sqlite": dbGraph} {
+		t.Run(name, func(t *testing.T) {
+			a, err := g.AddEntity(EntityFile, "Main.go", "apex", "")
+			require.NoError(t, err)
+			again, err := g.AddEntity(EntityFunction, "Main.go", "apex", "")
+			require.NoError(t, err)
+			assert.Equal(t, a.ID, again.ID, "dedup by canonical key")
+			b, _ := g.AddEntity(EntityFunction, "run", "apex", "")
+			c, _ := g.AddEntity(EntityPackage, "cmd", "other", "")
+
+			_, err = g.AddRelationship(a.ID, "missing", RelCalls, "")
+			assert.Error(t, err)
+			_, err = g.AddRelationship(a.ID, b.ID, RelContains, "main.go:3")
+			require.NoError(t, err)
+			rels, err := g.AddRelationships([]Relationship{{FromID: c.ID, ToID: a.ID, RelType: RelContains}})
+			require.NoError(t, err)
+			require.Len(t, rels, 1)
+			assert.NotEmpty(t, rels[0].ID)
+
+			assert.Equal(t, a.CanonicalName, g.GetEntity(a.ID).CanonicalName)
+			assert.Nil(t, g.GetEntity("missing"))
+			assert.Len(t, g.QueryByName("MAIN"), 1)
+			assert.Len(t, g.List(EntityFunction, ""), 1)
+			assert.Len(t, g.List("", "other"), 1)
+
+			related, relRels := g.QueryRelated(b.ID, 1, 0)
+			assert.Len(t, related, 1)
+			assert.Len(t, relRels, 1)
+			related, _ = g.QueryRelated(b.ID, 2, 0)
+			assert.Len(t, related, 2)
+
+			s := g.Stats()
+			assert.Equal(t, 3, s.TotalEntities)
+			assert.Equal(t, 2, s.TotalRelationships)
+			assert.Equal(t, 2, s.RelsByType[string(RelContains)])
+
+			require.NoError(t, g.RemoveEntity(a.ID))
+			assert.Error(t, g.RemoveEntity(a.ID))
+			s = g.Stats()
+			assert.Equal(t, 2, s.TotalEntities)
+			assert.Equal(t, 0, s.TotalRelationships, "relationships cascade")
+			require.NoError(t, g.Save())
+		})
+	}
+}
+
+func TestOpenDBImportsGraphJSON(t *testing.T) {
+	dir := t.TempDir()
+	old, err := New(dir)
+	require.NoError(t, err)
+	a, _ := old.AddEntity(EntityFile, "a.go", "apex", "")
+	b, _ := old.AddEntity(EntityFunction, "F", "apex", "")
+	_, err = old.AddRelationship(a.ID, b.ID, RelContains, "a.go:3@abcdef123456")
+	require.NoError(t, err)
+	require.NoError(t, old.Save())
+
+	db, err := OpenDB(filepath.Join(dir, "kg.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	g := NewDBGraph(db)
+	assert.Equal(t, old.Stats(), g.Stats())
+	assert.Equal(t, "a.go", g.GetEntity(a.ID).CanonicalName, "IDs are kept")
+
+	version, err := migration.GetVersion(db.db)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+
+	var kind, file, hash string
+	var line int
+	require.NoError(t, db.db.QueryRow(`SELECT evidence_kind, evidence_file, evidence_line, evidence_hash FROM relationships`).
+		Scan(&kind, &file, &line, &hash))
+	assert.Equal(t, []any{"source", "a.go", 3, "abcdef123456"}, []any{kind, file, line, hash})
+}
+
+func TestImportJSONMergesKeys(t *testing.T) {
+	g, db := testDBGraph(t)
+	existing, err := g.AddEntity(EntityFile, "a.go", "apex", "")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	other, err := New(dir)
+	require.NoError(t, err)
+	a, _ := other.AddEntity(EntityFile, "a.go", "apex", "")
+	b, _ := other.AddEntity(EntityFunction, "F", "apex", "")
+	_, err = other.AddRelationship(a.ID, b.ID, RelContains, "")
+	require.NoError(t, err)
+	require.NoError(t, other.Save())
+
+	ents, rels, err := db.ImportJSON(filepath.Join(dir, "graph.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, ents, "a.go already exists")
+	assert.Equal(t, 1, rels)
+	related, _ := g.QueryRelated(existing.ID, 1, 0)
+	require.Len(t, related, 1)
+	assert.Equal(t, "F", related[0].CanonicalName)
+
+	ents, rels, err = db.ImportJSON(filepath.Join(dir, "graph.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, ents+rels, "importing twice adds nothing")
+
+	_, _, err = db.ImportJSON(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestIndexIntoDB(t *testing.T) {
+	src := t.TempDir()
+	writeModule(t, src, map[string]string{
+		"main.go":      mainSrc,
+		"util/util.go": utilSrc,
+	})
+	g, _ := testDBGraph(t)
+	ix := &Indexer{Graph: g, StatePath: filepath.Join(t.TempDir(), "state.json")}
+	_, err := ix.Index(src)
+	require.NoError(t, err)
+	before := g.Stats()
+	assert.Equal(t, 1, before.EntitiesByType[string(EntityClass)])
+
+	result, err := ix.Index(src)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Parsed)
+	assert.Equal(t, before, g.Stats())
+
+	matches := g.QueryByName("util.Join")
+	require.Len(t, matches, 1)
+	related, _ := g.QueryRelated(matches[0].ID, 1, 0)
+	var names []string
+	for _, e := range related {
+		names = append(names, e.CanonicalName)
+	}
+	assert.ElementsMatch(t, []string{"util/util.go", "example.com/demo/util.trim", "example.com/demo.Server.Run"}, names)
+}
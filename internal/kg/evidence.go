@@ -0,0 +1,59 @@
+package kg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// EvidenceKind classifies the evidence string of a relationship.
+type EvidenceKind string
+
+const (
+	// EvidenceSource cites a line of a specific version of a file:
+	// "path:line@hash".
+	EvidenceSource EvidenceKind = "source"
+	// EvidenceLine cites a line of a file: "path:line".
+	EvidenceLine EvidenceKind = "line"
+	// EvidenceText is free-form text.
+	EvidenceText EvidenceKind = "text"
+)
+
+// Evidence is the typed form of Relationship.Evidence.
+type Evidence struct {
+	Kind EvidenceKind `json:"kind"`
+	File string       `json:"file,omitempty"`
+	Line int          `json:"line,omitempty"`
+	Hash string       `json:"hash,omitempty"` // content hash prefix of File
+	Text string       `json:"text,omitempty"` // the raw evidence
+}
+
+var evidencePattern = regexp.MustCompile(`^([^\s:@]+):(\d+)(?:@([0-9a-f]+))?$`)
+
+// ParseEvidence types an evidence string.
+func ParseEvidence(s string) Evidence {
+	m := evidencePattern.FindStringSubmatch(s)
+	if m == nil {
+		return Evidence{Kind: EvidenceText, Text: s}
+	}
+	line, err := strconv.Atoi(m[2])
+	if err != nil {
+		return Evidence{Kind: EvidenceText, Text: s}
+	}
+	ev := Evidence{Kind: EvidenceLine, File: m[1], Line: line, Hash: m[3], Text: s}
+	if ev.Hash != "" {
+		ev.Kind = EvidenceSource
+	}
+	return ev
+}
+
+// String renders e in the form ParseEvidence reads.
+func (e Evidence) String() string {
+	switch e.Kind {
+	case EvidenceSource:
+		return fmt.Sprintf("%s:%d@%s", e.File, e.Line, e.Hash)
+	case EvidenceLine:
+		return fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+	return e.Text
+}
@@ -0,0 +1,25 @@
+package kg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEvidence(t *testing.T) {
+	ev := ParseEvidence("internal/kg/graph.go:42@1a2b3c4d5e6f")
+	assert.Equal(t, Evidence{Kind: EvidenceSource, File: "internal/kg/graph.go", Line: 42, Hash: "1a2b3c4d5e6f",
+		Text: "internal/kg/graph.go:42@1a2b3c4d5e6f"}, ev)
+	assert.Equal(t, ev.Text, ev.String())
+
+	ev = ParseEvidence("main.go:7")
+	assert.Equal(t, EvidenceLine, ev.Kind)
+	assert.Equal(t, 7, ev.Line)
+	assert.Equal(t, "main.go:7", ev.String())
+
+	for _, s := range []string{"", "seen in review", "main.go", "a b.go:3"} {
+		ev := ParseEvidence(s)
+		assert.Equal(t, EvidenceText, ev.Kind, s)
+		assert.Equal(t, s, ev.String())
+	}
+}
@@ -125,3 +125,119 @@ func FormatJSON(v interface{}) string {
 	}
 	return string(data)
 }
+
+// ---------------------------------------------------------------------------
+// FormatQueryTable
+// ---------------------------------------------------------------------------
+
+// FormatQueryTable renders a query result as an aligned table with one
+// column per returned variable, or one line per path for shortestPath
+// queries. Returns "No matches." when nothing matched.
+func FormatQueryTable(res *QueryResult) string {
+	if len(res.Rows) == 0 && len(res.Paths) == 0 {
+		return "No matches.\n"
+	}
+
+	var b strings.Builder
+	for _, p := range res.Paths {
+		b.WriteString(formatPath(p))
+		b.WriteString("\n")
+	}
+	if len(res.Rows) == 0 {
+		return b.String()
+	}
+
+	cell := func(e *Entity) string {
+		return fmt.Sprintf("%s (%s)", e.CanonicalName, e.Type)
+	}
+	widths := make([]int, len(res.Columns))
+	for i, c := range res.Columns {
+		widths[i] = len(c)
+	}
+	for _, row := range res.Rows {
+		for i, e := range row {
+			if l := len(cell(e)); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+	writeRow := func(cells []string) {
+		for i, c := range cells {
+			if i == len(cells)-1 {
+				b.WriteString(c)
+			} else {
+				fmt.Fprintf(&b, "%-*s | ", widths[i], c)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(res.Columns)
+	lineLen := 0
+	for _, w := range widths {
+		lineLen += w + 3
+	}
+	b.WriteString(strings.Repeat("-", lineLen-3))
+	b.WriteString("\n")
+	for _, row := range res.Rows {
+		cells := make([]string, len(row))
+		for i, e := range row {
+			cells[i] = cell(e)
+		}
+		writeRow(cells)
+	}
+	fmt.Fprintf(&b, "(%d rows)\n", len(res.Rows))
+	return b.String()
+}
+
+// formatPath renders a path as "a -[calls]-> b <-[imports]- c".
+func formatPath(p Path) string {
+	var b strings.Builder
+	for i, e := range p.Entities {
+		if i > 0 {
+			r := p.Relationships[i-1]
+			if r.FromID == p.Entities[i-1].ID {
+				fmt.Fprintf(&b, " -[%s]-> ", r.RelType)
+			} else {
+				fmt.Fprintf(&b, " <-[%s]- ", r.RelType)
+			}
+		}
+		b.WriteString(e.CanonicalName)
+	}
+	return b.String()
+}
+
+// ---------------------------------------------------------------------------
+// FormatDOT
+// ---------------------------------------------------------------------------
+
+// FormatDOT renders entities and the relationships between them as a
+// Graphviz digraph. Repeated entities and relationships are drawn once, and
+// relationships whose endpoints are not among entities are left out.
+func FormatDOT(entities []*Entity, rels []*Relationship) string {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph kg {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	known := make(map[string]bool, len(entities))
+	for _, e := range entities {
+		if known[e.ID] {
+			continue
+		}
+		known[e.ID] = true
+		fmt.Fprintf(&b, "  %s [label=%s];\n", quote(e.ID), quote(e.CanonicalName+"\n("+string(e.Type)+")"))
+	}
+	drawn := make(map[string]bool, len(rels))
+	for _, r := range rels {
+		if known[r.FromID] && known[r.ToID] && !drawn[r.ID] {
+			drawn[r.ID] = true
+			fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", quote(r.FromID), quote(r.ToID), quote(string(r.RelType)))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -96,12 +96,14 @@ type graphFile struct {
 // Graph
 // ---------------------------------------------------------------------------
 
-// Graph is an in-memory knowledge graph with JSON persistence.
+// Graph is a knowledge graph: in memory with JSON persistence, or backed
+// by a kg.db when created with NewDBGraph.
 type Graph struct {
 	mu   sync.RWMutex
 	dir  string
 	ents map[string]*Entity       // id -> entity
 	rels map[string]*Relationship // id -> relationship
+	db   *DB                      // source of truth when set; ents and rels are unused
 }
 
 // New loads an existing graph.json from dir or creates an empty graph.
@@ -117,6 +119,13 @@ func New(dir string) (*Graph, error) {
 	return g, nil
 }
 
+// NewDBGraph returns a graph backed by db. Save is a no-op since every
+// write is committed as it happens. Read methods without an error result
+// return empty results if the database fails.
+func NewDBGraph(db *DB) *Graph {
+	return &Graph{dir: filepath.Dir(db.path), db: db}
+}
+
 // ---------------------------------------------------------------------------
 // AddEntity
 // ---------------------------------------------------------------------------
@@ -124,16 +133,6 @@ func New(dir string) (*Graph, error) {
 // AddEntity adds an entity to the graph. If an entity with the same
 // (CanonicalName, Project, Namespace) already exists, it is returned instead.
 func (g *Graph) AddEntity(etype EntityType, canonicalName, project, namespace string) (*Entity, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	// Dedup by canonical key.
-	for _, e := range g.ents {
-		if e.CanonicalName == canonicalName && e.Project == project && e.Namespace == namespace {
-			return e, nil
-		}
-	}
-
 	now := time.Now().UTC()
 	ent := &Entity{
 		ID:            uuid.New().String(),
@@ -144,16 +143,60 @@ func (g *Graph) AddEntity(etype EntityType, canonicalName, project, namespace st
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
+	if g.db != nil {
+		e, err := g.db.addEntity(ent)
+		if err != nil {
+			return nil, fmt.Errorf("kg: add entity: %w", err)
+		}
+		return e, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Dedup by canonical key.
+	for _, e := range g.ents {
+		if e.CanonicalName == canonicalName && e.Project == project && e.Namespace == namespace {
+			return e, nil
+		}
+	}
 	g.ents[ent.ID] = ent
 	return ent, nil
 }
 
+// AddEntities adds one entity per spec, using its Type, CanonicalName,
+// Project and Namespace, and returns them in order; like AddEntity, a spec
+// whose canonical key exists returns the existing entity. A kg.db graph
+// writes them in one batch.
+func (g *Graph) AddEntities(specs []Entity) ([]*Entity, error) {
+	if g.db != nil {
+		ents, err := g.db.addEntities(specs)
+		if err != nil {
+			return nil, fmt.Errorf("kg: add entities: %w", err)
+		}
+		return ents, nil
+	}
+	ents := make([]*Entity, len(specs))
+	for i, s := range specs {
+		e, err := g.AddEntity(s.Type, s.CanonicalName, s.Project, s.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		ents[i] = e
+	}
+	return ents, nil
+}
+
 // ---------------------------------------------------------------------------
 // GetEntity
 // ---------------------------------------------------------------------------
 
 // GetEntity returns the entity with the given ID, or nil if not found.
 func (g *Graph) GetEntity(id string) *Entity {
+	if g.db != nil {
+		e, _ := g.db.entity(id)
+		return e
+	}
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 	return g.ents[id]
@@ -166,6 +209,21 @@ func (g *Graph) GetEntity(id string) *Entity {
 // AddRelationship creates a directed relationship between two entities.
 // Both fromID and toID must reference existing entities.
 func (g *Graph) AddRelationship(fromID, toID string, relType RelType, evidence string) (*Relationship, error) {
+	rel := &Relationship{
+		ID:        uuid.New().String(),
+		FromID:    fromID,
+		ToID:      toID,
+		RelType:   relType,
+		Evidence:  evidence,
+		CreatedAt: time.Now().UTC(),
+	}
+	if g.db != nil {
+		if err := g.db.addRelationships([]*Relationship{rel}); err != nil {
+			return nil, err
+		}
+		return rel, nil
+	}
+
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -175,19 +233,48 @@ func (g *Graph) AddRelationship(fromID, toID string, relType RelType, evidence s
 	if _, ok := g.ents[toID]; !ok {
 		return nil, fmt.Errorf("kg: entity not found: %s", toID)
 	}
-
-	rel := &Relationship{
-		ID:        uuid.New().String(),
-		FromID:    fromID,
-		ToID:      toID,
-		RelType:   relType,
-		Evidence:  evidence,
-		CreatedAt: time.Now().UTC(),
-	}
 	g.rels[rel.ID] = rel
 	return rel, nil
 }
 
+// AddRelationships adds one relationship per spec, using its FromID, ToID,
+// RelType and Evidence, and returns them in order. Every endpoint must
+// exist; nothing is added otherwise. A kg.db graph writes them in one batch.
+func (g *Graph) AddRelationships(specs []Relationship) ([]*Relationship, error) {
+	now := time.Now().UTC()
+	rels := make([]*Relationship, len(specs))
+	for i, s := range specs {
+		rels[i] = &Relationship{
+			ID:        uuid.New().String(),
+			FromID:    s.FromID,
+			ToID:      s.ToID,
+			RelType:   s.RelType,
+			Evidence:  s.Evidence,
+			CreatedAt: now,
+		}
+	}
+	if g.db != nil {
+		if err := g.db.addRelationships(rels); err != nil {
+			return nil, err
+		}
+		return rels, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, r := range rels {
+		for _, id := range []string{r.FromID, r.ToID} {
+			if _, ok := g.ents[id]; !ok {
+				return nil, fmt.Errorf("kg: entity not found: %s", id)
+			}
+		}
+	}
+	for _, r := range rels {
+		g.rels[r.ID] = r
+	}
+	return rels, nil
+}
+
 // ---------------------------------------------------------------------------
 // QueryByName
 // ---------------------------------------------------------------------------
@@ -195,6 +282,10 @@ func (g *Graph) AddRelationship(fromID, toID string, relType RelType, evidence s
 // QueryByName returns all entities whose CanonicalName contains the given
 // substring (case-insensitive).
 func (g *Graph) QueryByName(name string) []*Entity {
+	if g.db != nil {
+		results, _ := g.db.queryByName(name)
+		return results
+	}
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
@@ -212,19 +303,47 @@ func (g *Graph) QueryByName(name string) []*Entity {
 // QueryRelated — BFS
 // ---------------------------------------------------------------------------
 
+// Defaults for QueryRelated, mirroring kg_query_depth and max_kg_nodes.
+const (
+	DefaultRelatedDepth = 2
+	DefaultMaxNodes     = 200
+)
+
 // QueryRelated performs a BFS from the given entity up to depth hops,
 // returning at most maxNodes connected entities (excluding the start node)
 // along with the relationships traversed during the search.
-// Defaults: depth=2, maxNodes=200 when zero values are passed.
+// Defaults: DefaultRelatedDepth and DefaultMaxNodes when zero values are
+// passed.
 func (g *Graph) QueryRelated(entityID string, depth, maxNodes int) ([]*Entity, []*Relationship) {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
+	edges := func(id string) []*Relationship {
+		var out []*Relationship
+		for _, r := range g.rels {
+			if r.FromID == id || r.ToID == id {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+	entity := func(id string) *Entity { return g.ents[id] }
+	if g.db != nil {
+		edges = func(id string) []*Relationship {
+			out, _ := g.db.edges(id)
+			return out
+		}
+		entity = func(id string) *Entity {
+			e, _ := g.db.entity(id)
+			return e
+		}
+	} else {
+		g.mu.RLock()
+		defer g.mu.RUnlock()
+	}
 
 	if depth <= 0 {
-		depth = 2
+		depth = DefaultRelatedDepth
 	}
 	if maxNodes <= 0 {
-		maxNodes = 200
+		maxNodes = DefaultMaxNodes
 	}
 
 	visited := map[string]bool{entityID: true}
@@ -236,14 +355,10 @@ func (g *Graph) QueryRelated(entityID string, depth, maxNodes int) ([]*Entity, [
 	for d := 0; d < depth && len(queue) > 0; d++ {
 		var next []string
 		for _, id := range queue {
-			for _, r := range g.rels {
-				var neighbour string
-				if r.FromID == id {
-					neighbour = r.ToID
-				} else if r.ToID == id {
+			for _, r := range edges(id) {
+				neighbour := r.ToID
+				if r.FromID != id {
 					neighbour = r.FromID
-				} else {
-					continue
 				}
 				if visited[neighbour] {
 					continue
@@ -253,7 +368,7 @@ func (g *Graph) QueryRelated(entityID string, depth, maxNodes int) ([]*Entity, [
 					visitedRels[r.ID] = true
 					rels = append(rels, r)
 				}
-				if e, ok := g.ents[neighbour]; ok {
+				if e := entity(neighbour); e != nil {
 					result = append(result, e)
 					if len(result) >= maxNodes {
 						return result, rels
@@ -274,6 +389,19 @@ func (g *Graph) QueryRelated(entityID string, depth, maxNodes int) ([]*Entity, [
 // RemoveEntity removes the entity with the given ID and all relationships
 // that reference it (cascading delete).
 func (g *Graph) RemoveEntity(id string) error {
+	if g.db != nil {
+		e, err := g.db.entity(id)
+		if err != nil {
+			return fmt.Errorf("kg: remove entity: %w", err)
+		}
+		if e == nil {
+			return fmt.Errorf("kg: entity not found: %s", id)
+		}
+		if err := g.db.removeEntity(id); err != nil {
+			return fmt.Errorf("kg: remove entity: %w", err)
+		}
+		return nil
+	}
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -291,6 +419,24 @@ func (g *Graph) RemoveEntity(id string) error {
 	return nil
 }
 
+// RemoveRelationship removes the relationships with the given IDs.
+// Removing an ID that is not present is a no-op, since cascading entity
+// deletes may already have removed it.
+func (g *Graph) RemoveRelationship(ids ...string) error {
+	if g.db != nil {
+		if err := g.db.removeRelationships(ids); err != nil {
+			return fmt.Errorf("kg: remove relationship: %w", err)
+		}
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, id := range ids {
+		delete(g.rels, id)
+	}
+	return nil
+}
+
 // ---------------------------------------------------------------------------
 // List
 // ---------------------------------------------------------------------------
@@ -298,6 +444,10 @@ func (g *Graph) RemoveEntity(id string) error {
 // List returns entities optionally filtered by type and/or project.
 // Pass empty string to skip a filter.
 func (g *Graph) List(entityType EntityType, project string) []*Entity {
+	if g.db != nil {
+		results, _ := g.db.list(entityType, project)
+		return results
+	}
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
@@ -320,6 +470,10 @@ func (g *Graph) List(entityType EntityType, project string) []*Entity {
 
 // Stats returns aggregate counts for the graph.
 func (g *Graph) Stats() Stats {
+	if g.db != nil {
+		s, _ := g.db.stats()
+		return s
+	}
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
@@ -342,8 +496,12 @@ func (g *Graph) Stats() Stats {
 // Persistence
 // ---------------------------------------------------------------------------
 
-// Save persists the graph to {dir}/graph.json.
+// Save persists the graph to {dir}/graph.json. A kg.db graph is always
+// up to date, so Save does nothing.
 func (g *Graph) Save() error {
+	if g.db != nil {
+		return nil
+	}
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -267,3 +267,16 @@ func TestSaveAndReload(t *testing.T) {
 	require.Len(t, results, 1)
 	assert.Equal(t, e1.ID, results[0].ID)
 }
+
+func TestRemoveRelationship(t *testing.T) {
+	g := testGraph(t)
+	a, _ := g.AddEntity(EntityFile, "a.go", "apex", "")
+	b, _ := g.AddEntity(EntityFile, "b.go", "apex", "")
+	r, err := g.AddRelationship(a.ID, b.ID, RelImports, "")
+	require.NoError(t, err)
+
+	g.RemoveRelationship(r.ID)
+	g.RemoveRelationship(r.ID) // no-op
+	assert.Equal(t, 0, g.Stats().TotalRelationships)
+	assert.Equal(t, 2, g.Stats().TotalEntities)
+}
@@ -0,0 +1,752 @@
+package kg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ---------------------------------------------------------------------------
+// Go source indexer
+// ---------------------------------------------------------------------------
+
+// GoNamespace is the namespace of every entity created by the Go indexer.
+const GoNamespace = "go"
+
+// Indexer populates a Graph from the Go source of a module. Canonical names
+// are import paths for packages, module-relative slash paths for files, and
+// "importpath.Name" / "importpath.Type.Method" for functions and types.
+//
+// Indexing is incremental: StatePath records the hash of every file seen and
+// the entities and relationships it contributed, so a later run re-parses
+// only new and changed files (plus files in, or importing, a package whose
+// declarations changed, so that calls into it are re-resolved).
+//
+// Calls are resolved syntactically: calls to functions of the same package,
+// to exported functions of other packages in the module through their
+// import name, and to methods on a method's own receiver. Test files and
+// vendor, testdata, hidden and underscore directories are skipped.
+type Indexer struct {
+	Graph     *Graph
+	StatePath string
+	// Project is the entity project; it defaults to the module path.
+	Project string
+}
+
+// IndexResult summarises one Index run.
+type IndexResult struct {
+	Module        string `json:"module"`
+	Files         int    `json:"files"`         // Go files found
+	Parsed        int    `json:"parsed"`        // files (re)parsed
+	Removed       int    `json:"removed"`       // files gone since the last run
+	Entities      int    `json:"entities"`      // entities defined by parsed files
+	Relationships int    `json:"relationships"` // relationships added
+	// Unparsable lists the files that failed to parse, with the parse
+	// error. They keep what they contributed before and are retried on
+	// the next run.
+	Unparsable []SkippedFile `json:"unparsable,omitempty"`
+}
+
+// SkippedFile is a file an index run left as it was.
+type SkippedFile struct {
+	File   string `json:"file"`   // module-relative slash path
+	Reason string `json:"reason"` // e.g. the parse error
+}
+
+// fileState is what the indexer remembers about one file.
+type fileState struct {
+	Hash     string   `json:"hash"`
+	Package  string   `json:"package"` // import path
+	Imports  []string `json:"imports,omitempty"`
+	Funcs    []string `json:"funcs,omitempty"` // "Name" or "Type.Method"
+	Types    []string `json:"types,omitempty"`
+	Entities []string `json:"entities,omitempty"` // IDs of entities the file defines
+	Rels     []string `json:"relationships,omitempty"`
+}
+
+type indexState struct {
+	Module string                `json:"module"`
+	Files  map[string]*fileState `json:"files"` // module-relative slash path -> state
+}
+
+// goFile is a parsed source file.
+type goFile struct {
+	rel     string
+	hash    string
+	pkg     string            // import path
+	imports map[string]string // import name -> import path
+	ast     *ast.File
+	fset    *token.FileSet
+	state   *fileState
+}
+
+// Index walks the Go files under root, which must be inside a module, and
+// brings the graph up to date with them. When root is a subdirectory, files
+// indexed elsewhere in the module are left as they are. The graph and the
+// state are saved on success.
+func (ix *Indexer) Index(root string) (*IndexResult, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("kg: index: %w", err)
+	}
+	modDir, module, err := FindModule(root)
+	if err != nil {
+		return nil, err
+	}
+	project := ix.Project
+	if project == "" {
+		project = module
+	}
+
+	state, err := ix.loadState()
+	if err != nil {
+		return nil, err
+	}
+	if state.Module != module {
+		// Another module was indexed with this state; start over.
+		state = &indexState{Module: module, Files: make(map[string]*fileState)}
+	}
+
+	sources, err := walkGoFiles(root, modDir)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := filepath.Rel(modDir, root)
+	if err != nil {
+		return nil, fmt.Errorf("kg: index: %w", err)
+	}
+	scope = filepath.ToSlash(scope)
+	result := &IndexResult{Module: module, Files: len(sources)}
+
+	// Work out which files need parsing.
+	hashes := make(map[string]string, len(sources))
+	contents := make(map[string][]byte, len(sources))
+	dirty := make(map[string]bool)
+	for rel, abs := range sources {
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("kg: index: %w", err)
+		}
+		sum := sha256.Sum256(data)
+		hashes[rel] = hex.EncodeToString(sum[:])
+		contents[rel] = data
+		if old, ok := state.Files[rel]; !ok || old.Hash != hashes[rel] {
+			dirty[rel] = true
+		}
+	}
+	// Only files under root can have been removed; the walk did not look
+	// anywhere else.
+	var removed []string
+	for rel := range state.Files {
+		if _, ok := sources[rel]; !ok && inScope(rel, scope) {
+			removed = append(removed, rel)
+		}
+	}
+	sort.Strings(removed)
+
+	// A file that does not parse keeps its previous state, if any, and is
+	// reported rather than failing the whole run.
+	parsed := make(map[string]*goFile)
+	parse := func(rel string) {
+		f, err := parseGoFile(module, rel, contents[rel])
+		if err != nil {
+			result.Unparsable = append(result.Unparsable, SkippedFile{File: rel, Reason: err.Error()})
+			return
+		}
+		f.hash = hashes[rel]
+		parsed[rel] = f
+	}
+	for rel := range dirty {
+		parse(rel)
+	}
+
+	// Packages whose declarations changed: their callers must be re-resolved.
+	changedPkgs := make(map[string]bool)
+	for rel, f := range parsed {
+		old, ok := state.Files[rel]
+		if !ok || old.Package != f.pkg || !equalStrings(old.Funcs, f.state.Funcs) || !equalStrings(old.Types, f.state.Types) {
+			changedPkgs[f.pkg] = true
+			if ok {
+				changedPkgs[old.Package] = true
+			}
+		}
+	}
+	for _, rel := range removed {
+		changedPkgs[state.Files[rel].Package] = true
+	}
+	for rel := range sources {
+		old := state.Files[rel]
+		if parsed[rel] != nil || dirty[rel] || old == nil {
+			continue
+		}
+		affected := changedPkgs[old.Package]
+		for _, imp := range old.Imports {
+			affected = affected || changedPkgs[imp]
+		}
+		if affected {
+			parse(rel)
+		}
+	}
+	sort.Slice(result.Unparsable, func(i, j int) bool { return result.Unparsable[i].File < result.Unparsable[j].File })
+
+	// Declarations of every package in the module, for call resolution:
+	// those of parsed files, and the indexed ones of every other file,
+	// including files outside root.
+	funcs := make(map[string]map[string]bool)
+	types := make(map[string]map[string]bool)
+	decls := make(map[string]*fileState, len(state.Files)+len(parsed))
+	for rel, fs := range state.Files {
+		decls[rel] = fs
+	}
+	for _, rel := range removed {
+		delete(decls, rel)
+	}
+	for rel, f := range parsed {
+		decls[rel] = f.state
+	}
+	for _, fs := range decls {
+		for _, name := range fs.Funcs {
+			addName(funcs, fs.Package, name)
+		}
+		for _, name := range fs.Types {
+			addName(types, fs.Package, name)
+		}
+	}
+
+	b := &graphBuilder{g: ix.Graph, project: project, cache: make(map[string]*Entity)}
+
+	// Drop what removed and re-parsed files contributed before.
+	for _, rel := range removed {
+		if err := b.drop(state.Files[rel], nil); err != nil {
+			return nil, err
+		}
+		delete(state.Files, rel)
+	}
+	result.Removed = len(removed)
+
+	rels := make([]string, 0, len(parsed))
+	for rel := range parsed {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	// Entities first, so that calls between re-parsed files resolve.
+	err = forEachFile(rels, func(rel string) error { return b.define(parsed[rel]) })
+	if err != nil {
+		return nil, err
+	}
+	// A declaration may have moved between re-parsed files, so keep every
+	// entity some re-parsed file still defines.
+	keep := make(map[string]bool)
+	for _, f := range parsed {
+		for _, id := range f.state.Entities {
+			keep[id] = true
+		}
+	}
+	for _, rel := range rels {
+		if old := state.Files[rel]; old != nil {
+			if err := b.drop(old, keep); err != nil {
+				return nil, err
+			}
+		}
+	}
+	err = forEachFile(rels, func(rel string) error { return b.relate(parsed[rel], funcs, types) })
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range rels {
+		f := parsed[rel]
+		state.Files[rel] = f.state
+		result.Entities += len(f.state.Entities)
+		result.Relationships += len(f.state.Rels)
+	}
+	result.Parsed = len(parsed)
+
+	if err := ix.Graph.Save(); err != nil {
+		return nil, err
+	}
+	if err := ix.saveState(state); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ---------------------------------------------------------------------------
+// Parsing
+// ---------------------------------------------------------------------------
+
+// FindModule returns the directory and module path of the go.mod governing dir.
+func FindModule(dir string) (string, string, error) {
+	for d := dir; ; {
+		data, err := os.ReadFile(filepath.Join(d, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok {
+					mod := strings.Trim(strings.TrimSpace(rest), `"`)
+					if mod != "" {
+						return d, mod, nil
+					}
+				}
+			}
+			return "", "", fmt.Errorf("kg: index: no module path in %s", filepath.Join(d, "go.mod"))
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return "", "", fmt.Errorf("kg: index: %s is not inside a Go module", dir)
+		}
+		d = parent
+	}
+}
+
+// walkGoFiles returns the non-test Go files under root keyed by their
+// slash path relative to modDir.
+func walkGoFiles(root, modDir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != root && (name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		rel, err := filepath.Rel(modDir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = p
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kg: index: walk %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// inScope reports whether the module-relative file rel is under scope, the
+// module-relative directory an index run walked.
+func inScope(rel, scope string) bool {
+	return scope == "." || rel == scope || strings.HasPrefix(rel, scope+"/")
+}
+
+// parseGoFile parses one file and records its package, imports and
+// declarations.
+func parseGoFile(module, rel string, src []byte) (*goFile, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, rel, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err // the parser's errors name the file and position
+	}
+	pkg := module
+	if dir := path.Dir(rel); dir != "." {
+		pkg = module + "/" + dir
+	}
+	f := &goFile{
+		rel:     rel,
+		pkg:     pkg,
+		imports: make(map[string]string),
+		ast:     file,
+		fset:    fset,
+		state:   &fileState{Package: pkg},
+	}
+	for _, spec := range file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(p)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		f.imports[name] = p
+		f.state.Imports = append(f.state.Imports, p)
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if name := funcName(d); name != "" {
+				f.state.Funcs = append(f.state.Funcs, name)
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name != "_" {
+					f.state.Types = append(f.state.Types, ts.Name.Name)
+				}
+			}
+		}
+	}
+	sort.Strings(f.state.Funcs)
+	sort.Strings(f.state.Types)
+	return f, nil
+}
+
+// funcName is "Name" for functions and "Type.Method" for methods. init and
+// blank functions, which may be declared many times, have no name.
+func funcName(d *ast.FuncDecl) string {
+	name := d.Name.Name
+	if name == "_" || (name == "init" && d.Recv == nil) {
+		return ""
+	}
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return name
+	}
+	if recv := receiverType(d.Recv.List[0].Type); recv != "" {
+		return recv + "." + name
+	}
+	return ""
+}
+
+// receiverType is the base type name of a method receiver expression.
+func receiverType(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr: // generic receiver T[P]
+			expr = e.X
+		case *ast.IndexListExpr: // generic receiver T[P, Q]
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Graph updates
+// ---------------------------------------------------------------------------
+
+// indexWorkers is how many files are written to the graph at once. A kg.db
+// graph commits each file's batch on a writer queue tick, so concurrent
+// files share ticks.
+const indexWorkers = 8
+
+// forEachFile calls fn for every file on up to indexWorkers goroutines and
+// returns the first error.
+func forEachFile(rels []string, fn func(string) error) error {
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	work := make(chan string)
+	for i := 0; i < indexWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rel := range work {
+				if err := fn(rel); err != nil {
+					once.Do(func() { first = err })
+				}
+			}
+		}()
+	}
+	for _, rel := range rels {
+		work <- rel
+	}
+	close(work)
+	wg.Wait()
+	return first
+}
+
+type graphBuilder struct {
+	g       *Graph
+	project string
+	mu      sync.Mutex
+	cache   map[string]*Entity // canonical name -> entity
+}
+
+// ref names an entity the builder needs.
+type ref struct {
+	etype EntityType
+	name  string
+}
+
+// edge is a relationship to add once its endpoints are resolved.
+type edge struct {
+	from, to ref
+	rel      RelType
+	pos      token.Pos
+}
+
+// resolve returns the entities refs name, creating the missing ones in a
+// single batch.
+func (b *graphBuilder) resolve(refs []ref) ([]*Entity, error) {
+	var specs []Entity
+	b.mu.Lock()
+	for _, r := range refs {
+		if _, ok := b.cache[r.name]; !ok {
+			specs = append(specs, Entity{Type: r.etype, CanonicalName: r.name, Project: b.project, Namespace: GoNamespace})
+		}
+	}
+	b.mu.Unlock()
+	if len(specs) > 0 {
+		ents, err := b.g.AddEntities(specs)
+		if err != nil {
+			return nil, err
+		}
+		b.mu.Lock()
+		for _, e := range ents {
+			b.cache[e.CanonicalName] = e
+		}
+		b.mu.Unlock()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]*Entity, len(refs))
+	for i, r := range refs {
+		out[i] = b.cache[r.name]
+	}
+	return out, nil
+}
+
+// drop removes the relationships fs recorded and the entities it defined
+// that are not in keep.
+func (b *graphBuilder) drop(fs *fileState, keep map[string]bool) error {
+	if err := b.g.RemoveRelationship(fs.Rels...); err != nil {
+		return err
+	}
+	for _, id := range fs.Entities {
+		if !keep[id] {
+			_ = b.g.RemoveEntity(id) // may already be gone
+		}
+	}
+	return nil
+}
+
+// define creates the package, file, function and type entities of f.
+func (b *graphBuilder) define(f *goFile) error {
+	f.state.Hash = f.hash
+	f.state.Entities = nil
+	f.state.Rels = nil
+	refs := []ref{{EntityPackage, f.pkg}, {EntityFile, f.rel}}
+	for _, name := range f.state.Types {
+		refs = append(refs, ref{EntityClass, f.pkg + "." + name})
+	}
+	for _, name := range f.state.Funcs {
+		refs = append(refs, ref{EntityFunction, f.pkg + "." + name})
+	}
+	ents, err := b.resolve(refs)
+	if err != nil {
+		return err
+	}
+	for _, e := range ents[1:] { // the package is shared with other files
+		f.state.Entities = append(f.state.Entities, e.ID)
+	}
+	return nil
+}
+
+// relate adds the contains, imports and calls relationships of f.
+func (b *graphBuilder) relate(f *goFile, funcs, types map[string]map[string]bool) error {
+	pkg := ref{EntityPackage, f.pkg}
+	file := ref{EntityFile, f.rel}
+	edges := []edge{{pkg, file, RelContains, f.ast.Package}}
+
+	for _, spec := range f.ast.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		edges = append(edges, edge{file, ref{EntityPackage, p}, RelImports, spec.Pos()})
+	}
+
+	for _, decl := range f.ast.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name != "_" {
+					edges = append(edges, edge{file, ref{EntityClass, f.pkg + "." + ts.Name.Name}, RelContains, ts.Pos()})
+				}
+			}
+		case *ast.FuncDecl:
+			name := funcName(d)
+			if name == "" {
+				continue
+			}
+			fn := ref{EntityFunction, f.pkg + "." + name}
+			edges = append(edges, edge{file, fn, RelContains, d.Pos()})
+			if recv, _, ok := strings.Cut(name, "."); ok && types[f.pkg][recv] {
+				edges = append(edges, edge{ref{EntityClass, f.pkg + "." + recv}, fn, RelContains, d.Pos()})
+			}
+			for _, c := range calls(f, d, funcs) {
+				edges = append(edges, edge{fn, ref{EntityFunction, c.target}, RelCalls, c.pos})
+			}
+		}
+	}
+
+	refs := make([]ref, 0, 2*len(edges))
+	for _, e := range edges {
+		refs = append(refs, e.from, e.to)
+	}
+	ents, err := b.resolve(refs)
+	if err != nil {
+		return err
+	}
+	specs := make([]Relationship, len(edges))
+	for i, e := range edges {
+		specs[i] = Relationship{
+			FromID:   ents[2*i].ID,
+			ToID:     ents[2*i+1].ID,
+			RelType:  e.rel,
+			Evidence: evidence(f.rel, f.fset.Position(e.pos).Line, f.hash),
+		}
+	}
+	rels, err := b.g.AddRelationships(specs)
+	if err != nil {
+		return err
+	}
+	for _, r := range rels {
+		f.state.Rels = append(f.state.Rels, r.ID)
+	}
+	return nil
+}
+
+type call struct {
+	target string // canonical name of the callee
+	pos    token.Pos
+}
+
+// calls returns the module functions d calls, each once, at its first call
+// site.
+func calls(f *goFile, d *ast.FuncDecl, funcs map[string]map[string]bool) []call {
+	if d.Body == nil {
+		return nil
+	}
+	recvName, recvType := "", ""
+	if d.Recv != nil && len(d.Recv.List) > 0 && len(d.Recv.List[0].Names) > 0 {
+		recvName = d.Recv.List[0].Names[0].Name
+		recvType = receiverType(d.Recv.List[0].Type)
+	}
+
+	var out []call
+	seen := make(map[string]bool)
+	ast.Inspect(d.Body, func(n ast.Node) bool {
+		ce, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		pkg, name := "", ""
+		switch fn := ce.Fun.(type) {
+		case *ast.Ident:
+			pkg, name = f.pkg, fn.Name
+		case *ast.SelectorExpr:
+			x, ok := fn.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if p, ok := f.imports[x.Name]; ok {
+				pkg, name = p, fn.Sel.Name
+			} else if recvName != "" && x.Name == recvName {
+				pkg, name = f.pkg, recvType+"."+fn.Sel.Name
+			}
+		}
+		if name == "" || !funcs[pkg][name] {
+			return true
+		}
+		target := pkg + "." + name
+		if !seen[target] {
+			seen[target] = true
+			out = append(out, call{target: target, pos: ce.Pos()})
+		}
+		return true
+	})
+	return out
+}
+
+// evidence cites a source line in a specific version of a file, e.g.
+// "internal/kg/graph.go:42@1a2b3c4d5e6f".
+func evidence(rel string, line int, hash string) string {
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	return Evidence{Kind: EvidenceSource, File: rel, Line: line, Hash: hash}.String()
+}
+
+// ---------------------------------------------------------------------------
+// State persistence
+// ---------------------------------------------------------------------------
+
+func (ix *Indexer) loadState() (*indexState, error) {
+	state := &indexState{Files: make(map[string]*fileState)}
+	if ix.StatePath == "" {
+		return state, nil
+	}
+	data, err := os.ReadFile(ix.StatePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kg: read index state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("kg: parse index state: %w", err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]*fileState)
+	}
+	return state, nil
+}
+
+func (ix *Indexer) saveState(state *indexState) error {
+	if ix.StatePath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(ix.StatePath), 0o755); err != nil {
+		return fmt.Errorf("kg: mkdir: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("kg: marshal index state: %w", err)
+	}
+	if err := os.WriteFile(ix.StatePath, data, 0o644); err != nil {
+		return fmt.Errorf("kg: write index state: %w", err)
+	}
+	return nil
+}
+
+func addName(m map[string]map[string]bool, pkg, name string) {
+	if m[pkg] == nil {
+		m[pkg] = make(map[string]bool)
+	}
+	m[pkg][name] = true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,265 @@
+package kg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeModule writes files (slash paths relative to the module root) into
+// a temporary module named example.com/demo.
+func writeModule(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	files["go.mod"] = "module example.com/demo\n\ngo 1.25\n"
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+}
+
+const utilSrc = `package util
+
+// Join joins words.
+func Join(words []string) string { return trim(words[0]) }
+
+func trim(s string) string { return s }
+`
+
+const mainSrc = `package main
+
+import (
+	"fmt"
+
+	"example.com/demo/util"
+)
+
+type Server struct{}
+
+func (s *Server) Run() { s.log(util.Join(nil)) }
+
+func (s *Server) log(msg string) { fmt.Println(msg) }
+
+func main() { start() }
+
+func start() {}
+`
+
+// related returns the canonical names reachable from name by one rel edge.
+func related(t *testing.T, g *Graph, name string, rel RelType) []string {
+	t.Helper()
+	from := entityNamed(t, g, name)
+	var out []string
+	for _, r := range g.rels {
+		if r.FromID == from.ID && r.RelType == rel {
+			out = append(out, g.ents[r.ToID].CanonicalName)
+		}
+	}
+	return out
+}
+
+func entityNamed(t *testing.T, g *Graph, name string) *Entity {
+	t.Helper()
+	for _, e := range g.ents {
+		if e.CanonicalName == name {
+			return e
+		}
+	}
+	t.Fatalf("no entity %q", name)
+	return nil
+}
+
+func hasEntity(g *Graph, name string) bool {
+	for _, e := range g.ents {
+		if e.CanonicalName == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIndexGoModule(t *testing.T) {
+	src := t.TempDir()
+	writeModule(t, src, map[string]string{
+		"main.go":           mainSrc,
+		"util/util.go":      utilSrc,
+		"util/util_test.go": "package util\n\nfunc helper() {}\n",
+		"vendor/x/x.go":     "package x\n",
+	})
+	g := testGraph(t)
+	ix := &Indexer{Graph: g, StatePath: filepath.Join(t.TempDir(), "state.json")}
+
+	result, err := ix.Index(src)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/demo", result.Module)
+	assert.Equal(t, 2, result.Files)
+	assert.Equal(t, 2, result.Parsed)
+
+	assert.Equal(t, EntityPackage, entityNamed(t, g, "example.com/demo/util").Type)
+	assert.Equal(t, EntityFile, entityNamed(t, g, "util/util.go").Type)
+	assert.Equal(t, EntityClass, entityNamed(t, g, "example.com/demo.Server").Type)
+	fn := entityNamed(t, g, "example.com/demo.Server.Run")
+	assert.Equal(t, EntityFunction, fn.Type)
+	assert.Equal(t, "example.com/demo", fn.Project)
+	assert.Equal(t, GoNamespace, fn.Namespace)
+	assert.False(t, hasEntity(g, "example.com/demo/util.helper"), "test files are skipped")
+
+	assert.ElementsMatch(t, []string{"main.go"}, related(t, g, "example.com/demo", RelContains))
+	assert.ElementsMatch(t, []string{"example.com/demo.Server.Run", "example.com/demo.Server.log"},
+		related(t, g, "example.com/demo.Server", RelContains))
+	assert.ElementsMatch(t, []string{"fmt", "example.com/demo/util"}, related(t, g, "main.go", RelImports))
+	assert.ElementsMatch(t, []string{"example.com/demo.Server.log", "example.com/demo/util.Join"},
+		related(t, g, "example.com/demo.Server.Run", RelCalls))
+	assert.ElementsMatch(t, []string{"example.com/demo.start"}, related(t, g, "example.com/demo.main", RelCalls))
+	assert.ElementsMatch(t, []string{"example.com/demo/util.trim"}, related(t, g, "example.com/demo/util.Join", RelCalls))
+
+	for _, r := range g.rels {
+		if r.FromID == fn.ID && r.RelType == RelCalls && g.ents[r.ToID].CanonicalName == "example.com/demo/util.Join" {
+			assert.Regexp(t, `^main\.go:11@[0-9a-f]{12}$`, r.Evidence)
+		}
+	}
+
+	// The graph was saved.
+	reloaded, err := New(g.dir)
+	require.NoError(t, err)
+	assert.Equal(t, g.Stats(), reloaded.Stats())
+}
+
+func TestIndexIsIncremental(t *testing.T) {
+	src := t.TempDir()
+	writeModule(t, src, map[string]string{
+		"main.go":      mainSrc,
+		"util/util.go": utilSrc,
+		"other/o.go":   "package other\n\nfunc Other() {}\n",
+	})
+	g := testGraph(t)
+	ix := &Indexer{Graph: g, StatePath: filepath.Join(t.TempDir(), "state.json")}
+	_, err := ix.Index(src)
+	require.NoError(t, err)
+	before := g.Stats()
+
+	result, err := ix.Index(src)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Parsed, "nothing changed")
+	assert.Equal(t, before, g.Stats())
+
+	// A body-only change re-parses just that file.
+	require.NoError(t, os.WriteFile(filepath.Join(src, "other", "o.go"),
+		[]byte("package other\n\n// Other does nothing.\nfunc Other() {}\n"), 0o644))
+	result, err = ix.Index(src)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Parsed)
+	assert.Equal(t, before, g.Stats())
+
+	// Renaming Join re-parses util and its importer, and re-resolves calls.
+	renamed := strings.ReplaceAll(utilSrc, "Join", "Concat")
+	require.NoError(t, os.WriteFile(filepath.Join(src, "util", "util.go"), []byte(renamed), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main.go"),
+		[]byte(strings.ReplaceAll(mainSrc, "util.Join", "util.Concat")), 0o644))
+	result, err = ix.Index(src)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Parsed)
+	assert.False(t, hasEntity(g, "example.com/demo/util.Join"))
+	assert.ElementsMatch(t, []string{"example.com/demo.Server.log", "example.com/demo/util.Concat"},
+		related(t, g, "example.com/demo.Server.Run", RelCalls))
+
+	// Deleting a file removes what it defined.
+	require.NoError(t, os.Remove(filepath.Join(src, "other", "o.go")))
+	result, err = ix.Index(src)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Removed)
+	assert.False(t, hasEntity(g, "other/o.go"))
+	assert.False(t, hasEntity(g, "example.com/demo/other.Other"))
+}
+
+func TestIndexDeclarationMovesBetweenFiles(t *testing.T) {
+	src := t.TempDir()
+	writeModule(t, src, map[string]string{
+		"a.go": "package demo\n\ntype T struct{}\n",
+		"b.go": "package demo\n\nfunc F() {}\n",
+	})
+	g := testGraph(t)
+	ix := &Indexer{Graph: g, StatePath: filepath.Join(t.TempDir(), "state.json")}
+	_, err := ix.Index(src)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.go"), []byte("package demo\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "b.go"), []byte("package demo\n\ntype T struct{}\n\nfunc F() {}\n"), 0o644))
+	_, err = ix.Index(src)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"example.com/demo.T", "example.com/demo.F"}, related(t, g, "b.go", RelContains))
+}
+
+func TestIndexSubdirKeepsRestOfModule(t *testing.T) {
+	src := t.TempDir()
+	writeModule(t, src, map[string]string{
+		"main.go":      mainSrc,
+		"util/util.go": utilSrc,
+	})
+	g := testGraph(t)
+	ix := &Indexer{Graph: g, StatePath: filepath.Join(t.TempDir(), "state.json")}
+	_, err := ix.Index(src)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(src, "util", "util.go"),
+		[]byte(utilSrc+"\nfunc Split(s string) []string { return nil }\n"), 0o644))
+	result, err := ix.Index(filepath.Join(src, "util"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Files)
+	assert.Equal(t, 0, result.Removed, "files outside the subtree are not removed")
+	assert.True(t, hasEntity(g, "main.go"))
+	assert.True(t, hasEntity(g, "example.com/demo/util.Split"))
+	assert.ElementsMatch(t, []string{"example.com/demo.Server.log", "example.com/demo/util.Join"},
+		related(t, g, "example.com/demo.Server.Run", RelCalls))
+
+	// Indexing the whole module afterwards finds nothing removed either.
+	result, err = ix.Index(src)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Removed)
+	assert.Equal(t, 0, result.Parsed)
+}
+
+func TestIndexSkipsUnparsableFiles(t *testing.T) {
+	src := t.TempDir()
+	writeModule(t, src, map[string]string{
+		"main.go":      mainSrc,
+		"util/util.go": utilSrc,
+	})
+	g := testGraph(t)
+	ix := &Indexer{Graph: g, StatePath: filepath.Join(t.TempDir(), "state.json")}
+	_, err := ix.Index(src)
+	require.NoError(t, err)
+	before := g.Stats()
+
+	// A syntax error in one file, and a new file that never parsed.
+	require.NoError(t, os.WriteFile(filepath.Join(src, "util", "util.go"), []byte("package util\n\nfunc Join(\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "broken.go"), []byte("package main\n\nfunc {\n"), 0o644))
+	result, err := ix.Index(src)
+	require.NoError(t, err)
+	require.Len(t, result.Unparsable, 2)
+	assert.Equal(t, "broken.go", result.Unparsable[0].File)
+	assert.Equal(t, "util/util.go", result.Unparsable[1].File)
+	assert.Contains(t, result.Unparsable[1].Reason, "util/util.go:")
+	assert.Equal(t, before, g.Stats(), "unparsable files keep what they contributed")
+	assert.True(t, hasEntity(g, "example.com/demo/util.Join"))
+
+	// Once fixed, the file is indexed again.
+	require.NoError(t, os.WriteFile(filepath.Join(src, "util", "util.go"),
+		[]byte(strings.ReplaceAll(utilSrc, "trim", "clean")), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(src, "broken.go")))
+	result, err = ix.Index(src)
+	require.NoError(t, err)
+	assert.Empty(t, result.Unparsable)
+	assert.True(t, hasEntity(g, "example.com/demo/util.clean"))
+	assert.False(t, hasEntity(g, "example.com/demo/util.trim"))
+}
+
+func TestIndexOutsideModule(t *testing.T) {
+	g := testGraph(t)
+	_, err := (&Indexer{Graph: g}).Index(t.TempDir())
+	assert.Error(t, err)
+}
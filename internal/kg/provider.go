@@ -0,0 +1,298 @@
+package kg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+)
+
+// ContextPriority places knowledge graph facts below memory and files but
+// above the repository map.
+const ContextPriority = 40
+
+// Provider injects the neighbourhood of every entity a task mentions into
+// prompts built by context.Builder, as one line of facts per entity.
+type Provider struct {
+	open     func() (*Graph, func(), error)
+	dbPath   string // "" for an in-memory graph
+	depth    int
+	maxNodes int
+	gate     apexctx.Gate
+}
+
+// NewProvider creates a Provider over g. depth and maxNodes bound the
+// neighbourhoods like QueryRelated; zero selects the defaults. maxNodes
+// applies to the whole block: entities beyond it are listed by ID only.
+func NewProvider(g *Graph, depth, maxNodes int) *Provider {
+	return newProvider(func() (*Graph, func(), error) { return g, func() {}, nil }, depth, maxNodes)
+}
+
+// NewDBProvider creates a Provider that opens the kg.db at path for every
+// prompt, so nodes see what the indexer wrote since the run started. A
+// missing database contributes nothing.
+func NewDBProvider(path string, depth, maxNodes int) *Provider {
+	p := newProvider(func() (*Graph, func(), error) {
+		if _, err := os.Stat(path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, nil, nil
+			}
+			return nil, nil, err
+		}
+		db, err := OpenDB(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return NewDBGraph(db), func() { db.Close() }, nil
+	}, depth, maxNodes)
+	p.dbPath = path
+	return p
+}
+
+func newProvider(open func() (*Graph, func(), error), depth, maxNodes int) *Provider {
+	if depth <= 0 {
+		depth = DefaultRelatedDepth
+	}
+	if maxNodes <= 0 {
+		maxNodes = DefaultMaxNodes
+	}
+	return &Provider{open: open, depth: depth, maxNodes: maxNodes}
+}
+
+// SetGate makes the provider vet the database with g before opening it and
+// redact the facts it renders. A database g refuses contributes nothing and
+// is reported in the error Provide returns.
+func (p *Provider) SetGate(g apexctx.Gate) {
+	p.gate = g
+}
+
+// Name implements context.Provider.
+func (p *Provider) Name() string { return "kg" }
+
+// Provide implements context.Provider. It returns nothing when the task
+// mentions no known entity.
+func (p *Provider) Provide(ctx context.Context, task string) ([]apexctx.ContentBlock, error) {
+	if p.gate != nil && p.dbPath != "" {
+		if err := p.gate.Check(p.dbPath); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+	g, closeGraph, err := p.open()
+	if err != nil || g == nil {
+		return nil, err
+	}
+	defer closeGraph()
+
+	centers := Mentioned(g, task)
+	if len(centers) == 0 {
+		return nil, nil
+	}
+	text := p.render(g, centers)
+	if p.gate != nil {
+		text = string(p.gate.Redact([]byte(text)))
+	}
+	return []apexctx.ContentBlock{{
+		ID:       "kg",
+		Source:   "kg",
+		Path:     "kg",
+		Title:    "Knowledge Graph",
+		Text:     text,
+		Policy:   apexctx.PolicyStructural,
+		Priority: ContextPriority,
+	}}, nil
+}
+
+// mentionPattern matches identifier-like words and `quoted` spans.
+var mentionPattern = regexp.MustCompile("`[^`]+`|[A-Za-z_][A-Za-z0-9_./-]*")
+
+// mentions returns the words of task that may name an entity: quoted
+// spans, and words with an upper-case letter, '.', '/' or '_' in them.
+// Plain lower-case prose is skipped.
+func mentions(task string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, m := range mentionPattern.FindAllString(task, -1) {
+		quoted := strings.HasPrefix(m, "`")
+		m = strings.TrimSuffix(strings.TrimRight(strings.Trim(m, "`"), ".,/-"), "()")
+		if len(m) < 3 || seen[m] {
+			continue
+		}
+		if !quoted && !strings.ContainsAny(m, "ABCDEFGHIJKLMNOPQRSTUVWXYZ._/") {
+			continue
+		}
+		seen[m] = true
+		out = append(out, m)
+	}
+	return out
+}
+
+// Mentioned returns the entities task refers to by name, ordered by
+// canonical name. A mention matches like a query's name property, or as
+// a trailing path ("internal/kg", "kg/graph.go").
+func Mentioned(g *Graph, task string) []*Entity {
+	seen := make(map[string]bool)
+	var out []*Entity
+	for _, m := range mentions(task) {
+		for _, e := range g.QueryByName(m) {
+			if seen[e.ID] || !(nameMatches(e, m) || strings.HasSuffix(e.CanonicalName, "/"+m)) {
+				continue
+			}
+			seen[e.ID] = true
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CanonicalName < out[j].CanonicalName })
+	return out
+}
+
+// relPhrases says how each relationship type reads from its source and
+// from its target, in the order facts are listed.
+var relPhrases = []struct {
+	rel     RelType
+	out, in string
+}{
+	{RelContains, "contains", "in"},
+	{RelCalls, "calls", "called by"},
+	{RelImports, "imports", "imported by"},
+	{RelDependsOn, "depends on", "needed by"},
+	{RelConfigures, "configures", "configured by"},
+	{RelProduces, "produces", "produced by"},
+	{RelConsumes, "consumes", "consumed by"},
+	{RelRelatesTo, "relates to", "related to"},
+}
+
+// typeLabel is how an entity type is written in facts.
+func typeLabel(t EntityType) string {
+	switch t {
+	case EntityConfigKey:
+		return "key"
+	case EntityAPIEndpoint:
+		return "endpoint"
+	}
+	return string(t)
+}
+
+// render lists facts about centers and their neighbourhoods. The first
+// maxNodes entities, centers first, are described; the others are listed
+// by ID.
+func (p *Provider) render(g *Graph, centers []*Entity) string {
+	var detailed []*Entity
+	inDetail := make(map[string]bool)
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(e *Entity) {
+		if seen[e.ID] {
+			return
+		}
+		seen[e.ID] = true
+		if len(detailed) < p.maxNodes {
+			detailed = append(detailed, e)
+			inDetail[e.ID] = true
+		} else if len(refs) < p.maxNodes {
+			refs = append(refs, e.ID)
+		}
+	}
+
+	for _, c := range centers {
+		add(c)
+	}
+	var rels []*Relationship
+	relSeen := make(map[string]bool)
+	for _, c := range centers {
+		// Look past maxNodes so the entities that do not fit are named.
+		related, rs := g.QueryRelated(c.ID, p.depth, 2*p.maxNodes)
+		for _, e := range related {
+			add(e)
+		}
+		for _, r := range rs {
+			if !relSeen[r.ID] {
+				relSeen[r.ID] = true
+				rels = append(rels, r)
+			}
+		}
+	}
+
+	byID := make(map[string]*Entity, len(detailed))
+	for _, e := range detailed {
+		byID[e.ID] = e
+	}
+	var b strings.Builder
+	rendered := make(map[string]bool)
+	for _, e := range detailed {
+		var mine []*Relationship
+		for _, r := range rels {
+			if !rendered[r.ID] && (r.FromID == e.ID || r.ToID == e.ID) && inDetail[r.FromID] && inDetail[r.ToID] {
+				rendered[r.ID] = true
+				mine = append(mine, r)
+			}
+		}
+		if len(mine) == 0 && !isCenter(centers, e) {
+			continue
+		}
+		b.WriteString("- ")
+		b.WriteString(fact(e, mine, byID))
+		b.WriteString("\n")
+	}
+	if len(refs) > 0 {
+		fmt.Fprintf(&b, "\nMore related entities (IDs only): %s\n", strings.Join(refs, ", "))
+	}
+	return b.String()
+}
+
+func isCenter(centers []*Entity, e *Entity) bool {
+	for _, c := range centers {
+		if c.ID == e.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// fact renders e and its relationships as one sentence, e.g. "function X
+// in file Y calls Z; configured by key K."
+func fact(e *Entity, rels []*Relationship, byID map[string]*Entity) string {
+	name := func(o *Entity) string {
+		if o.Type == e.Type {
+			return o.CanonicalName
+		}
+		return typeLabel(o.Type) + " " + o.CanonicalName
+	}
+	var clauses []string
+	var in string
+	for _, ph := range relPhrases {
+		var outs, ins []string
+		for _, r := range rels {
+			if r.RelType != ph.rel {
+				continue
+			}
+			if r.FromID == e.ID {
+				outs = append(outs, name(byID[r.ToID]))
+			} else {
+				ins = append(ins, name(byID[r.FromID]))
+			}
+		}
+		if len(ins) > 0 && ph.rel == RelContains {
+			in = " in " + strings.Join(ins, ", ")
+			ins = nil
+		}
+		if len(outs) > 0 {
+			clauses = append(clauses, ph.out+" "+strings.Join(outs, ", "))
+		}
+		if len(ins) > 0 {
+			clauses = append(clauses, ph.in+" "+strings.Join(ins, ", "))
+		}
+	}
+	s := typeLabel(e.Type) + " " + e.CanonicalName + in
+	if len(clauses) > 0 {
+		s += " " + strings.Join(clauses, "; ")
+	}
+	return s + "."
+}
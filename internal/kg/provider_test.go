@@ -0,0 +1,73 @@
+package kg
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+	"github.com/lyndonlyu/apex/internal/readgate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMentions(t *testing.T) {
+	got := mentions("Fix `read` in Graph.Save() and internal/kg, then check graph.go. Also the main loop.")
+	assert.Equal(t, []string{"Fix", "read", "Graph.Save", "internal/kg", "graph.go", "Also"}, got)
+}
+
+func TestProviderProvide(t *testing.T) {
+	g := queryGraph(t)
+	key, err := g.AddEntity(EntityConfigKey, "kg.depth", "apex", GoNamespace)
+	require.NoError(t, err)
+	read := g.QueryByName("a.read")[0]
+	_, err = g.AddRelationship(key.ID, read.ID, RelConfigures, "")
+	require.NoError(t, err)
+
+	blocks, err := NewProvider(g, 0, 0).Provide(context.Background(), "Why does `read` fail?")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, apexctx.PolicyStructural, blocks[0].Policy)
+	assert.Equal(t, ContextPriority, blocks[0].Priority)
+	assert.Equal(t, "- function example.com/a.read calls example.com/a.Open; called by example.com/a.main; configured by key kg.depth.\n"+
+		"- function example.com/a.Open called by example.com/b.Use.\n", blocks[0].Text)
+
+	blocks, err = NewProvider(g, 0, 0).Provide(context.Background(), "refactor the parser")
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+}
+
+func TestProviderMaxNodes(t *testing.T) {
+	g := queryGraph(t)
+	blocks, err := NewProvider(g, 2, 2).Provide(context.Background(), "Why does `read` fail?")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	text := blocks[0].Text
+	// read and one neighbour are described, the other two by ID only.
+	assert.Equal(t, 1, strings.Count(text, "- function example.com/a.read "), text)
+	assert.Regexp(t, `\n\nMore related entities \(IDs only\): [0-9a-f-]{36}, [0-9a-f-]{36}\n$`, text)
+	assert.Contains(t, text, g.QueryByName("b.Use")[0].ID, "two hops away, beyond the limit")
+}
+
+func TestDBProviderMissingGraph(t *testing.T) {
+	p := NewDBProvider(filepath.Join(t.TempDir(), "kg.db"), 0, 0)
+	blocks, err := p.Provide(context.Background(), "Graph.Save")
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+}
+
+func TestDBProviderGate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kg.db")
+	db, err := OpenDB(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	gate, err := readgate.New([]string{t.TempDir()}, nil)
+	require.NoError(t, err)
+	p := NewDBProvider(path, 0, 0)
+	p.SetGate(gate)
+	blocks, err := p.Provide(context.Background(), "Graph.Save")
+	assert.True(t, readgate.IsDenied(err), "got %v", err)
+	assert.Empty(t, blocks)
+}
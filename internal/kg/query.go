@@ -0,0 +1,980 @@
+package kg
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ---------------------------------------------------------------------------
+// Query language
+// ---------------------------------------------------------------------------
+//
+// A query matches a chain of node and relationship patterns, in a small
+// subset of Cypher:
+//
+//	MATCH (f:function)-[:calls*1..3]->(g) WHERE g.name = "Open" RETURN f
+//	MATCH (f:file)-[:imports]->(p:package {name: "fmt"})
+//	MATCH shortestPath((a {name: "main"})-[*..8]-(b {name: "Open"}))
+//
+// Node patterns are (var:type {prop: "value", ...}), every part optional.
+// Relationships are -[:type|type*min..max]-> (outgoing), <-[...]- (incoming)
+// or -[...]- (either way); -->, <-- and -- match one hop of any type.
+// Properties are name, type, project, namespace and id. WHERE joins
+// conditions var.prop = "v", != "v" or CONTAINS "v" with AND. A name
+// equals either the canonical name or its short form: the last path
+// element of a package or file ("kg", "graph.go") or the declaration of a
+// function or type ("Graph.Save" or "Save").
+//
+// Variable-length relationships match every entity reachable within the
+// bounds; each result row keeps one of the paths that reached it.
+
+// Default and maximum hop counts for variable-length relationships.
+const (
+	DefaultMaxHops = 5
+	MaxHops        = 15
+	// DefaultQueryLimit caps the rows of a query without LIMIT.
+	DefaultQueryLimit = 100
+)
+
+// Direction is the direction a relationship pattern is traversed in.
+type Direction int
+
+const (
+	DirOut  Direction = iota // (a)-[]->(b)
+	DirIn                    // (a)<-[]-(b)
+	DirBoth                  // (a)-[]-(b)
+)
+
+// NodePattern matches entities.
+type NodePattern struct {
+	Var   string
+	Type  EntityType
+	Props map[string]string
+}
+
+// RelPattern matches a path of MinHops to MaxHops relationships. A
+// negative MaxHops has no upper bound: DefaultMaxHops applies in a MATCH
+// and MaxHops in a shortestPath.
+type RelPattern struct {
+	Types   []RelType
+	Dir     Direction
+	MinHops int
+	MaxHops int
+}
+
+// Condition is one WHERE condition.
+type Condition struct {
+	Var   string
+	Prop  string
+	Op    string // "=", "!=" or "CONTAINS"
+	Value string
+}
+
+// Query is a parsed query: Nodes[i] and Nodes[i+1] are joined by Rels[i].
+type Query struct {
+	Nodes    []NodePattern
+	Rels     []RelPattern
+	Where    []Condition
+	Return   []string
+	Limit    int
+	Shortest bool // shortestPath: one relationship pattern, one path per start
+}
+
+// QueryOptions apply to every node of a query.
+type QueryOptions struct {
+	Project   string
+	Namespace string
+	Limit     int // overrides DefaultQueryLimit when the query has no LIMIT
+}
+
+// Path is a chain of entities joined by relationships.
+type Path struct {
+	Entities      []*Entity       `json:"entities"`
+	Relationships []*Relationship `json:"relationships"`
+}
+
+// QueryResult holds the rows of a MATCH, or the paths of a shortestPath
+// query, plus every relationship the matches traversed.
+type QueryResult struct {
+	Columns       []string        `json:"columns,omitempty"`
+	Rows          [][]*Entity     `json:"rows,omitempty"`
+	Paths         []Path          `json:"paths,omitempty"`
+	Relationships []*Relationship `json:"relationships"`
+}
+
+// Entities returns every distinct entity in the result.
+func (r *QueryResult) Entities() []*Entity {
+	seen := make(map[string]bool)
+	var out []*Entity
+	add := func(e *Entity) {
+		if e != nil && !seen[e.ID] {
+			seen[e.ID] = true
+			out = append(out, e)
+		}
+	}
+	for _, row := range r.Rows {
+		for _, e := range row {
+			add(e)
+		}
+	}
+	for _, p := range r.Paths {
+		for _, e := range p.Entities {
+			add(e)
+		}
+	}
+	return out
+}
+
+// IsQuery reports whether s is written in the query language rather than
+// being a plain name to look up.
+func IsQuery(s string) bool {
+	fields := strings.Fields(s)
+	return len(fields) > 0 && strings.EqualFold(fields[0], "MATCH")
+}
+
+// ---------------------------------------------------------------------------
+// Lexer
+// ---------------------------------------------------------------------------
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokPunct
+)
+
+type qtoken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(s string) ([]qtoken, error) {
+	var toks []qtoken
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(s) && rune(s[j]) != c; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("kg: query: unterminated string at %d", i)
+			}
+			toks = append(toks, qtoken{tokString, b.String(), i})
+			i = j + 1
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(s) && unicode.IsDigit(rune(s[j])) {
+				j++
+			}
+			toks = append(toks, qtoken{tokNumber, s[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			toks = append(toks, qtoken{tokIdent, s[i:j], i})
+			i = j
+		case strings.HasPrefix(s[i:], "..") || strings.HasPrefix(s[i:], "!="):
+			toks = append(toks, qtoken{tokPunct, s[i : i+2], i})
+			i += 2
+		case strings.ContainsRune("()[]{}:,.|*-<>=", c):
+			toks = append(toks, qtoken{tokPunct, string(c), i})
+			i++
+		default:
+			return nil, fmt.Errorf("kg: query: unexpected %q at %d", c, i)
+		}
+	}
+	return append(toks, qtoken{kind: tokEOF, pos: len(s)}), nil
+}
+
+// ---------------------------------------------------------------------------
+// Parser
+// ---------------------------------------------------------------------------
+
+type queryParser struct {
+	toks []qtoken
+	i    int
+}
+
+// ParseQuery parses a query.
+func ParseQuery(s string) (*Query, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{toks: toks}
+	q, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (p *queryParser) peek() qtoken { return p.toks[p.i] }
+
+func (p *queryParser) next() qtoken {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	t := p.peek()
+	at := "end of query"
+	if t.kind != tokEOF {
+		at = fmt.Sprintf("%q at %d", t.text, t.pos)
+	}
+	return fmt.Errorf("kg: query: %s, found %s", fmt.Sprintf(format, args...), at)
+}
+
+func (p *queryParser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+func (p *queryParser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+func (p *queryParser) punct(s string) error {
+	if !p.isPunct(s) {
+		return p.errorf("expected %q", s)
+	}
+	p.next()
+	return nil
+}
+
+func (p *queryParser) ident(what string) (string, error) {
+	if p.peek().kind != tokIdent {
+		return "", p.errorf("expected %s", what)
+	}
+	return p.next().text, nil
+}
+
+func (p *queryParser) number() (int, error) {
+	if p.peek().kind != tokNumber {
+		return 0, p.errorf("expected a number")
+	}
+	return strconv.Atoi(p.next().text)
+}
+
+func (p *queryParser) query() (*Query, error) {
+	if !p.isKeyword("MATCH") {
+		return nil, p.errorf("expected MATCH")
+	}
+	p.next()
+	q := &Query{}
+
+	// Optional "p =" before shortestPath, as in Cypher.
+	if p.peek().kind == tokIdent && p.toks[p.i+1].kind == tokPunct && p.toks[p.i+1].text == "=" {
+		p.i += 2
+	}
+	if p.isKeyword("shortestPath") {
+		p.next()
+		q.Shortest = true
+		if err := p.punct("("); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.pattern(q); err != nil {
+		return nil, err
+	}
+	if q.Shortest {
+		if err := p.punct(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isKeyword("WHERE") {
+		p.next()
+		for {
+			c, err := p.condition()
+			if err != nil {
+				return nil, err
+			}
+			q.Where = append(q.Where, c)
+			if !p.isKeyword("AND") {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.isKeyword("RETURN") {
+		p.next()
+		for {
+			v, err := p.ident("a variable")
+			if err != nil {
+				return nil, err
+			}
+			q.Return = append(q.Return, v)
+			if !p.isPunct(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.isKeyword("LIMIT") {
+		p.next()
+		n, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		q.Limit = n
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected input")
+	}
+	return q, nil
+}
+
+func (p *queryParser) pattern(q *Query) error {
+	n, err := p.node()
+	if err != nil {
+		return err
+	}
+	q.Nodes = append(q.Nodes, n)
+	for p.isPunct("-") || p.isPunct("<") {
+		r, err := p.rel()
+		if err != nil {
+			return err
+		}
+		n, err := p.node()
+		if err != nil {
+			return err
+		}
+		q.Rels = append(q.Rels, r)
+		q.Nodes = append(q.Nodes, n)
+	}
+	return nil
+}
+
+func (p *queryParser) node() (NodePattern, error) {
+	var n NodePattern
+	if err := p.punct("("); err != nil {
+		return n, err
+	}
+	if p.peek().kind == tokIdent {
+		n.Var = p.next().text
+	}
+	if p.isPunct(":") {
+		p.next()
+		t, err := p.ident("an entity type")
+		if err != nil {
+			return n, err
+		}
+		n.Type = EntityType(t)
+	}
+	if p.isPunct("{") {
+		p.next()
+		n.Props = make(map[string]string)
+		for !p.isPunct("}") {
+			if len(n.Props) > 0 {
+				if err := p.punct(","); err != nil {
+					return n, err
+				}
+			}
+			key, err := p.ident("a property")
+			if err != nil {
+				return n, err
+			}
+			if err := p.punct(":"); err != nil {
+				return n, err
+			}
+			if p.peek().kind != tokString {
+				return n, p.errorf("expected a string")
+			}
+			n.Props[key] = p.next().text
+		}
+		p.next()
+	}
+	return n, p.punct(")")
+}
+
+func (p *queryParser) rel() (RelPattern, error) {
+	r := RelPattern{Dir: DirBoth, MinHops: 1, MaxHops: 1}
+	if p.isPunct("<") {
+		p.next()
+		r.Dir = DirIn
+	}
+	if err := p.punct("-"); err != nil {
+		return r, err
+	}
+	if p.isPunct("[") {
+		p.next()
+		if p.peek().kind == tokIdent {
+			return r, p.errorf("relationship variables are not supported")
+		}
+		if p.isPunct(":") {
+			p.next()
+			for {
+				t, err := p.ident("a relationship type")
+				if err != nil {
+					return r, err
+				}
+				r.Types = append(r.Types, RelType(t))
+				if !p.isPunct("|") {
+					break
+				}
+				p.next()
+			}
+		}
+		if p.isPunct("*") {
+			p.next()
+			r.MinHops, r.MaxHops = 1, -1
+			if p.peek().kind == tokNumber {
+				n, err := p.number()
+				if err != nil {
+					return r, err
+				}
+				r.MinHops, r.MaxHops = n, n
+			}
+			if p.isPunct("..") {
+				p.next()
+				r.MaxHops = -1
+				if p.peek().kind == tokNumber {
+					n, err := p.number()
+					if err != nil {
+						return r, err
+					}
+					r.MaxHops = n
+				}
+			}
+		}
+		if err := p.punct("]"); err != nil {
+			return r, err
+		}
+	}
+	if err := p.punct("-"); err != nil {
+		return r, err
+	}
+	if p.isPunct(">") {
+		p.next()
+		if r.Dir == DirIn {
+			return r, p.errorf("a relationship cannot point both ways")
+		}
+		r.Dir = DirOut
+	}
+	return r, nil
+}
+
+func (p *queryParser) condition() (Condition, error) {
+	var c Condition
+	var err error
+	if c.Var, err = p.ident("a variable"); err != nil {
+		return c, err
+	}
+	if err := p.punct("."); err != nil {
+		return c, err
+	}
+	if c.Prop, err = p.ident("a property"); err != nil {
+		return c, err
+	}
+	switch {
+	case p.isPunct("="), p.isPunct("!="):
+		c.Op = p.next().text
+	case p.isKeyword("CONTAINS"):
+		p.next()
+		c.Op = "CONTAINS"
+	default:
+		return c, p.errorf("expected =, != or CONTAINS")
+	}
+	t := p.peek()
+	if t.kind != tokString && t.kind != tokNumber {
+		return c, p.errorf("expected a string")
+	}
+	c.Value = p.next().text
+	return c, nil
+}
+
+var queryProps = map[string]bool{"name": true, "type": true, "project": true, "namespace": true, "id": true}
+
+func (q *Query) validate() error {
+	vars := make(map[string]bool)
+	for _, n := range q.Nodes {
+		if n.Var != "" {
+			vars[n.Var] = true
+		}
+		for k := range n.Props {
+			if !queryProps[k] {
+				return fmt.Errorf("kg: query: unknown property %q", k)
+			}
+		}
+	}
+	for _, r := range q.Rels {
+		if r.MinHops > MaxHops || r.MaxHops > MaxHops || (r.MaxHops >= 0 && r.MaxHops < r.MinHops) {
+			return fmt.Errorf("kg: query: hops must satisfy 0 <= min <= max <= %d", MaxHops)
+		}
+	}
+	if q.Shortest && len(q.Rels) != 1 {
+		return fmt.Errorf("kg: query: shortestPath takes one relationship pattern")
+	}
+	for _, c := range q.Where {
+		if !vars[c.Var] {
+			return fmt.Errorf("kg: query: unknown variable %q", c.Var)
+		}
+		if !queryProps[c.Prop] {
+			return fmt.Errorf("kg: query: unknown property %q", c.Prop)
+		}
+	}
+	for _, v := range q.Return {
+		if !vars[v] {
+			return fmt.Errorf("kg: query: unknown variable %q", v)
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Evaluation
+// ---------------------------------------------------------------------------
+
+// Edges returns the relationships from or to the entity, ordered by ID for
+// the JSON graph and by creation for kg.db.
+func (g *Graph) Edges(id string) []*Relationship {
+	if g.db != nil {
+		out, _ := g.db.edges(id)
+		return out
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var out []*Relationship
+	for _, r := range g.rels {
+		if r.FromID == id || r.ToID == id {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Query evaluates q against the graph.
+func (g *Graph) Query(q *Query, opts QueryOptions) (*QueryResult, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = opts.Limit
+	}
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	ev := &evaluator{g: g, q: q, opts: opts, limit: limit, edges: make(map[string][]*Relationship)}
+	if q.Shortest {
+		return ev.shortest(), nil
+	}
+	return ev.match(), nil
+}
+
+type evaluator struct {
+	g     *Graph
+	q     *Query
+	opts  QueryOptions
+	limit int
+	edges map[string][]*Relationship // entity ID -> relationships, memoised
+}
+
+func (ev *evaluator) edgesOf(id string) []*Relationship {
+	if rels, ok := ev.edges[id]; ok {
+		return rels
+	}
+	rels := ev.g.Edges(id)
+	ev.edges[id] = rels
+	return rels
+}
+
+// candidates returns the entities matching node i of the query.
+func (ev *evaluator) candidates(i int) []*Entity {
+	n := ev.q.Nodes[i]
+	var pool []*Entity
+	if name, ok := ev.nameHint(n); ok {
+		pool = ev.g.QueryByName(name)
+	} else {
+		pool = ev.g.List(n.Type, ev.opts.Project)
+	}
+	var out []*Entity
+	for _, e := range pool {
+		if ev.matches(n, e) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].CanonicalName < out[b].CanonicalName })
+	return out
+}
+
+// nameHint returns a name the node must have, for an indexed lookup.
+func (ev *evaluator) nameHint(n NodePattern) (string, bool) {
+	if v, ok := n.Props["name"]; ok {
+		return v, true
+	}
+	for _, c := range ev.q.Where {
+		if n.Var != "" && c.Var == n.Var && c.Prop == "name" && (c.Op == "=" || c.Op == "CONTAINS") {
+			return c.Value, true
+		}
+	}
+	return "", false
+}
+
+// matches reports whether e satisfies the node pattern, the WHERE
+// conditions on its variable and the options.
+func (ev *evaluator) matches(n NodePattern, e *Entity) bool {
+	if n.Type != "" && e.Type != n.Type {
+		return false
+	}
+	if ev.opts.Project != "" && e.Project != ev.opts.Project {
+		return false
+	}
+	if ev.opts.Namespace != "" && e.Namespace != ev.opts.Namespace {
+		return false
+	}
+	for k, v := range n.Props {
+		if !compare(e, k, "=", v) {
+			return false
+		}
+	}
+	for _, c := range ev.q.Where {
+		if n.Var != "" && c.Var == n.Var && !compare(e, c.Prop, c.Op, c.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func compare(e *Entity, prop, op, value string) bool {
+	var ok bool
+	switch op {
+	case "CONTAINS":
+		return strings.Contains(strings.ToLower(property(e, prop)), strings.ToLower(value))
+	case "=", "!=":
+		if prop == "name" {
+			ok = nameMatches(e, value)
+		} else {
+			ok = property(e, prop) == value
+		}
+	}
+	if op == "!=" {
+		return !ok
+	}
+	return ok
+}
+
+func property(e *Entity, prop string) string {
+	switch prop {
+	case "name":
+		return e.CanonicalName
+	case "type":
+		return string(e.Type)
+	case "project":
+		return e.Project
+	case "namespace":
+		return e.Namespace
+	case "id":
+		return e.ID
+	}
+	return ""
+}
+
+// nameMatches compares v with the canonical name of e and its short forms.
+func nameMatches(e *Entity, v string) bool {
+	name := e.CanonicalName
+	if v == name {
+		return true
+	}
+	base := name[strings.LastIndex(name, "/")+1:]
+	if v == base {
+		return true
+	}
+	if e.Type == EntityFunction || e.Type == EntityClass {
+		if _, decl, ok := strings.Cut(base, "."); ok {
+			return v == decl || v == decl[strings.LastIndex(decl, ".")+1:]
+		}
+	}
+	return false
+}
+
+// step is one hop of a traversal: the relationship taken and the entity
+// reached.
+type step struct {
+	rel *Relationship
+	to  string
+}
+
+// reach returns the entities reachable from start over r, each with one
+// path of steps that reached it within r's hop bounds.
+func (ev *evaluator) reach(start string, r RelPattern) map[string][]step {
+	found := make(map[string][]step)
+	if r.MinHops == 0 {
+		found[start] = nil
+	}
+	type state struct {
+		id   string
+		path []step
+	}
+	frontier := []state{{id: start}}
+	for depth := 1; depth <= r.MaxHops && len(frontier) > 0; depth++ {
+		level := make(map[string]bool)
+		var next []state
+		for _, s := range frontier {
+			for _, rel := range ev.edgesOf(s.id) {
+				other, ok := traverse(rel, s.id, r)
+				if !ok || level[other] || onPath(s.path, rel) {
+					continue
+				}
+				// Below MinHops an entity may be passed through again at a
+				// depth that qualifies; beyond it, once found is enough.
+				if _, done := found[other]; done && depth > r.MinHops {
+					continue
+				}
+				level[other] = true
+				path := append(append([]step(nil), s.path...), step{rel, other})
+				if _, done := found[other]; !done && depth >= r.MinHops {
+					found[other] = path
+				}
+				next = append(next, state{other, path})
+			}
+		}
+		frontier = next
+	}
+	return found
+}
+
+// traverse returns the far end of rel from id if r allows taking it.
+func traverse(rel *Relationship, id string, r RelPattern) (string, bool) {
+	if len(r.Types) > 0 {
+		ok := false
+		for _, t := range r.Types {
+			ok = ok || rel.RelType == t
+		}
+		if !ok {
+			return "", false
+		}
+	}
+	switch {
+	case rel.FromID == id && r.Dir != DirIn:
+		return rel.ToID, true
+	case rel.ToID == id && r.Dir != DirOut:
+		return rel.FromID, true
+	}
+	return "", false
+}
+
+func onPath(path []step, rel *Relationship) bool {
+	for _, s := range path {
+		if s.rel.ID == rel.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// match evaluates a MATCH query.
+func (ev *evaluator) match() *QueryResult {
+	q := ev.q
+	res := &QueryResult{}
+	cols := q.Return
+	if len(cols) == 0 {
+		for _, n := range q.Nodes {
+			if n.Var != "" {
+				cols = append(cols, n.Var)
+			}
+		}
+	}
+	colIndex := make(map[string]int) // variable -> node index
+	for i, n := range q.Nodes {
+		if _, ok := colIndex[n.Var]; !ok && n.Var != "" {
+			colIndex[n.Var] = i
+		}
+	}
+	if len(cols) == 0 {
+		// No variables: report every node of the pattern.
+		for i := range q.Nodes {
+			v := fmt.Sprintf("_%d", i)
+			cols = append(cols, v)
+			colIndex[v] = i
+		}
+	}
+	res.Columns = cols
+
+	seenRows := make(map[string]bool)
+	relSeen := make(map[string]bool)
+	bound := make([]*Entity, len(q.Nodes))
+	var rels []*Relationship
+
+	var extend func(i int) bool
+	extend = func(i int) bool {
+		if i == len(q.Rels) {
+			row := make([]*Entity, len(cols))
+			var key strings.Builder
+			for c, v := range cols {
+				row[c] = bound[colIndex[v]]
+				key.WriteString(row[c].ID + "|")
+			}
+			if !seenRows[key.String()] {
+				seenRows[key.String()] = true
+				res.Rows = append(res.Rows, row)
+				for _, r := range rels {
+					if !relSeen[r.ID] {
+						relSeen[r.ID] = true
+						res.Relationships = append(res.Relationships, r)
+					}
+				}
+			}
+			return len(res.Rows) < ev.limit
+		}
+		r := q.Rels[i]
+		if r.MaxHops < 0 {
+			r.MaxHops = DefaultMaxHops
+		}
+		reached := ev.reach(bound[i].ID, r)
+		ids := make([]string, 0, len(reached))
+		for id := range reached {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		next := q.Nodes[i+1]
+		for _, id := range ids {
+			e := ev.g.GetEntity(id)
+			if e == nil || !ev.matches(next, e) || !consistent(q.Nodes, bound, i+1, e) {
+				continue
+			}
+			bound[i+1] = e
+			mark := len(rels)
+			for _, s := range reached[id] {
+				rels = append(rels, s.rel)
+			}
+			more := extend(i + 1)
+			rels = rels[:mark]
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, e := range ev.candidates(0) {
+		bound[0] = e
+		if !extend(0) {
+			break
+		}
+	}
+	return res
+}
+
+// consistent reports whether binding node i to e agrees with an earlier
+// node of the same variable.
+func consistent(nodes []NodePattern, bound []*Entity, i int, e *Entity) bool {
+	v := nodes[i].Var
+	if v == "" {
+		return true
+	}
+	for j := 0; j < i; j++ {
+		if nodes[j].Var == v && bound[j].ID != e.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// shortest evaluates a shortestPath query: for every start entity, a
+// shortest path to any matching end entity.
+func (ev *evaluator) shortest() *QueryResult {
+	r := ev.q.Rels[0]
+	if r.MaxHops < 0 {
+		r.MaxHops = MaxHops
+	}
+	ends := make(map[string]*Entity)
+	for _, e := range ev.candidates(1) {
+		ends[e.ID] = e
+	}
+	res := &QueryResult{}
+	relSeen := make(map[string]bool)
+	for _, start := range ev.candidates(0) {
+		if len(res.Paths) >= ev.limit {
+			break
+		}
+		path, ok := ev.bfs(start, ends, r)
+		if !ok {
+			continue
+		}
+		res.Paths = append(res.Paths, path)
+		for _, rel := range path.Relationships {
+			if !relSeen[rel.ID] {
+				relSeen[rel.ID] = true
+				res.Relationships = append(res.Relationships, rel)
+			}
+		}
+	}
+	sort.SliceStable(res.Paths, func(i, j int) bool {
+		return len(res.Paths[i].Relationships) < len(res.Paths[j].Relationships)
+	})
+	return res
+}
+
+// bfs finds a shortest path from start to any of ends, at least MinHops
+// and at most MaxHops long.
+func (ev *evaluator) bfs(start *Entity, ends map[string]*Entity, r RelPattern) (Path, bool) {
+	if _, ok := ends[start.ID]; ok && r.MinHops == 0 {
+		return Path{Entities: []*Entity{start}}, true
+	}
+	parent := map[string]step{}
+	visited := map[string]bool{start.ID: true}
+	frontier := []string{start.ID}
+	for depth := 1; depth <= r.MaxHops && len(frontier) > 0; depth++ {
+		var next []string
+		for _, id := range frontier {
+			for _, rel := range ev.edgesOf(id) {
+				other, ok := traverse(rel, id, r)
+				if !ok || visited[other] {
+					continue
+				}
+				visited[other] = true
+				parent[other] = step{rel, id}
+				if _, hit := ends[other]; hit && depth >= r.MinHops {
+					return ev.unwind(start.ID, other, parent), true
+				}
+				next = append(next, other)
+			}
+		}
+		frontier = next
+	}
+	return Path{}, false
+}
+
+// unwind rebuilds the path to end from BFS parents; step.to holds the
+// previous entity here.
+func (ev *evaluator) unwind(start, end string, parent map[string]step) Path {
+	var ids []string
+	var rels []*Relationship
+	for id := end; id != start; id = parent[id].to {
+		ids = append(ids, id)
+		rels = append(rels, parent[id].rel)
+	}
+	ids = append(ids, start)
+	p := Path{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		p.Entities = append(p.Entities, ev.g.GetEntity(ids[i]))
+	}
+	for i := len(rels) - 1; i >= 0; i-- {
+		p.Relationships = append(p.Relationships, rels[i])
+	}
+	return p
+}
@@ -0,0 +1,139 @@
+package kg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queryGraph builds:
+//
+//	a.main -calls-> a.read -calls-> a.Open <-calls- b.Use (project other)
+//	a.go -imports-> fmt <-imports- b.go
+func queryGraph(t *testing.T) *Graph {
+	t.Helper()
+	g := testGraph(t)
+	add := func(etype EntityType, name, project string) *Entity {
+		e, err := g.AddEntity(etype, name, project, GoNamespace)
+		require.NoError(t, err)
+		return e
+	}
+	link := func(from, to *Entity, rel RelType) {
+		_, err := g.AddRelationship(from.ID, to.ID, rel, "")
+		require.NoError(t, err)
+	}
+	main := add(EntityFunction, "example.com/a.main", "apex")
+	read := add(EntityFunction, "example.com/a.read", "apex")
+	open := add(EntityFunction, "example.com/a.Open", "apex")
+	use := add(EntityFunction, "example.com/b.Use", "other")
+	fmtPkg := add(EntityPackage, "fmt", "apex")
+	aFile := add(EntityFile, "a/a.go", "apex")
+	bFile := add(EntityFile, "b/b.go", "other")
+	link(main, read, RelCalls)
+	link(read, open, RelCalls)
+	link(use, open, RelCalls)
+	link(aFile, fmtPkg, RelImports)
+	link(bFile, fmtPkg, RelImports)
+	return g
+}
+
+func runQuery(t *testing.T, g *Graph, query string, opts QueryOptions) *QueryResult {
+	t.Helper()
+	q, err := ParseQuery(query)
+	require.NoError(t, err)
+	res, err := g.Query(q, opts)
+	require.NoError(t, err)
+	return res
+}
+
+// column returns the canonical names in column i of the result rows.
+func column(res *QueryResult, i int) []string {
+	var out []string
+	for _, row := range res.Rows {
+		out = append(out, row[i].CanonicalName)
+	}
+	return out
+}
+
+func TestQueryVariableLength(t *testing.T) {
+	g := queryGraph(t)
+	res := runQuery(t, g, `MATCH (f:function)-[:calls*1..3]->(g) WHERE g.name = "Open" RETURN f`, QueryOptions{})
+	assert.Equal(t, []string{"f"}, res.Columns)
+	assert.ElementsMatch(t, []string{"example.com/a.main", "example.com/a.read", "example.com/b.Use"}, column(res, 0))
+	assert.Len(t, res.Relationships, 3, "the paths that reached Open")
+
+	res = runQuery(t, g, `MATCH (f:function)-[:calls*1..3]->(g {name: "Open"}) RETURN f`, QueryOptions{Project: "apex"})
+	assert.ElementsMatch(t, []string{"example.com/a.main", "example.com/a.read"}, column(res, 0))
+
+	res = runQuery(t, g, `MATCH (f {name: "main"})-[:calls*2]->(g)`, QueryOptions{})
+	assert.Equal(t, []string{"example.com/a.Open"}, column(res, 1))
+
+	res = runQuery(t, g, `MATCH (f {name: "Open"})-[:calls]->(g)`, QueryOptions{})
+	assert.Empty(t, res.Rows, "calls are directed")
+
+	res = runQuery(t, g, `match (f:function)-->(g) limit 1`, QueryOptions{})
+	assert.Len(t, res.Rows, 1)
+}
+
+func TestQueryReverseAndFilters(t *testing.T) {
+	g := queryGraph(t)
+	res := runQuery(t, g, `MATCH (p:package {name: "fmt"})<-[:imports]-(f) RETURN f`, QueryOptions{})
+	assert.ElementsMatch(t, []string{"a/a.go", "b/b.go"}, column(res, 0))
+
+	res = runQuery(t, g, `MATCH (f:file)-[:imports]->(p) WHERE f.project != "apex" AND p.name CONTAINS "FM"`, QueryOptions{})
+	assert.Equal(t, []string{"b/b.go"}, column(res, 0))
+
+	res = runQuery(t, g, `MATCH (f:file)-[:imports]->(p)`, QueryOptions{Namespace: "elsewhere"})
+	assert.Empty(t, res.Rows)
+}
+
+func TestQueryShortestPath(t *testing.T) {
+	g := queryGraph(t)
+	res := runQuery(t, g, `MATCH p = shortestPath((a {name: "main"})-[*]-(b {name: "Use"}))`, QueryOptions{})
+	require.Len(t, res.Paths, 1)
+	assert.Equal(t, "example.com/a.main -[calls]-> example.com/a.read -[calls]-> example.com/a.Open <-[calls]- example.com/b.Use",
+		formatPath(res.Paths[0]))
+
+	res = runQuery(t, g, `MATCH shortestPath((a {name: "main"})-[:calls*]->(b {name: "Use"}))`, QueryOptions{})
+	assert.Empty(t, res.Paths, "no directed path")
+}
+
+func TestQueryFormats(t *testing.T) {
+	g := queryGraph(t)
+	res := runQuery(t, g, `MATCH (f:function)-[:calls]->(g {name: "Open"})`, QueryOptions{})
+
+	table := FormatQueryTable(res)
+	assert.Contains(t, table, "f ")
+	assert.Contains(t, table, "example.com/a.read (function)")
+	assert.Contains(t, table, "(2 rows)")
+
+	dot := FormatDOT(res.Entities(), res.Relationships)
+	assert.Contains(t, dot, "digraph kg {")
+	assert.Contains(t, dot, `[label="calls"]`)
+	assert.Contains(t, dot, `example.com/a.Open\n(function)`)
+
+	assert.Equal(t, "No matches.\n", FormatQueryTable(&QueryResult{}))
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, q := range []string{
+		`FIND (a)`,
+		`MATCH (a`,
+		`MATCH (a)-[r:calls]->(b)`,
+		`MATCH (a)<-[:calls]->(b)`,
+		`MATCH (a) WHERE b.name = "x"`,
+		`MATCH (a) WHERE a.colour = "x"`,
+		`MATCH (a {colour: "x"})`,
+		`MATCH (a)-[*20]->(b)`,
+		`MATCH (a)-[*3..1]->(b)`,
+		`MATCH shortestPath((a))`,
+		`MATCH (a) RETURN b`,
+		`MATCH (a) WHERE a.name = "x`,
+	} {
+		_, err := ParseQuery(q)
+		assert.Error(t, err, q)
+	}
+	assert.True(t, IsQuery("  match (a)"))
+	assert.False(t, IsQuery("Graph"))
+}
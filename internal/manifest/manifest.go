@@ -17,24 +17,49 @@ type NodeResult struct {
 	Status   string `json:"status"`
 	Error    string `json:"error,omitempty"`
 	ActionID string `json:"action_id,omitempty"`
+	// PromptHash and ContextReportHash reference the assembled prompt and
+	// its context build report in the artifact store.
+	PromptHash        string `json:"prompt_hash,omitempty"`
+	ContextReportHash string `json:"context_report_hash,omitempty"`
+	// MemoryIDs lists the memories included in the node's context.
+	MemoryIDs []string `json:"memory_ids,omitempty"`
+	// OutputHashes reference the node's result and the files it changed
+	// in the artifact store.
+	OutputHashes []string `json:"output_hashes,omitempty"`
+	// CacheHit is set when the result was reused from the result cache
+	// instead of calling the model; CachedFrom is the run that produced it.
+	CacheHit   bool   `json:"cache_hit,omitempty"`
+	CachedFrom string `json:"cached_from,omitempty"`
 }
 
 // Manifest holds the complete metadata for one execution run.
 type Manifest struct {
-	RunID      string       `json:"run_id"`
-	Task       string       `json:"task"`
-	Timestamp  string       `json:"timestamp"`
-	Model      string       `json:"model"`
-	Effort     string       `json:"effort"`
-	RiskLevel  string       `json:"risk_level"`
-	NodeCount  int          `json:"node_count"`
-	DurationMs int64        `json:"duration_ms"`
-	Outcome    string       `json:"outcome"`
+	RunID           string       `json:"run_id"`
+	Task            string       `json:"task"`
+	Timestamp       string       `json:"timestamp"`
+	Model           string       `json:"model"`
+	Effort          string       `json:"effort"`
+	RiskLevel       string       `json:"risk_level"`
+	NodeCount       int          `json:"node_count"`
+	DurationMs      int64        `json:"duration_ms"`
+	Outcome         string       `json:"outcome"`
 	TraceID         string       `json:"trace_id,omitempty"`
 	RollbackQuality string       `json:"rollback_quality,omitempty"`
+	MemoryVersion   string       `json:"memory_version,omitempty"` // memory snapshot the prompts were built from
 	Nodes           []NodeResult `json:"nodes"`
 }
 
+// Node returns the result for the node with the given ID, or nil if the
+// manifest does not contain it.
+func (m *Manifest) Node(id string) *NodeResult {
+	for i := range m.Nodes {
+		if m.Nodes[i].ID == id {
+			return &m.Nodes[i]
+		}
+	}
+	return nil
+}
+
 // Store manages manifest persistence under a root directory.
 type Store struct {
 	dir string
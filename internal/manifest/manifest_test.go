@@ -121,3 +121,20 @@ func TestNodeResultActionID(t *testing.T) {
 	assert.Equal(t, "act-001", loaded.Nodes[0].ActionID)
 	assert.Equal(t, "act-002", loaded.Nodes[1].ActionID)
 }
+
+func TestManifestNodeLookup(t *testing.T) {
+	m := &Manifest{
+		RunID: "lookup-run",
+		Nodes: []NodeResult{
+			{ID: "step-1", PromptHash: "abc", ContextReportHash: "def"},
+			{ID: "step-2"},
+		},
+	}
+
+	n := m.Node("step-1")
+	require.NotNil(t, n)
+	assert.Equal(t, "abc", n.PromptHash)
+	assert.Equal(t, "def", n.ContextReportHash)
+
+	assert.Nil(t, m.Node("missing"))
+}
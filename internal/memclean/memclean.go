@@ -3,11 +3,14 @@
 package memclean
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/lyndonlyu/apex/internal/memory"
 )
 
 // MemoryEntry represents a single memory file discovered during a scan.
@@ -17,6 +20,31 @@ type MemoryEntry struct {
 	Size       int64     `json:"size"`
 	ModTime    time.Time `json:"mod_time"`
 	Confidence float64   `json:"confidence"` // default 0.5 for files without explicit confidence
+	Hits       int       `json:"hits"`
+	LastUsed   time.Time `json:"last_used,omitempty"` // last access or validity start; zero for files without metadata
+	Confirmed  bool      `json:"confirmed,omitempty"` // confirmed by the user, never removed
+}
+
+// EffectiveConfidence returns the entry's confidence after weekly decay since
+// it was last used. Files without metadata do not decay.
+func (e MemoryEntry) EffectiveConfidence(now time.Time) float64 {
+	if e.LastUsed.IsZero() {
+		return e.Confidence
+	}
+	return memory.Entry{
+		Confidence:   e.Confidence,
+		LastAccessed: e.LastUsed.Format(time.RFC3339),
+		Confirmed:    e.Confirmed,
+	}.EffectiveConfidence(now)
+}
+
+// lastActivity is the time staleness is measured from: the last use when
+// known, else the file's modification time.
+func (e MemoryEntry) lastActivity() time.Time {
+	if !e.LastUsed.IsZero() {
+		return e.LastUsed
+	}
+	return e.ModTime
 }
 
 // CleanupConfig controls the cleanup behaviour.
@@ -48,8 +76,9 @@ func DefaultConfig() CleanupConfig {
 }
 
 // Scan walks memDir and collects all .md and .jsonl files as MemoryEntry values.
-// Category is determined by the first path component (subdirectory name).
-// Files without explicit confidence metadata receive a default of 0.5.
+// Category is the kind directory within the memory's tier, see category.
+// Markdown memories carry their confidence, hit count and last access in
+// frontmatter; other files receive a default confidence of 0.5.
 func Scan(memDir string) ([]MemoryEntry, error) {
 	var entries []MemoryEntry
 
@@ -71,20 +100,23 @@ func Scan(memDir string) ([]MemoryEntry, error) {
 			return nil
 		}
 
-		// Determine category from the first path component.
-		parts := strings.SplitN(rel, string(filepath.Separator), 2)
-		category := ""
-		if len(parts) > 1 {
-			category = parts[0]
-		}
-
-		entries = append(entries, MemoryEntry{
+		entry := MemoryEntry{
 			Path:       rel,
-			Category:   category,
+			Category:   category(filepath.ToSlash(rel)),
 			Size:       info.Size(),
 			ModTime:    info.ModTime(),
-			Confidence: 0.5,
-		})
+			Confidence: memory.DefaultConfidence,
+		}
+		if ext == ".md" {
+			if data, readErr := os.ReadFile(path); readErr == nil {
+				m := memory.ParseEntry(data, filepath.ToSlash(rel))
+				entry.Confidence = m.Confidence
+				entry.Hits = m.Hits
+				entry.LastUsed = m.LastUsed()
+				entry.Confirmed = m.Confirmed
+			}
+		}
+		entries = append(entries, entry)
 
 		return nil
 	})
@@ -95,6 +127,43 @@ func Scan(memDir string) ([]MemoryEntry, error) {
 	return entries, nil
 }
 
+// ScanStore collects the markdown memories of store, in every tier, as
+// MemoryEntry values carrying the store's confidence, hit count, last use
+// and confirmation. ModTime is the memory's creation time.
+func ScanStore(store *memory.Store) ([]MemoryEntry, error) {
+	all, err := store.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]MemoryEntry, 0, len(all))
+	for _, m := range all {
+		created, _ := time.Parse(time.RFC3339, m.Created)
+		entries = append(entries, MemoryEntry{
+			Path:       m.Path,
+			Category:   category(m.Path),
+			Size:       int64(len(m.Content)),
+			ModTime:    created,
+			Confidence: m.Confidence,
+			Hits:       m.Hits,
+			LastUsed:   m.LastUsed(),
+			Confirmed:  m.Confirmed,
+		})
+	}
+	return entries, nil
+}
+
+// category is the kind directory of the memory at the slash path rel,
+// whatever its tier: "decisions" for both decisions/x.md and
+// projects/<key>/decisions/x.md.
+func category(rel string) string {
+	_, _, inner := memory.SplitTier(rel)
+	dir, _, ok := strings.Cut(inner, "/")
+	if !ok {
+		return ""
+	}
+	return dir
+}
+
 // buildExemptSet returns a set of category names that should be exempt from cleanup.
 func buildExemptSet(cats []string) map[string]bool {
 	m := make(map[string]bool, len(cats))
@@ -107,9 +176,10 @@ func buildExemptSet(cats []string) map[string]bool {
 // Evaluate determines which entries should be removed and which should be kept.
 // If the number of entries is below the capacity threshold (MaxEntries * CapacityThreshold),
 // no entries are marked for removal. Otherwise, entries that are:
-//   - NOT in an exempt category, AND
-//   - have confidence below ConfidenceMin, AND
-//   - have a ModTime before now minus StaleAfterDays
+//   - NOT in an exempt category and not confirmed by the user, AND
+//   - have effective (decayed) confidence below ConfidenceMin, AND
+//   - were last used (or, without metadata, modified) before now minus
+//     StaleAfterDays
 //
 // are marked for removal.
 func Evaluate(entries []MemoryEntry, cfg CleanupConfig, now time.Time) (toRemove, toKeep []MemoryEntry) {
@@ -124,11 +194,11 @@ func Evaluate(entries []MemoryEntry, cfg CleanupConfig, now time.Time) (toRemove
 	staleCutoff := now.AddDate(0, 0, -cfg.StaleAfterDays)
 
 	for _, entry := range entries {
-		if exempt[entry.Category] {
+		if exempt[entry.Category] || entry.Confirmed {
 			toKeep = append(toKeep, entry)
 			continue
 		}
-		if entry.Confidence < cfg.ConfidenceMin && entry.ModTime.Before(staleCutoff) {
+		if entry.EffectiveConfidence(now) < cfg.ConfidenceMin && entry.lastActivity().Before(staleCutoff) {
 			toRemove = append(toRemove, entry)
 		} else {
 			toKeep = append(toKeep, entry)
@@ -138,23 +208,23 @@ func Evaluate(entries []MemoryEntry, cfg CleanupConfig, now time.Time) (toRemove
 	return toRemove, toKeep
 }
 
-// Execute removes the files listed in toRemove from memDir using a best-effort
-// approach: it processes every entry even if some removals fail, and returns a
-// partial CleanupResult with the count of successfully removed files. If any
-// non-NotExist errors occurred, they are collected and returned as a single
-// combined error after all entries have been attempted.
-func Execute(memDir string, toRemove []MemoryEntry) (*CleanupResult, int, error) {
+// Execute deletes the memories listed in toRemove from store, database row
+// and file alike, using a best-effort approach: it processes every entry even
+// if some removals fail, and returns a partial CleanupResult with the count
+// of successfully removed entries. If any errors other than an entry already
+// being gone occurred, they are collected and returned as a single combined
+// error after all entries have been attempted.
+func Execute(store *memory.Store, toRemove []MemoryEntry) (*CleanupResult, int, error) {
 	removed := 0
 	var errs []error
 
 	for _, entry := range toRemove {
-		fullPath := filepath.Join(memDir, entry.Path)
-		if err := os.Remove(fullPath); err != nil {
-			if !os.IsNotExist(err) {
+		if err := store.Delete(entry.Path); err != nil {
+			if !errors.Is(err, memory.ErrNotFound) {
 				errs = append(errs, fmt.Errorf("remove %s: %w", entry.Path, err))
 				continue
 			}
-			// File already gone — count it as removed.
+			// Entry already gone — count it as removed.
 		}
 		removed++
 	}
@@ -170,10 +240,10 @@ func Execute(memDir string, toRemove []MemoryEntry) (*CleanupResult, int, error)
 	return result, removed, nil
 }
 
-// DryRun performs Scan + Evaluate without deleting anything. It returns the
-// full CleanupResult and the list of entries that would be removed.
-func DryRun(memDir string, cfg CleanupConfig) (*CleanupResult, []MemoryEntry, error) {
-	entries, err := Scan(memDir)
+// DryRun performs ScanStore + Evaluate without deleting anything. It returns
+// the full CleanupResult and the list of entries that would be removed.
+func DryRun(store *memory.Store, cfg CleanupConfig) (*CleanupResult, []MemoryEntry, error) {
+	entries, err := ScanStore(store)
 	if err != nil {
 		return nil, nil, err
 	}
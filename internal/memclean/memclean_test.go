@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/lyndonlyu/apex/internal/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -137,6 +138,72 @@ func TestEvaluateOverThreshold(t *testing.T) {
 	assert.True(t, removedPaths["sessions/old.jsonl"])
 }
 
+func TestEvaluateUsesSignals(t *testing.T) {
+	now := time.Now()
+	staleTime := now.AddDate(0, 0, -60)
+	cfg := CleanupConfig{CapacityThreshold: 0.5, MaxEntries: 4, ConfidenceMin: 0.3, StaleAfterDays: 30}
+
+	entries := []MemoryEntry{
+		// 0.4 decays below 0.3 after 8 idle weeks → remove
+		{Path: "facts/decayed.md", Category: "facts", Confidence: 0.4, LastUsed: staleTime, ModTime: now},
+		// recently used despite an old file → keep
+		{Path: "facts/used.md", Category: "facts", Confidence: 0.1, LastUsed: now.AddDate(0, 0, -2), ModTime: staleTime},
+		// confirmed by the user → keep
+		{Path: "facts/confirmed.md", Category: "facts", Confidence: 0.1, Confirmed: true, LastUsed: staleTime, ModTime: staleTime},
+	}
+
+	toRemove, toKeep := Evaluate(entries, cfg, now)
+	require.Len(t, toRemove, 1)
+	assert.Equal(t, "facts/decayed.md", toRemove[0].Path)
+	assert.Len(t, toKeep, 2)
+}
+
+func TestScanReadsMetadata(t *testing.T) {
+	dir := t.TempDir()
+	store, err := memory.NewStore(dir)
+	require.NoError(t, err)
+	e, err := store.SaveEntryWithConfidence("fact", "ci", "CI runs on push", 0.7)
+	require.NoError(t, err)
+	require.NoError(t, store.RecordHit(e.ID))
+
+	entries, err := Scan(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 1, entries[0].Hits)
+	assert.InDelta(t, 0.75, entries[0].Confidence, 0.001)
+	assert.False(t, entries[0].LastUsed.IsZero())
+}
+
+func TestScanStoreReadsSignals(t *testing.T) {
+	dir := t.TempDir()
+	db, err := memory.OpenDB(filepath.Join(dir, "memory.db"), filepath.Join(dir, "memory"))
+	require.NoError(t, err)
+	defer db.Close()
+	store, err := memory.NewDBStore(filepath.Join(dir, "memory"), db, false)
+	require.NoError(t, err)
+	e, err := store.SaveEntryWithConfidence("fact", "ci", "CI runs on push", 0.7)
+	require.NoError(t, err)
+	require.NoError(t, store.RecordHit(e.ID))
+	d, err := store.SaveEntry("decision", "db", "Use SQLite")
+	require.NoError(t, err)
+	require.NoError(t, store.Confirm(d.ID))
+
+	entries, err := ScanStore(store)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	byPath := map[string]MemoryEntry{}
+	for _, entry := range entries {
+		byPath[entry.Path] = entry
+	}
+	fact := byPath[e.Path]
+	assert.Equal(t, "facts", fact.Category)
+	assert.Equal(t, 1, fact.Hits)
+	assert.InDelta(t, 0.75, fact.Confidence, 0.001)
+	assert.False(t, fact.LastUsed.IsZero())
+	assert.False(t, fact.ModTime.IsZero(), "creation time")
+	assert.True(t, byPath[d.Path].Confirmed)
+}
+
 func TestEvaluateExempt(t *testing.T) {
 	now := time.Now()
 	staleTime := now.AddDate(0, 0, -60)
@@ -192,7 +259,9 @@ func TestExecute(t *testing.T) {
 		{Path: filepath.Join("sessions", "2026-01.jsonl")},
 	}
 
-	result, removed, err := Execute(dir, toRemove)
+	store, err := memory.NewStore(dir)
+	require.NoError(t, err)
+	result, removed, err := Execute(store, toRemove)
 	require.NoError(t, err)
 
 	assert.Equal(t, 2, removed)
@@ -208,3 +277,61 @@ func TestExecute(t *testing.T) {
 	_, err = os.Stat(filepath.Join(dir, "decisions", "arch.md"))
 	assert.NoError(t, err)
 }
+
+func TestExecuteDeletesFromDatabase(t *testing.T) {
+	dir := t.TempDir()
+	db, err := memory.OpenDB(filepath.Join(dir, "memory.db"), filepath.Join(dir, "memory"))
+	require.NoError(t, err)
+	defer db.Close()
+	store, err := memory.NewDBStore(filepath.Join(dir, "memory"), db, true)
+	require.NoError(t, err)
+	stale, err := store.SaveEntry("fact", "stale", "The staging cluster is in eu-west")
+	require.NoError(t, err)
+	_, err = store.SaveEntry("fact", "fresh", "The prod cluster is in eu-west")
+	require.NoError(t, err)
+
+	_, removed, err := Execute(store, []MemoryEntry{{Path: stale.Path}, {Path: "facts/gone.md"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed, "an entry already gone counts as removed")
+
+	results, err := store.Search("cluster")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.NotEqual(t, stale.Path, results[0].Path)
+	assert.NoFileExists(t, filepath.Join(dir, "memory", filepath.FromSlash(stale.Path)), "the export is removed too")
+}
+
+func TestScanTieredCategories(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{
+		"projects/apex-1234/decisions/db.md",
+		"namespaces/review/decisions/style.md",
+		"projects/apex-1234/facts/ci.md",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("content"), 0644))
+	}
+
+	entries, err := Scan(dir)
+	require.NoError(t, err)
+	categories := map[string]string{}
+	for _, e := range entries {
+		categories[filepath.ToSlash(e.Path)] = e.Category
+	}
+	assert.Equal(t, map[string]string{
+		"projects/apex-1234/decisions/db.md":   "decisions",
+		"namespaces/review/decisions/style.md": "decisions",
+		"projects/apex-1234/facts/ci.md":       "facts",
+	}, categories)
+
+	// Stale, low-confidence project decisions are still exempt.
+	for i := range entries {
+		entries[i].Confidence = 0.1
+		entries[i].ModTime = time.Now().AddDate(0, 0, -60)
+	}
+	toRemove, _ := Evaluate(entries, CleanupConfig{MaxEntries: 1, CapacityThreshold: 1, ConfidenceMin: 0.3,
+		StaleAfterDays: 30, ExemptCategories: []string{"decisions"}}, time.Now())
+	require.Len(t, toRemove, 1)
+	assert.Equal(t, "facts", toRemove[0].Category)
+}
@@ -0,0 +1,496 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/lyndonlyu/apex/internal/migration"
+	"github.com/lyndonlyu/apex/internal/writerq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Full-text index implementations. FTS5 is used when the SQLite build has
+// it; otherwise the index falls back to FTS4 with BM25 computed from
+// matchinfo.
+const (
+	FTS5 = "fts5"
+	FTS4 = "fts4"
+)
+
+// BM25 parameters, the same defaults FTS5 uses.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// DB is the SQLite source of truth for memories. Markdown entries and
+// session records are rows of the memories table, indexed for full-text
+// search. All writes go through a writerq.Queue.
+type DB struct {
+	db    *sql.DB
+	path  string
+	queue *writerq.Queue
+	fts   string
+}
+
+// OpenDB opens or creates the memory database at path and migrates it. The
+// first migration imports the markdown and JSONL memories under dir.
+func OpenDB(path, dir string) (*DB, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("memory: open db: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("memory: ping db: %w", err)
+	}
+	for _, p := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000"} {
+		if _, err := db.Exec(p); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("memory: %s: %w", p, err)
+		}
+	}
+
+	fts, err := detectFTS(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	// Flush the WAL so the pre-migration backup is complete.
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("memory: checkpoint: %w", err)
+	}
+	if _, err := migrations(fts, dir).Migrate(db, path); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("memory: migrate: %w", err)
+	}
+	return &DB{db: db, path: path, queue: writerq.New(db), fts: fts}, nil
+}
+
+// Close drains pending writes and closes the database.
+func (d *DB) Close() error {
+	d.queue.Close()
+	return d.db.Close()
+}
+
+// FTS returns the full-text index implementation in use, FTS5 or FTS4.
+func (d *DB) FTS() string {
+	return d.fts
+}
+
+// detectFTS returns the index implementation of an existing database, or
+// the best one this SQLite build supports for a new one.
+func detectFTS(db *sql.DB) (string, error) {
+	var ddl string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'memories_fts'`).Scan(&ddl)
+	switch {
+	case err == nil:
+		if strings.Contains(strings.ToLower(ddl), FTS5) {
+			return FTS5, nil
+		}
+		return FTS4, nil
+	case err != sql.ErrNoRows:
+		return "", fmt.Errorf("memory: inspect schema: %w", err)
+	}
+	var has bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&has); err != nil {
+		return "", fmt.Errorf("memory: compile options: %w", err)
+	}
+	if has {
+		return FTS5, nil
+	}
+	return FTS4, nil
+}
+
+const memoriesSchema = `CREATE TABLE IF NOT EXISTS memories (
+	id            TEXT PRIMARY KEY,
+	path          TEXT NOT NULL,
+	type          TEXT NOT NULL,
+	slug          TEXT NOT NULL DEFAULT '',
+	version       INTEGER NOT NULL DEFAULT 1,
+	supersedes    TEXT NOT NULL DEFAULT '',
+	superseded_by TEXT NOT NULL DEFAULT '',
+	valid_from    TEXT NOT NULL DEFAULT '',
+	valid_to      TEXT NOT NULL DEFAULT '',
+	is_current    INTEGER NOT NULL DEFAULT 1,
+	created       TEXT NOT NULL DEFAULT '',
+	content       TEXT NOT NULL,
+	confidence    REAL NOT NULL DEFAULT 0.5,
+	hits          INTEGER NOT NULL DEFAULT 0,
+	last_accessed TEXT NOT NULL DEFAULT '',
+	confirmed     INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS memories_path ON memories(path);`
+
+// ftsSchema returns the DDL for the external-content full-text index and
+// the triggers that keep it in sync with the memories table.
+func ftsSchema(fts string) string {
+	if fts == FTS5 {
+		return `CREATE VIRTUAL TABLE memories_fts USING fts5(content, content='memories', content_rowid='rowid');
+CREATE TRIGGER memories_ai AFTER INSERT ON memories BEGIN
+	INSERT INTO memories_fts(rowid, content) VALUES (new.rowid, new.content);
+END;
+CREATE TRIGGER memories_ad AFTER DELETE ON memories BEGIN
+	INSERT INTO memories_fts(memories_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+END;
+CREATE TRIGGER memories_au AFTER UPDATE ON memories BEGIN
+	INSERT INTO memories_fts(memories_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+	INSERT INTO memories_fts(rowid, content) VALUES (new.rowid, new.content);
+END;
+INSERT INTO memories_fts(memories_fts) VALUES ('rebuild');`
+	}
+	return `CREATE VIRTUAL TABLE memories_fts USING fts4(content, content='memories');
+CREATE TRIGGER memories_bu BEFORE UPDATE ON memories BEGIN
+	DELETE FROM memories_fts WHERE docid = old.rowid;
+END;
+CREATE TRIGGER memories_bd BEFORE DELETE ON memories BEGIN
+	DELETE FROM memories_fts WHERE docid = old.rowid;
+END;
+CREATE TRIGGER memories_au AFTER UPDATE ON memories BEGIN
+	INSERT INTO memories_fts(docid, content) VALUES (new.rowid, new.content);
+END;
+CREATE TRIGGER memories_ai AFTER INSERT ON memories BEGIN
+	INSERT INTO memories_fts(docid, content) VALUES (new.rowid, new.content);
+END;
+INSERT INTO memories_fts(memories_fts) VALUES ('rebuild');`
+}
+
+// migrations returns the memory database schema history.
+func migrations(fts, dir string) *migration.Registry {
+	r := migration.NewRegistry()
+	r.Add(1, "create memories table", memoriesSchema)
+	r.Add(2, "create "+fts+" index", ftsSchema(fts))
+	r.AddFunc(3, "import memory files", func(db *sql.DB) error { return importFiles(db, dir) })
+	r.Add(4, "track vector index sync", vecSyncSchema)
+	r.AddFunc(5, "track retractions", func(db *sql.DB) error { return addRetractions(db, dir) })
+	return r
+}
+
+// importFiles copies the markdown and JSONL memories under dir into the
+// memories table. Files keep their paths, so they remain a valid export.
+func importFiles(db *sql.DB, dir string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(dir, p)
+		if relErr != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		switch {
+		case strings.HasSuffix(rel, ".md"):
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", rel, err)
+			}
+			e := ParseEntry(data, rel)
+			if e.Type == "" {
+				e.Type = typeFromPath(rel)
+			}
+			_, err = tx.Exec(`INSERT OR IGNORE INTO memories (`+importColumns+`) VALUES (`+importPlaceholders+`)`, entryArgs(e)[:importArgs]...)
+			return err
+		case strings.HasSuffix(rel, ".jsonl"):
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", rel, err)
+			}
+			for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+				if line == "" {
+					continue
+				}
+				if _, err := tx.Exec(insertSessionSQL, newSessionID(), rel, "", line); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// typeFromPath derives the memory type of a legacy file from its directory.
+func typeFromPath(rel string) string {
+	_, _, inner := SplitTier(rel)
+	dir, _, _ := strings.Cut(inner, "/")
+	for t, d := range kindDirs {
+		if d == dir {
+			return t
+		}
+	}
+	return strings.TrimSuffix(dir, "s")
+}
+
+const (
+	entryColumns      = importColumns + `, retracted_at, retract_reason`
+	entryPlaceholders = importPlaceholders + `, ?, ?`
+	// importColumns are the columns of the table the file import (version
+	// 3) writes to; later migrations add the rest.
+	importColumns      = `id, path, type, slug, version, supersedes, superseded_by, valid_from, valid_to, is_current, created, content, confidence, hits, last_accessed, confirmed`
+	importPlaceholders = `?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?`
+	importArgs         = 16
+	insertSessionSQL   = `INSERT INTO memories (id, path, type, created, content) VALUES (?, ?, 'session', ?, ?)`
+)
+
+func entryArgs(e Entry) []any {
+	return []any{e.ID, e.Path, e.Type, e.Slug, e.Version, e.Supersedes, e.SupersededBy,
+		e.ValidFrom, e.ValidTo, e.IsCurrent, e.Created, e.Content, e.Confidence, e.Hits,
+		e.LastAccessed, e.Confirmed, e.RetractedAt, e.RetractReason}
+}
+
+func scanDBEntry(row interface{ Scan(...any) error }) (Entry, error) {
+	var e Entry
+	err := row.Scan(&e.ID, &e.Path, &e.Type, &e.Slug, &e.Version, &e.Supersedes, &e.SupersededBy,
+		&e.ValidFrom, &e.ValidTo, &e.IsCurrent, &e.Created, &e.Content, &e.Confidence, &e.Hits,
+		&e.LastAccessed, &e.Confirmed, &e.RetractedAt, &e.RetractReason)
+	return e, err
+}
+
+func newSessionID() string {
+	return "ses-" + uuid.New().String()
+}
+
+// putEntry inserts or replaces a markdown memory row.
+func (d *DB) putEntry(e Entry) error {
+	err := d.queue.Submit(context.Background(),
+		`INSERT INTO memories (`+entryColumns+`) VALUES (`+entryPlaceholders+`)
+		ON CONFLICT(id) DO UPDATE SET path = excluded.path, type = excluded.type, slug = excluded.slug,
+			version = excluded.version, supersedes = excluded.supersedes, superseded_by = excluded.superseded_by,
+			valid_from = excluded.valid_from, valid_to = excluded.valid_to, is_current = excluded.is_current,
+			created = excluded.created, content = excluded.content, confidence = excluded.confidence,
+			hits = excluded.hits, last_accessed = excluded.last_accessed, confirmed = excluded.confirmed,
+			retracted_at = excluded.retracted_at, retract_reason = excluded.retract_reason,
+			vec_sync_status = CASE WHEN memories.content != excluded.content OR memories.is_current != excluded.is_current
+				THEN 'PENDING' ELSE memories.vec_sync_status END`,
+		entryArgs(e)...)
+	if err != nil {
+		return fmt.Errorf("memory: write %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// putSession appends a session record, a JSON line, to the session at rel.
+func (d *DB) putSession(rel, created, line string) error {
+	if err := d.queue.Submit(context.Background(), insertSessionSQL, newSessionID(), rel, created, line); err != nil {
+		return fmt.Errorf("memory: write %s: %w", rel, err)
+	}
+	return nil
+}
+
+// remove deletes the memory with the given ID, or every row at the given
+// path, and reports how many rows were removed.
+func (d *DB) remove(ref string) (int, error) {
+	var n int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM memories WHERE id = ? OR path = ?`, ref, ref).Scan(&n); err != nil {
+		return 0, fmt.Errorf("memory: delete %s: %w", ref, err)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if err := d.queue.Submit(context.Background(), `DELETE FROM memories WHERE id = ? OR path = ?`, ref, ref); err != nil {
+		return 0, fmt.Errorf("memory: delete %s: %w", ref, err)
+	}
+	return n, nil
+}
+
+// entries returns every markdown memory row ordered by path.
+func (d *DB) entries() ([]Entry, error) {
+	rows, err := d.db.Query(`SELECT ` + entryColumns + ` FROM memories WHERE type != 'session' ORDER BY path, rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("memory: list: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scanDBEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("memory: scan: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// entry returns the markdown memory whose column (id or path) equals value.
+func (d *DB) entry(column, value string) (Entry, bool, error) {
+	e, err := scanDBEntry(d.db.QueryRow(
+		`SELECT `+entryColumns+` FROM memories WHERE type != 'session' AND `+column+` = ? ORDER BY rowid DESC LIMIT 1`, value))
+	if err == sql.ErrNoRows {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("memory: get %s: %w", value, err)
+	}
+	return e, true, nil
+}
+
+// sessions returns the JSONL content of every session log by path.
+func (d *DB) sessions() (map[string]string, error) {
+	rows, err := d.db.Query(`SELECT path, content FROM memories WHERE type = 'session' ORDER BY rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("memory: list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	logs := make(map[string]string)
+	for rows.Next() {
+		var rel, line string
+		if err := rows.Scan(&rel, &line); err != nil {
+			return nil, fmt.Errorf("memory: scan: %w", err)
+		}
+		logs[rel] += line + "\n"
+	}
+	return logs, rows.Err()
+}
+
+// search runs a full-text query over current memories, best match first.
+// Scores are BM25 normalized so the best match scores 1; each path is
+// reported once.
+func (d *DB) search(keyword string) ([]SearchResult, error) {
+	query := ftsQuery(keyword)
+	if query == "" {
+		return nil, nil
+	}
+
+	var sqlStr string
+	if d.fts == FTS5 {
+		sqlStr = `SELECT m.path, snippet(memories_fts, 0, '**', '**', '...', 16), bm25(memories_fts)
+			FROM memories_fts JOIN memories m ON m.rowid = memories_fts.rowid
+			WHERE memories_fts MATCH ? AND m.is_current = 1`
+	} else {
+		sqlStr = `SELECT m.path, snippet(memories_fts, '**', '**', '...', 0, 16), matchinfo(memories_fts, 'pcnalx')
+			FROM memories_fts JOIN memories m ON m.rowid = memories_fts.docid
+			WHERE memories_fts MATCH ? AND m.is_current = 1`
+	}
+	rows, err := d.db.Query(sqlStr, query)
+	if err != nil {
+		return nil, fmt.Errorf("memory: search: %w", err)
+	}
+	defer rows.Close()
+
+	type hit struct {
+		SearchResult
+		rank float64 // lower is better, as returned by FTS5 bm25()
+	}
+	var hits []hit
+	for rows.Next() {
+		var h hit
+		if d.fts == FTS5 {
+			err = rows.Scan(&h.Path, &h.Snippet, &h.rank)
+		} else {
+			var info []byte
+			err = rows.Scan(&h.Path, &h.Snippet, &info)
+			h.rank = -bm25Matchinfo(info)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("memory: scan: %w", err)
+		}
+		_, _, inner := SplitTier(h.Path)
+		h.Type, _, _ = strings.Cut(inner, "/")
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("memory: search: %w", err)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].rank < hits[j].rank })
+	seen := make(map[string]bool, len(hits))
+	var results []SearchResult
+	for _, h := range hits {
+		if seen[h.Path] {
+			continue
+		}
+		seen[h.Path] = true
+		h.Score = 1
+		if best := hits[0].rank; best < 0 {
+			h.Score = h.rank / best
+		}
+		results = append(results, h.SearchResult)
+	}
+	return results, nil
+}
+
+// ftsQuery turns free text into a full-text query matching every word as
+// a prefix. Words are lowercased so they never read as query operators.
+func ftsQuery(keyword string) string {
+	words := strings.FieldsFunc(strings.ToLower(keyword), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + "*"
+	}
+	return strings.Join(words, " ")
+}
+
+// bm25Matchinfo computes the BM25 score of a row from an FTS4
+// matchinfo(..., 'pcnalx') blob. Higher is better.
+func bm25Matchinfo(info []byte) float64 {
+	if len(info)%4 != 0 || len(info) < 12 {
+		return 0
+	}
+	v := make([]float64, len(info)/4)
+	for i := range v {
+		v[i] = float64(binary.NativeEndian.Uint32(info[i*4:]))
+	}
+	p, c := int(v[0]), int(v[1])
+	if len(v) < 3+2*c+3*p*c {
+		return 0
+	}
+	n := v[2]
+	avg, length, x := v[3:3+c], v[3+c:3+2*c], v[3+2*c:]
+
+	score := 0.0
+	for i := 0; i < p; i++ {
+		for j := 0; j < c; j++ {
+			k := 3 * (i*c + j)
+			tf, docs := x[k], x[k+2]
+			if tf == 0 {
+				continue
+			}
+			idf := math.Max(math.Log((n-docs+0.5)/(docs+0.5)), 1e-6)
+			norm := 1 - bm25B
+			if avg[j] > 0 {
+				norm += bm25B * length[j] / avg[j]
+			}
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+	return score
+}
+
+// snapshotFiles renders the database as the path -> content map a
+// file-backed store would produce.
+func (d *DB) snapshotFiles() (map[string]string, error) {
+	files, err := d.sessions()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := d.entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		files[e.Path] = renderEntry(e)
+	}
+	return files, nil
+}
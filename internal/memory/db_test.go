@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDBStore(t *testing.T, dir string, export bool) *Store {
+	t.Helper()
+	db, err := OpenDB(filepath.Join(t.TempDir(), "memory.db"), dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	store, err := NewDBStore(dir, db, export)
+	require.NoError(t, err)
+	return store
+}
+
+func TestOpenDBImportsFiles(t *testing.T) {
+	dir := t.TempDir()
+	files, err := NewStore(dir)
+	require.NoError(t, err)
+	e, err := files.SaveEntry("fact", "cache", "The cache backend is redis.")
+	require.NoError(t, err)
+	require.NoError(t, files.SaveSession("s1", "deploy", "deployed the api"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "decisions", "legacy.md"), []byte("# legacy\n\nUse postgres."), 0644))
+	want, err := files.Snapshot()
+	require.NoError(t, err)
+
+	dbPath := filepath.Join(t.TempDir(), "memory.db")
+	db, err := OpenDB(dbPath, dir)
+	require.NoError(t, err)
+	store, err := NewDBStore(dir, db, false)
+	require.NoError(t, err)
+
+	got, err := store.Get(e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "The cache backend is redis.", got.Content)
+	legacy, ok := store.Lookup("decisions/legacy.md")
+	require.True(t, ok)
+	assert.Equal(t, "decision", legacy.Type)
+
+	snap, err := store.Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, want.Files["sessions/s1.jsonl"], snap.Files["sessions/s1.jsonl"])
+	assert.Equal(t, want.Files[e.Path], snap.Files[e.Path])
+
+	// Reopening does not import the files again.
+	require.NoError(t, db.Close())
+	db, err = OpenDB(dbPath, dir)
+	require.NoError(t, err)
+	defer db.Close()
+	store, err = NewDBStore(dir, db, false)
+	require.NoError(t, err)
+	entries, err := store.List()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestDBStoreSearchRanksByBM25(t *testing.T) {
+	store := openTestDBStore(t, t.TempDir(), false)
+	assert.Equal(t, FTS4, store.db.FTS(), "this SQLite build has no FTS5")
+
+	_, err := store.SaveEntry("fact", "one", "Redis caches sessions. Redis is fast and redis is simple.")
+	require.NoError(t, err)
+	_, err = store.SaveEntry("fact", "two", "The deploy pipeline mentions redis once among many other unrelated words here.")
+	require.NoError(t, err)
+	_, err = store.SaveEntry("decision", "three", "We chose postgres.")
+	require.NoError(t, err)
+
+	results, err := store.Search("Redis")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Contains(t, results[0].Snippet, "**Redis**")
+	assert.Equal(t, "facts", results[0].Type)
+	assert.Equal(t, 1.0, results[0].Score)
+	assert.Less(t, results[1].Score, 1.0)
+	assert.Greater(t, results[1].Score, 0.0)
+
+	// Words are matched as prefixes, all of them required.
+	results, err = store.Search("postg chose")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "decisions", results[0].Type)
+
+	results, err = store.Search("  ")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestDBStoreVersionsAndExport(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestDBStore(t, dir, false)
+
+	v1, err := store.SaveEntry("decision", "db", "Use mysql.")
+	require.NoError(t, err)
+	v2, err := store.Update(v1.ID, "Use postgres.")
+	require.NoError(t, err)
+	require.NoError(t, store.RecordHit(v2.Path))
+
+	chain, err := store.History(v2.ID)
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+	assert.False(t, chain[0].IsCurrent)
+	assert.Equal(t, 1, chain[1].Hits)
+
+	results, err := store.Search("use")
+	require.NoError(t, err)
+	require.Len(t, results, 1, "superseded versions are not searched")
+	assert.Equal(t, v2.Path, results[0].Path)
+
+	_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(v2.Path)))
+	assert.True(t, os.IsNotExist(err), "no files without export")
+
+	exported := openTestDBStore(t, t.TempDir(), true)
+	e, err := exported.SaveEntry("fact", "x", "exported")
+	require.NoError(t, err)
+	require.NoError(t, exported.SaveSession("s", "t", "r"))
+	assert.FileExists(t, filepath.Join(exported.dir, filepath.FromSlash(e.Path)))
+	assert.FileExists(t, filepath.Join(exported.dir, "sessions", "s.jsonl"))
+}
+
+func TestDBStoreDelete(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestDBStore(t, dir, true)
+	e, err := store.SaveEntry("fact", "gone", "temporary note")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Delete(e.Path))
+	_, err = store.Get(e.ID)
+	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, filepath.FromSlash(e.Path)))
+	results, err := store.Search("temporary")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	assert.Error(t, store.Delete("facts/missing.md"))
+}
+
+func TestBM25Matchinfo(t *testing.T) {
+	blob := func(v ...uint32) []byte {
+		b := make([]byte, 4*len(v))
+		for i, x := range v {
+			binary.NativeEndian.PutUint32(b[i*4:], x)
+		}
+		return b
+	}
+	// One phrase, one column, 10 docs averaging 10 tokens, a 10-token row.
+	rare := bm25Matchinfo(blob(1, 1, 10, 10, 10, 2, 2, 1))
+	common := bm25Matchinfo(blob(1, 1, 10, 10, 10, 2, 20, 8))
+	assert.Greater(t, rare, common)
+	assert.Greater(t, common, 0.0)
+	assert.Equal(t, 0.0, bm25Matchinfo([]byte{1, 2}))
+}
+
+func TestFTSQuery(t *testing.T) {
+	assert.Equal(t, "redis* or* cache*", ftsQuery(`Redis OR "cache"`))
+	assert.Equal(t, "", ftsQuery("-- !"))
+}
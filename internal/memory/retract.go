@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const retractionSchema = `ALTER TABLE memories ADD COLUMN retracted_at TEXT NOT NULL DEFAULT '';
+ALTER TABLE memories ADD COLUMN retract_reason TEXT NOT NULL DEFAULT '';`
+
+// addRetractions adds the retraction columns and fills them in for the
+// memory files under dir that were retracted before the database existed;
+// the file import (version 3) predates the columns.
+func addRetractions(db *sql.DB, dir string) error {
+	if _, err := db.Exec(retractionSchema); err != nil {
+		return err
+	}
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(p, ".md") {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		e := ParseEntry(data, filepath.ToSlash(rel))
+		if e.RetractedAt == "" {
+			return nil
+		}
+		_, err = db.Exec(`UPDATE memories SET retracted_at = ?, retract_reason = ? WHERE id = ?`,
+			e.RetractedAt, e.RetractReason, e.ID)
+		return err
+	})
+}
+
+// Retract withdraws a memory that turned out to be wrong. Unlike an
+// update it has no successor: the version is closed, recorded as retracted
+// with the reason, and no longer found by search or included in context.
+func (s *Store) Retract(id, reason string) (Entry, error) {
+	e, err := s.Get(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	if e.RetractedAt != "" {
+		return Entry{}, fmt.Errorf("memory: entry %s was already retracted at %s", e.ID, e.RetractedAt)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	e.RetractedAt = now
+	e.RetractReason = oneLine(reason)
+	if e.IsCurrent {
+		e.IsCurrent = false
+		e.ValidTo = now
+	}
+	if err := s.rewriteEntry(e); err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+// Retracted returns the entries among ids that have been retracted, keyed
+// by ID. Unknown IDs are ignored.
+func (s *Store) Retracted(ids []string) (map[string]Entry, error) {
+	out := make(map[string]Entry)
+	if len(ids) == 0 {
+		return out, nil
+	}
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	entries, err := s.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if want[e.ID] && e.RetractedAt != "" {
+			out[e.ID] = e
+		}
+	}
+	return out, nil
+}
+
+// oneLine folds whitespace so a value fits on one frontmatter line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetract(t *testing.T) {
+	for _, backend := range []string{"sqlite", "files"} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := NewStore(dir)
+			require.NoError(t, err)
+			if backend == "sqlite" {
+				store = openTestDBStore(t, dir, true)
+			}
+
+			e, err := store.SaveEntry("fact", "port", "The API listens on 8080.")
+			require.NoError(t, err)
+			other, err := store.SaveEntry("fact", "cache", "The cache is redis.")
+			require.NoError(t, err)
+
+			r, err := store.Retract(e.ID, "port changed to\n9090")
+			require.NoError(t, err)
+			assert.False(t, r.IsCurrent)
+			assert.NotEmpty(t, r.ValidTo)
+
+			got, err := store.Get(e.ID)
+			require.NoError(t, err)
+			assert.Equal(t, r.RetractedAt, got.RetractedAt)
+			assert.Equal(t, "port changed to 9090", got.RetractReason)
+
+			data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(e.Path)))
+			require.NoError(t, err)
+			parsed := ParseEntry(data, e.Path)
+			assert.Equal(t, "port changed to 9090", parsed.RetractReason)
+
+			results, err := store.Search("listens")
+			require.NoError(t, err)
+			assert.Empty(t, results)
+
+			retracted, err := store.Retracted([]string{e.ID, other.ID, "mem-unknown"})
+			require.NoError(t, err)
+			assert.Len(t, retracted, 1)
+			assert.Contains(t, retracted, e.ID)
+
+			_, err = store.Retract(e.ID, "again")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRetractionSurvivesImport(t *testing.T) {
+	dir := t.TempDir()
+	files, err := NewStore(dir)
+	require.NoError(t, err)
+	e, err := files.SaveEntry("decision", "orm", "Use an ORM.")
+	require.NoError(t, err)
+	_, err = files.Retract(e.ID, "we dropped it")
+	require.NoError(t, err)
+
+	store := openTestDBStore(t, dir, false)
+	got, err := store.Get(e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "we dropped it", got.RetractReason)
+	assert.False(t, got.IsCurrent)
+}
@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Memory tiers, narrowest first. Global memories live at the top of the
+// store, as they always have; project memories under projects/<key>/ and
+// session memories under namespaces/<name>/, each with the usual
+// decisions/facts/incidents/sessions layout.
+const (
+	TierSession = "session"
+	TierProject = "project"
+	TierGlobal  = "global"
+)
+
+// DefaultTierWeights scales search scores per tier so that, at equal
+// relevance, memories closer to the current work rank first.
+var DefaultTierWeights = map[string]float64{
+	TierSession: 1.0,
+	TierProject: 1.0,
+	TierGlobal:  0.8,
+}
+
+// Scope selects which tiers a store reads and where new memories go.
+// The zero Scope reads and writes the global tier only.
+type Scope struct {
+	Project   string             // project key (see ProjectKey); "" disables the project tier
+	Namespace string             // session namespace; "" disables the session tier
+	Weights   map[string]float64 // search score multiplier per tier; missing tiers weigh 1
+}
+
+// SetScope sets the tiers the store reads and writes. New entries are
+// written to the project tier when a project is set, otherwise to the
+// global tier; session logs go to the session tier when a namespace is set.
+func (s *Store) SetScope(sc Scope) {
+	s.scope = sc
+}
+
+// ProjectKey derives the key of the project rooted at root: the
+// directory's base name and a short hash of its absolute path, so two
+// checkouts named alike stay apart.
+func ProjectKey(root string) string {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = root
+	}
+	sum := sha256.Sum256([]byte(filepath.ToSlash(abs)))
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '-'
+		}
+		return r
+	}, filepath.Base(abs))
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// SplitTier splits a path relative to the store into its tier, the tier
+// key (project key or namespace, "" for global) and the path within the
+// tier.
+func SplitTier(rel string) (tier, key, inner string) {
+	for prefix, t := range map[string]string{"projects/": TierProject, "namespaces/": TierSession} {
+		if rest, ok := strings.CutPrefix(rel, prefix); ok {
+			if k, in, ok := strings.Cut(rest, "/"); ok {
+				return t, k, in
+			}
+		}
+	}
+	return TierGlobal, "", rel
+}
+
+// tierPrefix is the directory, relative to the store, of a tier.
+func tierPrefix(tier, key string) string {
+	switch {
+	case tier == TierProject && key != "":
+		return "projects/" + key + "/"
+	case tier == TierSession && key != "":
+		return "namespaces/" + key + "/"
+	}
+	return ""
+}
+
+// Visible reports whether the memory at rel is in a tier the store reads.
+func (s *Store) Visible(rel string) bool {
+	tier, key, _ := SplitTier(filepath.ToSlash(rel))
+	switch tier {
+	case TierProject:
+		return key == s.scope.Project
+	case TierSession:
+		return key == s.scope.Namespace
+	}
+	return true
+}
+
+// TierWeight returns the search score multiplier of the tier holding rel.
+func (s *Store) TierWeight(rel string) float64 {
+	tier, _, _ := SplitTier(filepath.ToSlash(rel))
+	if w, ok := s.scope.Weights[tier]; ok {
+		return w
+	}
+	return 1
+}
+
+// entryPrefix is the tier directory new entries are written to.
+func (s *Store) entryPrefix() string {
+	if s.scope.Project != "" {
+		return tierPrefix(TierProject, s.scope.Project)
+	}
+	return ""
+}
+
+// sessionPrefix is the tier directory session logs are written to.
+func (s *Store) sessionPrefix() string {
+	return tierPrefix(TierSession, s.scope.Namespace)
+}
+
+// weigh applies tier weights to search results and re-sorts them, best
+// first. Results from tiers the store does not read are dropped.
+func (s *Store) weigh(results []SearchResult) []SearchResult {
+	kept := results[:0]
+	for _, r := range results {
+		if !s.Visible(r.Path) {
+			continue
+		}
+		r.Tier, _, _ = SplitTier(r.Path)
+		r.Score *= s.TierWeight(r.Path)
+		kept = append(kept, r)
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Score > kept[j].Score })
+	return kept
+}
+
+// Promote moves a current project memory to the global tier: the global
+// copy becomes the next version and the project version is superseded by
+// it, so the history stays one chain.
+func (s *Store) Promote(id string) (Entry, error) {
+	old, err := s.Get(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	if tier, _, _ := SplitTier(old.Path); tier != TierProject {
+		return Entry{}, fmt.Errorf("memory: entry %s is a %s memory; only project memories can be promoted", id, tier)
+	}
+	if !old.IsCurrent {
+		return Entry{}, fmt.Errorf("memory: entry %s is superseded by %s", id, old.SupersededBy)
+	}
+	return s.successor(old, old.Content, tierPrefix(TierGlobal, ""))
+}
+
+// Contradiction is a memory name (type and slug) that is current in more
+// than one project, or in a project and the global tier, with different
+// content.
+type Contradiction struct {
+	Type    string
+	Slug    string
+	Entries []Entry // one per tier and project, ordered by path
+}
+
+// Contradictions compares the current project and global memories of
+// every project, not only those in scope, and returns each name whose
+// content disagrees between them.
+func (s *Store) Contradictions() ([]Contradiction, error) {
+	entries, err := s.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	type name struct{ typ, slug string }
+	groups := make(map[name]map[string]Entry) // name -> project key ("" = global) -> latest entry
+	for _, e := range entries {
+		tier, key, _ := SplitTier(e.Path)
+		if !e.IsCurrent || e.Slug == "" || tier == TierSession {
+			continue
+		}
+		n := name{e.Type, strings.ToLower(e.Slug)}
+		if groups[n] == nil {
+			groups[n] = make(map[string]Entry)
+		}
+		if prev, ok := groups[n][key]; !ok || prev.Created < e.Created {
+			groups[n][key] = e
+		}
+	}
+
+	var out []Contradiction
+	for n, byKey := range groups {
+		if len(byKey) < 2 {
+			continue
+		}
+		c := Contradiction{Type: n.typ, Slug: n.slug}
+		contents := make(map[string]bool)
+		for _, e := range byKey {
+			c.Entries = append(c.Entries, e)
+			contents[normalizeContent(e.Content)] = true
+		}
+		if len(contents) < 2 {
+			continue
+		}
+		sort.Slice(c.Entries, func(i, j int) bool { return c.Entries[i].Path < c.Entries[j].Path })
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return out[i].Type < out[j].Type
+		}
+		return out[i].Slug < out[j].Slug
+	})
+	return out, nil
+}
+
+// normalizeContent ignores case, whitespace and trailing punctuation so
+// that copies of one fact compare equal.
+func normalizeContent(s string) string {
+	return strings.TrimRight(strings.ToLower(strings.Join(strings.Fields(s), " ")), ".!")
+}
+
+// successor writes content as the next version of old under the tier
+// directory prefix and retires old.
+func (s *Store) successor(old Entry, content, prefix string) (Entry, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	next := Entry{
+		ID:         newEntryID(),
+		Type:       old.Type,
+		Slug:       old.Slug,
+		Version:    old.Version + 1,
+		Supersedes: old.ID,
+		ValidFrom:  now,
+		IsCurrent:  true,
+		Created:    now,
+		Content:    content,
+		Confidence: old.EffectiveConfidence(time.Now()),
+		Confirmed:  old.Confirmed,
+	}
+	if err := s.writeEntry(&next, prefix); err != nil {
+		return Entry{}, err
+	}
+	if err := s.retire(old, next.ID, now); err != nil {
+		return Entry{}, err
+	}
+	return next, nil
+}
@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitTier(t *testing.T) {
+	tier, key, inner := SplitTier("projects/apex-1a2b3c4d/facts/x.md")
+	assert.Equal(t, []string{TierProject, "apex-1a2b3c4d", "facts/x.md"}, []string{tier, key, inner})
+	tier, key, inner = SplitTier("namespaces/term2/sessions/s.jsonl")
+	assert.Equal(t, []string{TierSession, "term2", "sessions/s.jsonl"}, []string{tier, key, inner})
+	tier, key, inner = SplitTier("facts/x.md")
+	assert.Equal(t, []string{TierGlobal, "", "facts/x.md"}, []string{tier, key, inner})
+}
+
+func TestProjectKey(t *testing.T) {
+	a := ProjectKey("/src/one/apex")
+	assert.True(t, strings.HasPrefix(a, "apex-"))
+	assert.Equal(t, a, ProjectKey("/src/one/apex/"))
+	assert.NotEqual(t, a, ProjectKey("/src/two/apex"))
+}
+
+func TestScopeIsolatesProjects(t *testing.T) {
+	for _, backend := range []string{"sqlite", "files"} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := NewStore(dir)
+			require.NoError(t, err)
+			if backend == "sqlite" {
+				store = openTestDBStore(t, dir, false)
+			}
+
+			global, err := store.SaveEntry("fact", "db", "Staging database is postgres.")
+			require.NoError(t, err)
+			assert.Equal(t, "facts/", global.Path[:6])
+
+			store.SetScope(Scope{Project: "alpha"})
+			alpha, err := store.SaveEntry("fact", "db", "Alpha database is sqlite.")
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(alpha.Path, "projects/alpha/facts/"))
+
+			results, err := store.Search("database")
+			require.NoError(t, err)
+			assert.Len(t, results, 2)
+
+			store.SetScope(Scope{Project: "beta"})
+			results, err = store.Search("database")
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			assert.Equal(t, global.Path, results[0].Path)
+			assert.Equal(t, TierGlobal, results[0].Tier)
+
+			entries, err := store.List()
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+			all, err := store.ListAll()
+			require.NoError(t, err)
+			assert.Len(t, all, 2)
+
+			snap, err := store.Snapshot()
+			require.NoError(t, err)
+			assert.NotContains(t, snap.Files, alpha.Path)
+
+			// Updates stay in the tier of the memory they replace.
+			next, err := store.Update(alpha.ID, "Alpha database is postgres.")
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(next.Path, "projects/alpha/facts/"))
+		})
+	}
+}
+
+func TestScopeWeightsAndNamespaces(t *testing.T) {
+	store := openTestDBStore(t, t.TempDir(), false)
+	_, err := store.SaveEntry("fact", "ci", "CI runs on github actions.")
+	require.NoError(t, err)
+	store.SetScope(Scope{
+		Project:   "alpha",
+		Namespace: "term1",
+		Weights:   map[string]float64{TierGlobal: 0.5},
+	})
+	project, err := store.SaveEntry("fact", "ci", "CI runs on github actions.")
+	require.NoError(t, err)
+	require.NoError(t, store.SaveSession("s1", "fix ci", "ok"))
+
+	results, err := store.Search("ci")
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, TierGlobal, results[2].Tier, "global weighed down")
+	assert.InDelta(t, results[0].Score/2, results[2].Score, 1e-9)
+	var paths []string
+	for _, r := range results {
+		paths = append(paths, r.Path)
+	}
+	assert.Contains(t, paths, project.Path)
+	assert.Contains(t, paths, "namespaces/term1/sessions/s1.jsonl")
+
+	store.SetScope(Scope{Project: "alpha", Namespace: "term2"})
+	results, err = store.Search("ci")
+	require.NoError(t, err)
+	assert.Len(t, results, 2, "other terminal's session is hidden")
+}
+
+func TestPromote(t *testing.T) {
+	dir := t.TempDir()
+	store := openTestDBStore(t, dir, true)
+	store.SetScope(Scope{Project: "alpha"})
+	e, err := store.SaveEntry("decision", "lint", "Use golangci-lint.")
+	require.NoError(t, err)
+
+	g, err := store.Promote(e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "decisions/", g.Path[:10])
+	assert.Equal(t, 2, g.Version)
+	assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(g.Path)))
+
+	store.SetScope(Scope{Project: "beta"})
+	results, err := store.Search("golangci")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, g.Path, results[0].Path)
+
+	chain, err := store.History(g.ID)
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+	assert.Equal(t, e.ID, chain[0].ID)
+
+	_, err = store.Promote(e.ID)
+	assert.Error(t, err, "already superseded")
+	_, err = store.Promote(g.ID)
+	assert.Error(t, err, "already global")
+}
+
+func TestContradictions(t *testing.T) {
+	store := openTestDBStore(t, t.TempDir(), false)
+	store.SetScope(Scope{Project: "alpha"})
+	_, err := store.SaveEntry("fact", "Auth-Service", "Auth service listens on 8080.")
+	require.NoError(t, err)
+	_, err = store.SaveEntry("fact", "cache", "Cache is redis.")
+	require.NoError(t, err)
+	store.SetScope(Scope{Project: "beta"})
+	_, err = store.SaveEntry("fact", "auth-service", "Auth service listens on 9090.")
+	require.NoError(t, err)
+	_, err = store.SaveEntry("fact", "cache", "cache is  Redis")
+	require.NoError(t, err)
+
+	conflicts, err := store.Contradictions()
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "auth-service", conflicts[0].Slug)
+	require.Len(t, conflicts[0].Entries, 2)
+	assert.True(t, strings.HasPrefix(conflicts[0].Entries[0].Path, "projects/alpha/"))
+}
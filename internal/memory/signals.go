@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Confidence tuning. A memory gains HitBoost each time it is used in a
+// prompt and loses 5% per full week without use; confirmed memories stay at
+// 1.0.
+const (
+	DefaultConfidence = 0.5
+	HitBoost          = 0.05
+	WeeklyDecay       = 0.95
+)
+
+const week = 7 * 24 * time.Hour
+
+// LastUsed returns when the entry was last accessed, falling back to the
+// start of its validity window. The zero time means unknown.
+func (e Entry) LastUsed() time.Time {
+	for _, ts := range []string{e.LastAccessed, e.ValidFrom, e.Created} {
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// EffectiveConfidence returns the stored confidence decayed by WeeklyDecay
+// for every full week since the entry was last used. Decay is computed on
+// read, so it never compounds across repeated evaluations.
+func (e Entry) EffectiveConfidence(now time.Time) float64 {
+	if e.Confirmed {
+		return 1.0
+	}
+	last := e.LastUsed()
+	if last.IsZero() || !now.After(last) {
+		return e.Confidence
+	}
+	weeks := math.Floor(now.Sub(last).Hours() / week.Hours())
+	return e.Confidence * math.Pow(WeeklyDecay, weeks)
+}
+
+// RecordHit marks the memory at ref (an ID or a path relative to the store)
+// as used: its hit count grows, last_accessed becomes now, and its
+// confidence becomes its effective confidence plus HitBoost, capped at 1.0.
+// Session logs have no metadata and are ignored.
+func (s *Store) RecordHit(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok, err := s.lookup(ref)
+	if err != nil || !ok {
+		return err
+	}
+	now := time.Now().UTC()
+	e.Confidence = clampConfidence(e.EffectiveConfidence(now) + HitBoost)
+	e.Hits++
+	e.LastAccessed = now.Format(time.RFC3339)
+	return s.rewriteEntry(e)
+}
+
+// Confirm records a user confirmation: confidence is pinned to 1.0 and no
+// longer decays.
+func (s *Store) Confirm(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok, err := s.lookup(ref)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNotFound(ref)
+	}
+	e.Confidence = 1.0
+	e.Confirmed = true
+	return s.rewriteEntry(e)
+}
+
+// Lookup returns the markdown memory at ref, an ID or a path relative to
+// the store.
+func (s *Store) Lookup(ref string) (Entry, bool) {
+	e, ok, err := s.lookup(ref)
+	return e, ok && err == nil
+}
+
+func (s *Store) lookup(ref string) (Entry, bool, error) {
+	if strings.HasSuffix(ref, ".md") && s.db != nil {
+		return s.db.entry("path", filepath.ToSlash(ref))
+	}
+	if strings.HasSuffix(ref, ".md") {
+		e, err := s.readEntry(filepath.Join(s.dir, filepath.FromSlash(ref)))
+		if err != nil {
+			return Entry{}, false, nil
+		}
+		return e, true, nil
+	}
+	if strings.HasSuffix(ref, ".jsonl") {
+		return Entry{}, false, nil
+	}
+	e, err := s.Get(ref)
+	if err != nil {
+		return Entry{}, false, nil
+	}
+	return e, true, nil
+}
+
+func clampConfidence(c float64) float64 {
+	return math.Max(0, math.Min(1, c))
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffectiveConfidenceDecaysWeekly(t *testing.T) {
+	last := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := Entry{Confidence: 0.8, LastAccessed: last.Format(time.RFC3339)}
+
+	assert.Equal(t, 0.8, e.EffectiveConfidence(last.Add(6*24*time.Hour)), "no decay within the first week")
+	assert.InDelta(t, 0.8*0.95, e.EffectiveConfidence(last.Add(7*24*time.Hour)), 1e-9)
+	assert.InDelta(t, 0.8*0.95*0.95, e.EffectiveConfidence(last.Add(20*24*time.Hour)), 1e-9)
+
+	e.Confirmed = true
+	assert.Equal(t, 1.0, e.EffectiveConfidence(last.AddDate(1, 0, 0)))
+}
+
+func TestLastUsedFallsBackToValidFrom(t *testing.T) {
+	e := Entry{ValidFrom: "2026-02-01T00:00:00Z"}
+	assert.Equal(t, 2026, e.LastUsed().Year())
+	assert.True(t, Entry{}.LastUsed().IsZero())
+}
+
+func TestRecordHit(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	e, err := store.SaveEntryWithConfidence("fact", "lint", "golangci-lint runs in CI", 0.5)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RecordHit(e.Path))
+	require.NoError(t, store.RecordHit(e.ID))
+
+	got, err := store.Get(e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Hits)
+	assert.InDelta(t, 0.6, got.Confidence, 1e-9)
+	assert.NotEmpty(t, got.LastAccessed)
+
+	require.NoError(t, store.RecordHit("sessions/run.jsonl"), "session logs are ignored")
+}
+
+func TestRecordHitCapsAtOne(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	e, err := store.SaveEntryWithConfidence("fact", "go", "Go 1.25", 0.98)
+	require.NoError(t, err)
+	require.NoError(t, store.RecordHit(e.ID))
+	got, _ := store.Get(e.ID)
+	assert.Equal(t, 1.0, got.Confidence)
+}
+
+func TestConfirmPinsConfidence(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	e, err := store.SaveEntryWithConfidence("decision", "db", "Use Postgres", 0.4)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Confirm(e.ID))
+	got, _ := store.Get(e.ID)
+	assert.True(t, got.Confirmed)
+	assert.Equal(t, 1.0, got.Confidence)
+
+	next, err := store.Update(e.ID, "Use Postgres 16")
+	require.NoError(t, err)
+	assert.True(t, next.Confirmed, "confirmation carries over to new versions")
+
+	assert.Error(t, store.Confirm("mem-missing"))
+}
+
+func TestLegacyEntryDefaultConfidence(t *testing.T) {
+	e := ParseEntry([]byte("---\ntype: fact\nslug: x\n---\n\n# x\n\nbody\n"), "facts/x.md")
+	assert.Equal(t, DefaultConfidence, e.Confidence)
+	assert.Equal(t, "body", e.Content)
+	assert.Equal(t, "x", e.ID)
+}
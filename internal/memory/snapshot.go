@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxSnippetChars bounds the text returned per result by Snapshot.Rank.
+const maxSnippetChars = 1000
+
+// Snapshot is an immutable view of the memory store at one point in time.
+// Its Version is the SHA-256 of its canonical JSON encoding, so archiving
+// the encoding in the artifact store makes the version its artifact hash.
+type Snapshot struct {
+	Version string
+	Files   map[string]string // slash-separated path relative to the store -> content
+}
+
+// RankedResult is a memory entry matched by Snapshot.Rank.
+type RankedResult struct {
+	Path  string
+	Type  string
+	Text  string
+	Score int
+}
+
+// Snapshot reads every memory in the tiers the store reads, from the
+// database or the files, into an in-memory Snapshot.
+func (s *Store) Snapshot() (*Snapshot, error) {
+	files, err := s.Files()
+	if err != nil {
+		return nil, err
+	}
+	for rel := range files {
+		if !s.Visible(rel) {
+			delete(files, rel)
+		}
+	}
+	return newSnapshot(files)
+}
+
+// Files returns every memory in the store, across all tiers, keyed by its
+// slash-separated path relative to the store. A file-backed store returns
+// the files as they are on disk; a database-backed one renders them.
+func (s *Store) Files() (map[string]string, error) {
+	if s.db != nil {
+		return s.db.snapshotFiles()
+	}
+	files := make(map[string]string)
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if !strings.HasSuffix(path, ".md") && !strings.HasSuffix(path, ".jsonl") {
+			return nil
+		}
+		rel, _ := filepath.Rel(s.dir, path)
+		data, readErr := os.ReadFile(path)
+		if readErr != nil {
+			return fmt.Errorf("memory: snapshot %s: %w", path, readErr)
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Version returns the version of the store's current contents.
+func (s *Store) Version() (string, error) {
+	snap, err := s.Snapshot()
+	if err != nil {
+		return "", err
+	}
+	return snap.Version, nil
+}
+
+// ParseSnapshot decodes a snapshot previously encoded with Marshal and
+// checks that its content still matches version.
+func ParseSnapshot(data []byte, version string) (*Snapshot, error) {
+	var files map[string]string
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("memory: parse snapshot: %w", err)
+	}
+	snap, err := newSnapshot(files)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" && snap.Version != version {
+		return nil, fmt.Errorf("memory: snapshot content is version %s, expected %s", snap.Version, version)
+	}
+	return snap, nil
+}
+
+// Marshal returns the canonical JSON encoding of the snapshot.
+func (sn *Snapshot) Marshal() ([]byte, error) {
+	// encoding/json sorts map keys, which makes the output canonical.
+	data, err := json.Marshal(sn.Files)
+	if err != nil {
+		return nil, fmt.Errorf("memory: marshal snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// Rank returns up to topK current entries matching the words of query, best
+// first.
+// An entry's score is the number of distinct query words it contains; ties
+// are broken by path so results are deterministic for a given snapshot.
+func (sn *Snapshot) Rank(query string, topK int) []RankedResult {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []RankedResult
+	for path, content := range sn.Files {
+		if superseded(content) {
+			continue
+		}
+		_, _, inner := SplitTier(path)
+		lower := strings.ToLower(content)
+		score := 0
+		for _, t := range terms {
+			if strings.Contains(lower, t) {
+				score++
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		results = append(results, RankedResult{
+			Path:  path,
+			Type:  strings.SplitN(inner, "/", 2)[0],
+			Text:  snippet(content),
+			Score: score,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Path < results[j].Path
+	})
+	if topK > 0 && len(results) > topK {
+		results = results[:topK]
+	}
+	return results
+}
+
+func newSnapshot(files map[string]string) (*Snapshot, error) {
+	snap := &Snapshot{Files: files}
+	data, err := snap.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	snap.Version = hex.EncodeToString(sum[:])
+	return snap, nil
+}
+
+// queryTerms splits a query into distinct lowercase words of at least three
+// characters.
+func queryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r > 127)
+	}) {
+		if len([]rune(w)) < 3 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// snippet strips markdown frontmatter and truncates the content.
+func snippet(content string) string {
+	text := content
+	if strings.HasPrefix(text, "---\n") {
+		if end := strings.Index(text[4:], "\n---\n"); end >= 0 {
+			text = text[4+end+5:]
+		}
+	}
+	text = strings.TrimSpace(text)
+	if r := []rune(text); len(r) > maxSnippetChars {
+		text = string(r[:maxSnippetChars]) + "..."
+	}
+	return text
+}
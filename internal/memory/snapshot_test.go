@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotVersionTracksContent(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	empty, err := store.Version()
+	require.NoError(t, err)
+
+	require.NoError(t, store.SaveFact("go-version", "We use Go 1.25"))
+	v1, err := store.Version()
+	require.NoError(t, err)
+	assert.NotEqual(t, empty, v1)
+
+	v1again, err := store.Version()
+	require.NoError(t, err)
+	assert.Equal(t, v1, v1again)
+}
+
+func TestSnapshotVersionIsHashOfEncoding(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.SaveDecision("db", "Use SQLite"))
+
+	snap, err := store.Snapshot()
+	require.NoError(t, err)
+	data, err := snap.Marshal()
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), snap.Version)
+
+	parsed, err := ParseSnapshot(data, snap.Version)
+	require.NoError(t, err)
+	assert.Equal(t, snap.Files, parsed.Files)
+
+	_, err = ParseSnapshot(data, "deadbeef")
+	assert.Error(t, err)
+}
+
+func TestSnapshotRank(t *testing.T) {
+	snap, err := newSnapshot(map[string]string{
+		"facts/a.md":     "---\ntype: fact\n---\n\n# sqlite\n\nWe store runtime state in SQLite with WAL.",
+		"facts/b.md":     "Deploys use docker.",
+		"decisions/c.md": "SQLite chosen over Postgres for runtime state.",
+	})
+	require.NoError(t, err)
+
+	results := snap.Rank("Migrate the runtime SQLite state", 10)
+	require.Len(t, results, 2)
+	assert.Equal(t, "decisions/c.md", results[0].Path)
+	assert.Equal(t, "decisions", results[0].Type)
+	assert.Equal(t, "facts/a.md", results[1].Path)
+	assert.NotContains(t, results[1].Text, "type: fact")
+
+	assert.Len(t, snap.Rank("runtime", 1), 1)
+	assert.Empty(t, snap.Rank("a an", 10))
+}
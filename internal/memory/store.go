@@ -4,19 +4,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Store struct {
-	dir string
+	dir    string
+	mu     sync.Mutex // serializes read-modify-write of entry metadata
+	db     *DB        // source of truth when set; files are then an export view
+	export bool       // with db set, also write markdown and JSONL files
+	scope  Scope      // tiers read and written, see scope.go
 }
 
 type SearchResult struct {
 	Path    string
 	Type    string
 	Snippet string
+	Score   float64 // relevance, 1 for the best match, times the tier weight
+	Tier    string  // TierSession, TierProject or TierGlobal
 }
 
 type sessionRecord struct {
@@ -26,7 +34,7 @@ type sessionRecord struct {
 }
 
 func NewStore(dir string) (*Store, error) {
-	for _, sub := range []string{"decisions", "facts", "sessions"} {
+	for _, sub := range []string{"decisions", "facts", "incidents", "sessions"} {
 		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
 			return nil, err
 		}
@@ -34,35 +42,42 @@ func NewStore(dir string) (*Store, error) {
 	return &Store{dir: dir}, nil
 }
 
-func (s *Store) SaveDecision(slug string, content string) error {
-	return s.saveMarkdown("decisions", slug, "decision", content)
+// NewDBStore creates a store backed by db. Memories are read from and
+// written to the database; when export is set, every write is mirrored to
+// the markdown and JSONL files under dir.
+func NewDBStore(dir string, db *DB, export bool) (*Store, error) {
+	s, err := NewStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.db = db
+	s.export = export
+	return s, nil
 }
 
-func (s *Store) SaveFact(slug string, content string) error {
-	return s.saveMarkdown("facts", slug, "fact", content)
+// writesFiles reports whether writes go to the files under dir.
+func (s *Store) writesFiles() bool {
+	return s.db == nil || s.export
 }
 
-func (s *Store) saveMarkdown(subdir, slug, memType, content string) error {
-	ts := time.Now().UTC().Format("20060102-150405")
-	filename := fmt.Sprintf("%s-%s.md", ts, slug)
-	path := filepath.Join(s.dir, subdir, filename)
-
-	md := fmt.Sprintf(`---
-type: %s
-created: %s
-slug: %s
----
-
-# %s
+func (s *Store) SaveDecision(slug string, content string) error {
+	_, err := s.SaveEntry("decision", slug, content)
+	return err
+}
 
-%s
-`, memType, time.Now().UTC().Format(time.RFC3339), slug, slug, content)
+func (s *Store) SaveFact(slug string, content string) error {
+	_, err := s.SaveEntry("fact", slug, content)
+	return err
+}
 
-	return os.WriteFile(path, []byte(md), 0644)
+func (s *Store) SaveIncident(slug string, content string) error {
+	_, err := s.SaveEntry("incident", slug, content)
+	return err
 }
 
 func (s *Store) SaveSession(sessionID, task, result string) error {
-	path := filepath.Join(s.dir, "sessions", sessionID+".jsonl")
+	rel := s.sessionPrefix() + "sessions/" + sessionID + ".jsonl"
+	path := filepath.Join(s.dir, filepath.FromSlash(rel))
 
 	record := sessionRecord{
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
@@ -74,7 +89,18 @@ func (s *Store) SaveSession(sessionID, task, result string) error {
 	if err != nil {
 		return err
 	}
+	if s.db != nil {
+		if err := s.db.putSession(rel, record.Timestamp, string(data)); err != nil {
+			return err
+		}
+	}
+	if !s.writesFiles() {
+		return nil
+	}
 	data = append(data, '\n')
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
 
 	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -86,7 +112,16 @@ func (s *Store) SaveSession(sessionID, task, result string) error {
 	return err
 }
 
+// Search finds current memories containing keyword in the tiers the store
+// reads. With a database it is a full-text query ranked by BM25; otherwise
+// every file is scanned for the keyword as a substring and all matches
+// score 1. Scores are then multiplied by the tier weights.
 func (s *Store) Search(keyword string) ([]SearchResult, error) {
+	if s.db != nil {
+		results, err := s.db.search(keyword)
+		return s.weigh(results), err
+	}
+
 	var results []SearchResult
 	lower := strings.ToLower(keyword)
 
@@ -103,15 +138,18 @@ func (s *Store) Search(keyword string) ([]SearchResult, error) {
 			return nil
 		}
 
+		// Superseded versions are kept for history but not searched.
+		if strings.HasSuffix(path, ".md") && superseded(string(data)) {
+			return nil
+		}
+
 		content := strings.ToLower(string(data))
 		if strings.Contains(content, lower) {
 			// Determine type from parent dir
 			rel, _ := filepath.Rel(s.dir, path)
-			parts := strings.SplitN(rel, string(filepath.Separator), 2)
-			memType := "unknown"
-			if len(parts) > 0 {
-				memType = parts[0]
-			}
+			rel = filepath.ToSlash(rel)
+			_, _, inner := SplitTier(rel)
+			memType, _, _ := strings.Cut(inner, "/")
 
 			// Extract snippet (first line containing keyword, up to 120 chars)
 			snippet := ""
@@ -129,10 +167,107 @@ func (s *Store) Search(keyword string) ([]SearchResult, error) {
 				Path:    rel,
 				Type:    memType,
 				Snippet: snippet,
+				Score:   1,
 			})
 		}
 		return nil
 	})
 
-	return results, err
+	return s.weigh(results), err
+}
+
+// Delete removes the memory with the given ID, or every memory at the
+// given path relative to the store, from the database and the files.
+func (s *Store) Delete(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rel := filepath.ToSlash(ref)
+	if e, ok, err := s.lookup(ref); err == nil && ok {
+		rel = e.Path
+	}
+	found := false
+	if s.db != nil {
+		n, err := s.db.remove(ref)
+		if err != nil {
+			return err
+		}
+		found = n > 0
+	}
+	if strings.HasSuffix(rel, ".md") || strings.HasSuffix(rel, ".jsonl") {
+		err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(rel)))
+		switch {
+		case err == nil:
+			found = true
+		case !os.IsNotExist(err):
+			return fmt.Errorf("memory: delete %s: %w", rel, err)
+		}
+	}
+	if !found {
+		return errNotFound(ref)
+	}
+	return nil
+}
+
+// PutFile stores content, a markdown memory or a JSONL session log, as
+// the memory at rel, replacing whatever was there. A file-backed store
+// writes the file as given; a database-backed one parses it into rows the
+// way the migration from files does.
+func (s *Store) PutFile(rel, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rel = filepath.ToSlash(rel)
+	md := strings.HasSuffix(rel, ".md")
+	if !md && !strings.HasSuffix(rel, ".jsonl") {
+		return fmt.Errorf("memory: %s is not a memory file", rel)
+	}
+	if s.db == nil {
+		return s.writeFile(rel, content)
+	}
+	if _, err := s.db.remove(rel); err != nil {
+		return err
+	}
+	if md {
+		e := ParseEntry([]byte(content), rel)
+		if e.Type == "" {
+			e.Type = typeFromPath(rel)
+		}
+		if e.Slug == "" {
+			e.Slug = strings.TrimSuffix(path.Base(rel), ".md")
+		}
+		return s.rewriteEntry(e)
+	}
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var record sessionRecord
+		_ = json.Unmarshal([]byte(line), &record)
+		if err := s.db.putSession(rel, record.Timestamp, line); err != nil {
+			return err
+		}
+	}
+	if !s.writesFiles() {
+		return nil
+	}
+	return s.writeFile(rel, content)
+}
+
+// writeFile writes content to the file at rel under the store.
+func (s *Store) writeFile(rel, content string) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("memory: write %s: %w", rel, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("memory: write %s: %w", rel, err)
+	}
+	return nil
+}
+
+// superseded reports whether markdown memory content is marked as no
+// longer current.
+func superseded(content string) bool {
+	return strings.Contains(content, "\nis_current: false\n")
 }
@@ -45,6 +45,20 @@ func TestSaveFact(t *testing.T) {
 	assert.Len(t, files, 1)
 }
 
+func TestSaveIncident(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+
+	err = store.SaveIncident("flaky-ci", "CI timed out on the race detector; raised the timeout to 10m.")
+	require.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "incidents", "*-flaky-ci.md"))
+	require.Len(t, files, 1)
+	content, _ := os.ReadFile(files[0])
+	assert.Contains(t, string(content), "type: incident")
+}
+
 func TestSaveSession(t *testing.T) {
 	dir := t.TempDir()
 	store, err := NewStore(dir)
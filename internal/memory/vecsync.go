@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Vector sync states. Every row of the memories table carries one; it says
+// whether the vector index agrees with the row: a current memory has a
+// vector for its content, a superseded or deleted one has none.
+const (
+	VecPending = "PENDING"
+	VecSynced  = "SYNCED"
+	VecFailed  = "FAILED"
+)
+
+const vecSyncSchema = `ALTER TABLE memories ADD COLUMN vec_sync_status TEXT NOT NULL DEFAULT 'PENDING';
+ALTER TABLE memories ADD COLUMN vec_error TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS memories_vec_sync ON memories(vec_sync_status);`
+
+// ErrNoVectorSync is returned by the vector sync methods of a store without
+// a database.
+var ErrNoVectorSync = errors.New("memory: vector sync requires the sqlite backend")
+
+// VectorTask is one vector index ID (a memory path) that needs syncing.
+// Text is what to embed; it is empty when nothing current is stored at
+// Path any more and the vector should be deleted.
+type VectorTask struct {
+	Path string
+	Text string
+
+	upTo int64 // highest rowid covered by Text
+}
+
+// PendingVectors returns up to limit paths with PENDING rows, oldest first.
+func (s *Store) PendingVectors(limit int) ([]VectorTask, error) {
+	if s.db == nil {
+		return nil, ErrNoVectorSync
+	}
+	rows, err := s.db.db.Query(
+		`SELECT path FROM memories WHERE vec_sync_status = 'PENDING' GROUP BY path ORDER BY MIN(rowid) LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("memory: pending vectors: %w", err)
+	}
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("memory: scan: %w", err)
+		}
+		paths = append(paths, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("memory: pending vectors: %w", err)
+	}
+
+	tasks := make([]VectorTask, 0, len(paths))
+	for _, p := range paths {
+		t, err := s.db.vectorTask(p)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// vectorTask builds the task for path. Its text is a session's JSONL log
+// or a current entry's content, empty if neither exists.
+func (d *DB) vectorTask(path string) (VectorTask, error) {
+	t := VectorTask{Path: path}
+	if err := d.db.QueryRow(`SELECT MAX(rowid) FROM memories WHERE path = ?`, path).Scan(&t.upTo); err != nil {
+		return t, fmt.Errorf("memory: vector task %s: %w", path, err)
+	}
+	rows, err := d.db.Query(
+		`SELECT type, content FROM memories WHERE path = ? AND is_current = 1 AND rowid <= ? ORDER BY rowid`, path, t.upTo)
+	if err != nil {
+		return t, fmt.Errorf("memory: vector task %s: %w", path, err)
+	}
+	defer rows.Close()
+
+	var sb strings.Builder
+	for rows.Next() {
+		var typ, content string
+		if err := rows.Scan(&typ, &content); err != nil {
+			return t, fmt.Errorf("memory: scan: %w", err)
+		}
+		if typ != "session" {
+			// Entry paths are unique in practice; the latest write wins.
+			sb.Reset()
+			sb.WriteString(content)
+			continue
+		}
+		sb.WriteString(content)
+		sb.WriteString("\n")
+	}
+	t.Text = sb.String()
+	return t, rows.Err()
+}
+
+// MarkVector records the outcome of a task on the rows it covered; rows
+// added at the same path since PendingVectors stay PENDING. errMsg is kept
+// with FAILED rows and cleared otherwise.
+func (s *Store) MarkVector(t VectorTask, status, errMsg string) error {
+	if s.db == nil {
+		return ErrNoVectorSync
+	}
+	if status != VecFailed {
+		errMsg = ""
+	}
+	err := s.db.queue.Submit(context.Background(),
+		`UPDATE memories SET vec_sync_status = ?, vec_error = ? WHERE path = ? AND rowid <= ?`,
+		status, errMsg, t.Path, t.upTo)
+	if err != nil {
+		return fmt.Errorf("memory: mark vector %s: %w", t.Path, err)
+	}
+	return nil
+}
+
+// RequeueVectors marks the rows at the given paths, or every row when no
+// path is given, PENDING so they are synced again.
+func (s *Store) RequeueVectors(paths ...string) error {
+	if s.db == nil {
+		return ErrNoVectorSync
+	}
+	ctx := context.Background()
+	if len(paths) == 0 {
+		if err := s.db.queue.Submit(ctx, `UPDATE memories SET vec_sync_status = 'PENDING', vec_error = ''`); err != nil {
+			return fmt.Errorf("memory: requeue vectors: %w", err)
+		}
+		return nil
+	}
+	for _, p := range paths {
+		if err := s.db.queue.Submit(ctx,
+			`UPDATE memories SET vec_sync_status = 'PENDING', vec_error = '' WHERE path = ?`, p); err != nil {
+			return fmt.Errorf("memory: requeue vector %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// VectorSyncStatus returns the sync state of every path holding a current
+// memory. A path with any PENDING row is PENDING, else any FAILED row
+// makes it FAILED.
+func (s *Store) VectorSyncStatus() (map[string]string, error) {
+	if s.db == nil {
+		return nil, ErrNoVectorSync
+	}
+	rows, err := s.db.db.Query(`SELECT path, vec_sync_status FROM memories WHERE is_current = 1`)
+	if err != nil {
+		return nil, fmt.Errorf("memory: vector status: %w", err)
+	}
+	defer rows.Close()
+
+	rank := map[string]int{VecSynced: 0, VecFailed: 1, VecPending: 2}
+	status := make(map[string]string)
+	for rows.Next() {
+		var p, st string
+		if err := rows.Scan(&p, &st); err != nil {
+			return nil, fmt.Errorf("memory: scan: %w", err)
+		}
+		if cur, ok := status[p]; !ok || rank[st] > rank[cur] {
+			status[p] = st
+		}
+	}
+	return status, rows.Err()
+}
@@ -0,0 +1,426 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// kindDirs maps a memory type to the directory it is stored in.
+var kindDirs = map[string]string{
+	"decision": "decisions",
+	"fact":     "facts",
+	"incident": "incidents",
+}
+
+// Entry is a versioned markdown memory. Each version is its own file with
+// its own ID; versions of the same memory are linked through Supersedes and
+// SupersededBy. ValidTo is empty while the version is current.
+type Entry struct {
+	ID           string
+	Type         string
+	Slug         string
+	Version      int
+	Supersedes   string
+	SupersededBy string
+	ValidFrom    string
+	ValidTo      string
+	IsCurrent    bool
+	Created      string
+	Content      string
+	Path         string // relative to the store directory
+
+	// Usage signals, see signals.go.
+	Confidence   float64
+	Hits         int
+	LastAccessed string
+	Confirmed    bool
+
+	// Retraction, see retract.go. RetractedAt is empty unless the memory
+	// was withdrawn as wrong.
+	RetractedAt   string
+	RetractReason string
+}
+
+// SaveEntry writes a new memory of the given type ("decision", "fact" or
+// "incident") as version 1 with DefaultConfidence and returns it.
+func (s *Store) SaveEntry(memType, slug, content string) (Entry, error) {
+	return s.SaveEntryWithConfidence(memType, slug, content, DefaultConfidence)
+}
+
+// SaveEntryWithConfidence is SaveEntry with an explicit initial confidence.
+func (s *Store) SaveEntryWithConfidence(memType, slug, content string, confidence float64) (Entry, error) {
+	if _, ok := kindDirs[memType]; !ok {
+		return Entry{}, fmt.Errorf("memory: unknown type %q", memType)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	e := Entry{
+		ID:         newEntryID(),
+		Type:       memType,
+		Slug:       slug,
+		Version:    1,
+		ValidFrom:  now,
+		IsCurrent:  true,
+		Created:    now,
+		Content:    content,
+		Confidence: clampConfidence(confidence),
+	}
+	if err := s.writeEntry(&e, s.entryPrefix()); err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+// Get returns the memory with the given ID.
+func (s *Store) Get(id string) (Entry, error) {
+	if s.db != nil {
+		e, ok, err := s.db.entry("id", id)
+		if err != nil {
+			return Entry{}, err
+		}
+		if !ok {
+			return Entry{}, errNotFound(id)
+		}
+		return e, nil
+	}
+	entries, err := s.ListAll()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return Entry{}, errNotFound(id)
+}
+
+// ErrNotFound is wrapped by the errors of lookups and deletes that match
+// no memory.
+var ErrNotFound = errors.New("not found")
+
+func errNotFound(ref string) error {
+	return fmt.Errorf("memory: entry %s %w", ref, ErrNotFound)
+}
+
+// List returns every markdown memory in the tiers the store reads,
+// current or not, ordered by path.
+func (s *Store) List() ([]Entry, error) {
+	all, err := s.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	entries := all[:0]
+	for _, e := range all {
+		if s.Visible(e.Path) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// ListAll is List across every tier, project and namespace.
+func (s *Store) ListAll() ([]Entry, error) {
+	if s.db != nil {
+		return s.db.entries()
+	}
+	var entries []Entry
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		rel, _ := filepath.Rel(s.dir, path)
+		_, _, inner := SplitTier(filepath.ToSlash(rel))
+		dir, _, _ := strings.Cut(inner, "/")
+		if dir != "decisions" && dir != "facts" && dir != "incidents" {
+			return nil
+		}
+		e, err := s.readEntry(path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// Update replaces the content of a current memory with a new version that
+// supersedes it, and returns the new version. The new version starts from
+// the old one's effective confidence.
+func (s *Store) Update(id, content string) (Entry, error) {
+	old, err := s.Get(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	if !old.IsCurrent {
+		return Entry{}, fmt.Errorf("memory: entry %s is superseded by %s", id, old.SupersededBy)
+	}
+	tier, key, _ := SplitTier(old.Path)
+	return s.successor(old, content, tierPrefix(tier, key))
+}
+
+// Supersede marks oldID as replaced by the existing memory newID: the old
+// version's validity ends where the new one starts and the new version is
+// renumbered to follow it.
+func (s *Store) Supersede(oldID, newID string) error {
+	old, err := s.Get(oldID)
+	if err != nil {
+		return err
+	}
+	next, err := s.Get(newID)
+	if err != nil {
+		return err
+	}
+	if !old.IsCurrent {
+		return fmt.Errorf("memory: entry %s is superseded by %s", oldID, old.SupersededBy)
+	}
+	next.Supersedes = old.ID
+	next.Version = old.Version + 1
+	if err := s.rewriteEntry(next); err != nil {
+		return err
+	}
+	return s.retire(old, next.ID, next.ValidFrom)
+}
+
+// History returns the supersede chain containing id, oldest version first.
+// id may also be the entry's path relative to the store.
+func (s *Store) History(id string) ([]Entry, error) {
+	entries, err := s.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Entry, len(entries))
+	for _, e := range entries {
+		byID[e.ID] = e
+	}
+	start, ok := byID[id]
+	if !ok {
+		for _, e := range entries {
+			if e.Path == filepath.ToSlash(id) {
+				start, ok = e, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("memory: entry %s not found", id)
+	}
+
+	seen := map[string]bool{start.ID: true}
+	for start.Supersedes != "" {
+		prev, ok := byID[start.Supersedes]
+		if !ok || seen[prev.ID] {
+			break
+		}
+		seen[prev.ID] = true
+		start = prev
+	}
+
+	chain := []Entry{start}
+	for cur := start; cur.SupersededBy != ""; {
+		next, ok := byID[cur.SupersededBy]
+		if !ok || next.ID == start.ID || containsID(chain, next.ID) {
+			break
+		}
+		chain = append(chain, next)
+		cur = next
+	}
+	return chain, nil
+}
+
+// IsCurrent reports whether the memory file at rel (relative to the store)
+// is a current version. Session logs and unreadable files count as current.
+func (s *Store) IsCurrent(rel string) bool {
+	if !strings.HasSuffix(rel, ".md") {
+		return true
+	}
+	e, ok := s.Lookup(rel)
+	return !ok || e.IsCurrent
+}
+
+func containsID(entries []Entry, id string) bool {
+	for _, e := range entries {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func newEntryID() string {
+	return "mem-" + uuid.New().String()
+}
+
+// retire closes the validity window of old and points it at its successor.
+func (s *Store) retire(old Entry, successor, at string) error {
+	old.SupersededBy = successor
+	old.ValidTo = at
+	old.IsCurrent = false
+	return s.rewriteEntry(old)
+}
+
+// writeEntry creates a new file for e in the tier directory prefix and
+// sets e.Path.
+func (s *Store) writeEntry(e *Entry, prefix string) error {
+	ts := time.Now().UTC().Format("20060102-150405")
+	name := fmt.Sprintf("%s-%s.md", ts, e.Slug)
+	if e.Version > 1 {
+		name = fmt.Sprintf("%s-%s-v%d.md", ts, e.Slug, e.Version)
+	}
+	e.Path = prefix + kindDirs[e.Type] + "/" + name
+	return s.rewriteEntry(*e)
+}
+
+// rewriteEntry writes e to the database and, when the store writes files,
+// to its existing path.
+func (s *Store) rewriteEntry(e Entry) error {
+	if s.db != nil {
+		if err := s.db.putEntry(e); err != nil {
+			return err
+		}
+	}
+	if !s.writesFiles() {
+		return nil
+	}
+	path := filepath.Join(s.dir, filepath.FromSlash(e.Path))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("memory: write %s: %w", e.Path, err)
+	}
+	if err := os.WriteFile(path, []byte(renderEntry(e)), 0644); err != nil {
+		return fmt.Errorf("memory: write %s: %w", e.Path, err)
+	}
+	return nil
+}
+
+func renderEntry(e Entry) string {
+	retraction := ""
+	if e.RetractedAt != "" {
+		retraction = fmt.Sprintf("retracted_at: %s\nretract_reason: %s\n", e.RetractedAt, e.RetractReason)
+	}
+	return fmt.Sprintf(`---
+type: %s
+created: %s
+slug: %s
+id: %s
+version: %d
+supersedes: %s
+superseded_by: %s
+valid_from: %s
+valid_to: %s
+is_current: %t
+confidence: %s
+hits: %d
+last_accessed: %s
+confirmed: %t
+%s---
+
+# %s
+
+%s
+`, e.Type, e.Created, e.Slug, e.ID, e.Version, e.Supersedes, e.SupersededBy,
+		e.ValidFrom, e.ValidTo, e.IsCurrent, strconv.FormatFloat(e.Confidence, 'f', -1, 64),
+		e.Hits, e.LastAccessed, e.Confirmed, retraction, e.Slug, e.Content)
+}
+
+// readEntry reads and parses a markdown memory file under the store.
+func (s *Store) readEntry(path string) (Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, fmt.Errorf("memory: read %s: %w", path, err)
+	}
+	rel, _ := filepath.Rel(s.dir, path)
+	e := ParseEntry(data, filepath.ToSlash(rel))
+	return e, nil
+}
+
+// ParseEntry parses a markdown memory stored at rel. Files written before
+// versioning have no id; they are treated as current version 1 with the
+// file name as ID and DefaultConfidence.
+func ParseEntry(data []byte, rel string) Entry {
+	e := Entry{Path: rel, Version: 1, IsCurrent: true, Confidence: DefaultConfidence}
+
+	text := string(data)
+	body := text
+	if strings.HasPrefix(text, "---\n") {
+		if end := strings.Index(text[4:], "\n---\n"); end >= 0 {
+			parseFrontmatter(text[4:4+end], &e)
+			body = text[4+end+5:]
+		}
+	}
+	body = strings.TrimSpace(body)
+	if heading := "# " + e.Slug; e.Slug != "" && strings.HasPrefix(body, heading) {
+		body = strings.TrimSpace(strings.TrimPrefix(body, heading))
+	}
+	e.Content = body
+
+	if e.ID == "" {
+		e.ID = strings.TrimSuffix(path.Base(rel), ".md")
+	}
+	if e.ValidFrom == "" {
+		e.ValidFrom = e.Created
+	}
+	return e
+}
+
+func parseFrontmatter(fm string, e *Entry) {
+	for _, line := range strings.Split(fm, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "type":
+			e.Type = value
+		case "created":
+			e.Created = value
+		case "slug":
+			e.Slug = value
+		case "id":
+			e.ID = value
+		case "version":
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				e.Version = v
+			}
+		case "supersedes":
+			e.Supersedes = value
+		case "superseded_by":
+			e.SupersededBy = value
+		case "valid_from":
+			e.ValidFrom = value
+		case "valid_to":
+			e.ValidTo = value
+		case "is_current":
+			e.IsCurrent = value != "false"
+		case "confidence":
+			if c, err := strconv.ParseFloat(value, 64); err == nil {
+				e.Confidence = clampConfidence(c)
+			}
+		case "hits":
+			if h, err := strconv.Atoi(value); err == nil && h > 0 {
+				e.Hits = h
+			}
+		case "last_accessed":
+			e.LastAccessed = value
+		case "confirmed":
+			e.Confirmed = value == "true"
+		case "retracted_at":
+			e.RetractedAt = value
+		case "retract_reason":
+			e.RetractReason = value
+		}
+	}
+}
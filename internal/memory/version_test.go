@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveEntryFrontmatter(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	e, err := store.SaveEntry("fact", "api-timeout", "The API timeout is 30 seconds.")
+	require.NoError(t, err)
+	assert.NotEmpty(t, e.ID)
+	assert.Equal(t, 1, e.Version)
+	assert.True(t, e.IsCurrent)
+
+	got, err := store.Get(e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "The API timeout is 30 seconds.", got.Content)
+	assert.Equal(t, e.ValidFrom, got.ValidFrom)
+	assert.Equal(t, "", got.ValidTo)
+
+	_, err = store.SaveEntry("opinion", "x", "y")
+	assert.Error(t, err)
+}
+
+func TestUpdateCreatesChain(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	v1, err := store.SaveEntry("fact", "api-timeout", "The API timeout is 30 seconds.")
+	require.NoError(t, err)
+	v2, err := store.Update(v1.ID, "The API timeout is 60 seconds.")
+	require.NoError(t, err)
+	v3, err := store.Update(v2.ID, "The API timeout is 90 seconds.")
+	require.NoError(t, err)
+	assert.Equal(t, 3, v3.Version)
+
+	_, err = store.Update(v1.ID, "stale edit")
+	assert.Error(t, err, "only the current version can be updated")
+
+	chain, err := store.History(v2.ID)
+	require.NoError(t, err)
+	require.Len(t, chain, 3)
+	byPath, err := store.History(v3.Path)
+	require.NoError(t, err)
+	assert.Equal(t, chain, byPath)
+	assert.Equal(t, []string{v1.ID, v2.ID, v3.ID}, []string{chain[0].ID, chain[1].ID, chain[2].ID})
+	assert.False(t, chain[0].IsCurrent)
+	assert.Equal(t, v2.ID, chain[0].SupersededBy)
+	assert.Equal(t, chain[1].ValidFrom, chain[0].ValidTo)
+	assert.True(t, chain[2].IsCurrent)
+}
+
+func TestSearchReturnsCurrentOnly(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	v1, err := store.SaveEntry("fact", "redis-version", "Production runs Redis 6")
+	require.NoError(t, err)
+	v2, err := store.Update(v1.ID, "Production runs Redis 7")
+	require.NoError(t, err)
+
+	results, err := store.Search("redis")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, v2.Path, filepath.ToSlash(results[0].Path))
+	assert.False(t, store.IsCurrent(v1.Path))
+	assert.True(t, store.IsCurrent(v2.Path))
+
+	snap, err := store.Snapshot()
+	require.NoError(t, err)
+	ranked := snap.Rank("redis production", 10)
+	require.Len(t, ranked, 1)
+	assert.Equal(t, v2.Path, ranked[0].Path)
+}
+
+func TestSupersedeLinksExistingEntries(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	old, err := store.SaveEntry("decision", "cache", "Use memcache for sessions")
+	require.NoError(t, err)
+	next, err := store.SaveEntry("decision", "cache-new", "Use redis for sessions")
+	require.NoError(t, err)
+	require.NoError(t, store.Supersede(old.ID, next.ID))
+
+	chain, err := store.History(old.ID)
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+	assert.Equal(t, 2, chain[1].Version)
+	assert.Equal(t, old.ID, chain[1].Supersedes)
+	assert.Error(t, store.Supersede(old.ID, next.ID))
+}
+
+func TestLegacyEntryWithoutID(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+	legacy := "---\ntype: fact\ncreated: 2025-01-01T00:00:00Z\nslug: go-version\n---\n\n# go-version\n\nProject uses Go 1.22\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "facts", "20250101-000000-go-version.md"), []byte(legacy), 0644))
+
+	e, err := store.Get("20250101-000000-go-version")
+	require.NoError(t, err)
+	assert.True(t, e.IsCurrent)
+	assert.Equal(t, 1, e.Version)
+	assert.Equal(t, "2025-01-01T00:00:00Z", e.ValidFrom)
+
+	next, err := store.Update(e.ID, "Project uses Go 1.25")
+	require.NoError(t, err)
+	chain, err := store.History(next.ID)
+	require.NoError(t, err)
+	assert.Len(t, chain, 2)
+}
@@ -0,0 +1,88 @@
+package memport
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lyndonlyu/apex/internal/memory"
+)
+
+// JSONL archives hold one JSON object per line: a header with the format
+// version and export time, then one ExportEntry per line. They are written
+// and read an entry at a time, never holding the whole archive in memory.
+type jsonlHeader struct {
+	Format     string `json:"format"` // always "apex-memory-jsonl"
+	Version    string `json:"version"`
+	ExportedAt string `json:"exported_at"`
+}
+
+const jsonlFormat = "apex-memory-jsonl"
+
+// ExportJSONL streams the memories of store selected by opts to w as a
+// JSONL archive and returns the number of entries written.
+func ExportJSONL(w io.Writer, store *memory.Store, opts ExportOptions) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	err := enc.Encode(jsonlHeader{
+		Format:     jsonlFormat,
+		Version:    "1",
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = walk(store, opts, func(e ExportEntry) error {
+		n++
+		return enc.Encode(e)
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+// ReadJSONL reads a JSONL archive from r and calls fn for each entry in
+// order, stopping at the first error.
+func ReadJSONL(r io.Reader, fn func(ExportEntry) error) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var header jsonlHeader
+	if err := dec.Decode(&header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("memport: empty JSONL archive")
+		}
+		return fmt.Errorf("memport: read JSONL header: %w", err)
+	}
+	if header.Format != jsonlFormat {
+		return fmt.Errorf("memport: not a memory JSONL archive (format %q)", header.Format)
+	}
+	for i := 1; ; i++ {
+		var e ExportEntry
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("memport: read JSONL entry %d: %w", i, err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// ImportJSONL streams a JSONL archive from r into store.
+func ImportJSONL(store *memory.Store, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	im, err := newImporter(store, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := ReadJSONL(r, im.add); err != nil {
+		return nil, err
+	}
+	return im.finish()
+}
@@ -0,0 +1,39 @@
+package memport
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLRoundTrip(t *testing.T) {
+	src := seedMemDir(t)
+	var buf bytes.Buffer
+	n, err := ExportJSONL(&buf, fileStore(t, src), ExportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4, "header plus one line per entry")
+	assert.Contains(t, lines[0], `"format":"apex-memory-jsonl"`)
+
+	dst := t.TempDir()
+	result, err := ImportJSONL(fileStore(t, dst), bytes.NewReader(buf.Bytes()), ImportOptions{Strategy: MergeSkip})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Added)
+
+	content, err := os.ReadFile(filepath.Join(dst, "facts", "test-fact.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Test Fact\n\nProject uses Go 1.25.\n", string(content))
+}
+
+func TestReadJSONLErrors(t *testing.T) {
+	noop := func(ExportEntry) error { return nil }
+	assert.Error(t, ReadJSONL(strings.NewReader(""), noop))
+	assert.Error(t, ReadJSONL(strings.NewReader(`{"version":"1","entries":[]}`), noop), "a JSON archive is not JSONL")
+	assert.Error(t, ReadJSONL(strings.NewReader(`{"format":"apex-memory-jsonl","version":"1"}`+"\n{bad\n"), noop))
+}
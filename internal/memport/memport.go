@@ -4,17 +4,22 @@ import (
 	"encoding/json"
 	"errors"
 	"os"
-	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/redact"
 )
 
 // ExportEntry represents a single memory file in the export payload.
 type ExportEntry struct {
-	Key       string `json:"key"`        // relative path within memory dir
-	Value     string `json:"value"`      // full file content
-	Category  string `json:"category"`   // subdirectory name (decisions/facts/sessions)
-	CreatedAt string `json:"created_at"` // file mod time RFC3339
+	Key       string `json:"key"`            // path relative to the memory store
+	Value     string `json:"value"`          // full file content
+	Category  string `json:"category"`       // kind directory (decisions/facts/sessions)
+	CreatedAt string `json:"created_at"`     // creation time RFC3339, empty if unknown
+	Hash      string `json:"hash,omitempty"` // content identity, see ContentHash
 }
 
 // ExportData is the top-level envelope for an exported memory archive.
@@ -31,121 +36,143 @@ type MergeStrategy string
 const (
 	MergeSkip      MergeStrategy = "skip"
 	MergeOverwrite MergeStrategy = "overwrite"
+	// MergeThreeWay compares both sides with the content last imported for
+	// the key: a side that did not change since then yields to the other,
+	// and changes on both sides are conflicts, routed to a Stager.
+	MergeThreeWay MergeStrategy = "merge"
 )
 
 // ImportResult summarises what Import did.
 type ImportResult struct {
-	Added       int `json:"added"`
-	Skipped     int `json:"skipped"`
-	Overwritten int `json:"overwritten"`
+	Added       int      `json:"added"`
+	Skipped     int      `json:"skipped"`
+	Overwritten int      `json:"overwritten"`
+	Duplicates  int      `json:"duplicates"` // content already present under another key
+	Merged      int      `json:"merged"`     // local copy unchanged since the last import, updated
+	Conflicts   int      `json:"conflicts"`  // changed on both sides, local copy kept
+	Staged      []string `json:"staged,omitempty"`
+}
+
+// ExportOptions selects and transforms the files Export collects.
+type ExportOptions struct {
+	Categories []string         // kind directories, in any tier; empty means all
+	Since      time.Time        // zero means no lower bound
+	Until      time.Time        // zero means no upper bound
+	Redactor   *redact.Redactor // applied to every value when set
+}
+
+// Export collects every memory in store into an ExportData. If category
+// is non-empty, only memories in that kind directory are collected.
+func Export(store *memory.Store, category string) (*ExportData, error) {
+	var opts ExportOptions
+	if category != "" {
+		opts.Categories = []string{category}
+	}
+	return ExportWith(store, opts)
 }
 
-// Export walks memDir and collects all .md and .jsonl files into an ExportData.
-// If category is non-empty, only files under that subdirectory are collected.
-// A non-existent memDir returns an empty ExportData (not an error).
-func Export(memDir string, category string) (*ExportData, error) {
+// ExportWith is Export with category and date filters and redaction.
+func ExportWith(store *memory.Store, opts ExportOptions) (*ExportData, error) {
 	data := &ExportData{
 		Version:    "1",
 		ExportedAt: time.Now().UTC().Format(time.RFC3339),
 		Entries:    []ExportEntry{},
 	}
+	err := walk(store, opts, func(e ExportEntry) error {
+		data.Entries = append(data.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	data.Count = len(data.Entries)
+	return data, nil
+}
 
-	// Non-existent dir is not an error — just return empty.
-	if _, err := os.Stat(memDir); os.IsNotExist(err) {
-		return data, nil
+// walk calls fn, in key order, for every memory in store that opts
+// selects. Memories with no known creation time are left out when a date
+// filter is set.
+func walk(store *memory.Store, opts ExportOptions, fn func(ExportEntry) error) error {
+	files, err := store.Files()
+	if err != nil {
+		return err
 	}
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	err := filepath.Walk(memDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // skip unreadable entries
-		}
-		if info.IsDir() {
-			return nil
+	for _, key := range keys {
+		cat := category(key)
+		if len(opts.Categories) > 0 && !slices.Contains(opts.Categories, cat) {
+			continue
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext != ".md" && ext != ".jsonl" {
-			return nil
+		content := files[key]
+		created := createdAt(content)
+		if created.IsZero() && (!opts.Since.IsZero() || !opts.Until.IsZero()) {
+			continue
 		}
-
-		rel, err := filepath.Rel(memDir, path)
-		if err != nil {
-			return nil
+		if !opts.Since.IsZero() && created.Before(opts.Since) {
+			continue
 		}
-
-		// Determine category from first path component.
-		parts := strings.SplitN(rel, string(filepath.Separator), 2)
-		cat := ""
-		if len(parts) > 1 {
-			cat = parts[0]
+		if !opts.Until.IsZero() && created.After(opts.Until) {
+			continue
 		}
 
-		// Filter by category if requested.
-		if category != "" && cat != category {
-			return nil
+		value := content
+		if opts.Redactor != nil {
+			value = opts.Redactor.Redact(value)
 		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil
+		entry := ExportEntry{
+			Key:      key,
+			Value:    value,
+			Category: cat,
+			Hash:     ContentHash(value),
+		}
+		if !created.IsZero() {
+			entry.CreatedAt = created.UTC().Format(time.RFC3339)
+		}
+		if err := fn(entry); err != nil {
+			return err
 		}
-
-		data.Entries = append(data.Entries, ExportEntry{
-			Key:       rel,
-			Value:     string(content),
-			Category:  cat,
-			CreatedAt: info.ModTime().UTC().Format(time.RFC3339),
-		})
-
-		return nil
-	})
-	if err != nil {
-		return nil, err
 	}
+	return nil
+}
 
-	data.Count = len(data.Entries)
-	return data, nil
+// category is the kind directory of the memory at key, whatever its tier:
+// "facts" for both facts/x.md and projects/<key>/facts/x.md.
+func category(key string) string {
+	_, _, inner := memory.SplitTier(key)
+	dir, _, ok := strings.Cut(inner, "/")
+	if !ok {
+		return ""
+	}
+	return dir
 }
 
-// Import writes the entries from data into memDir according to the given
+// Import writes the entries from data into store according to the given
 // merge strategy. Returns counts of added, skipped, and overwritten files.
-func Import(memDir string, data *ExportData, strategy MergeStrategy) (*ImportResult, error) {
+func Import(store *memory.Store, data *ExportData, strategy MergeStrategy) (*ImportResult, error) {
+	return ImportWith(store, data, ImportOptions{Strategy: strategy})
+}
+
+// ImportWith is Import with a Stager for three-way merge conflicts.
+func ImportWith(store *memory.Store, data *ExportData, opts ImportOptions) (*ImportResult, error) {
 	if data == nil {
 		return nil, errors.New("memport: nil export data")
 	}
-
-	result := &ImportResult{}
-
+	im, err := newImporter(store, opts)
+	if err != nil {
+		return nil, err
+	}
 	for _, entry := range data.Entries {
-		fullPath := filepath.Join(memDir, entry.Key)
-
-		_, err := os.Stat(fullPath)
-		exists := err == nil
-
-		if exists {
-			switch strategy {
-			case MergeSkip:
-				result.Skipped++
-				continue
-			case MergeOverwrite:
-				if err := os.WriteFile(fullPath, []byte(entry.Value), 0644); err != nil {
-					return nil, err
-				}
-				result.Overwritten++
-			}
-		} else {
-			// File doesn't exist — create parent dirs and write.
-			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-				return nil, err
-			}
-			if err := os.WriteFile(fullPath, []byte(entry.Value), 0644); err != nil {
-				return nil, err
-			}
-			result.Added++
+		if err := im.add(entry); err != nil {
+			return nil, err
 		}
 	}
-
-	return result, nil
+	return im.finish()
 }
 
 // WriteFile serialises data as indented JSON and writes it to path.
@@ -5,10 +5,19 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/lyndonlyu/apex/internal/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// fileStore opens the file-backed memory store at dir.
+func fileStore(t *testing.T, dir string) *memory.Store {
+	t.Helper()
+	store, err := memory.NewStore(dir)
+	require.NoError(t, err)
+	return store
+}
+
 // helper: populate a temp memory directory with sample files.
 func seedMemDir(t *testing.T) string {
 	t.Helper()
@@ -18,7 +27,7 @@ func seedMemDir(t *testing.T) string {
 	require.NoError(t, os.MkdirAll(filepath.Join(dir, "decisions"), 0755))
 	require.NoError(t, os.WriteFile(
 		filepath.Join(dir, "decisions", "test-decision.md"),
-		[]byte("---\ntype: decision\nslug: test-decision\n---\n\n# Test Decision\n\nChose approach A over B.\n"),
+		[]byte("---\ntype: decision\ncreated: 2026-01-10T09:00:00Z\nslug: test-decision\n---\n\n# Test Decision\n\nChose approach A over B.\n"),
 		0644,
 	))
 
@@ -44,7 +53,7 @@ func seedMemDir(t *testing.T) string {
 func TestExportAll(t *testing.T) {
 	dir := seedMemDir(t)
 
-	data, err := Export(dir, "")
+	data, err := Export(fileStore(t, dir), "")
 	require.NoError(t, err)
 
 	assert.Equal(t, "1", data.Version)
@@ -58,8 +67,16 @@ func TestExportAll(t *testing.T) {
 		cats[e.Category] = true
 		assert.NotEmpty(t, e.Key)
 		assert.NotEmpty(t, e.Value)
-		assert.NotEmpty(t, e.CreatedAt)
 	}
+	created := map[string]string{}
+	for _, e := range data.Entries {
+		created[e.Key] = e.CreatedAt
+	}
+	assert.Equal(t, map[string]string{
+		"decisions/test-decision.md":  "2026-01-10T09:00:00Z",
+		"facts/test-fact.md":          "",
+		"sessions/test-session.jsonl": "2026-01-15T10:00:00Z",
+	}, created, "frontmatter or first session record; unknown is empty")
 	assert.True(t, cats["decisions"], "should contain decisions")
 	assert.True(t, cats["facts"], "should contain facts")
 	assert.True(t, cats["sessions"], "should contain sessions")
@@ -68,7 +85,7 @@ func TestExportAll(t *testing.T) {
 func TestExportByCategory(t *testing.T) {
 	dir := seedMemDir(t)
 
-	data, err := Export(dir, "decisions")
+	data, err := Export(fileStore(t, dir), "decisions")
 	require.NoError(t, err)
 
 	assert.Equal(t, 1, data.Count)
@@ -80,7 +97,7 @@ func TestExportByCategory(t *testing.T) {
 func TestExportEmpty(t *testing.T) {
 	dir := t.TempDir() // empty directory, no files
 
-	data, err := Export(dir, "")
+	data, err := Export(fileStore(t, dir), "")
 	require.NoError(t, err)
 
 	assert.Equal(t, 0, data.Count)
@@ -90,11 +107,11 @@ func TestExportEmpty(t *testing.T) {
 func TestImportNew(t *testing.T) {
 	// Export from a seeded dir, then import into a fresh dir.
 	srcDir := seedMemDir(t)
-	data, err := Export(srcDir, "")
+	data, err := Export(fileStore(t, srcDir), "")
 	require.NoError(t, err)
 
 	dstDir := t.TempDir()
-	result, err := Import(dstDir, data, MergeSkip)
+	result, err := Import(fileStore(t, dstDir), data, MergeSkip)
 	require.NoError(t, err)
 
 	assert.Equal(t, 3, result.Added)
@@ -111,11 +128,11 @@ func TestImportNew(t *testing.T) {
 
 func TestImportSkip(t *testing.T) {
 	dir := seedMemDir(t)
-	data, err := Export(dir, "")
+	data, err := Export(fileStore(t, dir), "")
 	require.NoError(t, err)
 
 	// Import into the same dir where files already exist — strategy=skip.
-	result, err := Import(dir, data, MergeSkip)
+	result, err := Import(fileStore(t, dir), data, MergeSkip)
 	require.NoError(t, err)
 
 	assert.Equal(t, 0, result.Added)
@@ -125,7 +142,7 @@ func TestImportSkip(t *testing.T) {
 
 func TestImportOverwrite(t *testing.T) {
 	dir := seedMemDir(t)
-	data, err := Export(dir, "")
+	data, err := Export(fileStore(t, dir), "")
 	require.NoError(t, err)
 
 	// Mutate entry values so we can verify overwrite actually wrote new content.
@@ -133,7 +150,7 @@ func TestImportOverwrite(t *testing.T) {
 		data.Entries[i].Value = "overwritten content for " + data.Entries[i].Key
 	}
 
-	result, err := Import(dir, data, MergeOverwrite)
+	result, err := Import(fileStore(t, dir), data, MergeOverwrite)
 	require.NoError(t, err)
 
 	assert.Equal(t, 0, result.Added)
@@ -150,7 +167,7 @@ func TestImportOverwrite(t *testing.T) {
 
 func TestWriteAndReadFile(t *testing.T) {
 	dir := seedMemDir(t)
-	original, err := Export(dir, "")
+	original, err := Export(fileStore(t, dir), "")
 	require.NoError(t, err)
 
 	// Write to a temp JSON file, then read it back.
@@ -175,14 +192,61 @@ func TestWriteAndReadFile(t *testing.T) {
 
 func TestImportNilData(t *testing.T) {
 	dir := t.TempDir()
-	result, err := Import(dir, nil, MergeSkip)
+	result, err := Import(fileStore(t, dir), nil, MergeSkip)
 	assert.Nil(t, result)
 	assert.EqualError(t, err, "memport: nil export data")
 }
 
-func TestExportNonExistentDir(t *testing.T) {
-	data, err := Export("/tmp/nonexistent-memport-dir-12345", "")
+func TestExportCategoryInAnyTier(t *testing.T) {
+	dir := seedMemDir(t)
+	project := filepath.Join(dir, "projects", "apex-1234", "facts")
+	require.NoError(t, os.MkdirAll(project, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "build.md"), []byte("Build with make.\n"), 0644))
+
+	data, err := Export(fileStore(t, dir), "facts")
+	require.NoError(t, err)
+	keys := map[string]string{}
+	for _, e := range data.Entries {
+		keys[e.Key] = e.Category
+	}
+	assert.Equal(t, map[string]string{
+		"facts/test-fact.md":                "facts",
+		"projects/apex-1234/facts/build.md": "facts",
+	}, keys)
+}
+
+func TestImportIntoDatabase(t *testing.T) {
+	data, err := Export(fileStore(t, seedMemDir(t)), "")
 	require.NoError(t, err)
-	assert.Equal(t, 0, data.Count)
-	assert.Empty(t, data.Entries)
+
+	dir := t.TempDir()
+	db, err := memory.OpenDB(filepath.Join(dir, "memory.db"), filepath.Join(dir, "memory"))
+	require.NoError(t, err)
+	defer db.Close()
+	store, err := memory.NewDBStore(filepath.Join(dir, "memory"), db, false)
+	require.NoError(t, err)
+
+	result, err := Import(store, data, MergeSkip)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Added)
+	assert.NoFileExists(t, filepath.Join(dir, "memory", "facts", "test-fact.md"), "the database is the store")
+
+	results, err := store.Search("approach")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "decisions/test-decision.md", results[0].Path)
+
+	// The database renders the memories with full metadata; they still
+	// match the archive, so importing it again adds nothing.
+	result, err = Import(store, data, MergeSkip)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Added)
+	assert.Equal(t, 3, result.Skipped)
+
+	again, err := Export(store, "")
+	require.NoError(t, err)
+	require.Equal(t, 3, again.Count)
+	for i, e := range again.Entries {
+		assert.Equal(t, data.Entries[i].Hash, e.Hash, e.Key)
+	}
 }
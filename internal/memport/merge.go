@@ -0,0 +1,217 @@
+package memport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lyndonlyu/apex/internal/memory"
+)
+
+// Stager receives the archive side of a three-way merge conflict for
+// review. *staging.Stager satisfies it.
+type Stager interface {
+	Stage(content, category, source string) (string, error)
+}
+
+// ImportOptions controls ImportWith and ImportJSONL.
+type ImportOptions struct {
+	Strategy MergeStrategy
+	// Stager, if set, receives conflicts under MergeThreeWay; without it
+	// they are only counted.
+	Stager Stager
+	// StatePath is the file recording, per key, the content hash last
+	// imported: the common base of a three-way merge. Without it every
+	// import starts with no base, and changes on both sides conflict.
+	StatePath string
+}
+
+// ContentHash identifies a memory by its content: the SHA-256 of the body
+// with any frontmatter and title heading removed and surrounding
+// whitespace trimmed, so the same memory saved under another name, with
+// other metadata, or rendered by a database-backed store matches.
+func ContentHash(value string) string {
+	sum := sha256.Sum256([]byte(body(value)))
+	return hex.EncodeToString(sum[:])
+}
+
+// body is the content of a memory file without its metadata.
+func body(value string) string {
+	return memory.ParseEntry([]byte(value), "").Content
+}
+
+// createdAt is the created time in a markdown memory's frontmatter or the
+// timestamp of a session log's first record, or zero if it has neither.
+func createdAt(value string) time.Time {
+	created := memory.ParseEntry([]byte(value), "").Created
+	if created == "" {
+		line, _, _ := strings.Cut(value, "\n")
+		var record struct {
+			Timestamp string `json:"timestamp"`
+		}
+		if json.Unmarshal([]byte(line), &record) == nil {
+			created = record.Timestamp
+		}
+	}
+	t, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// stagingCategory maps a memory kind directory to a staging category.
+func stagingCategory(dir string) string {
+	switch dir {
+	case "decisions", "facts", "incidents", "sessions":
+		return strings.TrimSuffix(dir, "s")
+	}
+	return "fact"
+}
+
+type syncState struct {
+	Synced map[string]string `json:"synced"` // key -> content hash last imported
+}
+
+// importer applies archive entries to a memory store one at a time, so
+// archives can be streamed.
+type importer struct {
+	store  *memory.Store
+	opts   ImportOptions
+	files  map[string]string // key -> content, for every memory in the store
+	hashes map[string]string // content hash -> key, for every memory in the store
+	state  syncState
+	result ImportResult
+}
+
+func newImporter(store *memory.Store, opts ImportOptions) (*importer, error) {
+	switch opts.Strategy {
+	case MergeSkip, MergeOverwrite, MergeThreeWay:
+	default:
+		return nil, fmt.Errorf("memport: unknown merge strategy %q", opts.Strategy)
+	}
+	files, err := store.Files()
+	if err != nil {
+		return nil, fmt.Errorf("memport: scan memories: %w", err)
+	}
+	im := &importer{
+		store:  store,
+		opts:   opts,
+		files:  files,
+		hashes: make(map[string]string),
+		state:  syncState{Synced: make(map[string]string)},
+	}
+	for key, content := range files {
+		im.hashes[ContentHash(content)] = key
+	}
+	if opts.StatePath == "" {
+		return im, nil
+	}
+	data, err := os.ReadFile(opts.StatePath)
+	if err == nil {
+		if err := json.Unmarshal(data, &im.state); err != nil {
+			return nil, fmt.Errorf("memport: parse %s: %w", opts.StatePath, err)
+		}
+		if im.state.Synced == nil {
+			im.state.Synced = make(map[string]string)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("memport: read %s: %w", opts.StatePath, err)
+	}
+	return im, nil
+}
+
+// add applies one entry.
+func (im *importer) add(entry ExportEntry) error {
+	key := path.Clean(entry.Key)
+	ext := path.Ext(key)
+	if path.IsAbs(key) || key == ".." || strings.HasPrefix(key, "../") || (ext != ".md" && ext != ".jsonl") {
+		return fmt.Errorf("memport: invalid key %q", entry.Key)
+	}
+	hash := ContentHash(entry.Value)
+
+	local, ok := im.files[key]
+	if !ok {
+		if _, dup := im.hashes[hash]; dup {
+			im.result.Duplicates++
+			return nil
+		}
+		if err := im.write(key, entry.Value, hash); err != nil {
+			return err
+		}
+		im.result.Added++
+		return nil
+	}
+
+	localHash := ContentHash(local)
+	if localHash == hash {
+		im.result.Skipped++
+		im.state.Synced[key] = hash
+		return nil
+	}
+
+	switch im.opts.Strategy {
+	case MergeSkip:
+		im.result.Skipped++
+	case MergeOverwrite:
+		if err := im.write(key, entry.Value, hash); err != nil {
+			return err
+		}
+		im.result.Overwritten++
+	case MergeThreeWay:
+		switch base := im.state.Synced[key]; base {
+		case localHash:
+			if err := im.write(key, entry.Value, hash); err != nil {
+				return err
+			}
+			im.result.Merged++
+		case hash:
+			// Only the local copy changed since the last import.
+			im.result.Skipped++
+		default:
+			im.result.Conflicts++
+			if im.opts.Stager != nil {
+				id, err := im.opts.Stager.Stage(body(entry.Value), stagingCategory(category(key)), "import:"+key)
+				if err != nil {
+					return fmt.Errorf("memport: stage %s: %w", key, err)
+				}
+				im.result.Staged = append(im.result.Staged, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (im *importer) write(key, value, hash string) error {
+	if err := im.store.PutFile(key, value); err != nil {
+		return err
+	}
+	im.files[key] = value
+	im.hashes[hash] = key
+	im.state.Synced[key] = hash
+	return nil
+}
+
+// finish records the merge base and returns the result.
+func (im *importer) finish() (*ImportResult, error) {
+	if im.opts.StatePath != "" && len(im.state.Synced) > 0 {
+		if err := os.MkdirAll(filepath.Dir(im.opts.StatePath), 0755); err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(im.state, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(im.opts.StatePath, data, 0644); err != nil {
+			return nil, fmt.Errorf("memport: write %s: %w", im.opts.StatePath, err)
+		}
+	}
+	result := im.result
+	return &result, nil
+}
@@ -0,0 +1,128 @@
+package memport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lyndonlyu/apex/internal/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStager struct {
+	staged []string
+}
+
+func (f *fakeStager) Stage(content, category, source string) (string, error) {
+	f.staged = append(f.staged, category+"|"+source+"|"+content)
+	return fmt.Sprintf("stg-%d", len(f.staged)), nil
+}
+
+func entry(key, value string) ExportEntry {
+	return ExportEntry{Key: key, Value: value}
+}
+
+func TestContentHashIgnoresFrontmatter(t *testing.T) {
+	a := ContentHash("---\nid: mem-1\ncreated: 2026-01-01T00:00:00Z\n---\n\nUse Go.\n")
+	b := ContentHash("---\nid: mem-2\n---\nUse Go.")
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, ContentHash("Use Rust."))
+}
+
+func TestImportDetectsDuplicatesUnderOtherNames(t *testing.T) {
+	dir := seedMemDir(t)
+	data := &ExportData{Entries: []ExportEntry{
+		entry("facts/go-version.md", "---\nslug: go-version\n---\n\n# Test Fact\n\nProject uses Go 1.25.\n"),
+		entry("facts/new.md", "A new fact."),
+		entry("facts/new-copy.md", "A new fact."),
+	}}
+
+	result, err := Import(fileStore(t, dir), data, MergeSkip)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Added)
+	assert.Equal(t, 2, result.Duplicates)
+	assert.NoFileExists(t, filepath.Join(dir, "facts", "go-version.md"))
+	assert.NoFileExists(t, filepath.Join(dir, "facts", "new-copy.md"))
+}
+
+func TestImportThreeWayMerge(t *testing.T) {
+	dir := t.TempDir()
+	stager := &fakeStager{}
+	opts := ImportOptions{Strategy: MergeThreeWay, Stager: stager, StatePath: filepath.Join(dir, ".memport-state.json")}
+
+	first := &ExportData{Entries: []ExportEntry{
+		entry("facts/a.md", "A v1"),
+		entry("facts/b.md", "B v1"),
+		entry("facts/c.md", "C v1"),
+	}}
+	result, err := ImportWith(fileStore(t, dir), first, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Added)
+
+	// Locally: b and c change. Upstream: a and c change.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "facts", "b.md"), []byte("B local"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "facts", "c.md"), []byte("C local"), 0644))
+	second := &ExportData{Entries: []ExportEntry{
+		entry("facts/a.md", "A v2"),
+		entry("facts/b.md", "B v1"),
+		entry("facts/c.md", "C v2"),
+	}}
+	result, err = ImportWith(fileStore(t, dir), second, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.Merged, "a changed upstream only")
+	assert.Equal(t, 1, result.Skipped, "b changed locally only")
+	assert.Equal(t, 1, result.Conflicts, "c changed on both sides")
+	assert.Equal(t, []string{"stg-1"}, result.Staged)
+	assert.Equal(t, []string{"fact|import:facts/c.md|C v2"}, stager.staged)
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, "facts", name))
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "A v2", read("a.md"))
+	assert.Equal(t, "B local", read("b.md"))
+	assert.Equal(t, "C local", read("c.md"), "conflicts keep the local copy")
+}
+
+func TestImportRejectsEscapingKeys(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Import(fileStore(t, dir), &ExportData{Entries: []ExportEntry{entry("../evil.md", "x")}}, MergeSkip)
+	assert.Error(t, err)
+	_, err = Import(fileStore(t, dir), &ExportData{}, MergeStrategy("newest"))
+	assert.Error(t, err)
+}
+
+func TestExportWithFilters(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "facts"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "decisions"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "facts", "old.md"),
+		[]byte("---\ncreated: 2025-06-01T00:00:00Z\n---\n\nOld fact.\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "facts", "new.md"),
+		[]byte("---\ncreated: 2026-02-01T00:00:00Z\n---\n\nToken sk-ant-REDACTED is live.\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "decisions", "d.md"),
+		[]byte("---\ncreated: 2026-02-01T00:00:00Z\n---\n\nA decision.\n"), 0644))
+
+	cfg := redact.DefaultConfig()
+	cfg.Enabled = true
+	data, err := ExportWith(fileStore(t, dir), ExportOptions{
+		Categories: []string{"facts"},
+		Since:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Redactor:   redact.New(cfg),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, data.Count)
+	assert.Equal(t, "facts/new.md", data.Entries[0].Key)
+	assert.NotContains(t, data.Entries[0].Value, "sk-ant-api03")
+	assert.Equal(t, ContentHash(data.Entries[0].Value), data.Entries[0].Hash)
+
+	data, err = ExportWith(fileStore(t, dir), ExportOptions{Until: time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.Equal(t, 1, data.Count)
+	assert.Equal(t, "facts/old.md", data.Entries[0].Key)
+}
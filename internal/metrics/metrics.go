@@ -72,6 +72,7 @@ func (c *Collector) runMetrics(manifests []*manifest.Manifest, now string) []Met
 	outcomes := map[string]int{}
 	totalNodes := 0
 	failedNodes := 0
+	cachedNodes := 0
 	var totalDuration int64
 
 	for _, m := range manifests {
@@ -82,6 +83,9 @@ func (c *Collector) runMetrics(manifests []*manifest.Manifest, now string) []Met
 			if strings.EqualFold(n.Status, "failed") {
 				failedNodes++
 			}
+			if n.CacheHit {
+				cachedNodes++
+			}
 		}
 	}
 
@@ -106,6 +110,9 @@ func (c *Collector) runMetrics(manifests []*manifest.Manifest, now string) []Met
 	metrics = append(metrics, Metric{
 		Name: "apex_dag_nodes_failed", Value: float64(failedNodes), Timestamp: now,
 	})
+	metrics = append(metrics, Metric{
+		Name: "apex_dag_nodes_cached", Value: float64(cachedNodes), Timestamp: now,
+	})
 
 	return metrics
 }
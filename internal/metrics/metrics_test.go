@@ -52,7 +52,7 @@ func TestCollectWithRuns(t *testing.T) {
 		"outcome": "success",
 		"nodes": [
 			{"id": "n1", "task": "step 1", "status": "completed"},
-			{"id": "n2", "task": "step 2", "status": "completed"}
+			{"id": "n2", "task": "step 2", "status": "completed", "cache_hit": true, "cached_from": "test-run-000"}
 		]
 	}`
 	os.WriteFile(filepath.Join(runDir, "manifest.json"), []byte(manifest), 0644)
@@ -78,6 +78,15 @@ func TestCollectWithRuns(t *testing.T) {
 	}
 	require.NotNil(t, nodesTotal, "should have apex_dag_nodes_total metric")
 	assert.Equal(t, float64(2), nodesTotal.Value)
+
+	var nodesCached *Metric
+	for i, m := range metrics {
+		if m.Name == "apex_dag_nodes_cached" {
+			nodesCached = &metrics[i]
+		}
+	}
+	require.NotNil(t, nodesCached, "should have apex_dag_nodes_cached metric")
+	assert.Equal(t, float64(1), nodesCached.Value)
 }
 
 func TestMetricJSON(t *testing.T) {
@@ -12,11 +12,13 @@ import (
 	"time"
 )
 
-// Migration represents a single schema migration step.
+// Migration represents a single schema migration step. A step runs either
+// its SQL or, for data migrations that need Go code, its Func.
 type Migration struct {
-	Version     int    `json:"version"`
-	Description string `json:"description"`
-	SQL         string `json:"sql"`
+	Version     int                 `json:"version"`
+	Description string              `json:"description"`
+	SQL         string              `json:"sql"`
+	Func        func(*sql.DB) error `json:"-"`
 }
 
 // MigrationResult describes what happened during a Migrate call.
@@ -39,15 +41,24 @@ func NewRegistry() *Registry {
 
 // Add registers a migration. The version must be sequential (len(migrations)+1).
 func (r *Registry) Add(version int, description, sql string) error {
+	return r.add(Migration{Version: version, Description: description, SQL: sql})
+}
+
+// AddFunc registers a migration implemented in Go, such as a data import.
+// The version must be sequential like Add.
+func (r *Registry) AddFunc(version int, description string, fn func(*sql.DB) error) error {
+	if fn == nil {
+		return fmt.Errorf("migration v%d: nil func", version)
+	}
+	return r.add(Migration{Version: version, Description: description, Func: fn})
+}
+
+func (r *Registry) add(m Migration) error {
 	expected := len(r.migrations) + 1
-	if version != expected {
-		return fmt.Errorf("expected version %d, got %d", expected, version)
-	}
-	r.migrations = append(r.migrations, Migration{
-		Version:     version,
-		Description: description,
-		SQL:         sql,
-	})
+	if m.Version != expected {
+		return fmt.Errorf("expected version %d, got %d", expected, m.Version)
+	}
+	r.migrations = append(r.migrations, m)
 	return nil
 }
 
@@ -143,7 +154,7 @@ func (r *Registry) Migrate(db *sql.DB, dbPath string) (*MigrationResult, error)
 		if m.Version <= current {
 			continue
 		}
-		if _, err := db.Exec(m.SQL); err != nil {
+		if err := m.apply(db); err != nil {
 			return nil, fmt.Errorf("migration v%d (%s): %w", m.Version, m.Description, err)
 		}
 		if err := SetVersion(db, m.Version); err != nil {
@@ -160,6 +171,14 @@ func (r *Registry) Migrate(db *sql.DB, dbPath string) (*MigrationResult, error)
 	}, nil
 }
 
+func (m Migration) apply(db *sql.DB) error {
+	if m.Func != nil {
+		return m.Func(db)
+	}
+	_, err := db.Exec(m.SQL)
+	return err
+}
+
 // Plan returns all migrations that have not yet been applied (Version > current).
 func (r *Registry) Plan(db *sql.DB) ([]Migration, error) {
 	current, err := GetVersion(db)
@@ -161,6 +161,38 @@ func TestMigrate(t *testing.T) {
 	assert.Equal(t, 2, v)
 }
 
+// TestMigrateFunc verifies that Go migrations run in order with SQL ones and
+// that a failing one stops the migration at the previous version.
+func TestMigrateFunc(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	r := NewRegistry()
+	require.NoError(t, r.Add(1, "create users", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);"))
+	require.NoError(t, r.AddFunc(2, "seed users", func(db *sql.DB) error {
+		_, err := db.Exec("INSERT INTO users (name) VALUES ('alice')")
+		return err
+	}))
+	assert.Error(t, r.AddFunc(3, "nil", nil))
+
+	result, err := r.Migrate(db, dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Applied)
+
+	var name string
+	require.NoError(t, db.QueryRow("SELECT name FROM users").Scan(&name))
+	assert.Equal(t, "alice", name)
+
+	require.NoError(t, r.AddFunc(3, "broken", func(*sql.DB) error { return os.ErrInvalid }))
+	_, err = r.Migrate(db, dbPath)
+	assert.ErrorContains(t, err, "migration v3 (broken)")
+	v, err := GetVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
 // TestMigrateAlreadyCurrent sets version to latest and verifies migrate returns Applied=0
 // with no backup created.
 func TestMigrateAlreadyCurrent(t *testing.T) {
@@ -14,6 +14,15 @@ type Event struct {
 	TaskID  string `json:"task_id"`
 	Message string `json:"message"`
 	Level   string `json:"level"` // INFO / WARN / ERROR
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// line formats an event as one line of text, with its trace ID if set.
+func (e Event) line() string {
+	if e.TraceID != "" {
+		return fmt.Sprintf("[%s] %s: %s (trace %s)\n", e.Level, e.Type, e.Message, e.TraceID)
+	}
+	return fmt.Sprintf("[%s] %s: %s\n", e.Level, e.Type, e.Message)
 }
 
 // Channel is the interface for notification backends.
@@ -141,7 +150,7 @@ func (c *StdoutChannel) Name() string { return "stdout" }
 
 // Send prints the event to stdout.
 func (c *StdoutChannel) Send(event Event) error {
-	fmt.Fprintf(os.Stdout, "[%s] %s: %s\n", event.Level, event.Type, event.Message)
+	fmt.Fprint(os.Stdout, event.line())
 	return nil
 }
 
@@ -163,6 +172,6 @@ func (c *FileChannel) Send(event Event) error {
 		return fmt.Errorf("notify: open file: %w", err)
 	}
 	defer f.Close()
-	_, err = fmt.Fprintf(f, "[%s] %s: %s\n", event.Level, event.Type, event.Message)
+	_, err = fmt.Fprint(f, event.line())
 	return err
 }
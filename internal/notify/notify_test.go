@@ -2,6 +2,8 @@ package notify
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -114,6 +116,17 @@ func TestDispatcherDispatchPartialFailure(t *testing.T) {
 	assert.Len(t, good.received, 1)
 }
 
+func TestFileChannelTraceID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	ch := NewFileChannel(path)
+	require.NoError(t, ch.Send(Event{Type: "retraction_warning", Level: "WARN", Message: "m", TraceID: "trace-1"}))
+	require.NoError(t, ch.Send(Event{Type: "test", Level: "INFO", Message: "plain"}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[WARN] retraction_warning: m (trace trace-1)\n[INFO] test: plain\n", string(data))
+}
+
 // mockChannel is a test double for Channel.
 type mockChannel struct {
 	name       string
@@ -19,6 +19,18 @@ type Pool struct {
 	maxWorkers  int
 	runner      Runner
 	RetryPolicy *retry.Policy
+
+	// Prepare, if set, is called right before a node runs and returns the
+	// prompt to execute in place of n.Task. It lets callers rebuild context
+	// with the results of upstream nodes.
+	Prepare func(n *dag.Node) string
+	// Cached, if set, is called with the prepared prompt before the runner.
+	// When it reports a stored result, the node is completed with it and
+	// the runner is not called.
+	Cached func(n *dag.Node, task string) (string, bool)
+	// Finish, if set, is called after a node has been marked completed or
+	// failed.
+	Finish func(n *dag.Node)
 }
 
 // New creates a new Pool with the given concurrency limit and task runner.
@@ -63,10 +75,24 @@ func (p *Pool) Execute(ctx context.Context, d *dag.DAG) error {
 			go func(n *dag.Node) {
 				defer wg.Done()
 				defer func() { <-sem }()
+				if p.Finish != nil {
+					defer p.Finish(n)
+				}
+
+				task := n.Task
+				if p.Prepare != nil {
+					task = p.Prepare(n)
+				}
+				if p.Cached != nil {
+					if result, ok := p.Cached(n, task); ok {
+						d.MarkCompleted(n.ID, result)
+						return
+					}
+				}
 
 				if p.RetryPolicy != nil {
 					result, err := p.RetryPolicy.Execute(ctx, func() (string, error, retry.ErrorKind) {
-						res, runErr := p.runner.RunTask(ctx, n.Task)
+						res, runErr := p.runner.RunTask(ctx, task)
 						if runErr != nil {
 							exitCode := 0
 							stderr := ""
@@ -84,7 +110,7 @@ func (p *Pool) Execute(ctx context.Context, d *dag.DAG) error {
 					}
 					d.MarkCompleted(n.ID, result)
 				} else {
-					result, err := p.runner.RunTask(ctx, n.Task)
+					result, err := p.runner.RunTask(ctx, task)
 					if err != nil {
 						d.MarkFailed(n.ID, err.Error())
 						return
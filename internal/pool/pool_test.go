@@ -253,3 +253,52 @@ func TestExecuteWithoutRetryPolicyFallsBack(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, dag.Failed, d.Nodes["a"].Status) // immediate fail, no retry
 }
+
+func TestExecutePrepareAndFinishHooks(t *testing.T) {
+	nodes := []dag.NodeSpec{
+		{ID: "a", Task: "first", Depends: []string{}},
+		{ID: "b", Task: "second", Depends: []string{"a"}},
+	}
+	d, _ := dag.New(nodes)
+	p := New(4, &mockRunner{})
+
+	var mu sync.Mutex
+	var finished []string
+	p.Prepare = func(n *dag.Node) string { return "prepared " + n.Task }
+	p.Finish = func(n *dag.Node) {
+		mu.Lock()
+		defer mu.Unlock()
+		finished = append(finished, n.ID+":"+n.Status.String())
+	}
+
+	require.NoError(t, p.Execute(context.Background(), d))
+	assert.Equal(t, "result for: prepared first", d.Nodes["a"].Result)
+	assert.Equal(t, "result for: prepared second", d.Nodes["b"].Result)
+	assert.Equal(t, []string{"a:COMPLETED", "b:COMPLETED"}, finished)
+}
+
+func TestExecuteCachedHook(t *testing.T) {
+	nodes := []dag.NodeSpec{
+		{ID: "a", Task: "first", Depends: []string{}},
+		{ID: "b", Task: "second", Depends: []string{"a"}},
+	}
+	d, _ := dag.New(nodes)
+	runner := &mockRunner{}
+	p := New(4, runner)
+
+	var finished []string
+	p.Cached = func(n *dag.Node, task string) (string, bool) {
+		if n.ID == "a" {
+			return "cached " + task, true
+		}
+		return "", false
+	}
+	p.Finish = func(n *dag.Node) { finished = append(finished, n.ID) }
+
+	require.NoError(t, p.Execute(context.Background(), d))
+	assert.Equal(t, "cached first", d.Nodes["a"].Result)
+	assert.Equal(t, dag.Completed, d.Nodes["a"].Status)
+	assert.Equal(t, "result for: second", d.Nodes["b"].Result)
+	assert.Equal(t, int32(1), runner.callCount.Load(), "runner skipped on a hit")
+	assert.Equal(t, []string{"a", "b"}, finished, "Finish runs for hits too")
+}
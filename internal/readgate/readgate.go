@@ -0,0 +1,201 @@
+// Package readgate is the path security contract for reads whose content
+// leaves apex, into prompts or onto the terminal: context building, paging
+// and artifact reads. A path is resolved with realpath and must stay inside
+// the allowed read paths, `..` traversal is rejected outright, the file is
+// opened without following symlinks, its content is redacted, and every
+// read, allowed or denied, is reported with the (dev, inode) of the file
+// that was actually read.
+package readgate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/lyndonlyu/apex/internal/redact"
+)
+
+// Read describes one read that went through the gate.
+type Read struct {
+	Path   string // as requested
+	Real   string // after resolving symlinks; empty if it could not be resolved
+	Dev    uint64
+	Inode  uint64
+	Size   int64
+	Denied string // why the read was refused; empty if it was allowed
+}
+
+// DeniedError is returned for reads the gate refuses.
+type DeniedError struct {
+	Path   string
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("readgate: read of %s denied: %s", e.Path, e.Reason)
+}
+
+// IsDenied reports whether err is a refusal by the gate.
+func IsDenied(err error) bool {
+	var de *DeniedError
+	return errors.As(err, &de)
+}
+
+// Gate checks and performs reads. It is safe for concurrent use as long as
+// Audit is.
+type Gate struct {
+	roots    []string
+	redactor *redact.Redactor
+
+	// Audit, if set, is called for every read that was allowed or denied.
+	// Reads of files that do not exist are not reported.
+	Audit func(Read)
+}
+
+// New creates a Gate that allows reads below the given paths. They are
+// resolved the same way as the paths read, so a symlinked root still
+// matches. A nil redactor leaves content as it is.
+func New(allowed []string, redactor *redact.Redactor) (*Gate, error) {
+	g := &Gate{redactor: redactor}
+	for _, p := range allowed {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("readgate: resolve %s: %w", p, err)
+		}
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = real
+		}
+		g.roots = append(g.roots, abs)
+	}
+	return g, nil
+}
+
+// Roots returns the resolved allowed read paths.
+func (g *Gate) Roots() []string {
+	return append([]string(nil), g.roots...)
+}
+
+// ReadFile returns the redacted content of the file at path, or a
+// *DeniedError if the gate refuses it.
+func (g *Gate) ReadFile(path string) ([]byte, error) {
+	f, err := g.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("readgate: read %s: %w", path, err)
+	}
+	return g.Redact(data), nil
+}
+
+// Open checks path like ReadFile and returns the opened file without
+// redacting it, for callers that have to decode the content first. They
+// must pass the decoded content through Redact.
+func (g *Gate) Open(path string) (*os.File, error) {
+	rd := Read{Path: path}
+	f, err := g.open(&rd)
+	if err != nil {
+		if IsDenied(err) {
+			g.audit(rd)
+		}
+		return nil, err
+	}
+	g.audit(rd)
+	return f, nil
+}
+
+// Check vets path like ReadFile without reading it, for files opened by
+// other means such as databases. The check is audited like a read.
+func (g *Gate) Check(path string) error {
+	f, err := g.Open(path)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Redact applies the gate's redactor to data.
+func (g *Gate) Redact(data []byte) []byte {
+	if g.redactor == nil {
+		return data
+	}
+	return []byte(g.redactor.Redact(string(data)))
+}
+
+func (g *Gate) open(rd *Read) (*os.File, error) {
+	deny := func(reason string) error {
+		rd.Denied = reason
+		return &DeniedError{Path: rd.Path, Reason: reason}
+	}
+
+	for _, elem := range strings.Split(filepath.ToSlash(rd.Path), "/") {
+		if elem == ".." {
+			return nil, deny("path traverses ..")
+		}
+	}
+	abs, err := filepath.Abs(rd.Path)
+	if err != nil {
+		return nil, fmt.Errorf("readgate: resolve %s: %w", rd.Path, err)
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("readgate: resolve %s: %w", rd.Path, err)
+	}
+	rd.Real = real
+	if !g.allowed(real) {
+		if real != abs {
+			return nil, deny("symlink resolves to " + real + ", outside the allowed read paths")
+		}
+		return nil, deny("outside the allowed read paths")
+	}
+
+	// real has no symlinks left; O_NOFOLLOW catches one swapped in since.
+	f, err := os.OpenFile(real, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		if errors.Is(err, syscall.ELOOP) {
+			return nil, deny("replaced by a symlink while opening")
+		}
+		return nil, fmt.Errorf("readgate: open %s: %w", rd.Path, err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("readgate: stat %s: %w", rd.Path, err)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		rd.Dev, rd.Inode = uint64(st.Dev), uint64(st.Ino)
+	}
+	if !fi.Mode().IsRegular() {
+		f.Close()
+		return nil, deny("not a regular file")
+	}
+	rd.Size = fi.Size()
+	return f, nil
+}
+
+// allowed reports whether real is one of the roots or below one.
+func (g *Gate) allowed(real string) bool {
+	for _, root := range g.roots {
+		rel, err := filepath.Rel(root, real)
+		if err != nil {
+			continue
+		}
+		if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Gate) audit(rd Read) {
+	if g.Audit != nil {
+		g.Audit(rd)
+	}
+}
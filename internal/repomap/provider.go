@@ -0,0 +1,95 @@
+package repomap
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+)
+
+// Priority is the block priority of the repository map. It sits below task,
+// memory, and file blocks so it is the first thing squeezed under pressure.
+const Priority = 20
+
+// Provider injects the repository map into prompts built by
+// context.Builder. The map is cached on disk and refreshed incrementally on
+// every call, so edits made by earlier nodes show up in later prompts.
+type Provider struct {
+	root      string
+	cachePath string
+	gate      apexctx.Gate
+
+	mu  sync.Mutex
+	cur *Map
+}
+
+// NewProvider creates a Provider for the tree rooted at root, caching the
+// map at cachePath. An empty cachePath disables the on-disk cache.
+func NewProvider(root, cachePath string) *Provider {
+	return &Provider{root: root, cachePath: cachePath}
+}
+
+// SetGate makes the provider read the Go files it parses through g. Files g refuses are left out of the map and reported in the
+// error Provide returns.
+func (p *Provider) SetGate(g apexctx.Gate) {
+	p.gate = g
+}
+
+// Name implements context.Provider.
+func (p *Provider) Name() string { return "repomap" }
+
+// Provide implements context.Provider. It returns a single low-priority
+// structural block that degrades straight to a reference line.
+func (p *Provider) Provide(ctx context.Context, task string) ([]apexctx.ContentBlock, error) {
+	m, unread, err := p.refresh()
+	if err != nil {
+		return nil, err
+	}
+	return []apexctx.ContentBlock{{
+		ID:        "repomap",
+		Source:    "repomap",
+		Path:      "repomap",
+		Title:     "Repository Map",
+		Text:      m.Render(),
+		Policy:    apexctx.PolicyStructural,
+		DegradeTo: apexctx.PolicyReference,
+		Priority:  Priority,
+	}}, errors.Join(unread...)
+}
+
+// Refresh rebuilds the map, reusing cached entries for unchanged files, and
+// persists it to the cache path.
+func (p *Provider) Refresh() (*Map, error) {
+	m, _, err := p.refresh()
+	return m, err
+}
+
+// refresh is Refresh, also returning the read errors of the files it had
+// to leave out.
+func (p *Provider) refresh() (*Map, []error, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var read func(string) ([]byte, error) // nil: the parser reads the files
+	if p.gate != nil {
+		read = p.gate.ReadFile
+	}
+	prev := p.cur
+	if prev == nil && p.cachePath != "" {
+		// The cache is apex's own state and holds only what earlier builds
+		// read; a corrupt or unreadable cache only costs a full rebuild.
+		prev, _ = Load(p.cachePath)
+	}
+	m, skipped, err := build(p.root, prev, read)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.cachePath != "" {
+		if err := m.Save(p.cachePath); err != nil {
+			return nil, nil, err
+		}
+	}
+	p.cur = m
+	return m, skipped, nil
+}
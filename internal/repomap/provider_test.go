@@ -0,0 +1,56 @@
+package repomap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apexctx "github.com/lyndonlyu/apex/internal/context"
+	"github.com/lyndonlyu/apex/internal/readgate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderProvide(t *testing.T) {
+	root := sampleTree(t)
+	cache := filepath.Join(t.TempDir(), "repomap.json")
+	p := NewProvider(root, cache)
+
+	blocks, err := p.Provide(context.Background(), "task")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, apexctx.PolicyStructural, blocks[0].Policy)
+	assert.Equal(t, apexctx.PolicyReference, blocks[0].DegradeTo)
+	assert.Equal(t, Priority, blocks[0].Priority)
+	assert.Contains(t, blocks[0].Text, "internal/store")
+
+	_, statErr := os.Stat(cache)
+	assert.NoError(t, statErr)
+}
+
+func TestProviderInBuilder(t *testing.T) {
+	root := sampleTree(t)
+	b := apexctx.NewBuilder(apexctx.Options{
+		TokenBudget: 60000,
+		Providers:   []apexctx.Provider{NewProvider(root, "")},
+	})
+	prompt, err := b.Build(context.Background(), "add a method to Store")
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "## Repository Map")
+	assert.Contains(t, prompt, "Entry points: cmd/app.")
+}
+
+func TestProviderGateSkipsDeniedFiles(t *testing.T) {
+	root := sampleTree(t)
+	gate, err := readgate.New([]string{filepath.Join(root, "cmd")}, nil)
+	require.NoError(t, err)
+	p := NewProvider(root, "")
+	p.SetGate(gate)
+
+	blocks, err := p.Provide(context.Background(), "task")
+	assert.True(t, readgate.IsDenied(err), "got %v", err)
+	require.Len(t, blocks, 1)
+	assert.Contains(t, blocks[0].Text, "cmd/app")
+	assert.NotContains(t, blocks[0].Text, "internal/store")
+}
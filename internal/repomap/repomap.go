@@ -0,0 +1,448 @@
+package repomap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxSymbolsPerPackage bounds how many exported symbols Render lists for a
+// single package before summarising the rest as "+N more".
+const maxSymbolsPerPackage = 25
+
+// skipDirs are never descended into when the tree is walked without git.
+var skipDirs = map[string]bool{
+	".git":         true,
+	"vendor":       true,
+	"node_modules": true,
+	".idea":        true,
+	".vscode":      true,
+}
+
+// languages maps file extensions to language names.
+var languages = map[string]string{
+	".go":   "Go",
+	".py":   "Python",
+	".js":   "JavaScript",
+	".ts":   "TypeScript",
+	".tsx":  "TypeScript",
+	".java": "Java",
+	".rs":   "Rust",
+	".c":    "C",
+	".h":    "C",
+	".cpp":  "C++",
+	".rb":   "Ruby",
+	".sh":   "Shell",
+	".md":   "Markdown",
+	".yaml": "YAML",
+	".yml":  "YAML",
+	".json": "JSON",
+	".toml": "TOML",
+	".sql":  "SQL",
+}
+
+// File is the cached per-file entry of a repository map. Entries are reused
+// across refreshes as long as the file's size and mtime are unchanged.
+type File struct {
+	Path     string    `json:"path"` // slash-separated, relative to Root
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Language string    `json:"language,omitempty"`
+	Package  string    `json:"package,omitempty"`  // Go package name
+	Exported []string  `json:"exported,omitempty"` // Go exported top-level symbols
+	Main     bool      `json:"main,omitempty"`     // defines func main in package main
+	Test     bool      `json:"test,omitempty"`
+}
+
+// Map is a high-level picture of a source tree.
+type Map struct {
+	Root      string    `json:"root"`
+	Generated time.Time `json:"generated"`
+	Files     []File    `json:"files"`
+}
+
+// Package summarises the Go files of a single directory.
+type Package struct {
+	Dir      string
+	Name     string
+	Exported []string
+	Tests    int
+}
+
+// FindRoot returns the top-level directory of the git work tree containing
+// dir, or dir itself when it is not inside a git repository.
+func FindRoot(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return dir
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Build scans root and returns a fresh map. Files whose size and mtime match
+// an entry in prev are not re-parsed, so passing the previous map makes the
+// refresh incremental. prev may be nil.
+func Build(root string, prev *Map) (*Map, error) {
+	m, _, err := build(root, prev, nil)
+	return m, err
+}
+
+// build is Build reading the Go files it parses with read; nil lets the
+// parser read them. Files read refuses are left out of the map and
+// returned as errors.
+func build(root string, prev *Map, read func(string) ([]byte, error)) (*Map, []error, error) {
+	paths, err := listFiles(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("repomap: list files: %w", err)
+	}
+
+	cached := make(map[string]File)
+	if prev != nil && prev.Root == root {
+		for _, f := range prev.Files {
+			cached[f.Path] = f
+		}
+	}
+
+	m := &Map{Root: root, Generated: time.Now().UTC()}
+	var unread []error
+	for _, rel := range paths {
+		info, statErr := os.Lstat(filepath.Join(root, filepath.FromSlash(rel)))
+		if statErr != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if old, ok := cached[rel]; ok && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) {
+			m.Files = append(m.Files, old)
+			continue
+		}
+		var src []byte
+		if read != nil && languages[strings.ToLower(path.Ext(rel))] == "Go" {
+			data, readErr := read(filepath.Join(root, filepath.FromSlash(rel)))
+			if readErr != nil {
+				if !errors.Is(readErr, os.ErrNotExist) {
+					unread = append(unread, readErr)
+				}
+				continue
+			}
+			src = data
+		}
+		m.Files = append(m.Files, scanFile(root, rel, info, src))
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	return m, unread, nil
+}
+
+// Load reads a map previously written by Save. A missing file returns
+// (nil, nil) so callers can fall back to a full build.
+func Load(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repomap: read cache: %w", err)
+	}
+	var m Map
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("repomap: parse cache: %w", err)
+	}
+	return &m, nil
+}
+
+// Save writes the map to path as JSON, creating parent directories.
+func (m *Map) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("repomap: mkdir cache dir: %w", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("repomap: marshal: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("repomap: write cache: %w", err)
+	}
+	return nil
+}
+
+// Languages returns the number of files per detected language.
+func (m *Map) Languages() map[string]int {
+	counts := make(map[string]int)
+	for _, f := range m.Files {
+		if f.Language != "" {
+			counts[f.Language]++
+		}
+	}
+	return counts
+}
+
+// Dirs returns every directory in the map with the number of files it
+// directly contains. Intermediate directories without files of their own are
+// included with a count of zero. The root directory is reported as ".".
+func (m *Map) Dirs() map[string]int {
+	counts := make(map[string]int)
+	for _, f := range m.Files {
+		dir := path.Dir(f.Path)
+		counts[dir]++
+		for dir != "." {
+			dir = path.Dir(dir)
+			if _, ok := counts[dir]; !ok {
+				counts[dir] = 0
+			}
+		}
+	}
+	return counts
+}
+
+// Packages returns the Go packages in the map, sorted by directory.
+func (m *Map) Packages() []Package {
+	byDir := make(map[string]*Package)
+	for _, f := range m.Files {
+		if f.Language != "Go" || f.Package == "" {
+			continue
+		}
+		dir := path.Dir(f.Path)
+		p, ok := byDir[dir]
+		if !ok {
+			p = &Package{Dir: dir}
+			byDir[dir] = p
+		}
+		if f.Test {
+			p.Tests++
+			continue
+		}
+		p.Name = f.Package
+		p.Exported = append(p.Exported, f.Exported...)
+	}
+	out := make([]Package, 0, len(byDir))
+	for _, p := range byDir {
+		if p.Name == "" {
+			// Directory only holds tests; report it under its test package.
+			p.Name = strings.TrimSuffix(path.Base(p.Dir), "_test")
+		}
+		sort.Strings(p.Exported)
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Dir < out[j].Dir })
+	return out
+}
+
+// EntryPoints returns the directories holding a main function.
+func (m *Map) EntryPoints() []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, f := range m.Files {
+		if f.Main && !seen[path.Dir(f.Path)] {
+			seen[path.Dir(f.Path)] = true
+			out = append(out, path.Dir(f.Path))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// TestDirs returns the directories containing test files with their counts.
+func (m *Map) TestDirs() map[string]int {
+	counts := make(map[string]int)
+	for _, f := range m.Files {
+		if f.Test {
+			counts[path.Dir(f.Path)]++
+		}
+	}
+	return counts
+}
+
+// Render formats the map as markdown. The summary lines come first and every
+// Go package gets its own heading, so summarising compression still leaves a
+// usable outline.
+func (m *Map) Render() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Repository: %s\n\n", filepath.Base(m.Root))
+
+	langs := m.Languages()
+	names := make([]string, 0, len(langs))
+	for name := range langs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if langs[names[i]] != langs[names[j]] {
+			return langs[names[i]] > langs[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s (%d)", name, langs[name])
+	}
+	fmt.Fprintf(&sb, "Files: %d. Languages: %s.\n", len(m.Files), strings.Join(parts, ", "))
+	if eps := m.EntryPoints(); len(eps) > 0 {
+		fmt.Fprintf(&sb, "Entry points: %s.\n", strings.Join(eps, ", "))
+	}
+	tests := m.TestDirs()
+	if len(tests) > 0 {
+		testDirs := sortedKeys(tests)
+		fmt.Fprintf(&sb, "Tests: %s.\n", strings.Join(testDirs, ", "))
+	}
+
+	sb.WriteString("\n## Directories\n\n")
+	dirs := m.Dirs()
+	for _, d := range sortedKeys(dirs) {
+		depth := 0
+		if d != "." {
+			depth = strings.Count(d, "/") + 1
+		}
+		fmt.Fprintf(&sb, "%s%s/ (%d files)\n", strings.Repeat("  ", depth), path.Base(d), dirs[d])
+	}
+
+	pkgs := m.Packages()
+	if len(pkgs) > 0 {
+		sb.WriteString("\n## Go packages\n")
+		for _, p := range pkgs {
+			fmt.Fprintf(&sb, "\n### %s (package %s)\n", p.Dir, p.Name)
+			if len(p.Exported) > 0 {
+				shown := p.Exported
+				more := 0
+				if len(shown) > maxSymbolsPerPackage {
+					more = len(shown) - maxSymbolsPerPackage
+					shown = shown[:maxSymbolsPerPackage]
+				}
+				line := strings.Join(shown, ", ")
+				if more > 0 {
+					line += fmt.Sprintf(", +%d more", more)
+				}
+				fmt.Fprintf(&sb, "Exported: %s\n", line)
+			}
+			if p.Tests > 0 {
+				fmt.Fprintf(&sb, "Tests: %d files\n", p.Tests)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// listFiles returns slash-separated paths relative to root. Inside a git
+// work tree it asks git for tracked and untracked-but-not-ignored files so
+// that .gitignore is honoured; otherwise it walks the tree.
+func listFiles(root string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "--cached", "--others", "--exclude-standard", "-z")
+	cmd.Dir = root
+	if out, err := cmd.Output(); err == nil {
+		var paths []string
+		for _, p := range strings.Split(string(out), "\x00") {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+		return paths, nil
+	}
+
+	var paths []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != root && (skipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, p)
+		if relErr != nil {
+			return nil
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	return paths, err
+}
+
+// scanFile builds the cache entry for a single file, parsing Go sources for
+// their package name, exported symbols, and main function.
+func scanFile(root, rel string, info os.FileInfo, src []byte) File {
+	f := File{
+		Path:     rel,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Language: languages[strings.ToLower(path.Ext(rel))],
+		Test:     isTestFile(rel),
+	}
+	if f.Language != "Go" {
+		return f
+	}
+
+	fset := token.NewFileSet()
+	var source any
+	if src != nil {
+		source = src
+	}
+	file, err := parser.ParseFile(fset, filepath.Join(root, filepath.FromSlash(rel)), source, parser.SkipObjectResolution)
+	if err != nil {
+		return f
+	}
+	f.Package = file.Name.Name
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Name == "main" && f.Package == "main" {
+				f.Main = true
+			}
+			if d.Recv == nil && d.Name.IsExported() {
+				f.Exported = append(f.Exported, d.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						f.Exported = append(f.Exported, s.Name.Name)
+					}
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						if n.IsExported() {
+							f.Exported = append(f.Exported, n.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return f
+}
+
+// isTestFile reports whether a path looks like a test file in any of the
+// common language conventions.
+func isTestFile(rel string) bool {
+	base := path.Base(rel)
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	switch {
+	case strings.HasSuffix(stem, "_test"):
+		return true
+	case strings.HasPrefix(stem, "test_"):
+		return true
+	case strings.HasSuffix(stem, ".test"), strings.HasSuffix(stem, ".spec"):
+		return true
+	}
+	return false
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,119 @@
+package repomap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, rel)
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+}
+
+func sampleTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFile(t, root, "cmd/app/main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, root, "internal/store/store.go", "package store\n\ntype Store struct{}\n\nfunc NewStore() *Store { return nil }\n\nfunc helper() {}\n\nconst MaxSize = 10\n")
+	writeFile(t, root, "internal/store/store_test.go", "package store\n\nfunc TestX() {}\n")
+	writeFile(t, root, "scripts/test_deploy.py", "print('hi')\n")
+	writeFile(t, root, "README.md", "# App\n")
+	return root
+}
+
+func TestBuildScansTree(t *testing.T) {
+	root := sampleTree(t)
+	m, err := Build(root, nil)
+	require.NoError(t, err)
+	require.Len(t, m.Files, 5)
+
+	assert.Equal(t, map[string]int{"Go": 3, "Python": 1, "Markdown": 1}, m.Languages())
+	assert.Equal(t, []string{"cmd/app"}, m.EntryPoints())
+	assert.Equal(t, map[string]int{"internal/store": 1, "scripts": 1}, m.TestDirs())
+
+	pkgs := m.Packages()
+	require.Len(t, pkgs, 2)
+	assert.Equal(t, "internal/store", pkgs[1].Dir)
+	assert.Equal(t, "store", pkgs[1].Name)
+	assert.Equal(t, []string{"MaxSize", "NewStore", "Store"}, pkgs[1].Exported)
+	assert.Equal(t, 1, pkgs[1].Tests)
+
+	dirs := m.Dirs()
+	assert.Equal(t, 0, dirs["internal"])
+	assert.Equal(t, 2, dirs["internal/store"])
+}
+
+func TestBuildIncrementalReusesUnchanged(t *testing.T) {
+	root := sampleTree(t)
+	first, err := Build(root, nil)
+	require.NoError(t, err)
+
+	// Poison the cached entry: if Build reuses it, the poisoned value survives.
+	for i := range first.Files {
+		if first.Files[i].Path == "cmd/app/main.go" {
+			first.Files[i].Exported = []string{"Cached"}
+		}
+	}
+
+	later := time.Now().Add(time.Minute)
+	writeFile(t, root, "internal/store/store.go", "package store\n\nfunc Open() {}\n")
+	require.NoError(t, os.Chtimes(filepath.Join(root, "internal/store/store.go"), later, later))
+
+	second, err := Build(root, first)
+	require.NoError(t, err)
+	for _, f := range second.Files {
+		switch f.Path {
+		case "cmd/app/main.go":
+			assert.Equal(t, []string{"Cached"}, f.Exported)
+		case "internal/store/store.go":
+			assert.Equal(t, []string{"Open"}, f.Exported)
+		}
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	root := sampleTree(t)
+	m, err := Build(root, nil)
+	require.NoError(t, err)
+
+	cache := filepath.Join(t.TempDir(), "cache", "map.json")
+	require.NoError(t, m.Save(cache))
+
+	loaded, err := Load(cache)
+	require.NoError(t, err)
+	assert.Equal(t, m.Root, loaded.Root)
+	assert.Len(t, loaded.Files, len(m.Files))
+}
+
+func TestLoadMissing(t *testing.T) {
+	m, err := Load(filepath.Join(t.TempDir(), "nope.json"))
+	require.NoError(t, err)
+	assert.Nil(t, m)
+}
+
+func TestRender(t *testing.T) {
+	root := sampleTree(t)
+	m, err := Build(root, nil)
+	require.NoError(t, err)
+
+	out := m.Render()
+	assert.Contains(t, out, "Languages: Go (3)")
+	assert.Contains(t, out, "Entry points: cmd/app.")
+	assert.Contains(t, out, "### internal/store (package store)")
+	assert.Contains(t, out, "Exported: MaxSize, NewStore, Store")
+	assert.NotContains(t, out, "helper")
+}
+
+func TestIsTestFile(t *testing.T) {
+	assert.True(t, isTestFile("a/b_test.go"))
+	assert.True(t, isTestFile("test_a.py"))
+	assert.True(t, isTestFile("web/a.spec.ts"))
+	assert.False(t, isTestFile("a/testing.go"))
+}
@@ -0,0 +1,170 @@
+// Package retraction traces what a retracted memory influenced: the past
+// actions whose context included it, found in run manifests and the audit
+// log, and the nodes of a running DAG that were prepared with it.
+package retraction
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lyndonlyu/apex/internal/audit"
+	"github.com/lyndonlyu/apex/internal/dag"
+	"github.com/lyndonlyu/apex/internal/manifest"
+)
+
+// Use is one action whose context included a memory.
+type Use struct {
+	RunID     string // "" when only the audit log recorded the action
+	NodeID    string
+	ActionID  string
+	TraceID   string
+	Task      string
+	Status    string // node status from the manifest, else the audit outcome
+	Timestamp string
+}
+
+// Find returns the actions whose context included memoryID, from the
+// manifests and the audit records, merged by action ID and ordered oldest
+// first.
+func Find(manifests []*manifest.Manifest, records []audit.Record, memoryID string) []Use {
+	byAction := make(map[string]*Use)
+	var uses []*Use
+	runOfTrace := make(map[string]*manifest.Manifest)
+	for _, m := range manifests {
+		if m.TraceID != "" {
+			runOfTrace[m.TraceID] = m
+		}
+		for _, n := range m.Nodes {
+			if !contains(n.MemoryIDs, memoryID) {
+				continue
+			}
+			u := &Use{
+				RunID:     m.RunID,
+				NodeID:    n.ID,
+				ActionID:  n.ActionID,
+				TraceID:   m.TraceID,
+				Task:      n.Task,
+				Status:    n.Status,
+				Timestamp: m.Timestamp,
+			}
+			uses = append(uses, u)
+			if u.ActionID != "" {
+				byAction[u.ActionID] = u
+			}
+		}
+	}
+	for _, r := range records {
+		if !contains(r.MemoryIDs, memoryID) {
+			continue
+		}
+		if u, ok := byAction[r.ActionID]; ok {
+			if u.TraceID == "" {
+				u.TraceID = r.TraceID
+			}
+			continue
+		}
+		u := &Use{
+			ActionID:  r.ActionID,
+			TraceID:   r.TraceID,
+			Task:      r.Task,
+			Status:    r.Outcome,
+			Timestamp: r.Timestamp,
+		}
+		if m, ok := runOfTrace[r.TraceID]; ok {
+			u.RunID = m.RunID
+		}
+		uses = append(uses, u)
+		byAction[r.ActionID] = u
+	}
+
+	out := make([]Use, len(uses))
+	for i, u := range uses {
+		out[i] = *u
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp < out[j].Timestamp })
+	return out
+}
+
+// TraceIDs returns the distinct trace IDs of uses, in order.
+func TraceIDs(uses []Use) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, u := range uses {
+		if u.TraceID != "" && !seen[u.TraceID] {
+			seen[u.TraceID] = true
+			ids = append(ids, u.TraceID)
+		}
+	}
+	return ids
+}
+
+// Result is what Propagate did to a running DAG. Node IDs are sorted.
+type Result struct {
+	Invalidated []string // unstarted nodes rebuilt without the memory and requeued
+	Escalated   []string // unstarted nodes held for a human
+	Started     []string // nodes already running or finished with the memory
+}
+
+// Propagate invalidates the unstarted nodes of d whose context included
+// a retracted memory; used maps node IDs to the memory IDs in their
+// context. Each invalidated node is rebuilt by rebuild and requeued. When
+// more than limit nodes are affected, the blast radius is too large to
+// repair unattended: none is rebuilt and all are held for a human
+// (limit <= 0 means no limit). A node whose rebuild fails is held too.
+func Propagate(d *dag.DAG, used map[string][]string, retracted map[string]bool, limit int, rebuild func(*dag.Node) error) (Result, error) {
+	var users []string
+	for id, mems := range used {
+		for _, m := range mems {
+			if retracted[m] {
+				users = append(users, id)
+				break
+			}
+		}
+	}
+	sort.Strings(users)
+
+	var res Result
+	var revoked []string
+	for _, id := range users {
+		if err := d.Revoke(id); err != nil {
+			res.Started = append(res.Started, id)
+			continue
+		}
+		revoked = append(revoked, id)
+	}
+
+	if limit > 0 && len(revoked) > limit {
+		reason := fmt.Sprintf("context included a retracted memory; %d nodes affected exceeds the blast radius of %d", len(revoked), limit)
+		for _, id := range revoked {
+			if err := d.Hold(id, reason); err != nil {
+				return res, err
+			}
+			res.Escalated = append(res.Escalated, id)
+		}
+		return res, nil
+	}
+
+	for _, id := range revoked {
+		if err := rebuild(d.Nodes[id]); err != nil {
+			if holdErr := d.Hold(id, fmt.Sprintf("context included a retracted memory; rebuild failed: %v", err)); holdErr != nil {
+				return res, holdErr
+			}
+			res.Escalated = append(res.Escalated, id)
+			continue
+		}
+		if err := d.Requeue(id); err != nil {
+			return res, err
+		}
+		res.Invalidated = append(res.Invalidated, id)
+	}
+	return res, nil
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
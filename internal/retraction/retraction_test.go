@@ -0,0 +1,104 @@
+package retraction
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lyndonlyu/apex/internal/audit"
+	"github.com/lyndonlyu/apex/internal/dag"
+	"github.com/lyndonlyu/apex/internal/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	manifests := []*manifest.Manifest{
+		{RunID: "run-2", TraceID: "trace-2", Timestamp: "2026-01-02T00:00:00Z", Nodes: []manifest.NodeResult{
+			{ID: "a", ActionID: "act-a", Status: "COMPLETED", MemoryIDs: []string{"mem-1"}},
+			{ID: "b", ActionID: "act-b", Status: "COMPLETED", MemoryIDs: []string{"mem-2"}},
+		}},
+		{RunID: "run-1", TraceID: "trace-1", Timestamp: "2026-01-01T00:00:00Z", Nodes: []manifest.NodeResult{
+			{ID: "x", ActionID: "act-x", Status: "FAILED", MemoryIDs: []string{"mem-1", "mem-2"}},
+		}},
+	}
+	records := []audit.Record{
+		{ActionID: "act-a", TraceID: "trace-2", Outcome: "success", MemoryIDs: []string{"mem-1"}},
+		{ActionID: "act-z", TraceID: "trace-3", Task: "[z] orphan", Outcome: "success",
+			Timestamp: "2026-01-03T00:00:00Z", MemoryIDs: []string{"mem-1"}},
+		{ActionID: "act-y", TraceID: "trace-3", MemoryIDs: []string{"mem-2"}},
+	}
+
+	uses := Find(manifests, records, "mem-1")
+	require.Len(t, uses, 3)
+	assert.Equal(t, "run-1", uses[0].RunID)
+	assert.Equal(t, "x", uses[0].NodeID)
+	assert.Equal(t, "act-a", uses[1].ActionID, "audit record merged into the manifest node")
+	assert.Equal(t, "", uses[2].RunID, "audit-only action")
+	assert.Equal(t, "success", uses[2].Status)
+	assert.Equal(t, []string{"trace-1", "trace-2", "trace-3"}, TraceIDs(uses))
+}
+
+func newDAG(t *testing.T) *dag.DAG {
+	t.Helper()
+	d, err := dag.New([]dag.NodeSpec{
+		{ID: "a", Task: "a"},
+		{ID: "b", Task: "b", Depends: []string{"a"}},
+		{ID: "c", Task: "c", Depends: []string{"a"}},
+		{ID: "d", Task: "d", Depends: []string{"c"}},
+	})
+	require.NoError(t, err)
+	return d
+}
+
+func TestPropagateRebuildsUnstartedNodes(t *testing.T) {
+	d := newDAG(t)
+	d.MarkCompleted("a", "ok")
+	used := map[string][]string{
+		"a": {"mem-1"},
+		"b": {"mem-1", "mem-2"},
+		"c": {"mem-2"},
+		"d": {"mem-3"},
+	}
+	var rebuilt []string
+	res, err := Propagate(d, used, map[string]bool{"mem-1": true, "mem-2": true}, 0, func(n *dag.Node) error {
+		rebuilt = append(rebuilt, n.ID)
+		n.Task = n.ID + " (rebuilt)"
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"b", "c"}, res.Invalidated)
+	assert.Equal(t, []string{"a"}, res.Started)
+	assert.Empty(t, res.Escalated)
+	assert.Equal(t, []string{"b", "c"}, rebuilt)
+	assert.Equal(t, dag.Pending, d.Nodes["b"].Status)
+	assert.Equal(t, "b (rebuilt)", d.Nodes["b"].Task)
+	assert.Equal(t, dag.Pending, d.Nodes["d"].Status)
+}
+
+func TestPropagateEscalatesBeyondBlastRadius(t *testing.T) {
+	d := newDAG(t)
+	d.MarkCompleted("a", "ok")
+	used := map[string][]string{"b": {"mem-1"}, "c": {"mem-1"}}
+	res, err := Propagate(d, used, map[string]bool{"mem-1": true}, 1, func(n *dag.Node) error {
+		t.Fatalf("rebuild called for %s", n.ID)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"b", "c"}, res.Escalated)
+	assert.Empty(t, res.Invalidated)
+	assert.Equal(t, dag.Escalated, d.Nodes["c"].Status)
+	assert.Equal(t, dag.Skipped, d.Nodes["d"].Status)
+	assert.True(t, d.IsComplete(), "held nodes do not stall the run")
+}
+
+func TestPropagateHoldsFailedRebuild(t *testing.T) {
+	d := newDAG(t)
+	res, err := Propagate(d, map[string][]string{"a": {"mem-1"}}, map[string]bool{"mem-1": true}, 5, func(*dag.Node) error {
+		return errors.New("no context")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, res.Escalated)
+	assert.Equal(t, dag.Escalated, d.Nodes["a"].Status)
+}
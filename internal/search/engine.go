@@ -2,7 +2,9 @@ package search
 
 import (
 	"context"
+	"math"
 	"sort"
+	"time"
 
 	"github.com/lyndonlyu/apex/internal/memory"
 	"github.com/lyndonlyu/apex/internal/vectordb"
@@ -26,6 +28,20 @@ type Result struct {
 	Score  float32
 	Source string // "vector" | "keyword" | "both"
 	Type   string // "decision" | "fact" | "session"
+	Tier   string // memory.TierSession | TierProject | TierGlobal
+
+	Confidence float64 // effective confidence, 0 when the memory has no metadata
+}
+
+// signalWeight scales a relevance score by the memory's effective
+// confidence and by how recently it was used (half weight after 30 days
+// idle). Neither factor can remove a result on its own.
+func signalWeight(entry memory.Entry, now time.Time) float64 {
+	recency := 1.0
+	if last := entry.LastUsed(); !last.IsZero() && now.After(last) {
+		recency = math.Pow(0.5, now.Sub(last).Hours()/24/30)
+	}
+	return (0.6 + 0.4*entry.EffectiveConfidence(now)) * (0.8 + 0.2*recency)
 }
 
 // Engine performs hybrid search combining vector and keyword results.
@@ -57,7 +73,16 @@ func (e *Engine) Hybrid(ctx context.Context, query string, topK int) ([]Result,
 			if err == nil {
 				vectorOK = true
 				for _, vr := range vecResults {
+					// The index may still hold superseded versions, and holds
+					// every project's memories.
+					if e.memStore != nil && (!e.memStore.IsCurrent(vr.MemoryID) || !e.memStore.Visible(vr.MemoryID)) {
+						continue
+					}
 					score := 1.0 / (1.0 + vr.Distance)
+					if e.memStore != nil {
+						// Keyword scores come weighted from the store.
+						score *= float32(e.memStore.TierWeight(vr.MemoryID))
+					}
 					merged[vr.MemoryID] = &Result{
 						ID:     vr.MemoryID,
 						Text:   vr.Text,
@@ -69,33 +94,46 @@ func (e *Engine) Hybrid(ctx context.Context, query string, topK int) ([]Result,
 		}
 	}
 
-	// Keyword search (always available)
+	// Keyword search (always available), scored by relevance in (0, 1]
+	keywordScores := make(map[string]float32)
 	if e.memStore != nil {
 		kwResults, err := e.memStore.Search(query)
 		if err == nil {
 			for _, kr := range kwResults {
 				if existing, ok := merged[kr.Path]; ok {
-					existing.Score += keywordWeight
+					existing.Score += float32(kr.Score) * keywordWeight
 					existing.Source = "both"
 					existing.Type = kr.Type
 				} else {
 					merged[kr.Path] = &Result{
 						ID:     kr.Path,
 						Text:   kr.Snippet,
-						Score:  keywordWeight,
+						Score:  float32(kr.Score) * keywordWeight,
 						Source: "keyword",
 						Type:   kr.Type,
 					}
 				}
+				keywordScores[kr.Path] = float32(kr.Score)
 			}
 		}
 	}
 
-	// If no vector search was done, keyword results get full score
+	// If no vector search was done, keyword results get their full score
 	if !vectorOK {
-		for _, r := range merged {
+		for id, r := range merged {
 			if r.Source == "keyword" {
-				r.Score = 1.0
+				r.Score = keywordScores[id]
+			}
+		}
+	}
+
+	// Weight by usage signals so trusted, recently used memories rank first
+	if e.memStore != nil {
+		now := time.Now()
+		for id, r := range merged {
+			if entry, ok := e.memStore.Lookup(id); ok {
+				r.Confidence = entry.EffectiveConfidence(now)
+				r.Score *= float32(signalWeight(entry, now))
 			}
 		}
 	}
@@ -103,6 +141,7 @@ func (e *Engine) Hybrid(ctx context.Context, query string, topK int) ([]Result,
 	// Sort by score descending
 	results := make([]Result, 0, len(merged))
 	for _, r := range merged {
+		r.Tier, _, _ = memory.SplitTier(r.ID)
 		results = append(results, *r)
 	}
 	sort.Slice(results, func(i, j int) bool {
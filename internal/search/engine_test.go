@@ -2,7 +2,9 @@ package search
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/lyndonlyu/apex/internal/memory"
 	"github.com/lyndonlyu/apex/internal/vectordb"
@@ -110,3 +112,99 @@ func TestHybridMerge(t *testing.T) {
 	require.NoError(t, err)
 	assert.GreaterOrEqual(t, len(results), 1)
 }
+
+func TestHybridSkipsSupersededVectors(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := memory.NewStore(dir)
+	v1, err := store.SaveEntry("fact", "lang", "Services are written in Python")
+	require.NoError(t, err)
+	v2, err := store.Update(v1.ID, "Services are written in Go")
+	require.NoError(t, err)
+
+	vdb, err := vectordb.Open(dir+"/vectors.db", 4)
+	require.NoError(t, err)
+	defer vdb.Close()
+
+	vec := []float32{0.5, 0.5, 0.5, 0.5}
+	vdb.Index(context.Background(), v1.Path, "Services are written in Python", vec)
+	vdb.Index(context.Background(), v2.Path, "Services are written in Go", vec)
+
+	e := New(vdb, store, &mockEmbedder{available: true, vec: vec})
+	results, err := e.Hybrid(context.Background(), "services", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, v2.Path, results[0].ID)
+}
+
+func TestHybridHidesOtherProjects(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := memory.NewStore(dir)
+	store.SetScope(memory.Scope{Project: "alpha"})
+	alpha, err := store.SaveEntry("fact", "queue", "Jobs go through kafka")
+	require.NoError(t, err)
+	store.SetScope(memory.Scope{Project: "beta"})
+	beta, err := store.SaveEntry("fact", "queue", "Jobs go through rabbitmq")
+	require.NoError(t, err)
+
+	vdb, err := vectordb.Open(dir+"/vectors.db", 4)
+	require.NoError(t, err)
+	defer vdb.Close()
+	vec := []float32{0.5, 0.5, 0.5, 0.5}
+	vdb.Index(context.Background(), alpha.Path, "Jobs go through kafka", vec)
+	vdb.Index(context.Background(), beta.Path, "Jobs go through rabbitmq", vec)
+
+	e := New(vdb, store, &mockEmbedder{available: true, vec: vec})
+	results, err := e.Hybrid(context.Background(), "jobs", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, beta.Path, results[0].ID)
+	assert.Equal(t, memory.TierProject, results[0].Tier)
+}
+
+func TestHybridRanksByConfidence(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := memory.NewStore(dir)
+	low, err := store.SaveEntryWithConfidence("fact", "deploy-low", "Deploys use the blue pipeline", 0.1)
+	require.NoError(t, err)
+	high, err := store.SaveEntryWithConfidence("fact", "deploy-high", "Deploys use the green pipeline", 0.9)
+	require.NoError(t, err)
+
+	e := New(nil, store, nil)
+	results, err := e.Hybrid(context.Background(), "deploys", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, high.Path, results[0].ID)
+	assert.Equal(t, low.Path, results[1].ID)
+	assert.InDelta(t, 0.9, results[0].Confidence, 0.001)
+}
+
+func TestHybridKeywordUsesFullTextRank(t *testing.T) {
+	dir := t.TempDir()
+	db, err := memory.OpenDB(filepath.Join(t.TempDir(), "memory.db"), dir)
+	require.NoError(t, err)
+	defer db.Close()
+	store, err := memory.NewDBStore(dir, db, false)
+	require.NoError(t, err)
+
+	weak, err := store.SaveEntry("fact", "weak", "The release notes mention redis once among many other words.")
+	require.NoError(t, err)
+	strong, err := store.SaveEntry("fact", "strong", "Redis caches sessions; redis is fast.")
+	require.NoError(t, err)
+
+	e := New(nil, store, nil)
+	results, err := e.Hybrid(context.Background(), "redis", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, strong.Path, results[0].ID)
+	assert.Equal(t, weak.Path, results[1].ID)
+	assert.Less(t, results[1].Score, results[0].Score)
+}
+
+func TestSignalWeight(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	fresh := memory.Entry{Confidence: 1.0, LastAccessed: now.Format(time.RFC3339)}
+	idle := memory.Entry{Confidence: 1.0, LastAccessed: now.AddDate(0, -3, 0).Format(time.RFC3339)}
+	assert.InDelta(t, 1.0, signalWeight(fresh, now), 0.001)
+	assert.Less(t, signalWeight(idle, now), signalWeight(fresh, now))
+	assert.Greater(t, signalWeight(memory.Entry{}, now), 0.0)
+}
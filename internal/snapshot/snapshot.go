@@ -58,6 +58,21 @@ func (m *Manager) Apply(runID string) error {
 	return nil
 }
 
+// Base returns the commit the run's working tree can be diffed against: the
+// snapshot's stash commit when the run started with local changes, HEAD
+// otherwise.
+func (m *Manager) Base(runID string) (string, error) {
+	ref := "HEAD"
+	if idx, err := m.findStash(runID); err == nil {
+		ref = fmt.Sprintf("stash@{%d}", idx)
+	}
+	out, err := m.git("rev-parse", ref)
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse %s failed: %w: %s", ref, err, out)
+	}
+	return out, nil
+}
+
 func (m *Manager) Restore(runID string) error {
 	idx, err := m.findStash(runID)
 	if err != nil {
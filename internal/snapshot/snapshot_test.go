@@ -116,3 +116,23 @@ func TestCreateInNonGitDir(t *testing.T) {
 	_, err := m.Create("test-run")
 	assert.Error(t, err)
 }
+
+func TestBaseUsesStashCommit(t *testing.T) {
+	dir := initGitRepo(t)
+	m := New(dir)
+
+	head, err := m.Base("no-such-run")
+	require.NoError(t, err)
+	assert.Len(t, head, 40)
+
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("user-edits"), 0644)
+	_, err = m.Create("test-base")
+	require.NoError(t, err)
+
+	base, err := m.Base("test-base")
+	require.NoError(t, err)
+	assert.NotEqual(t, head, base)
+	out, err := m.git("show", base+":file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "user-edits", out)
+}
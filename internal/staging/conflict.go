@@ -0,0 +1,491 @@
+package staging
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lyndonlyu/apex/internal/vectordb"
+)
+
+// Detection tiers, from cheapest to most expensive.
+const (
+	TierStructured = "structured" // claims with the same entity and property
+	TierEmbedding  = "embedding"  // cosine similarity against the vector index
+	TierKeyword    = "keyword"    // keyword overlap when no vector index is available
+	TierNLI        = "nli"        // model classification of a similar pair
+)
+
+// Resolution actions.
+const (
+	ActionCommit    = "commit"    // no conflict, candidate committed
+	ActionSupersede = "supersede" // candidate committed, conflicting entries superseded
+	ActionReject    = "reject"    // an existing entry wins, candidate rejected
+	ActionDuplicate = "duplicate" // candidate restates an existing entry
+	ActionEscalate  = "escalate"  // left PENDING for a human decision
+)
+
+// confidenceMargin is how much more confident one side of a contradiction
+// must be to win outright; closer pairs are decided by recency.
+const confidenceMargin = 0.2
+
+// Claim is a structured statement: Entity's Property has Value during
+// [ValidFrom, ValidTo). Empty bounds are open.
+type Claim struct {
+	Entity    string `json:"entity"`
+	Property  string `json:"property"`
+	Value     string `json:"value"`
+	ValidFrom string `json:"valid_from,omitempty"`
+	ValidTo   string `json:"valid_to,omitempty"`
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// compareClaims classifies two claims. Claims about different keys or
+// non-overlapping validity periods are neutral.
+func compareClaims(a, b Claim) ConflictType {
+	if normalize(a.Entity) != normalize(b.Entity) || normalize(a.Property) != normalize(b.Property) {
+		return Neutral
+	}
+	if !overlaps(a, b) {
+		return Neutral
+	}
+	if normalize(a.Value) == normalize(b.Value) {
+		return Entailment
+	}
+	return Contradiction
+}
+
+// overlaps reports whether two validity periods intersect. Bounds are
+// RFC 3339 timestamps and compare lexically.
+func overlaps(a, b Claim) bool {
+	if a.ValidTo != "" && b.ValidFrom != "" && a.ValidTo <= b.ValidFrom {
+		return false
+	}
+	if b.ValidTo != "" && a.ValidFrom != "" && b.ValidTo <= a.ValidFrom {
+		return false
+	}
+	return true
+}
+
+// Embedder turns text into a vector. *embedding.Client satisfies it.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// VectorIndex finds stored memories near a vector. *vectordb.VectorDB
+// satisfies it.
+type VectorIndex interface {
+	Search(ctx context.Context, query []float32, topK int) ([]vectordb.VectorResult, error)
+}
+
+// DetectorOptions configures a Detector. Embedder, Index and NLI are
+// optional; without an index the detector falls back to keyword overlap,
+// and without NLI similar pairs are classified by ClassifyConflict.
+type DetectorOptions struct {
+	Embedder            Embedder
+	Index               VectorIndex
+	NLI                 NLI
+	SimilarityThreshold float64 // minimum cosine similarity for an embedding match (default 0.85)
+	EscalateAbove       float64 // contradictions between two entries at or above this confidence are escalated (default 0.9)
+	TopK                int     // neighbours fetched from the index (default 5)
+}
+
+// Detector finds conflicts between a staged candidate and committed
+// memories in three tiers: structured claims, embedding similarity, and
+// optional NLI classification of the similar pairs.
+type Detector struct {
+	opts DetectorOptions
+}
+
+// NewDetector creates a Detector, filling in defaults.
+func NewDetector(opts DetectorOptions) *Detector {
+	if opts.SimilarityThreshold == 0 {
+		opts.SimilarityThreshold = 0.85
+	}
+	if opts.EscalateAbove == 0 {
+		opts.EscalateAbove = 0.9
+	}
+	if opts.TopK == 0 {
+		opts.TopK = 5
+	}
+	return &Detector{opts: opts}
+}
+
+// Conflict is a relationship found between a candidate and an existing
+// memory. Existing.ID is empty when the match came from the vector index
+// and could not be tied to a staging entry; Ref then holds the memory path.
+type Conflict struct {
+	Type       ConflictType
+	Tier       string
+	Existing   StagingEntry
+	Ref        string
+	Similarity float64
+}
+
+// Detect compares candidate with the existing committed entries and the
+// vector index. Each existing memory is reported at most once, by the
+// cheapest tier that matched it. Neutral pairs are not reported.
+func (d *Detector) Detect(ctx context.Context, candidate StagingEntry, existing []StagingEntry) []Conflict {
+	var conflicts []Conflict
+	seen := make(map[string]bool)
+
+	candClaim, hasClaim := entryClaim(candidate)
+	if hasClaim {
+		for _, e := range existing {
+			claim, ok := entryClaim(e)
+			if !ok {
+				continue
+			}
+			if t := compareClaims(claim, candClaim); t != Neutral {
+				seen[e.ID] = true
+				conflicts = append(conflicts, Conflict{Type: t, Tier: TierStructured, Existing: e, Ref: e.ID, Similarity: 1})
+			}
+		}
+	}
+
+	for _, p := range d.similar(ctx, candidate, existing) {
+		key := p.Existing.ID
+		if key == "" {
+			key = p.Ref
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		p.Type = ClassifyConflict(p.Existing.Content, candidate.Content)
+		if d.opts.NLI != nil {
+			if t, err := d.opts.NLI.Classify(ctx, p.Existing.Content, candidate.Content); err == nil {
+				p.Type, p.Tier = t, TierNLI
+			}
+		}
+		if p.Type != Neutral {
+			conflicts = append(conflicts, p)
+		}
+	}
+	return conflicts
+}
+
+// similar returns existing memories close to the candidate, using the
+// vector index when available and keyword overlap otherwise.
+func (d *Detector) similar(ctx context.Context, candidate StagingEntry, existing []StagingEntry) []Conflict {
+	if d.opts.Embedder != nil && d.opts.Index != nil {
+		if pairs, err := d.embeddingMatches(ctx, candidate, existing); err == nil {
+			return pairs
+		}
+	}
+
+	var pairs []Conflict
+	candWords := tokenize(strings.ToLower(candidate.Content))
+	for _, e := range existing {
+		overlap := keywordOverlap(tokenize(strings.ToLower(e.Content)), candWords)
+		if overlap > 0.5 {
+			pairs = append(pairs, Conflict{Tier: TierKeyword, Existing: e, Ref: e.ID, Similarity: overlap})
+		}
+	}
+	return pairs
+}
+
+func (d *Detector) embeddingMatches(ctx context.Context, candidate StagingEntry, existing []StagingEntry) ([]Conflict, error) {
+	vec, err := d.opts.Embedder.Embed(ctx, candidate.Content)
+	if err != nil {
+		return nil, err
+	}
+	results, err := d.opts.Index.Search(ctx, unit(vec), d.opts.TopK)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []Conflict
+	for _, r := range results {
+		sim := cosineFromL2(r.Distance)
+		if sim < d.opts.SimilarityThreshold {
+			continue
+		}
+		p := Conflict{Tier: TierEmbedding, Ref: r.MemoryID, Similarity: sim,
+			Existing: StagingEntry{Content: r.Text, Confidence: 1.0}}
+		for _, e := range existing {
+			if strings.Contains(r.MemoryID, stagedSlug(e.ID)) {
+				p.Existing, p.Ref = e, e.ID
+				break
+			}
+		}
+		if p.Existing.ID == candidate.ID && p.Existing.ID != "" {
+			continue
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, nil
+}
+
+// cosineFromL2 converts the L2 distance reported by the vector index into
+// cosine similarity. Stored embeddings are unit length, so
+// cos = 1 - d²/2.
+func cosineFromL2(distance float32) float64 {
+	d := float64(distance)
+	return 1 - d*d/2
+}
+
+func unit(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+// entryClaim returns the structured claim attached to e. Only claims the
+// extractor returned explicitly are compared; free text is never parsed
+// into one, since "X uses A" and "X uses B" are usually both true.
+func entryClaim(e StagingEntry) (Claim, bool) {
+	if e.Claim == "" {
+		return Claim{}, false
+	}
+	var c Claim
+	if err := json.Unmarshal([]byte(e.Claim), &c); err != nil || c.Entity == "" {
+		return Claim{}, false
+	}
+	if c.ValidFrom == "" {
+		c.ValidFrom = e.CreatedAt
+	}
+	return c, true
+}
+
+// Resolution records how a candidate was handled after conflict detection.
+type Resolution struct {
+	ID          string   `json:"id"`
+	CandidateID string   `json:"candidate_id"`
+	Action      string   `json:"action"`
+	Tier        string   `json:"tier,omitempty"`
+	Superseded  []string `json:"superseded,omitempty"`
+	Reason      string   `json:"reason"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+func createResolutionsTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS memory_resolutions (
+		id           TEXT PRIMARY KEY,
+		candidate_id TEXT NOT NULL,
+		action       TEXT NOT NULL,
+		tier         TEXT NOT NULL DEFAULT '',
+		superseded   TEXT NOT NULL DEFAULT '[]',
+		reason       TEXT NOT NULL,
+		created_at   TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("staging: create resolutions table: %w", err)
+	}
+	return nil
+}
+
+// Resolve runs conflict detection for a PENDING candidate and acts on it.
+// Without contradictions the candidate is committed (or rejected as a
+// duplicate). A contradiction is won by the side that is more than 0.2
+// more confident; otherwise the newer candidate wins, unless both sides are
+// at or above the escalation threshold, in which case the candidate stays
+// PENDING for a human. Session entries are committed without detection.
+// Every outcome other than a plain commit is recorded in memory_resolutions.
+func (s *Stager) Resolve(ctx context.Context, id string, d *Detector) (Resolution, error) {
+	return s.resolve(ctx, id, d, false)
+}
+
+// Confirm records a human confirmation of a PENDING candidate: its
+// confidence becomes 1.0, contradictions are resolved in its favour, and
+// the committed memory is pinned so it does not decay.
+func (s *Stager) Confirm(ctx context.Context, id string, d *Detector) (Resolution, error) {
+	if _, err := s.db.Exec(`UPDATE staging_memories SET confidence = 1.0 WHERE id = ? AND staging_state = 'PENDING'`, id); err != nil {
+		return Resolution{}, fmt.Errorf("staging: confirm: %w", err)
+	}
+	res, err := s.resolve(ctx, id, d, true)
+	if err != nil {
+		return res, err
+	}
+	if e, getErr := s.Get(id); getErr == nil && e.MemoryID != "" {
+		if err := s.store.Confirm(e.MemoryID); err != nil {
+			return res, fmt.Errorf("staging: confirm: %w", err)
+		}
+	}
+	return res, nil
+}
+
+func (s *Stager) resolve(ctx context.Context, id string, d *Detector, confirmed bool) (Resolution, error) {
+	cand, err := s.Get(id)
+	if err != nil {
+		return Resolution{}, err
+	}
+	if cand.StagingState != "PENDING" {
+		return Resolution{}, fmt.Errorf("staging: entry %s not found or not PENDING", id)
+	}
+	res := Resolution{CandidateID: id, Action: ActionCommit}
+
+	var conflicts []Conflict
+	if cand.Category != "session" {
+		existing, listErr := s.listCommitted()
+		if listErr != nil {
+			return Resolution{}, listErr
+		}
+		conflicts = d.Detect(ctx, cand, existing)
+	}
+
+	var escalate, losers, winners []string
+	for _, c := range conflicts {
+		switch c.Type {
+		case Entailment:
+			if res.Action == ActionCommit {
+				res.Action, res.Tier = ActionDuplicate, c.Tier
+				res.Reason = fmt.Sprintf("restates %s", c.Ref)
+			}
+		case Contradiction:
+			res.Tier = c.Tier
+			diff := cand.Confidence - c.Existing.Confidence
+			switch {
+			case confirmed:
+				winners = append(winners, c.Ref)
+			case diff < -confidenceMargin:
+				losers = append(losers, fmt.Sprintf("%s is more confident (%.2f vs %.2f)", c.Ref, c.Existing.Confidence, cand.Confidence))
+			case diff > confidenceMargin:
+				winners = append(winners, c.Ref)
+			case cand.Confidence >= d.opts.EscalateAbove && c.Existing.Confidence >= d.opts.EscalateAbove:
+				escalate = append(escalate, fmt.Sprintf("contradicts %s (%.2f vs %.2f)", c.Ref, c.Existing.Confidence, cand.Confidence))
+			default:
+				winners = append(winners, c.Ref)
+			}
+		}
+	}
+
+	switch {
+	case len(escalate) > 0:
+		res.Action = ActionEscalate
+		res.Reason = strings.Join(escalate, "; ")
+	case len(losers) > 0:
+		res.Action = ActionReject
+		res.Reason = strings.Join(losers, "; ")
+		if err := s.Reject(id); err != nil {
+			return Resolution{}, err
+		}
+	case len(winners) > 0:
+		res.Action = ActionSupersede
+		res.Superseded = winners
+		if confirmed {
+			res.Reason = "confirmed by user"
+		} else {
+			res.Reason = fmt.Sprintf("newer or more confident than %s", strings.Join(winners, ", "))
+		}
+		if err := s.commitCandidate(id); err != nil {
+			return Resolution{}, err
+		}
+		committed, err := s.Get(id)
+		if err != nil {
+			return Resolution{}, err
+		}
+		for _, ref := range winners {
+			if err := s.supersede(ref, committed.MemoryID); err != nil {
+				return Resolution{}, err
+			}
+		}
+	case res.Action == ActionDuplicate:
+		if err := s.Reject(id); err != nil {
+			return Resolution{}, err
+		}
+	default:
+		if err := s.commitCandidate(id); err != nil {
+			return Resolution{}, err
+		}
+		return res, nil
+	}
+
+	if err := s.recordResolution(&res); err != nil {
+		return Resolution{}, err
+	}
+	return res, nil
+}
+
+// supersede marks the memory behind ref, a staging ID or a memory path from
+// the vector index, as replaced by the memory newMemoryID.
+func (s *Stager) supersede(ref, newMemoryID string) error {
+	oldMemoryID := ""
+	if old, err := s.Get(ref); err == nil {
+		if _, err := s.db.Exec(`UPDATE staging_memories SET staging_state = 'SUPERSEDED' WHERE id = ? AND staging_state = 'COMMITTED'`, ref); err != nil {
+			return fmt.Errorf("staging: supersede %s: %w", ref, err)
+		}
+		oldMemoryID = old.MemoryID
+	} else if entries, listErr := s.store.List(); listErr == nil {
+		for _, e := range entries {
+			if e.Path == ref {
+				oldMemoryID = e.ID
+				break
+			}
+		}
+	}
+	if oldMemoryID == "" || newMemoryID == "" {
+		return nil
+	}
+	if err := s.store.Supersede(oldMemoryID, newMemoryID); err != nil {
+		return fmt.Errorf("staging: supersede %s: %w", ref, err)
+	}
+	return nil
+}
+
+func (s *Stager) commitCandidate(id string) error {
+	if err := s.Verify(id); err != nil {
+		return err
+	}
+	return s.Commit(id)
+}
+
+func (s *Stager) recordResolution(res *Resolution) error {
+	res.ID = fmt.Sprintf("res-%s", uuid.New().String())
+	res.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	superseded, _ := json.Marshal(res.Superseded)
+	if res.Superseded == nil {
+		superseded = []byte("[]")
+	}
+	_, err := s.db.Exec(
+		`INSERT INTO memory_resolutions (id, candidate_id, action, tier, superseded, reason, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		res.ID, res.CandidateID, res.Action, res.Tier, string(superseded), res.Reason, res.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("staging: record resolution: %w", err)
+	}
+	return nil
+}
+
+// Resolutions returns the recorded resolutions for a candidate, oldest
+// first.
+func (s *Stager) Resolutions(candidateID string) ([]Resolution, error) {
+	rows, err := s.db.Query(
+		`SELECT id, candidate_id, action, tier, superseded, reason, created_at FROM memory_resolutions WHERE candidate_id = ? ORDER BY created_at, rowid`,
+		candidateID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("staging: list resolutions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Resolution
+	for rows.Next() {
+		var r Resolution
+		var superseded string
+		if err := rows.Scan(&r.ID, &r.CandidateID, &r.Action, &r.Tier, &superseded, &r.Reason, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("staging: scan resolution: %w", err)
+		}
+		json.Unmarshal([]byte(superseded), &r.Superseded)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,245 @@
+package staging
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lyndonlyu/apex/internal/executor"
+	"github.com/lyndonlyu/apex/internal/vectordb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareClaims(t *testing.T) {
+	a := Claim{Entity: "api", Property: "timeout", Value: "30s"}
+	assert.Equal(t, Contradiction, compareClaims(a, Claim{Entity: "API", Property: "Timeout", Value: "60s"}))
+	assert.Equal(t, Entailment, compareClaims(a, Claim{Entity: "api", Property: "timeout", Value: "30S"}))
+	assert.Equal(t, Neutral, compareClaims(a, Claim{Entity: "api", Property: "port", Value: "30s"}))
+
+	old := Claim{Entity: "api", Property: "timeout", Value: "30s", ValidTo: "2026-01-01T00:00:00Z"}
+	newer := Claim{Entity: "api", Property: "timeout", Value: "60s", ValidFrom: "2026-01-01T00:00:00Z"}
+	assert.Equal(t, Neutral, compareClaims(old, newer), "disjoint validity periods do not conflict")
+}
+
+func commitEntry(t *testing.T, s *Stager, content, category string, confidence float64) string {
+	t.Helper()
+	id, err := s.StageWithConfidence(content, category, "run-old", confidence, "")
+	require.NoError(t, err)
+	require.NoError(t, s.Verify(id))
+	require.NoError(t, s.Commit(id))
+	return id
+}
+
+func timeoutClaim(value string) Claim {
+	return Claim{Entity: "api", Property: "timeout", Value: value}
+}
+
+func TestResolveNoConflictCommits(t *testing.T) {
+	s, _ := setupTest(t)
+	commitEntry(t, s, "The API timeout is 30 seconds", "fact", 0.9)
+
+	id, err := s.StageWithConfidence("The server port is 8080", "fact", "run-new", 0.8, "")
+	require.NoError(t, err)
+	res, err := s.Resolve(context.Background(), id, NewDetector(DetectorOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, ActionCommit, res.Action)
+
+	e, err := s.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, "COMMITTED", e.StagingState)
+}
+
+func TestResolveNewerSupersedes(t *testing.T) {
+	s, _ := setupTest(t)
+	oldID := commitEntry(t, s, "The API timeout is 30 seconds", "fact", 0.8)
+	require.NoError(t, s.SetClaim(oldID, timeoutClaim("30 seconds")))
+
+	id, err := s.StageWithConfidence("The API timeout is 60 seconds", "fact", "run-new", 0.85, "")
+	require.NoError(t, err)
+	require.NoError(t, s.SetClaim(id, timeoutClaim("60 seconds")))
+	res, err := s.Resolve(context.Background(), id, NewDetector(DetectorOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, ActionSupersede, res.Action)
+	assert.Equal(t, TierStructured, res.Tier)
+	assert.Equal(t, []string{oldID}, res.Superseded)
+
+	old, err := s.Get(oldID)
+	require.NoError(t, err)
+	assert.Equal(t, "SUPERSEDED", old.StagingState)
+
+	committed, err := s.Get(id)
+	require.NoError(t, err)
+	chain, err := s.store.History(committed.MemoryID)
+	require.NoError(t, err)
+	require.Len(t, chain, 2, "memory files are linked as versions")
+	assert.Equal(t, old.MemoryID, chain[0].ID)
+	assert.False(t, chain[0].IsCurrent)
+
+	recorded, err := s.Resolutions(id)
+	require.NoError(t, err)
+	require.Len(t, recorded, 1)
+	assert.Equal(t, []string{oldID}, recorded[0].Superseded)
+	assert.NotEmpty(t, recorded[0].Reason)
+}
+
+func TestResolveMoreConfidentExistingWins(t *testing.T) {
+	s, _ := setupTest(t)
+	oldID := commitEntry(t, s, "The API timeout is 30 seconds", "fact", 1.0)
+	require.NoError(t, s.SetClaim(oldID, timeoutClaim("30 seconds")))
+
+	id, err := s.StageWithConfidence("The API timeout is 60 seconds", "fact", "run-new", 0.5, "")
+	require.NoError(t, err)
+	require.NoError(t, s.SetClaim(id, timeoutClaim("60 seconds")))
+	res, err := s.Resolve(context.Background(), id, NewDetector(DetectorOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, ActionReject, res.Action)
+
+	e, _ := s.Get(id)
+	assert.Equal(t, "REJECTED", e.StagingState)
+}
+
+func TestResolveEscalatesAndConfirm(t *testing.T) {
+	s, _ := setupTest(t)
+	oldID := commitEntry(t, s, "The API timeout is 30 seconds", "fact", 0.95)
+	require.NoError(t, s.SetClaim(oldID, timeoutClaim("30 seconds")))
+
+	id, err := s.StageWithConfidence("The API timeout is 60 seconds", "fact", "run-new", 0.95, "")
+	require.NoError(t, err)
+	require.NoError(t, s.SetClaim(id, timeoutClaim("60 seconds")))
+	d := NewDetector(DetectorOptions{})
+	res, err := s.Resolve(context.Background(), id, d)
+	require.NoError(t, err)
+	assert.Equal(t, ActionEscalate, res.Action)
+
+	pending, err := s.ListPending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+
+	res, err = s.Confirm(context.Background(), id, d)
+	require.NoError(t, err)
+	assert.Equal(t, ActionSupersede, res.Action)
+	assert.Equal(t, "confirmed by user", res.Reason)
+	old, _ := s.Get(oldID)
+	assert.Equal(t, "SUPERSEDED", old.StagingState)
+
+	confirmed, _ := s.Get(id)
+	mem, err := s.store.Get(confirmed.MemoryID)
+	require.NoError(t, err)
+	assert.True(t, mem.Confirmed)
+	assert.Equal(t, 1.0, mem.Confidence)
+}
+
+func TestResolveDuplicate(t *testing.T) {
+	s, _ := setupTest(t)
+	commitEntry(t, s, "The API timeout is 30 seconds", "fact", 0.9)
+
+	id, err := s.StageWithConfidence("the api timeout is 30 seconds.", "fact", "run-new", 0.9, "")
+	require.NoError(t, err)
+	res, err := s.Resolve(context.Background(), id, NewDetector(DetectorOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, ActionDuplicate, res.Action)
+}
+
+func TestResolveUsesStoredClaim(t *testing.T) {
+	s, _ := setupTest(t)
+	oldID := commitEntry(t, s, "We cache sessions in memcache", "decision", 0.7)
+	require.NoError(t, s.SetClaim(oldID, Claim{Entity: "sessions", Property: "cache", Value: "memcache"}))
+
+	id, err := s.StageWithConfidence("Sessions moved to redis", "decision", "run-new", 0.7, "")
+	require.NoError(t, err)
+	require.NoError(t, s.SetClaim(id, Claim{Entity: "sessions", Property: "cache", Value: "redis"}))
+
+	res, err := s.Resolve(context.Background(), id, NewDetector(DetectorOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, ActionSupersede, res.Action)
+}
+
+func TestResolveKeepsCompatibleProse(t *testing.T) {
+	s, _ := setupTest(t)
+	oldID := commitEntry(t, s, "The project uses Go.", "fact", 0.9)
+
+	id, err := s.StageWithConfidence("The project uses SQLite for storage.", "fact", "run-new", 0.5, "")
+	require.NoError(t, err)
+	res, err := s.Resolve(context.Background(), id, NewDetector(DetectorOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, ActionCommit, res.Action, "both facts hold; prose is not parsed into claims")
+
+	old, err := s.Get(oldID)
+	require.NoError(t, err)
+	assert.Equal(t, "COMMITTED", old.StagingState)
+}
+
+func TestResolveSessionSkipsDetection(t *testing.T) {
+	s, _ := setupTest(t)
+	id, err := s.Stage("run summary", "session", "run-1")
+	require.NoError(t, err)
+	res, err := s.Resolve(context.Background(), id, NewDetector(DetectorOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, ActionCommit, res.Action)
+}
+
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return []float32{3, 4}, nil
+}
+
+type fakeIndex struct{ results []vectordb.VectorResult }
+
+func (f fakeIndex) Search(ctx context.Context, q []float32, topK int) ([]vectordb.VectorResult, error) {
+	return f.results, nil
+}
+
+type fakeNLI struct {
+	label ConflictType
+	err   error
+}
+
+func (f fakeNLI) Classify(ctx context.Context, premise, hypothesis string) (ConflictType, error) {
+	return f.label, f.err
+}
+
+func TestDetectEmbeddingWithNLI(t *testing.T) {
+	existing := StagingEntry{ID: "stg-abc", Content: "Deploys happen on Fridays", Confidence: 0.9}
+	d := NewDetector(DetectorOptions{
+		Embedder: fakeEmbedder{},
+		Index: fakeIndex{results: []vectordb.VectorResult{
+			{MemoryID: "facts/20260101-000000-staged-stg-abc.md", Distance: 0.2, Text: existing.Content},
+			{MemoryID: "facts/far.md", Distance: 1.2, Text: "unrelated"},
+		}},
+		NLI: fakeNLI{label: Contradiction},
+	})
+	cand := StagingEntry{ID: "stg-new", Content: "We never deploy on Fridays", Confidence: 0.9}
+	conflicts := d.Detect(context.Background(), cand, []StagingEntry{existing})
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, TierNLI, conflicts[0].Tier)
+	assert.Equal(t, "stg-abc", conflicts[0].Ref)
+	assert.InDelta(t, 0.98, conflicts[0].Similarity, 0.001)
+}
+
+func TestDetectNLIErrorFallsBackToKeywords(t *testing.T) {
+	existing := StagingEntry{ID: "stg-1", Content: "deployments run during the freeze window"}
+	d := NewDetector(DetectorOptions{NLI: fakeNLI{err: errors.New("down")}})
+	cand := StagingEntry{ID: "stg-2", Content: "deployments do not run during the freeze window"}
+	conflicts := d.Detect(context.Background(), cand, []StagingEntry{existing})
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, Contradiction, conflicts[0].Type)
+	assert.Equal(t, TierKeyword, conflicts[0].Tier)
+}
+
+type fakeRunner struct{ output string }
+
+func (f fakeRunner) Run(ctx context.Context, task string) (executor.Result, error) {
+	return executor.Result{Output: f.output}, nil
+}
+
+func TestModelNLI(t *testing.T) {
+	n := NewModelNLI(fakeRunner{output: "**Contradiction**"})
+	got, err := n.Classify(context.Background(), "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, Contradiction, got)
+
+	_, err = NewModelNLI(fakeRunner{output: "not sure"}).Classify(context.Background(), "a", "b")
+	assert.Error(t, err)
+}
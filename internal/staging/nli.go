@@ -1,6 +1,12 @@
 package staging
 
-import "strings"
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lyndonlyu/apex/internal/executor"
+)
 
 // ConflictType represents the relationship between two memories.
 type ConflictType string
@@ -48,6 +54,54 @@ func ClassifyConflict(existing, candidate string) ConflictType {
 	return Neutral
 }
 
+// NLI classifies whether hypothesis contradicts, entails or is neutral
+// towards premise.
+type NLI interface {
+	Classify(ctx context.Context, premise, hypothesis string) (ConflictType, error)
+}
+
+// Runner runs a prompt against a model. *executor.Executor satisfies it.
+type Runner interface {
+	Run(ctx context.Context, task string) (executor.Result, error)
+}
+
+// ModelNLI classifies memory pairs with a small model.
+type ModelNLI struct {
+	runner Runner
+}
+
+// NewModelNLI creates an NLI backed by runner.
+func NewModelNLI(runner Runner) *ModelNLI {
+	return &ModelNLI{runner: runner}
+}
+
+// Classify implements NLI. The model must answer with one of the three
+// labels; anything else is an error so callers can fall back.
+func (m *ModelNLI) Classify(ctx context.Context, premise, hypothesis string) (ConflictType, error) {
+	prompt := fmt.Sprintf(`Classify the relationship between two statements about the same project.
+
+Answer with exactly one word: CONTRADICTION, ENTAILMENT or NEUTRAL.
+
+Premise: %s
+Hypothesis: %s`, premise, hypothesis)
+	result, err := m.runner.Run(ctx, prompt)
+	if err != nil {
+		return Neutral, fmt.Errorf("staging: nli: %w", err)
+	}
+	return ParseNLILabel(result.Output)
+}
+
+// ParseNLILabel extracts the first conflict label from model output.
+func ParseNLILabel(output string) (ConflictType, error) {
+	for _, w := range strings.Fields(strings.ToUpper(output)) {
+		switch t := ConflictType(strings.Trim(w, ".,:;*\"'`")); t {
+		case Contradiction, Entailment, Neutral:
+			return t, nil
+		}
+	}
+	return Neutral, fmt.Errorf("staging: nli: no label in %q", output)
+}
+
 // tokenize splits text into lowercase word tokens.
 func tokenize(text string) map[string]bool {
 	words := make(map[string]bool)
@@ -1,8 +1,12 @@
 // Package staging implements a memory verification pipeline with staged commit.
+// Entries move PENDING→VERIFIED/UNVERIFIED/REJECTED/EXPIRED→COMMITTED; a
+// committed entry becomes SUPERSEDED when a later candidate wins a conflict
+// with it.
 package staging
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -18,6 +22,9 @@ type StagingEntry struct {
 	Source       string  `json:"source"`
 	StagingState string  `json:"staging_state"`
 	Confidence   float64 `json:"confidence"`
+	Evidence     string  `json:"evidence,omitempty"`
+	Claim        string  `json:"claim,omitempty"`
+	MemoryID     string  `json:"memory_id,omitempty"` // memory.Entry ID written by Commit
 	CreatedAt    string  `json:"created_at"`
 	CommittedAt  string  `json:"committed_at,omitempty"`
 	ExpiredAt    string  `json:"expired_at,omitempty"`
@@ -40,21 +47,67 @@ func New(db *sql.DB, store *memory.Store) (*Stager, error) {
 		confidence    REAL NOT NULL DEFAULT 1.0,
 		created_at    TEXT NOT NULL,
 		committed_at  TEXT,
-		expired_at    TEXT
+		expired_at    TEXT,
+		evidence      TEXT NOT NULL DEFAULT '',
+		claim         TEXT NOT NULL DEFAULT '',
+		memory_id     TEXT NOT NULL DEFAULT ''
 	)`
 	if _, err := db.Exec(createTable); err != nil {
 		return nil, fmt.Errorf("staging: create table: %w", err)
 	}
+	for _, col := range []string{"evidence", "claim", "memory_id"} {
+		if err := ensureColumn(db, col, `TEXT NOT NULL DEFAULT ''`); err != nil {
+			return nil, err
+		}
+	}
+	if err := createResolutionsTable(db); err != nil {
+		return nil, err
+	}
 	return &Stager{db: db, store: store}, nil
 }
 
-// Stage inserts a new memory candidate into the staging pipeline.
+// ensureColumn adds a column to staging_memories in databases created
+// before the column existed.
+func ensureColumn(db *sql.DB, name, def string) error {
+	rows, err := db.Query(`PRAGMA table_info(staging_memories)`)
+	if err != nil {
+		return fmt.Errorf("staging: table info: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			col, typ         string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &col, &typ, &notNull, &dflt, &pk); err != nil {
+			return fmt.Errorf("staging: table info: %w", err)
+		}
+		if col == name {
+			return nil
+		}
+	}
+	rows.Close()
+	if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE staging_memories ADD COLUMN %s %s`, name, def)); err != nil {
+		return fmt.Errorf("staging: add column %s: %w", name, err)
+	}
+	return nil
+}
+
+// Stage inserts a new memory candidate into the staging pipeline with full
+// confidence and no evidence.
 func (s *Stager) Stage(content, category, source string) (string, error) {
+	return s.StageWithConfidence(content, category, source, 1.0, "")
+}
+
+// StageWithConfidence inserts a new memory candidate with the given
+// confidence and serialized evidence.
+func (s *Stager) StageWithConfidence(content, category, source string, confidence float64, evidence string) (string, error) {
 	id := fmt.Sprintf("stg-%s", uuid.New().String())
 	now := time.Now().UTC().Format(time.RFC3339)
 	_, err := s.db.Exec(
-		`INSERT INTO staging_memories (id, content, category, source, staging_state, confidence, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		id, content, category, source, "PENDING", 1.0, now,
+		`INSERT INTO staging_memories (id, content, category, source, staging_state, confidence, created_at, evidence) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, content, category, source, "PENDING", confidence, now, evidence,
 	)
 	if err != nil {
 		return "", fmt.Errorf("staging: insert: %w", err)
@@ -62,6 +115,58 @@ func (s *Stager) Stage(content, category, source string) (string, error) {
 	return id, nil
 }
 
+// SetClaim attaches a structured claim to an entry for conflict detection.
+func (s *Stager) SetClaim(id string, c Claim) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("staging: marshal claim: %w", err)
+	}
+	if _, err := s.db.Exec(`UPDATE staging_memories SET claim = ? WHERE id = ?`, string(data), id); err != nil {
+		return fmt.Errorf("staging: set claim: %w", err)
+	}
+	return nil
+}
+
+// entryColumns is the column list scanned by scanEntry.
+const entryColumns = `id, content, category, source, staging_state, confidence, created_at, COALESCE(committed_at, ''), evidence, claim, memory_id`
+
+func scanEntry(row interface{ Scan(...any) error }) (StagingEntry, error) {
+	var e StagingEntry
+	err := row.Scan(&e.ID, &e.Content, &e.Category, &e.Source, &e.StagingState, &e.Confidence, &e.CreatedAt, &e.CommittedAt, &e.Evidence, &e.Claim, &e.MemoryID)
+	return e, err
+}
+
+// Get returns a single staging entry.
+func (s *Stager) Get(id string) (StagingEntry, error) {
+	e, err := scanEntry(s.db.QueryRow(`SELECT `+entryColumns+` FROM staging_memories WHERE id = ?`, id))
+	if err != nil {
+		return StagingEntry{}, fmt.Errorf("staging: get %s: %w", id, err)
+	}
+	return e, nil
+}
+
+// listCommitted returns the committed fact, decision and incident entries
+// that conflict detection compares candidates against.
+func (s *Stager) listCommitted() ([]StagingEntry, error) {
+	rows, err := s.db.Query(
+		`SELECT ` + entryColumns + ` FROM staging_memories WHERE staging_state = 'COMMITTED' AND category != 'session' ORDER BY committed_at`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("staging: list committed: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []StagingEntry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("staging: scan: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
 // Verify transitions a staging entry from PENDING to VERIFIED.
 func (s *Stager) Verify(id string) error {
 	result, err := s.db.Exec(
@@ -106,19 +211,15 @@ func (s *Stager) Commit(id string) error {
 		return fmt.Errorf("staging: commit lookup: %w", err)
 	}
 
-	slug := fmt.Sprintf("staged-%s", id)
-	if len(slug) > 40 {
-		slug = slug[:40]
-	}
+	slug := stagedSlug(id)
+	var memoryID string
 	switch category {
-	case "decision":
-		if err := s.store.SaveDecision(slug, content); err != nil {
-			return fmt.Errorf("staging: commit write: %w", err)
-		}
-	case "fact":
-		if err := s.store.SaveFact(slug, content); err != nil {
+	case "decision", "fact", "incident":
+		e, err := s.store.SaveEntryWithConfidence(category, slug, content, confidence)
+		if err != nil {
 			return fmt.Errorf("staging: commit write: %w", err)
 		}
+		memoryID = e.ID
 	case "session":
 		if err := s.store.SaveSession(slug, "staged-commit", content); err != nil {
 			return fmt.Errorf("staging: commit write: %w", err)
@@ -127,8 +228,8 @@ func (s *Stager) Commit(id string) error {
 
 	now := time.Now().UTC().Format(time.RFC3339)
 	_, err = s.db.Exec(
-		`UPDATE staging_memories SET staging_state = 'COMMITTED', committed_at = ? WHERE id = ?`,
-		now, id,
+		`UPDATE staging_memories SET staging_state = 'COMMITTED', committed_at = ?, memory_id = ? WHERE id = ?`,
+		now, memoryID, id,
 	)
 	if err != nil {
 		return fmt.Errorf("staging: commit update: %w", err)
@@ -136,6 +237,15 @@ func (s *Stager) Commit(id string) error {
 	return nil
 }
 
+// stagedSlug is the memory file slug Commit uses for an entry.
+func stagedSlug(id string) string {
+	slug := fmt.Sprintf("staged-%s", id)
+	if len(slug) > 40 {
+		slug = slug[:40]
+	}
+	return slug
+}
+
 // CommitAll commits all VERIFIED entries and returns the count.
 func (s *Stager) CommitAll() (int, error) {
 	rows, err := s.db.Query(
@@ -180,7 +290,7 @@ func (s *Stager) ExpireStale(timeout time.Duration) (int, error) {
 // ListPending returns all entries in PENDING state.
 func (s *Stager) ListPending() ([]StagingEntry, error) {
 	rows, err := s.db.Query(
-		`SELECT id, content, category, source, staging_state, confidence, created_at FROM staging_memories WHERE staging_state = 'PENDING' ORDER BY created_at`,
+		`SELECT ` + entryColumns + ` FROM staging_memories WHERE staging_state = 'PENDING' ORDER BY created_at`,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("staging: list pending: %w", err)
@@ -189,8 +299,8 @@ func (s *Stager) ListPending() ([]StagingEntry, error) {
 
 	var entries []StagingEntry
 	for rows.Next() {
-		var e StagingEntry
-		if err := rows.Scan(&e.ID, &e.Content, &e.Category, &e.Source, &e.StagingState, &e.Confidence, &e.CreatedAt); err != nil {
+		e, err := scanEntry(rows)
+		if err != nil {
 			return nil, fmt.Errorf("staging: scan: %w", err)
 		}
 		entries = append(entries, e)
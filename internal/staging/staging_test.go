@@ -119,3 +119,75 @@ func TestCommitUnverified(t *testing.T) {
 	err = s.Commit(id)
 	require.NoError(t, err)
 }
+
+func TestStageWithConfidence(t *testing.T) {
+	s, _ := setupTest(t)
+
+	id, err := s.StageWithConfidence("chose redis for caching", "decision", "run-4", 0.6, `[{"type":"action_id","ref":"impl"}]`)
+	require.NoError(t, err)
+
+	entries, err := s.ListPending()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, id, entries[0].ID)
+	assert.Equal(t, 0.6, entries[0].Confidence)
+	assert.Contains(t, entries[0].Evidence, "action_id")
+}
+
+func TestCommitIncident(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	memDir := filepath.Join(dir, "memory")
+	store, err := memory.NewStore(memDir)
+	require.NoError(t, err)
+	s, err := New(db, store)
+	require.NoError(t, err)
+
+	id, err := s.StageWithConfidence("migration locked the table", "incident", "run-5", 0.95, "")
+	require.NoError(t, err)
+	require.NoError(t, s.Verify(id))
+	require.NoError(t, s.Commit(id))
+
+	files, _ := filepath.Glob(filepath.Join(memDir, "incidents", "*.md"))
+	assert.Len(t, files, 1)
+}
+
+func TestNewAddsEvidenceColumn(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "old.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE staging_memories (
+		id TEXT PRIMARY KEY, content TEXT NOT NULL, category TEXT NOT NULL, source TEXT NOT NULL,
+		staging_state TEXT NOT NULL DEFAULT 'PENDING', confidence REAL NOT NULL DEFAULT 1.0,
+		created_at TEXT NOT NULL, committed_at TEXT, expired_at TEXT)`)
+	require.NoError(t, err)
+
+	store, err := memory.NewStore(filepath.Join(dir, "memory"))
+	require.NoError(t, err)
+	s, err := New(db, store)
+	require.NoError(t, err)
+
+	_, err = s.StageWithConfidence("x", "fact", "run", 0.5, "e")
+	require.NoError(t, err)
+	_, err = New(db, store)
+	require.NoError(t, err, "second open must not re-add the column")
+}
+
+func TestCommitCarriesConfidence(t *testing.T) {
+	s, _ := setupTest(t)
+
+	id, err := s.StageWithConfidence("CI runs on every push", "fact", "run-6", 0.7, "")
+	require.NoError(t, err)
+	require.NoError(t, s.Verify(id))
+	require.NoError(t, s.Commit(id))
+
+	e, err := s.Get(id)
+	require.NoError(t, err)
+	mem, err := s.store.Get(e.MemoryID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.7, mem.Confidence)
+}
@@ -0,0 +1,216 @@
+// Package vecsync keeps the vector index in step with the memory database.
+// Every memory row carries a vec_sync_status; the Reconciler embeds PENDING
+// rows in the background, retrying with backoff and marking rows FAILED
+// when retries run out, and Check repairs drift such as a crash between
+// writing a memory and writing its vector.
+package vecsync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/retry"
+)
+
+// maxTextChars bounds the text sent to the embedder per memory.
+const maxTextChars = 8000
+
+// batchSize is the number of paths synced per pass of Sync.
+const batchSize = 50
+
+// Embedder generates vector embeddings from text.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	Available() bool
+}
+
+// Index is the vector store. *vectordb.VectorDB satisfies it.
+type Index interface {
+	Index(ctx context.Context, memoryID, text string, embedding []float32) error
+	Delete(memoryID string) error
+	IDs(ctx context.Context) ([]string, error)
+}
+
+// Stats counts what a Check or Sync pass did.
+type Stats struct {
+	Synced   int // vectors written
+	Deleted  int // vectors removed
+	Failed   int // paths marked FAILED
+	Requeued int // paths found out of sync and marked PENDING
+}
+
+// Reconciler syncs a memory store's PENDING rows into a vector index.
+type Reconciler struct {
+	store    *memory.Store
+	index    Index
+	embedder Embedder
+	policy   retry.Policy
+	interval time.Duration
+
+	mu     sync.Mutex // one pass at a time
+	notify chan struct{}
+}
+
+// New creates a Reconciler. policy governs retries of each embedding;
+// interval is how often Run looks for pending rows without being notified.
+func New(store *memory.Store, index Index, embedder Embedder, policy retry.Policy, interval time.Duration) *Reconciler {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &Reconciler{
+		store:    store,
+		index:    index,
+		embedder: embedder,
+		policy:   policy,
+		interval: interval,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// Check compares the index with the store. Current memories that are
+// SYNCED but have no vector, and FAILED ones, are marked PENDING; vectors
+// with no current memory are deleted.
+func (r *Reconciler) Check(ctx context.Context) (Stats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var st Stats
+	status, err := r.store.VectorSyncStatus()
+	if err != nil {
+		return st, err
+	}
+	ids, err := r.index.IDs(ctx)
+	if err != nil {
+		return st, fmt.Errorf("vecsync: list vectors: %w", err)
+	}
+	indexed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		indexed[id] = true
+		if _, ok := status[id]; !ok {
+			if err := r.index.Delete(id); err != nil {
+				return st, fmt.Errorf("vecsync: delete %s: %w", id, err)
+			}
+			st.Deleted++
+		}
+	}
+
+	var requeue []string
+	for path, s := range status {
+		if s == memory.VecFailed || (s == memory.VecSynced && !indexed[path]) {
+			requeue = append(requeue, path)
+		}
+	}
+	if len(requeue) > 0 {
+		if err := r.store.RequeueVectors(requeue...); err != nil {
+			return st, err
+		}
+		st.Requeued = len(requeue)
+	}
+	return st, nil
+}
+
+// Sync works through every PENDING path: current memories are embedded and
+// indexed, paths with nothing current have their vector deleted. It does
+// nothing when the embedder is unavailable.
+func (r *Reconciler) Sync(ctx context.Context) (Stats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var st Stats
+	if !r.embedder.Available() {
+		return st, nil
+	}
+	// Paths that fail stay out of the pending set, so every pass makes
+	// progress and the loop ends.
+	for {
+		tasks, err := r.store.PendingVectors(batchSize)
+		if err != nil {
+			return st, err
+		}
+		if len(tasks) == 0 {
+			return st, nil
+		}
+		for _, t := range tasks {
+			if err := ctx.Err(); err != nil {
+				return st, err
+			}
+			if err := r.syncOne(ctx, t, &st); err != nil {
+				return st, err
+			}
+		}
+	}
+}
+
+func (r *Reconciler) syncOne(ctx context.Context, t memory.VectorTask, st *Stats) error {
+	if t.Text == "" {
+		if err := r.index.Delete(t.Path); err != nil {
+			return r.fail(t, err, st)
+		}
+		st.Deleted++
+		return r.store.MarkVector(t, memory.VecSynced, "")
+	}
+
+	text := t.Text
+	if len(text) > maxTextChars {
+		text = text[:maxTextChars]
+	}
+	_, err := r.policy.Execute(ctx, func() (string, error, retry.ErrorKind) {
+		vec, err := r.embedder.Embed(ctx, text)
+		if err != nil {
+			return "", err, retry.Classify(err, 0, err.Error())
+		}
+		if err := r.index.Index(ctx, t.Path, text, vec); err != nil {
+			return "", err, retry.Unknown
+		}
+		return "", nil, retry.Retriable
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return r.fail(t, err, st)
+	}
+	st.Synced++
+	return r.store.MarkVector(t, memory.VecSynced, "")
+}
+
+func (r *Reconciler) fail(t memory.VectorTask, cause error, st *Stats) error {
+	st.Failed++
+	return r.store.MarkVector(t, memory.VecFailed, cause.Error())
+}
+
+// Notify asks a running Run loop to sync now instead of at its next tick.
+func (r *Reconciler) Notify() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run checks the index once, then syncs pending rows on every tick or
+// Notify until ctx is done. errf, if non-nil, receives pass errors.
+func (r *Reconciler) Run(ctx context.Context, errf func(error)) {
+	report := func(err error) {
+		if err != nil && ctx.Err() == nil && errf != nil {
+			errf(err)
+		}
+	}
+	_, err := r.Check(ctx)
+	report(err)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		_, err := r.Sync(ctx)
+		report(err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.notify:
+		}
+	}
+}
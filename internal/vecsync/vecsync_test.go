@@ -0,0 +1,210 @@
+package vecsync
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lyndonlyu/apex/internal/memory"
+	"github.com/lyndonlyu/apex/internal/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEmbedder struct {
+	mu        sync.Mutex
+	available bool
+	failFor   map[string]bool // texts that always fail
+	calls     int
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.failFor[text] {
+		return nil, errors.New("connection reset")
+	}
+	return []float32{float32(len(text))}, nil
+}
+
+func (f *fakeEmbedder) Available() bool { return f.available }
+
+type fakeIndex struct {
+	mu   sync.Mutex
+	text map[string]string
+}
+
+func newFakeIndex() *fakeIndex { return &fakeIndex{text: map[string]string{}} }
+
+func (f *fakeIndex) Index(ctx context.Context, id, text string, vec []float32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.text[id] = text
+	return nil
+}
+
+func (f *fakeIndex) Delete(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.text, id)
+	return nil
+}
+
+func (f *fakeIndex) IDs(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	for id := range f.text {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func newTestStore(t *testing.T) *memory.Store {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := memory.OpenDB(filepath.Join(t.TempDir(), "memory.db"), dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	store, err := memory.NewDBStore(dir, db, false)
+	require.NoError(t, err)
+	return store
+}
+
+func fastPolicy() retry.Policy {
+	return retry.Policy{MaxAttempts: 2, InitDelay: time.Millisecond, Multiplier: 1, MaxDelay: time.Millisecond}
+}
+
+func TestSyncIndexesPendingMemories(t *testing.T) {
+	store := newTestStore(t)
+	v1, err := store.SaveEntry("fact", "db", "Use mysql.")
+	require.NoError(t, err)
+	require.NoError(t, store.SaveSession("s1", "deploy", "ok"))
+
+	idx := newFakeIndex()
+	emb := &fakeEmbedder{available: true}
+	r := New(store, idx, emb, fastPolicy(), time.Minute)
+
+	st, err := r.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, st.Synced)
+	assert.Equal(t, "Use mysql.", idx.text[v1.Path])
+	assert.Contains(t, idx.text["sessions/s1.jsonl"], `"task":"deploy"`)
+
+	// Superseding moves the vector to the new version.
+	v2, err := store.Update(v1.ID, "Use postgres.")
+	require.NoError(t, err)
+	st, err = r.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Synced)
+	assert.Equal(t, 1, st.Deleted)
+	assert.NotContains(t, idx.text, v1.Path)
+	assert.Equal(t, "Use postgres.", idx.text[v2.Path])
+
+	// Hits do not change content, so nothing is re-embedded.
+	calls := emb.calls
+	require.NoError(t, store.RecordHit(v2.Path))
+	st, err = r.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Stats{}, st)
+	assert.Equal(t, calls, emb.calls)
+
+	status, err := store.VectorSyncStatus()
+	require.NoError(t, err)
+	assert.Equal(t, memory.VecSynced, status[v2.Path])
+}
+
+func TestSyncMarksFailedAndCheckRequeues(t *testing.T) {
+	store := newTestStore(t)
+	e, err := store.SaveEntry("fact", "bad", "unembeddable")
+	require.NoError(t, err)
+
+	idx := newFakeIndex()
+	emb := &fakeEmbedder{available: true, failFor: map[string]bool{"unembeddable": true}}
+	r := New(store, idx, emb, fastPolicy(), time.Minute)
+
+	st, err := r.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Failed)
+	assert.Equal(t, 2, emb.calls, "retried per policy")
+	status, err := store.VectorSyncStatus()
+	require.NoError(t, err)
+	assert.Equal(t, memory.VecFailed, status[e.Path])
+
+	emb.failFor = nil
+	st, err = r.Check(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Requeued)
+	st, err = r.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Synced)
+}
+
+func TestCheckRepairsDrift(t *testing.T) {
+	store := newTestStore(t)
+	e, err := store.SaveEntry("fact", "cache", "Redis.")
+	require.NoError(t, err)
+
+	idx := newFakeIndex()
+	r := New(store, idx, &fakeEmbedder{available: true}, fastPolicy(), time.Minute)
+	_, err = r.Sync(context.Background())
+	require.NoError(t, err)
+
+	// The vector is lost (e.g. vectors.db deleted) and an orphan appears.
+	delete(idx.text, e.Path)
+	idx.text["facts/gone.md"] = "stale"
+
+	st, err := r.Check(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Requeued)
+	assert.Equal(t, 1, st.Deleted)
+	assert.NotContains(t, idx.text, "facts/gone.md")
+
+	_, err = r.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Redis.", idx.text[e.Path])
+}
+
+func TestSyncWithoutEmbedder(t *testing.T) {
+	store := newTestStore(t)
+	e, err := store.SaveEntry("fact", "x", "pending forever")
+	require.NoError(t, err)
+
+	r := New(store, newFakeIndex(), &fakeEmbedder{}, fastPolicy(), time.Minute)
+	st, err := r.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Stats{}, st)
+	status, err := store.VectorSyncStatus()
+	require.NoError(t, err)
+	assert.Equal(t, memory.VecPending, status[e.Path])
+}
+
+func TestRunSyncsOnNotify(t *testing.T) {
+	store := newTestStore(t)
+	idx := newFakeIndex()
+	r := New(store, idx, &fakeEmbedder{available: true}, fastPolicy(), time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, func(err error) { t.Errorf("run: %v", err) })
+		close(done)
+	}()
+
+	e, err := store.SaveEntry("fact", "late", "written while running")
+	require.NoError(t, err)
+	r.Notify()
+	assert.Eventually(t, func() bool {
+		ids, _ := idx.IDs(context.Background())
+		return len(ids) == 1 && ids[0] == e.Path
+	}, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
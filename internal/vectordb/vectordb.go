@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"os"
 	"time"
 
 	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
@@ -54,6 +55,15 @@ func Open(dbPath string, dimensions int) (*VectorDB, error) {
 		return nil, fmt.Errorf("create meta table: %w", err)
 	}
 
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS vec_info (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create info table: %w", err)
+	}
+
 	createVec := fmt.Sprintf(
 		`CREATE VIRTUAL TABLE IF NOT EXISTS vec_memories USING vec0(embedding float[%d])`,
 		dimensions,
@@ -180,3 +190,61 @@ func (v *VectorDB) Count() (int, error) {
 	err := v.db.QueryRow(`SELECT COUNT(*) FROM vec_meta`).Scan(&count)
 	return count, err
 }
+
+// IDs returns the memory IDs that have both metadata and a vector. An
+// interrupted Index can leave metadata without a vector; such IDs are not
+// returned.
+func (v *VectorDB) IDs(ctx context.Context) ([]string, error) {
+	rows, err := v.db.QueryContext(ctx,
+		`SELECT m.memory_id FROM vec_meta m JOIN vec_memories v ON v.rowid = m.rowid ORDER BY m.memory_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Fingerprint returns the embedder fingerprint recorded with
+// SetFingerprint, or "" for an index that has none.
+func (v *VectorDB) Fingerprint() (string, error) {
+	var fp string
+	err := v.db.QueryRow(`SELECT value FROM vec_info WHERE key = 'fingerprint'`).Scan(&fp)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read fingerprint: %w", err)
+	}
+	return fp, nil
+}
+
+// SetFingerprint records which embedder produced the stored vectors.
+func (v *VectorDB) SetFingerprint(fp string) error {
+	_, err := v.db.Exec(
+		`INSERT INTO vec_info (key, value) VALUES ('fingerprint', ?)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value`, fp)
+	if err != nil {
+		return fmt.Errorf("write fingerprint: %w", err)
+	}
+	return nil
+}
+
+// Recreate deletes the database at dbPath, if any, and opens an empty one.
+// Use it to rebuild the index from scratch, e.g. after changing dimensions.
+func Recreate(dbPath string, dimensions int) (*VectorDB, error) {
+	for _, p := range []string{dbPath, dbPath + "-wal", dbPath + "-shm"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("remove %s: %w", p, err)
+		}
+	}
+	return Open(dbPath, dimensions)
+}
@@ -123,3 +123,57 @@ func TestIndexDuplicate(t *testing.T) {
 	count, _ := vdb.Count()
 	assert.Equal(t, 1, count)
 }
+
+func TestIDs(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	vdb, err := Open(dbPath, 4)
+	require.NoError(t, err)
+	defer vdb.Close()
+
+	ctx := context.Background()
+	require.NoError(t, vdb.Index(ctx, "facts/b.md", "b", makeTestVector(4, 0.2)))
+	require.NoError(t, vdb.Index(ctx, "facts/a.md", "a", makeTestVector(4, 0.1)))
+	// Metadata without a vector, as left by an interrupted Index.
+	_, err = vdb.db.Exec(`INSERT INTO vec_meta (memory_id, text) VALUES ('facts/orphan.md', 'x')`)
+	require.NoError(t, err)
+
+	ids, err := vdb.IDs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"facts/a.md", "facts/b.md"}, ids)
+}
+
+func TestRecreate(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	vdb, err := Open(dbPath, 4)
+	require.NoError(t, err)
+	require.NoError(t, vdb.Index(context.Background(), "facts/a.md", "a", makeTestVector(4, 0.1)))
+	require.NoError(t, vdb.Close())
+
+	vdb, err = Recreate(dbPath, 8)
+	require.NoError(t, err)
+	defer vdb.Close()
+	count, err := vdb.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	require.NoError(t, vdb.Index(context.Background(), "facts/a.md", "a", makeTestVector(8, 0.1)))
+}
+
+func TestFingerprint(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	vdb, err := Open(dbPath, 4)
+	require.NoError(t, err)
+	fp, err := vdb.Fingerprint()
+	require.NoError(t, err)
+	assert.Empty(t, fp)
+
+	require.NoError(t, vdb.SetFingerprint("hash|ngram-v1|4"))
+	require.NoError(t, vdb.SetFingerprint("hash|ngram-v1|4"))
+	require.NoError(t, vdb.Close())
+
+	vdb, err = Open(dbPath, 4)
+	require.NoError(t, err)
+	defer vdb.Close()
+	fp, err = vdb.Fingerprint()
+	require.NoError(t, err)
+	assert.Equal(t, "hash|ngram-v1|4", fp)
+}
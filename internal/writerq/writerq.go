@@ -91,6 +91,36 @@ func (q *Queue) Submit(ctx context.Context, sqlStr string, args ...any) error {
 	}
 }
 
+// SubmitAll sends ops to the queue in order and blocks until all of them
+// have executed, returning the first error. Consecutive ops usually share a
+// transaction, but a large slice may span several, so SubmitAll is not
+// atomic. Ops already queued when ctx is cancelled still run.
+func (q *Queue) SubmitAll(ctx context.Context, ops []Op) error {
+	pending := make([]chan error, 0, len(ops))
+	for _, op := range ops {
+		op.result = make(chan error, 1)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case q.ops <- op:
+		}
+		pending = append(pending, op.result)
+	}
+
+	var first error
+	for _, result := range pending {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-result:
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
 // Close signals the writer goroutine to stop, drains remaining operations,
 // and waits for the goroutine to finish. It is safe to call multiple times.
 func (q *Queue) Close() error {
@@ -134,3 +134,29 @@ func TestBackpressureDoesNotDeadlock(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, n, count)
 }
+
+func TestSubmitAll(t *testing.T) {
+	db := openTestDB(t)
+	q := New(db)
+	defer q.Close()
+
+	ops := make([]Op, 250)
+	for i := range ops {
+		ops[i] = Op{SQL: "INSERT INTO items (name) VALUES (?)", Args: []any{"bulk"}}
+	}
+	start := time.Now()
+	require.NoError(t, q.SubmitAll(context.Background(), ops))
+	assert.Less(t, time.Since(start), 2*time.Second, "ops are batched, not flushed one tick at a time")
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM items WHERE name = 'bulk'").Scan(&count))
+	assert.Equal(t, 250, count)
+
+	err := q.SubmitAll(context.Background(), []Op{
+		{SQL: "INSERT INTO items (name) VALUES (?)", Args: []any{"ok"}},
+		{SQL: "INSERT INTO missing (name) VALUES (?)", Args: []any{"bad"}},
+	})
+	assert.Error(t, err)
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM items WHERE name = 'ok'").Scan(&count))
+	assert.Equal(t, 1, count, "a failed op does not undo the others")
+}